@@ -0,0 +1,138 @@
+// Package requestctx describes the API request a call serves, shared by the gateway and
+// the services. The gateway keeps it in the request context and forwards it to the
+// services as gRPC metadata with every call, so their logs and decisions can refer to
+// the original request. The services read it back with UnaryServerInterceptor.
+package requestctx
+
+import (
+	"context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"log"
+)
+
+// Metadata keys the backends read the request from.
+const (
+	RequestIDKey       = "x-request-id"
+	CallerUserIDKey    = "x-caller-user-id"
+	CallerActorIDKey   = "x-caller-actor-id"
+	LocaleKey          = "x-locale"
+	ClientIPKey        = "x-client-ip"
+	ClientUserAgentKey = "x-client-user-agent"
+)
+
+// Info describes an API request. The caller is only known once the request passed
+// middlewares.Authenticate, CallerActorID is set for impersonated requests.
+type Info struct {
+	RequestID     string
+	Locale        string
+	ClientIP      string
+	UserAgent     string
+	CallerUserID  string
+	CallerActorID string
+}
+
+type infoKey struct{}
+
+func NewContext(ctx context.Context, info Info) context.Context {
+	return context.WithValue(ctx, infoKey{}, info)
+}
+
+// FromContext returns the Info stored by NewContext.
+func FromContext(ctx context.Context) (Info, bool) {
+	info, ok := ctx.Value(infoKey{}).(Info)
+	return info, ok
+}
+
+// WithCaller returns a copy of ctx whose Info names the authenticated caller.
+func WithCaller(ctx context.Context, userID string, actorID string) context.Context {
+	info, _ := FromContext(ctx)
+	info.CallerUserID = userID
+	info.CallerActorID = actorID
+	return NewContext(ctx, info)
+}
+
+// UnaryClientInterceptor forwards the Info of the call context as outgoing metadata.
+// Empty fields are left out.
+func UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if info, ok := FromContext(ctx); ok {
+			ctx = metadata.AppendToOutgoingContext(ctx, info.metadataPairs()...)
+		}
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+func (i Info) metadataPairs() []string {
+	var pairs []string
+	for _, field := range [][2]string{
+		{RequestIDKey, i.RequestID},
+		{LocaleKey, i.Locale},
+		{ClientIPKey, i.ClientIP},
+		{ClientUserAgentKey, i.UserAgent},
+		{CallerUserIDKey, i.CallerUserID},
+		{CallerActorIDKey, i.CallerActorID},
+	} {
+		if field[1] != "" {
+			pairs = append(pairs, field[0], field[1])
+		}
+	}
+	return pairs
+}
+
+// UnaryServerInterceptor reads the forwarded metadata into an Info in the call context.
+// Calls whose deadline passed or whose caller went away before they started are not run.
+// Impersonated calls are logged with the actor behind them, and calls that failed on the
+// server's side with their request and caller.
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, call *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if err := ctx.Err(); err != nil {
+			return nil, status.FromContextError(err).Err()
+		}
+
+		info := infoFromMetadata(ctx)
+		ctx = NewContext(ctx, info)
+		if info.CallerActorID != "" {
+			log.Printf("%s called by %s acting as %s (request %s)", call.FullMethod, info.CallerActorID, info.CallerUserID, info.RequestID)
+		}
+
+		response, err := handler(ctx, req)
+		switch status.Code(err) {
+		case codes.Unknown, codes.Internal, codes.DataLoss:
+			log.Printf("%s failed (request %s, caller %s): %v", call.FullMethod, info.RequestID, info.caller(), err)
+		}
+		return response, err
+	}
+}
+
+func infoFromMetadata(ctx context.Context) Info {
+	md, _ := metadata.FromIncomingContext(ctx)
+	first := func(key string) string {
+		if values := md.Get(key); len(values) > 0 {
+			return values[0]
+		}
+		return ""
+	}
+	return Info{
+		RequestID:     first(RequestIDKey),
+		Locale:        first(LocaleKey),
+		ClientIP:      first(ClientIPKey),
+		UserAgent:     first(ClientUserAgentKey),
+		CallerUserID:  first(CallerUserIDKey),
+		CallerActorID: first(CallerActorIDKey),
+	}
+}
+
+// caller names the caller for logs, with the actor of impersonated requests.
+func (i Info) caller() string {
+	switch {
+	case i.CallerUserID == "":
+		return "anonymous"
+	case i.CallerActorID != "":
+		return i.CallerActorID + " as " + i.CallerUserID
+	default:
+		return i.CallerUserID
+	}
+}
@@ -0,0 +1,42 @@
+package requestctx
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestUnaryServerInterceptor_ReadsTheForwardedRequest(t *testing.T) {
+	sent := Info{
+		RequestID:     "req-1234",
+		Locale:        "de-DE",
+		CallerUserID:  "user-id",
+		CallerActorID: "admin-id",
+	}
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(sent.metadataPairs()...))
+
+	var received Info
+	_, err := UnaryServerInterceptor()(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/test/Call"}, func(ctx context.Context, _ any) (any, error) {
+		received, _ = FromContext(ctx)
+		return nil, nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, sent, received)
+	require.Equal(t, "admin-id as user-id", received.caller())
+}
+
+func TestUnaryServerInterceptor_SkipsCallsWhoseCallerWentAway(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := UnaryServerInterceptor()(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/test/Call"}, func(context.Context, any) (any, error) {
+		t.Fatal("the handler ran for a canceled call")
+		return nil, nil
+	})
+	require.Equal(t, codes.Canceled, status.Code(err))
+}
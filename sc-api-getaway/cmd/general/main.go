@@ -10,11 +10,14 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/go-redis/redis/v8"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
+	"soul-connect/pkg/requestctx"
 	"soul-connect/sc-api-getaway/internal/config"
 	"soul-connect/sc-api-getaway/internal/controllers"
 	"soul-connect/sc-api-getaway/internal/generated"
+	"soul-connect/sc-api-getaway/internal/ratelimit"
 	"soul-connect/sc-api-getaway/internal/routers"
 	postpb "soul-connect/sc-post/pkg/postpb"
 )
@@ -26,24 +29,37 @@ func main() {
 		os.Exit(1)
 	}
 
-	conn, err := grpc.Dial(newConfig.GrpcAuthTarget, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	// Every call carries the request id, caller and locale of the API request it serves
+	dialOptions := []grpc.DialOption{
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithUnaryInterceptor(requestctx.UnaryClientInterceptor()),
+	}
+
+	authConn, err := grpc.Dial(newConfig.GrpcAuthTarget, dialOptions...)
 
 	if err != nil {
 		log.Fatalf("failed to connect to Auth Service: %v", err)
 	}
 	defer authConn.Close()
 
-	postConn, err := grpc.NewClient("localhost"+":"+newConfig.GrpcPostPort, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	postConn, err := grpc.NewClient("localhost"+":"+newConfig.GrpcPostPort, dialOptions...)
 	if err != nil {
 		log.Fatalf("failed to connect to Post Service: %v", err)
 	}
 	defer postConn.Close()
 
-	authServiceClient := generated.NewAuthServiceClient(conn)
+	userConn, err := grpc.NewClient(newConfig.GrpcUserTarget, dialOptions...)
+	if err != nil {
+		log.Fatalf("failed to connect to User Service: %v", err)
+	}
+	defer userConn.Close()
+
+	authServiceClient := generated.NewAuthServiceClient(authConn)
 	postServiceClient := postpb.NewPostServiceClient(postConn)
-	newController := controllers.NewController(authServiceClient, postServiceClient)
+	userServiceClient := generated.NewUserServiceClient(userConn)
+	newController := controllers.NewController(authServiceClient, postServiceClient, userServiceClient)
 
-	newRouter := routers.NewRouter(&newConfig, newController)
+	newRouter := routers.NewRouter(&newConfig, newController, authServiceClient, newRateLimiter(&newConfig))
 	newRouter.SetRoutes()
 
 	newServer := &http.Server{
@@ -81,3 +97,25 @@ func main() {
 	log.Println("Server exiting")
 	os.Exit(0)
 }
+
+// newRateLimiter shares rate limits between the gateway instances through Redis. Without
+// REDIS_URL, and whenever Redis cannot be reached, every instance limits on its own.
+func newRateLimiter(cfg *config.Config) ratelimit.Limiter {
+	if cfg.RedisURL == "" {
+		log.Println("REDIS_URL is not set, keeping rate limits in memory")
+		return ratelimit.NewMemoryLimiter()
+	}
+
+	options, err := redis.ParseURL(cfg.RedisURL)
+	if err != nil {
+		log.Fatalf("invalid REDIS_URL: %v", err)
+	}
+	client := redis.NewClient(options)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		log.Printf("cannot connect to redis, keeping rate limits in memory until it is reachable: %v", err)
+	}
+	return ratelimit.NewFallbackLimiter(ratelimit.NewRedisLimiter(client), ratelimit.NewMemoryLimiter())
+}
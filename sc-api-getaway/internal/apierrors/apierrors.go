@@ -0,0 +1,178 @@
+// Package apierrors writes the error responses of the gateway. Every error is answered
+// with the same JSON envelope, whether the gateway rejected the request itself or a
+// backend returned a gRPC status:
+//
+//	{"code": "invalid_argument", "message": "title is required", "fields": [{"field": "title", "description": "title is required"}]}
+//
+// code is the snake_case name of the gRPC status code, fields is only present when the
+// error concerns particular fields of the request.
+package apierrors
+
+import (
+	"github.com/gin-gonic/gin"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// StatusClientClosedRequest is answered when the client went away before the backend
+// finished, as nginx does. It keeps canceled requests apart from failures in access logs.
+const StatusClientClosedRequest = 499
+
+// Response is the body of every error answer.
+type Response struct {
+	Code    string           `json:"code"`
+	Message string           `json:"message"`
+	Fields  []FieldViolation `json:"fields,omitempty"`
+}
+
+// FieldViolation names a request field and what is wrong with it.
+type FieldViolation struct {
+	Field       string `json:"field"`
+	Description string `json:"description"`
+}
+
+// Abort answers with the error envelope and stops the handler chain.
+func Abort(gc *gin.Context, httpStatus int, code codes.Code, message string, fields ...FieldViolation) {
+	gc.AbortWithStatusJSON(httpStatus, Response{
+		Code:    CodeName(code),
+		Message: message,
+		Fields:  fields,
+	})
+}
+
+// InvalidRequest answers a body or query that could not be parsed.
+func InvalidRequest(gc *gin.Context) {
+	Abort(gc, http.StatusBadRequest, codes.InvalidArgument, "invalid request")
+}
+
+// BadRequest answers a request the gateway rejected for a reason that is not tied to a single field.
+func BadRequest(gc *gin.Context, message string) {
+	Abort(gc, http.StatusBadRequest, codes.InvalidArgument, message)
+}
+
+// InvalidField answers a request with a field the gateway rejected. problem completes
+// the field name to the message, like "is required".
+func InvalidField(gc *gin.Context, field string, problem string) {
+	message := field + " " + problem
+	Abort(gc, http.StatusBadRequest, codes.InvalidArgument, message, FieldViolation{
+		Field:       field,
+		Description: message,
+	})
+}
+
+// RequiredField answers a request that is missing a field.
+func RequiredField(gc *gin.Context, field string) {
+	InvalidField(gc, field, "is required")
+}
+
+// Unauthenticated answers a request without valid credentials.
+func Unauthenticated(gc *gin.Context, message string) {
+	Abort(gc, http.StatusUnauthorized, codes.Unauthenticated, message)
+}
+
+// PermissionDenied answers a caller that is not allowed to do what it asked for.
+func PermissionDenied(gc *gin.Context, message string) {
+	Abort(gc, http.StatusForbidden, codes.PermissionDenied, message)
+}
+
+// TooManyRequests answers a caller that exceeded a rate limit. retryAfter is sent as
+// Retry-After in whole seconds.
+func TooManyRequests(gc *gin.Context, retryAfter time.Duration) {
+	setRetryAfter(gc, retryAfter)
+	Abort(gc, http.StatusTooManyRequests, codes.ResourceExhausted, "too many requests, try again later")
+}
+
+// FromGRPC answers with the HTTP equivalent of the gRPC status of err. Field violations
+// the backend attached are passed on, and so is the retry delay of ResourceExhausted
+// as Retry-After. Internal errors are logged and answered without their message, which
+// may leak details of the backend.
+func FromGRPC(gc *gin.Context, err error) {
+	st := status.Convert(err)
+	httpStatus := HTTPStatus(st.Code())
+
+	message := st.Message()
+	switch httpStatus {
+	case http.StatusInternalServerError:
+		log.Printf("%s %s failed: %v", gc.Request.Method, gc.FullPath(), err)
+		message = "internal server error"
+	case http.StatusServiceUnavailable:
+		log.Printf("%s %s failed: %v", gc.Request.Method, gc.FullPath(), err)
+		message = "service is temporarily unavailable"
+	case http.StatusGatewayTimeout:
+		message = "the request timed out"
+	}
+
+	var fields []FieldViolation
+	for _, detail := range st.Details() {
+		switch detail := detail.(type) {
+		case *errdetails.BadRequest:
+			for _, violation := range detail.GetFieldViolations() {
+				fields = append(fields, FieldViolation{
+					Field:       violation.GetField(),
+					Description: violation.GetDescription(),
+				})
+			}
+		case *errdetails.RetryInfo:
+			if delay := detail.GetRetryDelay(); delay != nil {
+				setRetryAfter(gc, delay.AsDuration())
+			}
+		}
+	}
+
+	Abort(gc, httpStatus, st.Code(), message, fields...)
+}
+
+func setRetryAfter(gc *gin.Context, delay time.Duration) {
+	seconds := int(math.Ceil(delay.Seconds()))
+	gc.Header("Retry-After", strconv.Itoa(max(seconds, 1)))
+}
+
+// HTTPStatus returns the HTTP status a gRPC status code is answered with.
+func HTTPStatus(code codes.Code) int {
+	switch code {
+	case codes.InvalidArgument, codes.OutOfRange:
+		return http.StatusBadRequest
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.AlreadyExists, codes.Aborted, codes.FailedPrecondition:
+		return http.StatusConflict
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.Canceled:
+		return StatusClientClosedRequest
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	}
+	return http.StatusInternalServerError
+}
+
+// CodeName returns the snake_case name of a gRPC status code, like not_found.
+func CodeName(code codes.Code) string {
+	var name strings.Builder
+	for i, r := range code.String() {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				name.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		name.WriteRune(r)
+	}
+	return name.String()
+}
@@ -3,6 +3,7 @@ package config
 import (
 	"errors"
 	"log"
+	"time"
 
 	"github.com/spf13/viper"
 )
@@ -12,7 +13,63 @@ type Config struct {
 	ServerPort     string `mapstructure:"SERVER_PORT"`
 	DBSource       string `mapstructure:"DB_SOURCE"`
 	GrpcAuthTarget string `mapstructure:"GRPC_AUTH_PORT"`
+	GrpcPostPort   string `mapstructure:"GRPC_POST_PORT"`
+	GrpcUserTarget string `mapstructure:"GRPC_USER_TARGET"`
 	WebappBaseUrl  string `mapstructure:"WEBAPP_BASE_URL"`
+	// RequestTimeout bounds the backend calls of a request, route groups may override it
+	RequestTimeout     time.Duration `mapstructure:"REQUEST_TIMEOUT"`
+	AuthRequestTimeout time.Duration `mapstructure:"AUTH_REQUEST_TIMEOUT"`
+	PostRequestTimeout time.Duration `mapstructure:"POST_REQUEST_TIMEOUT"`
+	UserRequestTimeout time.Duration `mapstructure:"USER_REQUEST_TIMEOUT"`
+	// RedisURL points to the Redis that shares rate limits between instances, without
+	// it every instance limits on its own
+	RedisURL string `mapstructure:"REDIS_URL"`
+	// TrustedProxies lists the addresses and networks of proxies, like the bundled nginx,
+	// whose X-Forwarded-For names the client IP. Without it the peer address is used.
+	TrustedProxies []string `mapstructure:"TRUSTED_PROXIES"`
+	// Rate limits are written as <requests>/<period>, like 5/1m, or off. Unset limits
+	// use the defaults below.
+	AuthRateLimit      string `mapstructure:"AUTH_RATE_LIMIT"`
+	LoginRateLimit     string `mapstructure:"LOGIN_RATE_LIMIT"`
+	PostRateLimit      string `mapstructure:"POST_RATE_LIMIT"`
+	PostWriteRateLimit string `mapstructure:"POST_WRITE_RATE_LIMIT"`
+	UserRateLimit      string `mapstructure:"USER_RATE_LIMIT"`
+	UserWriteRateLimit string `mapstructure:"USER_WRITE_RATE_LIMIT"`
+}
+
+// DefaultRequestTimeout applies when REQUEST_TIMEOUT is not set.
+const DefaultRequestTimeout = 10 * time.Second
+
+// Default rate limits of the route groups. Anonymous requests are limited per client IP
+// and authenticated ones per user, logins tighter against credential stuffing, and post
+// and profile writes against spam.
+const (
+	DefaultAuthRateLimit      = "60/1m"
+	DefaultLoginRateLimit     = "10/1m"
+	DefaultPostRateLimit      = "300/1m"
+	DefaultPostWriteRateLimit = "30/1m"
+	DefaultUserRateLimit      = "300/1m"
+	DefaultUserWriteRateLimit = "30/1m"
+)
+
+// Timeout returns the timeout of a route group. Groups without their own fall back to
+// REQUEST_TIMEOUT.
+func (c *Config) Timeout(groupTimeout time.Duration) time.Duration {
+	if groupTimeout > 0 {
+		return groupTimeout
+	}
+	if c.RequestTimeout > 0 {
+		return c.RequestTimeout
+	}
+	return DefaultRequestTimeout
+}
+
+// RateLimit returns the rate limit of a route group, or defaultLimit when the group has none.
+func (c *Config) RateLimit(groupLimit string, defaultLimit string) string {
+	if groupLimit != "" {
+		return groupLimit
+	}
+	return defaultLimit
 }
 
 func LoadConfig(path string) (config Config, err error) {
@@ -1,12 +1,15 @@
 package controllers
 
 import (
-	"context"
 	"fmt"
 	"github.com/gin-gonic/gin"
+	"google.golang.org/protobuf/types/known/emptypb"
 	"net/http"
+	"soul-connect/sc-api-getaway/internal/apierrors"
 	"soul-connect/sc-api-getaway/internal/generated"
+	"strconv"
 	"strings"
+	"time"
 )
 
 type AuthController struct {
@@ -23,30 +26,30 @@ func (c *AuthController) Register(gc *gin.Context) {
 	var req generated.RegisterUserRequest
 
 	if err := gc.ShouldBindJSON(&req); err != nil {
-		gc.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		apierrors.InvalidRequest(gc)
 		return
 	}
 
 	if req.Username == "" {
-		gc.JSON(http.StatusBadRequest, gin.H{"error": "username is required"})
+		apierrors.RequiredField(gc, "username")
 		return
 	}
 
 	if req.Email == "" {
-		gc.JSON(http.StatusBadRequest, gin.H{"error": "email is required"})
+		apierrors.RequiredField(gc, "email")
 		return
 	}
 
 	if req.Password == "" {
-		gc.JSON(http.StatusBadRequest, gin.H{"error": "password is required"})
+		apierrors.RequiredField(gc, "password")
 		return
 	}
 
-	ctx := context.Background()
+	ctx := gc.Request.Context()
 
 	response, err := c.client.Register(ctx, &req)
 	if err != nil {
-		gc.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		apierrors.FromGRPC(gc, err)
 		return
 	}
 
@@ -57,41 +60,557 @@ func (c *AuthController) Login(gc *gin.Context) {
 	var req generated.LoginUserRequest
 
 	if err := gc.ShouldBindJSON(&req); err != nil {
-		gc.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		apierrors.InvalidRequest(gc)
 		return
 	}
 
-	ctx := context.Background()
+	if req.Username == "" && req.Email == "" {
+		apierrors.BadRequest(gc, "username or email is required")
+		return
+	}
+
+	if req.Password == "" {
+		apierrors.RequiredField(gc, "password")
+		return
+	}
+
+	ctx := gc.Request.Context()
 
 	response, err := c.client.Login(ctx, &req)
 	if err != nil {
-		gc.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		apierrors.FromGRPC(gc, err)
 		return
 	}
 
 	gc.JSON(http.StatusOK, response)
 }
 
+func (c *AuthController) Refresh(gc *gin.Context) {
+	var req generated.RefreshTokenRequest
+
+	// The refresh token is accepted either as a bearer token or in the JSON body
+	authHeader := gc.GetHeader("Authorization")
+	if authHeader != "" {
+		req.RefreshToken = strings.TrimPrefix(authHeader, "Bearer ")
+	} else if err := gc.ShouldBindJSON(&req); err != nil {
+		apierrors.InvalidRequest(gc)
+		return
+	}
+
+	if req.RefreshToken == "" {
+		apierrors.RequiredField(gc, "refresh_token")
+		return
+	}
+
+	ctx := gc.Request.Context()
+
+	response, err := c.client.RefreshToken(ctx, &req)
+	if err != nil {
+		apierrors.FromGRPC(gc, err)
+		return
+	}
+
+	gc.JSON(http.StatusOK, response)
+}
+
+func (c *AuthController) JWKS(gc *gin.Context) {
+	ctx := gc.Request.Context()
+
+	response, err := c.client.GetJWKS(ctx, &emptypb.Empty{})
+	if err != nil {
+		apierrors.FromGRPC(gc, err)
+		return
+	}
+
+	gc.JSON(http.StatusOK, response)
+}
+
+func (c *AuthController) RequestPasswordReset(gc *gin.Context) {
+	var req generated.RequestPasswordResetRequest
+
+	if err := gc.ShouldBindJSON(&req); err != nil {
+		apierrors.InvalidRequest(gc)
+		return
+	}
+
+	if req.Email == "" {
+		apierrors.RequiredField(gc, "email")
+		return
+	}
+
+	ctx := gc.Request.Context()
+
+	_, err := c.client.RequestPasswordReset(ctx, &req)
+	if err != nil {
+		apierrors.FromGRPC(gc, err)
+		return
+	}
+
+	// The response is the same whether or not the email belongs to an account
+	gc.JSON(http.StatusOK, "If the email is registered, a password reset link has been sent")
+}
+
+func (c *AuthController) ResetPassword(gc *gin.Context) {
+	var req generated.ResetPasswordRequest
+
+	if err := gc.ShouldBindJSON(&req); err != nil {
+		apierrors.InvalidRequest(gc)
+		return
+	}
+
+	if req.Token == "" {
+		apierrors.RequiredField(gc, "token")
+		return
+	}
+
+	if req.NewPassword == "" {
+		apierrors.RequiredField(gc, "new_password")
+		return
+	}
+
+	ctx := gc.Request.Context()
+
+	_, err := c.client.ResetPassword(ctx, &req)
+	if err != nil {
+		apierrors.FromGRPC(gc, err)
+		return
+	}
+
+	gc.JSON(http.StatusOK, "Password has been reset")
+}
+
+func (c *AuthController) ChangePassword(gc *gin.Context) {
+	token, ok := bearerToken(gc)
+	if !ok {
+		return
+	}
+
+	var req generated.ChangePasswordRequest
+	if err := gc.ShouldBindJSON(&req); err != nil {
+		apierrors.InvalidRequest(gc)
+		return
+	}
+	req.AccessToken = token
+
+	if req.OldPassword == "" {
+		apierrors.RequiredField(gc, "old_password")
+		return
+	}
+
+	if req.NewPassword == "" {
+		apierrors.RequiredField(gc, "new_password")
+		return
+	}
+
+	ctx := gc.Request.Context()
+
+	_, err := c.client.ChangePassword(ctx, &req)
+	if err != nil {
+		apierrors.FromGRPC(gc, err)
+		return
+	}
+
+	gc.JSON(http.StatusOK, "Password has been changed")
+}
+
+func (c *AuthController) VerifyEmail(gc *gin.Context) {
+	var req generated.VerifyEmailRequest
+
+	if err := gc.ShouldBindJSON(&req); err != nil {
+		apierrors.InvalidRequest(gc)
+		return
+	}
+
+	if req.Token == "" {
+		apierrors.RequiredField(gc, "token")
+		return
+	}
+
+	ctx := gc.Request.Context()
+
+	_, err := c.client.VerifyEmail(ctx, &req)
+	if err != nil {
+		apierrors.FromGRPC(gc, err)
+		return
+	}
+
+	gc.JSON(http.StatusOK, "Email has been verified")
+}
+
+func (c *AuthController) ResendVerificationEmail(gc *gin.Context) {
+	var req generated.ResendVerificationEmailRequest
+
+	if err := gc.ShouldBindJSON(&req); err != nil {
+		apierrors.InvalidRequest(gc)
+		return
+	}
+
+	if req.Email == "" {
+		apierrors.RequiredField(gc, "email")
+		return
+	}
+
+	ctx := gc.Request.Context()
+
+	_, err := c.client.ResendVerificationEmail(ctx, &req)
+	if err != nil {
+		apierrors.FromGRPC(gc, err)
+		return
+	}
+
+	gc.JSON(http.StatusOK, "If the email is registered and not verified yet, a verification link has been sent")
+}
+
+func (c *AuthController) VerifySecondFactor(gc *gin.Context) {
+	var req generated.VerifySecondFactorRequest
+
+	if err := gc.ShouldBindJSON(&req); err != nil {
+		apierrors.InvalidRequest(gc)
+		return
+	}
+
+	if req.ChallengeToken == "" {
+		apierrors.RequiredField(gc, "challenge_token")
+		return
+	}
+
+	if req.Code == "" {
+		apierrors.RequiredField(gc, "code")
+		return
+	}
+
+	ctx := gc.Request.Context()
+
+	response, err := c.client.VerifySecondFactor(ctx, &req)
+	if err != nil {
+		apierrors.FromGRPC(gc, err)
+		return
+	}
+
+	gc.JSON(http.StatusOK, response)
+}
+
+func (c *AuthController) RequestMagicLink(gc *gin.Context) {
+	var req generated.RequestMagicLinkRequest
+
+	if err := gc.ShouldBindJSON(&req); err != nil {
+		apierrors.InvalidRequest(gc)
+		return
+	}
+
+	if req.Email == "" {
+		apierrors.RequiredField(gc, "email")
+		return
+	}
+
+	ctx := gc.Request.Context()
+
+	_, err := c.client.RequestMagicLink(ctx, &req)
+	if err != nil {
+		apierrors.FromGRPC(gc, err)
+		return
+	}
+
+	// The response is the same whether or not the email belongs to an account
+	gc.JSON(http.StatusOK, "If the email is registered, a sign-in link has been sent")
+}
+
+func (c *AuthController) ConsumeMagicLink(gc *gin.Context) {
+	var req generated.ConsumeMagicLinkRequest
+
+	if err := gc.ShouldBindJSON(&req); err != nil {
+		apierrors.InvalidRequest(gc)
+		return
+	}
+
+	if req.Token == "" {
+		apierrors.RequiredField(gc, "token")
+		return
+	}
+
+	ctx := gc.Request.Context()
+
+	response, err := c.client.ConsumeMagicLink(ctx, &req)
+	if err != nil {
+		apierrors.FromGRPC(gc, err)
+		return
+	}
+
+	gc.JSON(http.StatusOK, response)
+}
+
+func (c *AuthController) EnrollTwoFactor(gc *gin.Context) {
+	token, ok := bearerToken(gc)
+	if !ok {
+		return
+	}
+
+	ctx := gc.Request.Context()
+
+	response, err := c.client.EnrollTwoFactor(ctx, &generated.EnrollTwoFactorRequest{
+		AccessToken: token,
+	})
+	if err != nil {
+		apierrors.FromGRPC(gc, err)
+		return
+	}
+
+	gc.JSON(http.StatusOK, response)
+}
+
+func (c *AuthController) ConfirmTwoFactor(gc *gin.Context) {
+	token, ok := bearerToken(gc)
+	if !ok {
+		return
+	}
+
+	var req generated.ConfirmTwoFactorRequest
+	if err := gc.ShouldBindJSON(&req); err != nil {
+		apierrors.InvalidRequest(gc)
+		return
+	}
+	req.AccessToken = token
+
+	if req.Code == "" {
+		apierrors.RequiredField(gc, "code")
+		return
+	}
+
+	ctx := gc.Request.Context()
+
+	_, err := c.client.ConfirmTwoFactor(ctx, &req)
+	if err != nil {
+		apierrors.FromGRPC(gc, err)
+		return
+	}
+
+	gc.JSON(http.StatusOK, "Two-factor authentication has been enabled")
+}
+
+func (c *AuthController) DisableTwoFactor(gc *gin.Context) {
+	token, ok := bearerToken(gc)
+	if !ok {
+		return
+	}
+
+	var req generated.DisableTwoFactorRequest
+	if err := gc.ShouldBindJSON(&req); err != nil {
+		apierrors.InvalidRequest(gc)
+		return
+	}
+	req.AccessToken = token
+
+	ctx := gc.Request.Context()
+
+	_, err := c.client.DisableTwoFactor(ctx, &req)
+	if err != nil {
+		apierrors.FromGRPC(gc, err)
+		return
+	}
+
+	gc.JSON(http.StatusOK, "Two-factor authentication has been disabled")
+}
+
+func (c *AuthController) ListSessions(gc *gin.Context) {
+	token, ok := bearerToken(gc)
+	if !ok {
+		return
+	}
+
+	ctx := gc.Request.Context()
+
+	response, err := c.client.ListSessions(ctx, &generated.ListSessionsRequest{
+		AccessToken: token,
+	})
+	if err != nil {
+		apierrors.FromGRPC(gc, err)
+		return
+	}
+
+	gc.JSON(http.StatusOK, response)
+}
+
+func (c *AuthController) RevokeSession(gc *gin.Context) {
+	token, ok := bearerToken(gc)
+	if !ok {
+		return
+	}
+
+	ctx := gc.Request.Context()
+
+	_, err := c.client.RevokeSession(ctx, &generated.RevokeSessionRequest{
+		AccessToken: token,
+		SessionId:   gc.Param("session_id"),
+	})
+	if err != nil {
+		apierrors.FromGRPC(gc, err)
+		return
+	}
+
+	gc.JSON(http.StatusOK, "Session has been revoked")
+}
+
+func (c *AuthController) GetLoginHistory(gc *gin.Context) {
+	token, ok := bearerToken(gc)
+	if !ok {
+		return
+	}
+
+	var limit int64
+	if raw := gc.Query("limit"); raw != "" {
+		parsed, err := strconv.ParseInt(raw, 10, 32)
+		if err != nil || parsed < 1 {
+			apierrors.InvalidField(gc, "limit", "must be a positive number")
+			return
+		}
+		limit = parsed
+	}
+
+	ctx := gc.Request.Context()
+
+	response, err := c.client.GetLoginHistory(ctx, &generated.GetLoginHistoryRequest{
+		AccessToken: token,
+		Limit:       int32(limit),
+	})
+	if err != nil {
+		apierrors.FromGRPC(gc, err)
+		return
+	}
+
+	gc.JSON(http.StatusOK, response)
+}
+
+// ExportLoginAttempts downloads the login audit log as CSV (the default) or JSON lines.
+// from and to are RFC 3339 timestamps, both optional.
+func (c *AuthController) ExportLoginAttempts(gc *gin.Context) {
+	token, ok := bearerToken(gc)
+	if !ok {
+		return
+	}
+
+	req := generated.ExportLoginAttemptsRequest{
+		AccessToken: token,
+		Format:      gc.DefaultQuery("format", "csv"),
+		Username:    gc.Query("username"),
+	}
+	for param, target := range map[string]*int64{"from": &req.From, "to": &req.To} {
+		raw := gc.Query(param)
+		if raw == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			apierrors.InvalidField(gc, param, "must be an RFC 3339 timestamp")
+			return
+		}
+		*target = parsed.Unix()
+	}
+
+	ctx := gc.Request.Context()
+
+	response, err := c.client.ExportLoginAttempts(ctx, &req)
+	if err != nil {
+		apierrors.FromGRPC(gc, err)
+		return
+	}
+
+	gc.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", response.Filename))
+	gc.Data(http.StatusOK, response.ContentType, response.Content)
+}
+
+func (c *AuthController) CreatePersonalAccessToken(gc *gin.Context) {
+	token, ok := bearerToken(gc)
+	if !ok {
+		return
+	}
+
+	var req generated.CreatePersonalAccessTokenRequest
+	if err := gc.ShouldBindJSON(&req); err != nil {
+		apierrors.InvalidRequest(gc)
+		return
+	}
+	req.AccessToken = token
+
+	if req.Name == "" {
+		apierrors.RequiredField(gc, "name")
+		return
+	}
+
+	if len(req.Scopes) == 0 {
+		apierrors.RequiredField(gc, "scopes")
+		return
+	}
+
+	ctx := gc.Request.Context()
+
+	response, err := c.client.CreatePersonalAccessToken(ctx, &req)
+	if err != nil {
+		apierrors.FromGRPC(gc, err)
+		return
+	}
+
+	gc.JSON(http.StatusCreated, response)
+}
+
+func (c *AuthController) ListPersonalAccessTokens(gc *gin.Context) {
+	token, ok := bearerToken(gc)
+	if !ok {
+		return
+	}
+
+	ctx := gc.Request.Context()
+
+	response, err := c.client.ListPersonalAccessTokens(ctx, &generated.ListPersonalAccessTokensRequest{
+		AccessToken: token,
+	})
+	if err != nil {
+		apierrors.FromGRPC(gc, err)
+		return
+	}
+
+	gc.JSON(http.StatusOK, response)
+}
+
+func (c *AuthController) RevokePersonalAccessToken(gc *gin.Context) {
+	token, ok := bearerToken(gc)
+	if !ok {
+		return
+	}
+
+	ctx := gc.Request.Context()
+
+	_, err := c.client.RevokePersonalAccessToken(ctx, &generated.RevokePersonalAccessTokenRequest{
+		AccessToken: token,
+		TokenId:     gc.Param("token_id"),
+	})
+	if err != nil {
+		apierrors.FromGRPC(gc, err)
+		return
+	}
+
+	gc.JSON(http.StatusOK, "Personal access token has been revoked")
+}
+
 func (c *AuthController) Logout(gc *gin.Context) {
 	authHeader := gc.GetHeader("Authorization")
 	if authHeader == "" {
-		gc.JSON(http.StatusBadRequest, gin.H{"error": "Authorization header is missing"})
+		apierrors.BadRequest(gc, "Authorization header is missing")
 		return
 	}
 
 	token := strings.TrimPrefix(authHeader, "Bearer ")
 	if token == "" {
-		gc.JSON(http.StatusBadRequest, gin.H{"error": "Invalid token"})
+		apierrors.BadRequest(gc, "Invalid token")
 		return
 	}
 
-	ctx := context.Background()
+	ctx := gc.Request.Context()
 
 	_, err := c.client.Logout(ctx, &generated.LogoutUserRequest{
 		Token: token,
 	})
 	if err != nil {
-		gc.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		apierrors.FromGRPC(gc, err)
 		return
 	}
 
@@ -99,16 +618,18 @@ func (c *AuthController) Logout(gc *gin.Context) {
 }
 
 func (c *AuthController) LogoutFromAllDevices(gc *gin.Context) {
-	var req generated.LogoutUserFromAllDevicesRequest
-	if err := gc.ShouldBindJSON(&req); err != nil {
-		gc.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+	token, ok := bearerToken(gc)
+	if !ok {
+		return
 	}
 
-	ctx := context.Background()
+	ctx := gc.Request.Context()
 
-	_, err := c.client.LogoutFromAllDevices(ctx, &req)
+	_, err := c.client.LogoutFromAllDevices(ctx, &generated.LogoutUserFromAllDevicesRequest{
+		AccessToken: token,
+	})
 	if err != nil {
-		gc.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		apierrors.FromGRPC(gc, err)
 		return
 	}
 
@@ -116,21 +637,177 @@ func (c *AuthController) LogoutFromAllDevices(gc *gin.Context) {
 }
 
 func (c *AuthController) DeleteUser(gc *gin.Context) {
-	fmt.Println(gc.Params)
+	token, ok := bearerToken(gc)
+	if !ok {
+		return
+	}
 
 	userId := gc.Param("user_id")
-	fmt.Println(userId)
 
-	ctx := context.Background()
+	ctx := gc.Request.Context()
 
 	_, err := c.client.DeleteUser(ctx, &generated.DeleteUserRequest{
-		UserId: userId,
+		UserId:      userId,
+		AccessToken: token,
 	})
 
 	if err != nil {
-		gc.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		apierrors.FromGRPC(gc, err)
 		return
 	}
 
 	gc.JSON(http.StatusOK, "Successful deleted user")
 }
+
+func (c *AuthController) UnlockAccount(gc *gin.Context) {
+	token, ok := bearerToken(gc)
+	if !ok {
+		return
+	}
+
+	var req generated.UnlockAccountRequest
+	if err := gc.ShouldBindJSON(&req); err != nil {
+		apierrors.InvalidRequest(gc)
+		return
+	}
+	req.AccessToken = token
+
+	if req.Username == "" {
+		apierrors.RequiredField(gc, "username")
+		return
+	}
+
+	ctx := gc.Request.Context()
+
+	_, err := c.client.UnlockAccount(ctx, &req)
+	if err != nil {
+		apierrors.FromGRPC(gc, err)
+		return
+	}
+
+	gc.JSON(http.StatusOK, "Account has been unlocked")
+}
+
+func (c *AuthController) GrantRole(gc *gin.Context) {
+	token, ok := bearerToken(gc)
+	if !ok {
+		return
+	}
+
+	var req generated.GrantRoleRequest
+	if err := gc.ShouldBindJSON(&req); err != nil {
+		apierrors.InvalidRequest(gc)
+		return
+	}
+	req.AccessToken = token
+	req.UserId = gc.Param("user_id")
+
+	if req.Role == "" {
+		apierrors.RequiredField(gc, "role")
+		return
+	}
+
+	ctx := gc.Request.Context()
+
+	_, err := c.client.GrantRole(ctx, &req)
+	if err != nil {
+		apierrors.FromGRPC(gc, err)
+		return
+	}
+
+	gc.JSON(http.StatusOK, "Role has been granted")
+}
+
+func (c *AuthController) RevokeRole(gc *gin.Context) {
+	token, ok := bearerToken(gc)
+	if !ok {
+		return
+	}
+
+	ctx := gc.Request.Context()
+
+	_, err := c.client.RevokeRole(ctx, &generated.RevokeRoleRequest{
+		AccessToken: token,
+		UserId:      gc.Param("user_id"),
+		Role:        gc.Param("role"),
+	})
+	if err != nil {
+		apierrors.FromGRPC(gc, err)
+		return
+	}
+
+	gc.JSON(http.StatusOK, "Role has been revoked")
+}
+
+// Impersonate hands an admin a short-lived token to act as the user. Every write made
+// with it is flagged by the gateway, see middlewares.Authenticate.
+func (c *AuthController) Impersonate(gc *gin.Context) {
+	token, ok := bearerToken(gc)
+	if !ok {
+		return
+	}
+
+	var req generated.ImpersonateRequest
+	if err := gc.ShouldBindJSON(&req); err != nil {
+		apierrors.InvalidRequest(gc)
+		return
+	}
+	req.AccessToken = token
+	req.UserId = gc.Param("user_id")
+
+	if strings.TrimSpace(req.Reason) == "" {
+		apierrors.RequiredField(gc, "reason")
+		return
+	}
+
+	ctx := gc.Request.Context()
+
+	response, err := c.client.Impersonate(ctx, &req)
+	if err != nil {
+		apierrors.FromGRPC(gc, err)
+		return
+	}
+
+	gc.Header("Cache-Control", "no-store")
+	gc.JSON(http.StatusCreated, response)
+}
+
+func (c *AuthController) ListImpersonationSessions(gc *gin.Context) {
+	token, ok := bearerToken(gc)
+	if !ok {
+		return
+	}
+
+	var limit int64
+	if raw := gc.Query("limit"); raw != "" {
+		parsed, err := strconv.ParseInt(raw, 10, 32)
+		if err != nil || parsed < 1 {
+			apierrors.InvalidField(gc, "limit", "must be a positive number")
+			return
+		}
+		limit = parsed
+	}
+
+	ctx := gc.Request.Context()
+
+	response, err := c.client.ListImpersonationSessions(ctx, &generated.ListImpersonationSessionsRequest{
+		AccessToken: token,
+		Limit:       int32(limit),
+	})
+	if err != nil {
+		apierrors.FromGRPC(gc, err)
+		return
+	}
+
+	gc.JSON(http.StatusOK, response)
+}
+
+// bearerToken reads the access token from the Authorization header and answers 401 when it is missing.
+func bearerToken(gc *gin.Context) (string, bool) {
+	token := strings.TrimPrefix(gc.GetHeader("Authorization"), "Bearer ")
+	if token == "" {
+		apierrors.Unauthenticated(gc, "Authorization header is missing")
+		return "", false
+	}
+	return token, true
+}
@@ -1,19 +1,39 @@
 package controllers
 
 import (
+	"github.com/gin-gonic/gin"
+	"soul-connect/sc-api-getaway/internal/apierrors"
 	"soul-connect/sc-api-getaway/internal/generated"
+	"soul-connect/sc-api-getaway/internal/middlewares"
 	postpb "soul-connect/sc-post/pkg/postpb"
 )
 
 type Controller struct {
 	AuthController *AuthController
 	PostController *PostController
+	UserController *UserController
 }
 
-func NewController(authClient generated.AuthServiceClient, postClient postpb.PostServiceClient) *Controller {
+func NewController(authClient generated.AuthServiceClient, postClient postpb.PostServiceClient, userClient generated.UserServiceClient) *Controller {
 	return &Controller{
 		AuthController: NewAuthController(authClient),
-		PostController: NewPostController(postClient),
+		PostController: NewPostController(postClient, userClient),
+		UserController: NewUserController(userClient),
+	}
+}
 
+// actorID returns the user a write acts for, which is always the authenticated caller.
+// Clients may still name the user in the body, but only as themselves: a different id is
+// answered with 403. Must run behind middlewares.Authenticate.
+func actorID(gc *gin.Context, claimed string) (string, bool) {
+	callerID := middlewares.CallerUserID(gc)
+	if callerID == "" {
+		apierrors.Unauthenticated(gc, "authentication required")
+		return "", false
+	}
+	if claimed != "" && claimed != callerID {
+		apierrors.PermissionDenied(gc, "user_id does not match the authenticated user")
+		return "", false
 	}
+	return callerID, true
 }
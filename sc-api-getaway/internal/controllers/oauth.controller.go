@@ -0,0 +1,270 @@
+package controllers
+
+import (
+	"github.com/gin-gonic/gin"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"net/http"
+	"net/url"
+	"soul-connect/sc-api-getaway/internal/apierrors"
+	"soul-connect/sc-api-getaway/internal/generated"
+	"strings"
+)
+
+// oauthAuthorizePayload carries the parameters of the authorization request the user
+// approved, as the client sent them. scope is space separated like in the OAuth request.
+type oauthAuthorizePayload struct {
+	ClientID            string `json:"client_id"`
+	RedirectURI         string `json:"redirect_uri"`
+	Scope               string `json:"scope"`
+	State               string `json:"state"`
+	CodeChallenge       string `json:"code_challenge"`
+	CodeChallengeMethod string `json:"code_challenge_method"`
+}
+
+// GetOAuthClient returns what the consent screen shows about an app.
+func (c *AuthController) GetOAuthClient(gc *gin.Context) {
+	ctx := gc.Request.Context()
+
+	response, err := c.client.GetOAuthClient(ctx, &generated.GetOAuthClientRequest{
+		ClientId: gc.Param("client_id"),
+	})
+	if err != nil {
+		apierrors.FromGRPC(gc, err)
+		return
+	}
+
+	gc.JSON(http.StatusOK, response)
+}
+
+// AuthorizeOAuthClient is called by the consent screen once the signed-in user approved
+// an authorization request. The answer tells the webapp where to send the user next.
+func (c *AuthController) AuthorizeOAuthClient(gc *gin.Context) {
+	token, ok := bearerToken(gc)
+	if !ok {
+		return
+	}
+
+	var payload oauthAuthorizePayload
+	if err := gc.ShouldBindJSON(&payload); err != nil {
+		writeOAuthProtocolError(gc, http.StatusBadRequest, "invalid_request", "Invalid request")
+		return
+	}
+
+	if payload.ClientID == "" || payload.RedirectURI == "" {
+		writeOAuthProtocolError(gc, http.StatusBadRequest, "invalid_request", "client_id and redirect_uri are required")
+		return
+	}
+
+	ctx := gc.Request.Context()
+
+	response, err := c.client.AuthorizeOAuthClient(ctx, &generated.AuthorizeOAuthClientRequest{
+		AccessToken:         token,
+		ClientId:            payload.ClientID,
+		RedirectUri:         payload.RedirectURI,
+		Scopes:              strings.Fields(payload.Scope),
+		State:               payload.State,
+		CodeChallenge:       payload.CodeChallenge,
+		CodeChallengeMethod: payload.CodeChallengeMethod,
+	})
+	if err != nil {
+		writeOAuthError(gc, err)
+		return
+	}
+
+	gc.JSON(http.StatusOK, gin.H{
+		"code":        response.Code,
+		"redirect_to": response.RedirectTo,
+		"scope":       strings.Join(response.Scopes, " "),
+	})
+}
+
+// OAuthToken is the token endpoint of RFC 6749. It takes a form encoded body, client
+// credentials are read from HTTP basic auth or from the body.
+func (c *AuthController) OAuthToken(gc *gin.Context) {
+	req := generated.ExchangeOAuthTokenRequest{
+		GrantType:    gc.PostForm("grant_type"),
+		ClientId:     gc.PostForm("client_id"),
+		ClientSecret: gc.PostForm("client_secret"),
+		Code:         gc.PostForm("code"),
+		RedirectUri:  gc.PostForm("redirect_uri"),
+		CodeVerifier: gc.PostForm("code_verifier"),
+		RefreshToken: gc.PostForm("refresh_token"),
+	}
+
+	// Basic auth credentials are form encoded before they are joined
+	if username, password, ok := gc.Request.BasicAuth(); ok {
+		clientID, idErr := url.QueryUnescape(username)
+		clientSecret, secretErr := url.QueryUnescape(password)
+		if idErr != nil || secretErr != nil {
+			writeOAuthProtocolError(gc, http.StatusUnauthorized, "invalid_client", "malformed client credentials")
+			return
+		}
+		req.ClientId = clientID
+		req.ClientSecret = clientSecret
+	}
+
+	if req.GrantType == "" || req.ClientId == "" {
+		writeOAuthProtocolError(gc, http.StatusBadRequest, "invalid_request", "grant_type and client_id are required")
+		return
+	}
+
+	ctx := gc.Request.Context()
+
+	response, err := c.client.ExchangeOAuthToken(ctx, &req)
+	if err != nil {
+		writeOAuthError(gc, err)
+		return
+	}
+
+	gc.Header("Cache-Control", "no-store")
+	gc.JSON(http.StatusOK, gin.H{
+		"access_token":  response.AccessToken,
+		"token_type":    "Bearer",
+		"expires_in":    response.ExpiresIn,
+		"refresh_token": response.RefreshToken,
+		"scope":         strings.Join(response.Scopes, " "),
+	})
+}
+
+func (c *AuthController) RegisterOAuthClient(gc *gin.Context) {
+	token, ok := bearerToken(gc)
+	if !ok {
+		return
+	}
+
+	var req generated.RegisterOAuthClientRequest
+	if err := gc.ShouldBindJSON(&req); err != nil {
+		apierrors.InvalidRequest(gc)
+		return
+	}
+	req.AccessToken = token
+
+	if req.Name == "" {
+		apierrors.RequiredField(gc, "name")
+		return
+	}
+
+	if len(req.RedirectUris) == 0 {
+		apierrors.RequiredField(gc, "redirect_uris")
+		return
+	}
+
+	if len(req.Scopes) == 0 {
+		apierrors.RequiredField(gc, "scopes")
+		return
+	}
+
+	ctx := gc.Request.Context()
+
+	response, err := c.client.RegisterOAuthClient(ctx, &req)
+	if err != nil {
+		apierrors.FromGRPC(gc, err)
+		return
+	}
+
+	gc.JSON(http.StatusCreated, response)
+}
+
+func (c *AuthController) ListOAuthClients(gc *gin.Context) {
+	token, ok := bearerToken(gc)
+	if !ok {
+		return
+	}
+
+	ctx := gc.Request.Context()
+
+	response, err := c.client.ListOAuthClients(ctx, &generated.ListOAuthClientsRequest{
+		AccessToken: token,
+	})
+	if err != nil {
+		apierrors.FromGRPC(gc, err)
+		return
+	}
+
+	gc.JSON(http.StatusOK, response)
+}
+
+func (c *AuthController) DeleteOAuthClient(gc *gin.Context) {
+	token, ok := bearerToken(gc)
+	if !ok {
+		return
+	}
+
+	ctx := gc.Request.Context()
+
+	_, err := c.client.DeleteOAuthClient(ctx, &generated.DeleteOAuthClientRequest{
+		AccessToken: token,
+		ClientId:    gc.Param("client_id"),
+	})
+	if err != nil {
+		apierrors.FromGRPC(gc, err)
+		return
+	}
+
+	gc.JSON(http.StatusOK, "OAuth client has been deleted")
+}
+
+func (c *AuthController) ListOAuthConsents(gc *gin.Context) {
+	token, ok := bearerToken(gc)
+	if !ok {
+		return
+	}
+
+	ctx := gc.Request.Context()
+
+	response, err := c.client.ListOAuthConsents(ctx, &generated.ListOAuthConsentsRequest{
+		AccessToken: token,
+	})
+	if err != nil {
+		apierrors.FromGRPC(gc, err)
+		return
+	}
+
+	gc.JSON(http.StatusOK, response)
+}
+
+func (c *AuthController) RevokeOAuthConsent(gc *gin.Context) {
+	token, ok := bearerToken(gc)
+	if !ok {
+		return
+	}
+
+	ctx := gc.Request.Context()
+
+	_, err := c.client.RevokeOAuthConsent(ctx, &generated.RevokeOAuthConsentRequest{
+		AccessToken: token,
+		ClientId:    gc.Param("client_id"),
+	})
+	if err != nil {
+		apierrors.FromGRPC(gc, err)
+		return
+	}
+
+	gc.JSON(http.StatusOK, "Access of the app has been revoked")
+}
+
+// writeOAuthError answers with the RFC 6749 error code sc-auth attached to the status.
+// Other errors, like an invalid access token of the user, are reported as usual.
+func writeOAuthError(gc *gin.Context, err error) {
+	st := status.Convert(err)
+	for _, detail := range st.Details() {
+		info, ok := detail.(*errdetails.ErrorInfo)
+		if !ok || info.Domain != "oauth" {
+			continue
+		}
+		httpStatus := http.StatusBadRequest
+		if st.Code() == codes.Unauthenticated {
+			httpStatus = http.StatusUnauthorized
+		}
+		writeOAuthProtocolError(gc, httpStatus, info.Reason, st.Message())
+		return
+	}
+	apierrors.FromGRPC(gc, err)
+}
+
+func writeOAuthProtocolError(gc *gin.Context, httpStatus int, code string, description string) {
+	gc.Header("Cache-Control", "no-store")
+	gc.JSON(httpStatus, gin.H{"error": code, "error_description": description})
+}
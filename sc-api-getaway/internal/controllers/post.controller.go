@@ -1,20 +1,26 @@
 package controllers
 
 import (
-	"context"
-	"net/http"
-	"strings"
-
 	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"net/http"
+	"soul-connect/sc-api-getaway/internal/apierrors"
+	"soul-connect/sc-api-getaway/internal/generated"
+	"soul-connect/sc-api-getaway/internal/middlewares"
+	"soul-connect/sc-auth/pkg/authz"
 	postpb "soul-connect/sc-post/pkg/postpb"
+	"strings"
 )
 
 type PostController struct {
 	client postpb.PostServiceClient
+	// userClient resolves callers to their profiles, sc-post refers to users by profile id
+	userClient generated.UserServiceClient
 }
 
-func NewPostController(client postpb.PostServiceClient) *PostController {
-	return &PostController{client: client}
+func NewPostController(client postpb.PostServiceClient, userClient generated.UserServiceClient) *PostController {
+	return &PostController{client: client, userClient: userClient}
 }
 
 func (c *PostController) CreatePost(gc *gin.Context) {
@@ -25,23 +31,27 @@ func (c *PostController) CreatePost(gc *gin.Context) {
 		LabelIDs    []string `json:"label_ids"`
 	}
 	if err := gc.ShouldBindJSON(&req); err != nil {
-		gc.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		apierrors.InvalidRequest(gc)
 		return
 	}
-	if req.UserID == "" || req.Title == "" {
-		gc.JSON(http.StatusBadRequest, gin.H{"error": "user_id and title are required"})
+	userID, ok := c.authorID(gc, req.UserID)
+	if !ok {
+		return
+	}
+	if req.Title == "" {
+		apierrors.RequiredField(gc, "title")
 		return
 	}
 
-	ctx := context.Background()
+	ctx := gc.Request.Context()
 	resp, err := c.client.CreatePost(ctx, &postpb.CreatePostRequest{
-		UserId:      req.UserID,
+		UserId:      userID,
 		Title:       req.Title,
 		Description: req.Description,
 		LabelIds:    req.LabelIDs,
 	})
 	if err != nil {
-		gc.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		apierrors.FromGRPC(gc, err)
 		return
 	}
 	gc.JSON(http.StatusCreated, postToResponse(resp.Post))
@@ -50,13 +60,13 @@ func (c *PostController) CreatePost(gc *gin.Context) {
 func (c *PostController) GetPost(gc *gin.Context) {
 	postID := gc.Param("post_id")
 	if postID == "" {
-		gc.JSON(http.StatusBadRequest, gin.H{"error": "post_id is required"})
+		apierrors.RequiredField(gc, "post_id")
 		return
 	}
-	ctx := context.Background()
+	ctx := gc.Request.Context()
 	resp, err := c.client.GetPost(ctx, &postpb.GetPostRequest{Id: postID})
 	if err != nil {
-		gc.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		apierrors.FromGRPC(gc, err)
 		return
 	}
 	gc.JSON(http.StatusOK, gin.H{
@@ -76,10 +86,10 @@ func (c *PostController) ListPosts(gc *gin.Context) {
 			}
 		}
 	}
-	ctx := context.Background()
+	ctx := gc.Request.Context()
 	resp, err := c.client.ListPosts(ctx, &postpb.ListPostsRequest{LabelIds: labelIDs})
 	if err != nil {
-		gc.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		apierrors.FromGRPC(gc, err)
 		return
 	}
 	posts := make([]gin.H, 0, len(resp.Posts))
@@ -96,7 +106,7 @@ func (c *PostController) ListPosts(gc *gin.Context) {
 func (c *PostController) AddComment(gc *gin.Context) {
 	postID := gc.Param("post_id")
 	if postID == "" {
-		gc.JSON(http.StatusBadRequest, gin.H{"error": "post_id is required"})
+		apierrors.RequiredField(gc, "post_id")
 		return
 	}
 	var req struct {
@@ -104,17 +114,21 @@ func (c *PostController) AddComment(gc *gin.Context) {
 		Content string `json:"content"`
 	}
 	if err := gc.ShouldBindJSON(&req); err != nil {
-		gc.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		apierrors.InvalidRequest(gc)
+		return
+	}
+	userID, ok := c.authorID(gc, req.UserID)
+	if !ok {
 		return
 	}
-	if req.UserID == "" || req.Content == "" {
-		gc.JSON(http.StatusBadRequest, gin.H{"error": "user_id and content are required"})
+	if req.Content == "" {
+		apierrors.RequiredField(gc, "content")
 		return
 	}
-	ctx := context.Background()
-	resp, err := c.client.AddComment(ctx, &postpb.AddCommentRequest{PostId: postID, UserId: req.UserID, Content: req.Content})
+	ctx := gc.Request.Context()
+	resp, err := c.client.AddComment(ctx, &postpb.AddCommentRequest{PostId: postID, UserId: userID, Content: req.Content})
 	if err != nil {
-		gc.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		apierrors.FromGRPC(gc, err)
 		return
 	}
 	gc.JSON(http.StatusCreated, commentToResponse(resp.Comment))
@@ -123,13 +137,13 @@ func (c *PostController) AddComment(gc *gin.Context) {
 func (c *PostController) ListComments(gc *gin.Context) {
 	postID := gc.Param("post_id")
 	if postID == "" {
-		gc.JSON(http.StatusBadRequest, gin.H{"error": "post_id is required"})
+		apierrors.RequiredField(gc, "post_id")
 		return
 	}
-	ctx := context.Background()
+	ctx := gc.Request.Context()
 	resp, err := c.client.ListComments(ctx, &postpb.ListCommentsRequest{PostId: postID})
 	if err != nil {
-		gc.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		apierrors.FromGRPC(gc, err)
 		return
 	}
 	gc.JSON(http.StatusOK, gin.H{"comments": commentsToResponse(resp.Comments)})
@@ -152,10 +166,10 @@ func (c *PostController) UnlikeComment(gc *gin.Context) {
 }
 
 func (c *PostController) ListLabels(gc *gin.Context) {
-	ctx := context.Background()
+	ctx := gc.Request.Context()
 	resp, err := c.client.ListLabels(ctx, &postpb.Empty{})
 	if err != nil {
-		gc.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		apierrors.FromGRPC(gc, err)
 		return
 	}
 	gc.JSON(http.StatusOK, gin.H{"labels": labelsToResponse(resp.Labels)})
@@ -164,19 +178,22 @@ func (c *PostController) ListLabels(gc *gin.Context) {
 func (c *PostController) AddLabelToPost(gc *gin.Context) {
 	postID := gc.Param("post_id")
 	if postID == "" {
-		gc.JSON(http.StatusBadRequest, gin.H{"error": "post_id is required"})
+		apierrors.RequiredField(gc, "post_id")
 		return
 	}
 	var req struct {
 		LabelID string `json:"label_id"`
 	}
 	if err := gc.ShouldBindJSON(&req); err != nil || req.LabelID == "" {
-		gc.JSON(http.StatusBadRequest, gin.H{"error": "label_id is required"})
+		apierrors.RequiredField(gc, "label_id")
+		return
+	}
+	if !c.requirePostAuthor(gc, postID, false) {
 		return
 	}
-	ctx := context.Background()
+	ctx := gc.Request.Context()
 	if _, err := c.client.AddLabelToPost(ctx, &postpb.AddLabelToPostRequest{PostId: postID, LabelId: req.LabelID}); err != nil {
-		gc.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		apierrors.FromGRPC(gc, err)
 		return
 	}
 	gc.Status(http.StatusNoContent)
@@ -185,17 +202,20 @@ func (c *PostController) AddLabelToPost(gc *gin.Context) {
 func (c *PostController) RemoveLabelFromPost(gc *gin.Context) {
 	postID := gc.Param("post_id")
 	if postID == "" {
-		gc.JSON(http.StatusBadRequest, gin.H{"error": "post_id is required"})
+		apierrors.RequiredField(gc, "post_id")
 		return
 	}
 	labelID := gc.Param("label_id")
 	if labelID == "" {
-		gc.JSON(http.StatusBadRequest, gin.H{"error": "label_id is required"})
+		apierrors.RequiredField(gc, "label_id")
 		return
 	}
-	ctx := context.Background()
+	if !c.requirePostAuthor(gc, postID, false) {
+		return
+	}
+	ctx := gc.Request.Context()
 	if _, err := c.client.RemoveLabelFromPost(ctx, &postpb.RemoveLabelFromPostRequest{PostId: postID, LabelId: labelID}); err != nil {
-		gc.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		apierrors.FromGRPC(gc, err)
 		return
 	}
 	gc.Status(http.StatusNoContent)
@@ -204,7 +224,7 @@ func (c *PostController) RemoveLabelFromPost(gc *gin.Context) {
 func (c *PostController) UpdatePost(gc *gin.Context) {
 	postID := gc.Param("post_id")
 	if postID == "" {
-		gc.JSON(http.StatusBadRequest, gin.H{"error": "post_id is required"})
+		apierrors.RequiredField(gc, "post_id")
 		return
 	}
 	var req struct {
@@ -212,13 +232,16 @@ func (c *PostController) UpdatePost(gc *gin.Context) {
 		Description string `json:"description"`
 	}
 	if err := gc.ShouldBindJSON(&req); err != nil {
-		gc.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		apierrors.InvalidRequest(gc)
 		return
 	}
-	ctx := context.Background()
+	if !c.requirePostAuthor(gc, postID, false) {
+		return
+	}
+	ctx := gc.Request.Context()
 	resp, err := c.client.UpdatePost(ctx, &postpb.UpdatePostRequest{Id: postID, Title: req.Title, Description: req.Description})
 	if err != nil {
-		gc.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		apierrors.FromGRPC(gc, err)
 		return
 	}
 	gc.JSON(http.StatusOK, postToResponse(resp))
@@ -227,12 +250,16 @@ func (c *PostController) UpdatePost(gc *gin.Context) {
 func (c *PostController) DeletePost(gc *gin.Context) {
 	postID := gc.Param("post_id")
 	if postID == "" {
-		gc.JSON(http.StatusBadRequest, gin.H{"error": "post_id is required"})
+		apierrors.RequiredField(gc, "post_id")
+		return
+	}
+	// Moderators may take down any post
+	if !c.requirePostAuthor(gc, postID, true) {
 		return
 	}
-	ctx := context.Background()
+	ctx := gc.Request.Context()
 	if _, err := c.client.DeletePost(ctx, &postpb.GetPostRequest{Id: postID}); err != nil {
-		gc.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		apierrors.FromGRPC(gc, err)
 		return
 	}
 	gc.Status(http.StatusNoContent)
@@ -241,28 +268,35 @@ func (c *PostController) DeletePost(gc *gin.Context) {
 func (c *PostController) handleLike(gc *gin.Context, like bool) {
 	postID := gc.Param("post_id")
 	if postID == "" {
-		gc.JSON(http.StatusBadRequest, gin.H{"error": "post_id is required"})
+		apierrors.RequiredField(gc, "post_id")
 		return
 	}
 	var req struct {
 		UserID string `json:"user_id"`
 	}
-	if err := gc.ShouldBindJSON(&req); err != nil || req.UserID == "" {
-		gc.JSON(http.StatusBadRequest, gin.H{"error": "user_id is required"})
+	// The body is optional now that the user comes from the token
+	if gc.Request.ContentLength != 0 {
+		if err := gc.ShouldBindJSON(&req); err != nil {
+			apierrors.InvalidRequest(gc)
+			return
+		}
+	}
+	userID, ok := c.authorID(gc, req.UserID)
+	if !ok {
 		return
 	}
-	ctx := context.Background()
+	ctx := gc.Request.Context()
 	var (
 		resp *postpb.LikeCountResponse
 		err  error
 	)
 	if like {
-		resp, err = c.client.LikePost(ctx, &postpb.LikePostRequest{PostId: postID, UserId: req.UserID})
+		resp, err = c.client.LikePost(ctx, &postpb.LikePostRequest{PostId: postID, UserId: userID})
 	} else {
-		resp, err = c.client.UnlikePost(ctx, &postpb.UnlikePostRequest{PostId: postID, UserId: req.UserID})
+		resp, err = c.client.UnlikePost(ctx, &postpb.UnlikePostRequest{PostId: postID, UserId: userID})
 	}
 	if err != nil {
-		gc.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		apierrors.FromGRPC(gc, err)
 		return
 	}
 	gc.JSON(http.StatusOK, gin.H{"likes_count": resp.LikesCount})
@@ -271,33 +305,99 @@ func (c *PostController) handleLike(gc *gin.Context, like bool) {
 func (c *PostController) handleCommentLike(gc *gin.Context, like bool) {
 	commentID := gc.Param("comment_id")
 	if commentID == "" {
-		gc.JSON(http.StatusBadRequest, gin.H{"error": "comment_id is required"})
+		apierrors.RequiredField(gc, "comment_id")
 		return
 	}
 	var req struct {
 		UserID string `json:"user_id"`
 	}
-	if err := gc.ShouldBindJSON(&req); err != nil || req.UserID == "" {
-		gc.JSON(http.StatusBadRequest, gin.H{"error": "user_id is required"})
+	// The body is optional now that the user comes from the token
+	if gc.Request.ContentLength != 0 {
+		if err := gc.ShouldBindJSON(&req); err != nil {
+			apierrors.InvalidRequest(gc)
+			return
+		}
+	}
+	userID, ok := c.authorID(gc, req.UserID)
+	if !ok {
 		return
 	}
-	ctx := context.Background()
+	ctx := gc.Request.Context()
 	var (
 		resp *postpb.LikeCountResponse
 		err  error
 	)
 	if like {
-		resp, err = c.client.LikeComment(ctx, &postpb.LikeCommentRequest{CommentId: commentID, UserId: req.UserID})
+		resp, err = c.client.LikeComment(ctx, &postpb.LikeCommentRequest{CommentId: commentID, UserId: userID})
 	} else {
-		resp, err = c.client.UnlikeComment(ctx, &postpb.UnlikeCommentRequest{CommentId: commentID, UserId: req.UserID})
+		resp, err = c.client.UnlikeComment(ctx, &postpb.UnlikeCommentRequest{CommentId: commentID, UserId: userID})
 	}
 	if err != nil {
-		gc.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		apierrors.FromGRPC(gc, err)
 		return
 	}
 	gc.JSON(http.StatusOK, gin.H{"likes_count": resp.LikesCount})
 }
 
+// authorID returns the profile id of the caller, which sc-post stores as the user of
+// posts, comments and likes. A user_id in the body may name the caller by profile or
+// account id, anything else is answered with 403. Callers without a profile get 409.
+// Must run behind middlewares.Authenticate.
+func (c *PostController) authorID(gc *gin.Context, claimed string) (string, bool) {
+	accountID, ok := actorID(gc, "")
+	if !ok {
+		return "", false
+	}
+
+	profile, err := c.callerProfile(gc)
+	if status.Code(err) == codes.NotFound {
+		apierrors.Abort(gc, http.StatusConflict, codes.FailedPrecondition, "create a profile before writing posts")
+		return "", false
+	}
+	if err != nil {
+		apierrors.FromGRPC(gc, err)
+		return "", false
+	}
+
+	if claimed != "" && claimed != profile.Id && claimed != accountID {
+		apierrors.PermissionDenied(gc, "user_id does not match the authenticated user")
+		return "", false
+	}
+	return profile.Id, true
+}
+
+// callerProfile looks up the sc-user profile of the authenticated caller.
+func (c *PostController) callerProfile(gc *gin.Context) (*generated.UserProfile, error) {
+	return c.userClient.GetProfileByAuthId(gc.Request.Context(), &generated.GetProfileByAuthIdRequest{AuthId: middlewares.CallerUserID(gc)})
+}
+
+// requirePostAuthor lets only the author of the post change it, or a moderator when
+// moderatorsAllowed is set. Must run behind middlewares.Authenticate.
+func (c *PostController) requirePostAuthor(gc *gin.Context, postID string, moderatorsAllowed bool) bool {
+	ctx := gc.Request.Context()
+	resp, err := c.client.GetPost(ctx, &postpb.GetPostRequest{Id: postID})
+	if err != nil {
+		apierrors.FromGRPC(gc, err)
+		return false
+	}
+
+	// Moderators need no profile of their own to take posts down
+	if moderatorsAllowed && middlewares.CallerPrincipal(gc).HasPermission(authz.PermissionModeratePosts) {
+		return true
+	}
+	// Callers without a profile have not written any post
+	profile, err := c.callerProfile(gc)
+	if err != nil && status.Code(err) != codes.NotFound {
+		apierrors.FromGRPC(gc, err)
+		return false
+	}
+	if err != nil || resp.Post.GetUserId() != profile.GetId() {
+		apierrors.PermissionDenied(gc, "only the author can change this post")
+		return false
+	}
+	return true
+}
+
 func postToResponse(post *postpb.Post) gin.H {
 	if post == nil {
 		return gin.H{}
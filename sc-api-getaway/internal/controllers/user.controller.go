@@ -1,10 +1,9 @@
 package controllers
 
 import (
-	"context"
-	"net/http"
-
 	"github.com/gin-gonic/gin"
+	"net/http"
+	"soul-connect/sc-api-getaway/internal/apierrors"
 	"soul-connect/sc-api-getaway/internal/generated"
 )
 
@@ -36,16 +35,20 @@ type subscriptionPayload struct {
 func (c *UserController) CreateProfile(gc *gin.Context) {
 	var payload createProfilePayload
 	if err := gc.ShouldBindJSON(&payload); err != nil {
-		gc.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		apierrors.InvalidRequest(gc)
 		return
 	}
-	if payload.AuthID == "" || payload.FullName == "" {
-		gc.JSON(http.StatusBadRequest, gin.H{"error": "auth_id and full_name are required"})
+	authID, ok := actorID(gc, payload.AuthID)
+	if !ok {
+		return
+	}
+	if payload.FullName == "" {
+		apierrors.RequiredField(gc, "full_name")
 		return
 	}
 
 	req := &generated.CreateProfileRequest{
-		AuthId:   payload.AuthID,
+		AuthId:   authID,
 		FullName: payload.FullName,
 	}
 	if payload.Bio != nil {
@@ -55,10 +58,10 @@ func (c *UserController) CreateProfile(gc *gin.Context) {
 		req.PhotoLink = payload.PhotoLink
 	}
 
-	ctx := context.Background()
+	ctx := gc.Request.Context()
 	profile, err := c.client.CreateProfile(ctx, req)
 	if err != nil {
-		gc.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		apierrors.FromGRPC(gc, err)
 		return
 	}
 
@@ -68,14 +71,14 @@ func (c *UserController) CreateProfile(gc *gin.Context) {
 func (c *UserController) GetProfile(gc *gin.Context) {
 	id := gc.Param("id")
 	if id == "" {
-		gc.JSON(http.StatusBadRequest, gin.H{"error": "id is required"})
+		apierrors.RequiredField(gc, "id")
 		return
 	}
 
-	ctx := context.Background()
+	ctx := gc.Request.Context()
 	profile, err := c.client.GetProfile(ctx, &generated.GetProfileRequest{Id: id})
 	if err != nil {
-		gc.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		apierrors.FromGRPC(gc, err)
 		return
 	}
 
@@ -85,13 +88,16 @@ func (c *UserController) GetProfile(gc *gin.Context) {
 func (c *UserController) UpdateProfile(gc *gin.Context) {
 	id := gc.Param("id")
 	if id == "" {
-		gc.JSON(http.StatusBadRequest, gin.H{"error": "id is required"})
+		apierrors.RequiredField(gc, "id")
 		return
 	}
 
 	var payload updateProfilePayload
 	if err := gc.ShouldBindJSON(&payload); err != nil {
-		gc.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		apierrors.InvalidRequest(gc)
+		return
+	}
+	if !c.requireProfileOwner(gc, id) {
 		return
 	}
 
@@ -106,10 +112,10 @@ func (c *UserController) UpdateProfile(gc *gin.Context) {
 		req.PhotoLink = payload.PhotoLink
 	}
 
-	ctx := context.Background()
+	ctx := gc.Request.Context()
 	profile, err := c.client.UpdateProfile(ctx, req)
 	if err != nil {
-		gc.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		apierrors.FromGRPC(gc, err)
 		return
 	}
 
@@ -119,13 +125,17 @@ func (c *UserController) UpdateProfile(gc *gin.Context) {
 func (c *UserController) DeleteProfile(gc *gin.Context) {
 	id := gc.Param("id")
 	if id == "" {
-		gc.JSON(http.StatusBadRequest, gin.H{"error": "id is required"})
+		apierrors.RequiredField(gc, "id")
 		return
 	}
 
-	ctx := context.Background()
+	if !c.requireProfileOwner(gc, id) {
+		return
+	}
+
+	ctx := gc.Request.Context()
 	if _, err := c.client.DeleteProfile(ctx, &generated.DeleteProfileRequest{Id: id}); err != nil {
-		gc.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		apierrors.FromGRPC(gc, err)
 		return
 	}
 
@@ -135,26 +145,29 @@ func (c *UserController) DeleteProfile(gc *gin.Context) {
 func (c *UserController) Subscribe(gc *gin.Context) {
 	subscriberID := gc.Param("id")
 	if subscriberID == "" {
-		gc.JSON(http.StatusBadRequest, gin.H{"error": "subscriber id is required"})
+		apierrors.RequiredField(gc, "subscriber_id")
 		return
 	}
 
 	var payload subscriptionPayload
 	if err := gc.ShouldBindJSON(&payload); err != nil {
-		gc.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		apierrors.InvalidRequest(gc)
 		return
 	}
 	if payload.AuthorID == "" {
-		gc.JSON(http.StatusBadRequest, gin.H{"error": "author_id is required"})
+		apierrors.RequiredField(gc, "author_id")
+		return
+	}
+	if !c.requireProfileOwner(gc, subscriberID) {
 		return
 	}
 
-	ctx := context.Background()
+	ctx := gc.Request.Context()
 	if _, err := c.client.Subscribe(ctx, &generated.ModifySubscriptionRequest{
 		SubscriberId: subscriberID,
 		AuthorId:     payload.AuthorID,
 	}); err != nil {
-		gc.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		apierrors.FromGRPC(gc, err)
 		return
 	}
 
@@ -165,16 +178,19 @@ func (c *UserController) Unsubscribe(gc *gin.Context) {
 	subscriberID := gc.Param("id")
 	authorID := gc.Param("author_id")
 	if subscriberID == "" || authorID == "" {
-		gc.JSON(http.StatusBadRequest, gin.H{"error": "subscriber and author ids are required"})
+		apierrors.BadRequest(gc, "subscriber and author ids are required")
+		return
+	}
+	if !c.requireProfileOwner(gc, subscriberID) {
 		return
 	}
 
-	ctx := context.Background()
+	ctx := gc.Request.Context()
 	if _, err := c.client.Unsubscribe(ctx, &generated.ModifySubscriptionRequest{
 		SubscriberId: subscriberID,
 		AuthorId:     authorID,
 	}); err != nil {
-		gc.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		apierrors.FromGRPC(gc, err)
 		return
 	}
 
@@ -184,14 +200,14 @@ func (c *UserController) Unsubscribe(gc *gin.Context) {
 func (c *UserController) ListSubscriptions(gc *gin.Context) {
 	subscriberID := gc.Param("id")
 	if subscriberID == "" {
-		gc.JSON(http.StatusBadRequest, gin.H{"error": "subscriber id is required"})
+		apierrors.RequiredField(gc, "subscriber_id")
 		return
 	}
 
-	ctx := context.Background()
+	ctx := gc.Request.Context()
 	resp, err := c.client.ListSubscriptions(ctx, &generated.ListSubscriptionsRequest{SubscriberId: subscriberID})
 	if err != nil {
-		gc.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		apierrors.FromGRPC(gc, err)
 		return
 	}
 
@@ -201,6 +217,28 @@ func (c *UserController) ListSubscriptions(gc *gin.Context) {
 	})
 }
 
+// requireProfileOwner lets only the account a profile belongs to change it or its
+// subscriptions. Must run behind middlewares.Authenticate.
+func (c *UserController) requireProfileOwner(gc *gin.Context, profileID string) bool {
+	callerID, ok := actorID(gc, "")
+	if !ok {
+		return false
+	}
+
+	ctx := gc.Request.Context()
+	profile, err := c.client.GetProfile(ctx, &generated.GetProfileRequest{Id: profileID})
+	if err != nil {
+		apierrors.FromGRPC(gc, err)
+		return false
+	}
+
+	if profile.AuthId != callerID {
+		apierrors.PermissionDenied(gc, "only the owner can change this profile")
+		return false
+	}
+	return true
+}
+
 func toUserProfileResponse(profile *generated.UserProfile) gin.H {
 	if profile == nil {
 		return gin.H{}
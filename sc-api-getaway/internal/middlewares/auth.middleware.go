@@ -0,0 +1,135 @@
+package middlewares
+
+import (
+	"github.com/gin-gonic/gin"
+	"log"
+	"net/http"
+	"slices"
+	"soul-connect/pkg/requestctx"
+	"soul-connect/sc-api-getaway/internal/apierrors"
+	"soul-connect/sc-api-getaway/internal/generated"
+	"soul-connect/sc-auth/pkg/authz"
+	"strings"
+)
+
+// Token types reported by sc-auth introspection that may call the API.
+const (
+	AccessTokenType         = "access"
+	PersonalAccessTokenType = "personal_access_token"
+	OAuthAccessTokenType    = "oauth_access_token"
+)
+
+// Gin context keys the authenticated caller is stored under.
+const (
+	CallerUserIDKey      = "caller_user_id"
+	CallerTokenTypeKey   = "caller_token_type"
+	CallerScopesKey      = "caller_scopes"
+	CallerRolesKey       = "caller_roles"
+	CallerPermissionsKey = "caller_permissions"
+	CallerActorIDKey     = "caller_actor_id"
+)
+
+// ImpersonatedByHeader is set on every response to a request made with an impersonation
+// token. It names the admin acting as the user, so the webapp can show who is in control.
+const ImpersonatedByHeader = "X-Impersonated-By"
+
+// Authenticate accepts an access JWT, a personal access token or an access token issued
+// to an OAuth client as bearer credential. All are checked through sc-auth introspection,
+// so revoked tokens are rejected right away. The caller is stored in the gin context and,
+// as an authz.Principal, in the request context.
+// Requests made by an admin impersonating the user are flagged with ImpersonatedByHeader,
+// and every write among them is logged with the admin and the user.
+func Authenticate(authClient generated.AuthServiceClient) gin.HandlerFunc {
+	return func(gc *gin.Context) {
+		token := strings.TrimPrefix(gc.GetHeader("Authorization"), "Bearer ")
+		if token == "" {
+			apierrors.Unauthenticated(gc, "Authorization header is missing")
+			return
+		}
+
+		introspection, err := authClient.IntrospectToken(gc.Request.Context(), &generated.IntrospectTokenRequest{
+			Token: token,
+		})
+		if err != nil {
+			apierrors.FromGRPC(gc, err)
+			return
+		}
+
+		if !introspection.Active || !slices.Contains([]string{AccessTokenType, PersonalAccessTokenType, OAuthAccessTokenType}, introspection.TokenType) {
+			apierrors.Unauthenticated(gc, "invalid or expired token")
+			return
+		}
+
+		gc.Set(CallerUserIDKey, introspection.UserId)
+		gc.Set(CallerTokenTypeKey, introspection.TokenType)
+		gc.Set(CallerScopesKey, introspection.Scopes)
+		gc.Set(CallerRolesKey, introspection.Roles)
+		gc.Set(CallerPermissionsKey, introspection.Permissions)
+		ctx := requestctx.WithCaller(gc.Request.Context(), introspection.UserId, introspection.ActorId)
+		gc.Request = gc.Request.WithContext(authz.NewContext(ctx, CallerPrincipal(gc)))
+
+		if introspection.ActorId == "" {
+			gc.Next()
+			return
+		}
+
+		gc.Set(CallerActorIDKey, introspection.ActorId)
+		gc.Header(ImpersonatedByHeader, introspection.ActorId)
+		gc.Next()
+
+		if isWrite(gc.Request.Method) {
+			log.Printf("impersonated write: admin %s as user %s: %s %s -> %d",
+				introspection.ActorId, introspection.UserId, gc.Request.Method, gc.Request.URL.Path, gc.Writer.Status())
+		}
+	}
+}
+
+// isWrite tells requests that may change state apart from reads.
+func isWrite(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return false
+	}
+	return true
+}
+
+// CallerUserID returns the id of the user the request was authenticated as, empty when
+// Authenticate did not run.
+func CallerUserID(gc *gin.Context) string {
+	return gc.GetString(CallerUserIDKey)
+}
+
+// CallerPrincipal returns the caller stored by Authenticate.
+func CallerPrincipal(gc *gin.Context) authz.Principal {
+	return authz.Principal{
+		UserID:      gc.GetString(CallerUserIDKey),
+		Roles:       gc.GetStringSlice(CallerRolesKey),
+		Permissions: gc.GetStringSlice(CallerPermissionsKey),
+	}
+}
+
+// RequirePermission rejects callers whose access token does not grant the permission.
+// Personal access tokens and tokens of OAuth clients never carry permissions. Must run after Authenticate.
+func RequirePermission(permission string) gin.HandlerFunc {
+	return func(gc *gin.Context) {
+		if !CallerPrincipal(gc).HasPermission(permission) {
+			apierrors.PermissionDenied(gc, "permission denied")
+			return
+		}
+		gc.Next()
+	}
+}
+
+// RequireScope limits personal access tokens and tokens of OAuth clients to routes their
+// scopes cover. Access tokens act with the full rights of the user. Must run after Authenticate.
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(gc *gin.Context) {
+		tokenType := gc.GetString(CallerTokenTypeKey)
+		scoped := tokenType == PersonalAccessTokenType || tokenType == OAuthAccessTokenType
+		if scoped && !slices.Contains(gc.GetStringSlice(CallerScopesKey), scope) {
+			apierrors.PermissionDenied(gc, "token is missing the "+scope+" scope")
+			return
+		}
+		gc.Next()
+	}
+}
@@ -0,0 +1,44 @@
+package middlewares
+
+import (
+	"github.com/gin-gonic/gin"
+	"log"
+	"soul-connect/sc-api-getaway/internal/apierrors"
+	"soul-connect/sc-api-getaway/internal/ratelimit"
+)
+
+// RateLimit takes a token from the bucket of the caller for every request and answers
+// with 429 once it is empty. Authenticated callers are limited per user, everybody else
+// per client IP, so it must run after Authenticate to limit users. Buckets are kept per
+// name, routes sharing a name share their buckets.
+func RateLimit(limiter ratelimit.Limiter, name string, policy ratelimit.Policy) gin.HandlerFunc {
+	if !policy.Enabled() {
+		return func(gc *gin.Context) {
+			gc.Next()
+		}
+	}
+
+	return func(gc *gin.Context) {
+		result, err := limiter.Allow(gc.Request.Context(), name+":"+rateLimitKey(gc), policy)
+		if err != nil {
+			// Without a limiter requests are let through rather than failing them all
+			log.Printf("rate limit %s not checked: %v", name, err)
+			gc.Next()
+			return
+		}
+		if !result.Allowed {
+			apierrors.TooManyRequests(gc, result.RetryAfter)
+			return
+		}
+		gc.Next()
+	}
+}
+
+// rateLimitKey identifies the caller by user id or client IP. The client IP is read from
+// X-Forwarded-For when the request came through a trusted proxy, see Config.TrustedProxies.
+func rateLimitKey(gc *gin.Context) string {
+	if userID := CallerUserID(gc); userID != "" {
+		return "user:" + userID
+	}
+	return "ip:" + gc.ClientIP()
+}
@@ -0,0 +1,95 @@
+package middlewares
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"soul-connect/pkg/requestctx"
+	"strings"
+	"time"
+)
+
+// RequestIDHeader carries the id of a request. A valid id sent by the client or a proxy
+// is kept, otherwise one is generated. Either way it is echoed on the response.
+const RequestIDHeader = "X-Request-ID"
+
+const (
+	maxRequestIDLength = 128
+	maxLocaleLength    = 35
+	maxUserAgentLength = 512
+)
+
+// RequestContext stores the request id, the preferred locale and the client of the
+// request in the request context, from where they are forwarded to the backends.
+func RequestContext() gin.HandlerFunc {
+	return func(gc *gin.Context) {
+		requestID := gc.GetHeader(RequestIDHeader)
+		if !isValidRequestID(requestID) {
+			requestID = uuid.NewString()
+		}
+		gc.Header(RequestIDHeader, requestID)
+
+		gc.Request = gc.Request.WithContext(requestctx.NewContext(gc.Request.Context(), requestctx.Info{
+			RequestID: requestID,
+			Locale:    preferredLocale(gc.GetHeader("Accept-Language")),
+			ClientIP:  gc.ClientIP(),
+			UserAgent: printableUserAgent(gc.Request.UserAgent()),
+		}))
+		gc.Next()
+	}
+}
+
+// Timeout bounds the time the backends get to answer a request. Calls still running
+// when it expires are canceled and the request is answered with 504.
+func Timeout(timeout time.Duration) gin.HandlerFunc {
+	return func(gc *gin.Context) {
+		ctx, cancel := context.WithTimeout(gc.Request.Context(), timeout)
+		defer cancel()
+
+		gc.Request = gc.Request.WithContext(ctx)
+		gc.Next()
+	}
+}
+
+// isValidRequestID accepts ids of printable ASCII, which can be passed on as gRPC metadata.
+func isValidRequestID(requestID string) bool {
+	if requestID == "" || len(requestID) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(requestID); i++ {
+		if requestID[i] < 0x21 || requestID[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// printableUserAgent keeps the printable ASCII of a User-Agent header, up to
+// maxUserAgentLength bytes. gRPC rejects metadata with any other byte, which would fail
+// every backend call of the request.
+func printableUserAgent(userAgent string) string {
+	printable := make([]byte, 0, min(len(userAgent), maxUserAgentLength))
+	for i := 0; i < len(userAgent) && len(printable) < maxUserAgentLength; i++ {
+		if userAgent[i] >= 0x20 && userAgent[i] <= 0x7e {
+			printable = append(printable, userAgent[i])
+		}
+	}
+	return strings.TrimSpace(string(printable))
+}
+
+// preferredLocale returns the first language tag of an Accept-Language header, like
+// de-DE for "de-DE,de;q=0.9,en;q=0.8". Malformed tags and the wildcard are ignored.
+func preferredLocale(acceptLanguage string) string {
+	tag, _, _ := strings.Cut(acceptLanguage, ",")
+	tag, _, _ = strings.Cut(tag, ";")
+	tag = strings.TrimSpace(tag)
+	if tag == "" || tag == "*" || len(tag) > maxLocaleLength {
+		return ""
+	}
+	for _, r := range tag {
+		if !(r == '-' || r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z') {
+			return ""
+		}
+	}
+	return tag
+}
@@ -0,0 +1,232 @@
+// Package ratelimit limits how often clients may call the gateway with token buckets.
+// Every key, like a user or a client IP, owns a bucket per policy that holds up to
+// Policy.Requests tokens and refills at Policy.Requests per Policy.Period. A request
+// takes a token and is rejected while the bucket is empty.
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/go-redis/redis/v8"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Policy allows bursts of Requests requests and Requests requests per Period on average.
+// The zero Policy does not limit at all.
+type Policy struct {
+	Requests int
+	Period   time.Duration
+}
+
+// Off is the policy configured with "off".
+const Off = "off"
+
+// ParsePolicy reads a policy written as <requests>/<period>, like 5/1m for five
+// requests a minute, or "off".
+func ParsePolicy(value string) (Policy, error) {
+	if value == Off {
+		return Policy{}, nil
+	}
+	requests, period, ok := strings.Cut(value, "/")
+	if !ok {
+		return Policy{}, fmt.Errorf("rate limit %q is not of the form <requests>/<period>", value)
+	}
+	policy := Policy{}
+	var err error
+	if policy.Requests, err = strconv.Atoi(requests); err != nil || policy.Requests <= 0 {
+		return Policy{}, fmt.Errorf("rate limit %q needs a positive number of requests", value)
+	}
+	if policy.Period, err = time.ParseDuration(period); err != nil || policy.Period <= 0 {
+		return Policy{}, fmt.Errorf("rate limit %q needs a positive period like 1m", value)
+	}
+	if policy.interval() < time.Microsecond {
+		return Policy{}, fmt.Errorf("rate limit %q allows more than a request per microsecond", value)
+	}
+	return policy, nil
+}
+
+// Enabled reports whether the policy limits requests.
+func (p Policy) Enabled() bool {
+	return p.Requests > 0 && p.Period > 0
+}
+
+// interval is the time it takes to refill one token.
+func (p Policy) interval() time.Duration {
+	return p.Period / time.Duration(p.Requests)
+}
+
+// Result is the outcome of taking a token from a bucket.
+type Result struct {
+	Allowed bool
+	// RetryAfter is how long until the bucket holds a token again, set when not Allowed.
+	RetryAfter time.Duration
+}
+
+// Limiter keeps the buckets.
+type Limiter interface {
+	// Allow takes a token from the bucket of key under the policy.
+	Allow(ctx context.Context, key string, policy Policy) (Result, error)
+}
+
+const keyPrefix = "sc-api-getaway:ratelimit:"
+
+// tokenBucketScript updates a bucket stored as a hash of its tokens and the time they
+// were counted in microseconds. The time of the Redis server is used, so all gateway
+// instances agree on it. Buckets expire once they would be full again.
+var tokenBucketScript = redis.NewScript(`
+local capacity = tonumber(ARGV[1])
+local interval = tonumber(ARGV[2])
+local time = redis.call('TIME')
+local now = tonumber(time[1]) * 1000000 + tonumber(time[2])
+
+local bucket = redis.call('HMGET', KEYS[1], 'tokens', 'updated')
+local tokens = tonumber(bucket[1])
+local updated = tonumber(bucket[2])
+if tokens == nil or updated == nil then
+	tokens = capacity
+	updated = now
+end
+tokens = math.min(capacity, tokens + math.max(0, now - updated) / interval)
+
+local allowed = 0
+local wait = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+else
+	wait = math.ceil((1 - tokens) * interval)
+end
+
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'updated', tostring(now))
+redis.call('PEXPIRE', KEYS[1], math.ceil((capacity - tokens) * interval / 1000) + 1)
+return {allowed, wait}
+`)
+
+// RedisLimiter shares the buckets between all gateway instances.
+type RedisLimiter struct {
+	client *redis.Client
+}
+
+func NewRedisLimiter(client *redis.Client) *RedisLimiter {
+	return &RedisLimiter{client: client}
+}
+
+func (l *RedisLimiter) Allow(ctx context.Context, key string, policy Policy) (Result, error) {
+	if !policy.Enabled() {
+		return Result{Allowed: true}, nil
+	}
+	values, err := tokenBucketScript.Run(ctx, l.client, []string{keyPrefix + key}, policy.Requests, policy.interval().Microseconds()).Int64Slice()
+	if err != nil {
+		return Result{}, err
+	}
+	if len(values) != 2 {
+		return Result{}, fmt.Errorf("token bucket script returned %d values", len(values))
+	}
+	return Result{Allowed: values[0] == 1, RetryAfter: time.Duration(values[1]) * time.Microsecond}, nil
+}
+
+// sweepInterval is how often MemoryLimiter drops the buckets that filled up again.
+const sweepInterval = time.Minute
+
+// MemoryLimiter keeps the buckets in process memory. Every gateway instance limits on
+// its own, so it is meant for local development, tests and as a fallback for Redis.
+type MemoryLimiter struct {
+	mu        sync.Mutex
+	buckets   map[string]*memoryBucket
+	nextSweep time.Time
+	now       func() time.Time
+}
+
+type memoryBucket struct {
+	tokens  float64
+	updated time.Time
+	// full is when the bucket holds all tokens again and can be forgotten
+	full time.Time
+}
+
+func NewMemoryLimiter() *MemoryLimiter {
+	return &MemoryLimiter{
+		buckets: map[string]*memoryBucket{},
+		now:     time.Now,
+	}
+}
+
+func (l *MemoryLimiter) Allow(_ context.Context, key string, policy Policy) (Result, error) {
+	if !policy.Enabled() {
+		return Result{Allowed: true}, nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	l.removeFull(now)
+
+	capacity := float64(policy.Requests)
+	interval := policy.interval()
+	bucket, ok := l.buckets[key]
+	if !ok {
+		bucket = &memoryBucket{tokens: capacity, updated: now}
+		l.buckets[key] = bucket
+	}
+	if elapsed := now.Sub(bucket.updated); elapsed > 0 {
+		bucket.tokens = math.Min(capacity, bucket.tokens+float64(elapsed)/float64(interval))
+	}
+	bucket.updated = now
+
+	result := Result{Allowed: bucket.tokens >= 1}
+	if result.Allowed {
+		bucket.tokens--
+	} else {
+		result.RetryAfter = time.Duration(math.Ceil((1 - bucket.tokens) * float64(interval)))
+	}
+	bucket.full = now.Add(time.Duration((capacity - bucket.tokens) * float64(interval)))
+	return result, nil
+}
+
+func (l *MemoryLimiter) removeFull(now time.Time) {
+	if now.Before(l.nextSweep) {
+		return
+	}
+	l.nextSweep = now.Add(sweepInterval)
+	for key, bucket := range l.buckets {
+		if !now.Before(bucket.full) {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// FallbackLimiter asks the primary limiter and turns to the fallback while the primary
+// fails, so requests stay limited per instance when Redis cannot be reached.
+type FallbackLimiter struct {
+	primary  Limiter
+	fallback Limiter
+	failing  atomic.Bool
+}
+
+func NewFallbackLimiter(primary Limiter, fallback Limiter) *FallbackLimiter {
+	return &FallbackLimiter{primary: primary, fallback: fallback}
+}
+
+func (l *FallbackLimiter) Allow(ctx context.Context, key string, policy Policy) (Result, error) {
+	result, err := l.primary.Allow(ctx, key, policy)
+	if err == nil {
+		if l.failing.CompareAndSwap(true, false) {
+			log.Println("rate limiter recovered, leaving the in-memory fallback")
+		}
+		return result, nil
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return Result{}, err
+	}
+	if !l.failing.Swap(true) {
+		log.Printf("rate limiter failed, limiting in memory until it recovers: %v", err)
+	}
+	return l.fallback.Allow(ctx, key, policy)
+}
@@ -0,0 +1,103 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePolicy(t *testing.T) {
+	policy, err := ParsePolicy("5/1m")
+	require.NoError(t, err)
+	require.Equal(t, Policy{Requests: 5, Period: time.Minute}, policy)
+	require.True(t, policy.Enabled())
+
+	policy, err = ParsePolicy("off")
+	require.NoError(t, err)
+	require.False(t, policy.Enabled())
+
+	for _, invalid := range []string{"", "5", "0/1m", "-1/1m", "five/1m", "5/minute", "5/0s", "5000000/1s"} {
+		_, err := ParsePolicy(invalid)
+		require.Error(t, err, invalid)
+	}
+}
+
+func TestMemoryLimiter_RefillsBucketsOverTime(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	limiter := NewMemoryLimiter()
+	limiter.now = func() time.Time { return now }
+	policy := Policy{Requests: 2, Period: time.Minute}
+
+	for range 2 {
+		result, err := limiter.Allow(ctx, "alice", policy)
+		require.NoError(t, err)
+		require.True(t, result.Allowed)
+	}
+	result, err := limiter.Allow(ctx, "alice", policy)
+	require.NoError(t, err)
+	require.False(t, result.Allowed)
+	require.InDelta(t, 30*time.Second, result.RetryAfter, float64(time.Millisecond))
+
+	result, err = limiter.Allow(ctx, "bob", policy)
+	require.NoError(t, err)
+	require.True(t, result.Allowed, "buckets are kept per key")
+
+	now = now.Add(20 * time.Second)
+	result, err = limiter.Allow(ctx, "alice", policy)
+	require.NoError(t, err)
+	require.False(t, result.Allowed)
+	require.InDelta(t, 10*time.Second, result.RetryAfter, float64(time.Millisecond))
+
+	now = now.Add(10 * time.Second)
+	result, err = limiter.Allow(ctx, "alice", policy)
+	require.NoError(t, err)
+	require.True(t, result.Allowed)
+}
+
+func TestMemoryLimiter_ForgetsFullBuckets(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	limiter := NewMemoryLimiter()
+	limiter.now = func() time.Time { return now }
+	policy := Policy{Requests: 10, Period: time.Minute}
+
+	_, err := limiter.Allow(ctx, "alice", policy)
+	require.NoError(t, err)
+	require.Contains(t, limiter.buckets, "alice")
+
+	now = now.Add(2 * sweepInterval)
+	_, err = limiter.Allow(ctx, "bob", policy)
+	require.NoError(t, err)
+	require.NotContains(t, limiter.buckets, "alice")
+	require.Contains(t, limiter.buckets, "bob")
+}
+
+type failingLimiter struct {
+	err error
+}
+
+func (l failingLimiter) Allow(context.Context, string, Policy) (Result, error) {
+	return Result{}, l.err
+}
+
+func TestFallbackLimiter_LimitsInMemoryWhileThePrimaryFails(t *testing.T) {
+	ctx := context.Background()
+	policy := Policy{Requests: 1, Period: time.Minute}
+	limiter := NewFallbackLimiter(failingLimiter{err: errors.New("connection refused")}, NewMemoryLimiter())
+
+	result, err := limiter.Allow(ctx, "alice", policy)
+	require.NoError(t, err)
+	require.True(t, result.Allowed)
+
+	result, err = limiter.Allow(ctx, "alice", policy)
+	require.NoError(t, err)
+	require.False(t, result.Allowed)
+
+	limiter = NewFallbackLimiter(failingLimiter{err: context.Canceled}, NewMemoryLimiter())
+	_, err = limiter.Allow(ctx, "alice", policy)
+	require.ErrorIs(t, err, context.Canceled)
+}
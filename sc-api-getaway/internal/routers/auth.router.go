@@ -4,22 +4,72 @@ import (
 	"github.com/gin-gonic/gin"
 	"soul-connect/sc-api-getaway/internal/config"
 	"soul-connect/sc-api-getaway/internal/controllers"
+	"soul-connect/sc-api-getaway/internal/middlewares"
+	"soul-connect/sc-auth/pkg/authz"
 )
 
 type authRouter struct {
 	authController *controllers.AuthController
 	config         *config.Config
+	authenticate   gin.HandlerFunc
+	// limit limits all requests, limitLogin also the attempts to sign in against credential stuffing
+	limit      gin.HandlerFunc
+	limitLogin gin.HandlerFunc
 }
 
-func newAuthRouter(authController *controllers.AuthController, config *config.Config) *authRouter {
-	return &authRouter{authController, config}
+func newAuthRouter(authController *controllers.AuthController, config *config.Config, authenticate gin.HandlerFunc, limit gin.HandlerFunc, limitLogin gin.HandlerFunc) *authRouter {
+	return &authRouter{authController, config, authenticate, limit, limitLogin}
 }
 
 func (ar *authRouter) setAuthRoutes(rg *gin.RouterGroup) {
-	router := rg.Group("auth")
+	// Routes that need an access token run behind Authenticate and are rate limited per
+	// user, the others per client IP
+	router := rg.Group("auth", ar.limit)
+	authenticated := rg.Group("auth", ar.authenticate, ar.limit)
+
 	router.POST("/register", ar.authController.Register)
-	router.POST("/login", ar.authController.Login)
+	router.POST("/login", ar.limitLogin, ar.authController.Login)
+	router.POST("/login/2fa", ar.limitLogin, ar.authController.VerifySecondFactor)
+	router.POST("/login/magic-link", ar.authController.RequestMagicLink)
+	router.POST("/login/magic-link/consume", ar.authController.ConsumeMagicLink)
+	router.POST("/refresh", ar.authController.Refresh)
+	router.GET("/jwks", ar.authController.JWKS)
+	router.POST("/password/forgot", ar.authController.RequestPasswordReset)
+	router.POST("/password/reset", ar.authController.ResetPassword)
+	authenticated.POST("/password/change", ar.authController.ChangePassword)
+	router.POST("/email/verify", ar.authController.VerifyEmail)
+	router.POST("/email/resend", ar.authController.ResendVerificationEmail)
+	authenticated.POST("/2fa/enroll", ar.authController.EnrollTwoFactor)
+	authenticated.POST("/2fa/confirm", ar.authController.ConfirmTwoFactor)
+	authenticated.POST("/2fa/disable", ar.authController.DisableTwoFactor)
+	authenticated.GET("/sessions", ar.authController.ListSessions)
+	authenticated.DELETE("/sessions/:session_id", ar.authController.RevokeSession)
+	authenticated.GET("/login-history", ar.authController.GetLoginHistory)
+	authenticated.POST("/tokens", ar.authController.CreatePersonalAccessToken)
+	authenticated.GET("/tokens", ar.authController.ListPersonalAccessTokens)
+	authenticated.DELETE("/tokens/:token_id", ar.authController.RevokePersonalAccessToken)
+	authenticated.POST("/oauth/clients", ar.authController.RegisterOAuthClient)
+	authenticated.GET("/oauth/clients", ar.authController.ListOAuthClients)
+	authenticated.DELETE("/oauth/clients/:client_id", ar.authController.DeleteOAuthClient)
+	authenticated.GET("/oauth/consents", ar.authController.ListOAuthConsents)
+	authenticated.DELETE("/oauth/consents/:client_id", ar.authController.RevokeOAuthConsent)
+	// Logout also takes a refresh token, which Authenticate does not accept
 	router.POST("/logout", ar.authController.Logout)
-	router.POST("/logout-from-all-devices", ar.authController.LogoutFromAllDevices)
-	router.DELETE("/delete-user/:user_id", ar.authController.DeleteUser)
+	authenticated.POST("/logout-from-all-devices", ar.authController.LogoutFromAllDevices)
+	authenticated.DELETE("/delete-user/:user_id", middlewares.RequirePermission(authz.PermissionDeleteUsers), ar.authController.DeleteUser)
+
+	admin := authenticated.Group("admin")
+	admin.POST("/unlock", middlewares.RequirePermission(authz.PermissionUnlockUsers), ar.authController.UnlockAccount)
+	admin.POST("/users/:user_id/roles", middlewares.RequirePermission(authz.PermissionManageRoles), ar.authController.GrantRole)
+	admin.DELETE("/users/:user_id/roles/:role", middlewares.RequirePermission(authz.PermissionManageRoles), ar.authController.RevokeRole)
+	admin.GET("/login-attempts/export", middlewares.RequirePermission(authz.PermissionReadAuditLog), ar.authController.ExportLoginAttempts)
+	admin.POST("/users/:user_id/impersonate", middlewares.RequirePermission(authz.PermissionImpersonateUsers), ar.authController.Impersonate)
+	admin.GET("/impersonations", middlewares.RequirePermission(authz.PermissionReadAuditLog), ar.authController.ListImpersonationSessions)
+
+	// OAuth2 authorization server for third-party apps. The consent screen of the webapp
+	// calls authorize for the signed-in user, apps redeem the code at the token endpoint.
+	oauth := rg.Group("oauth", ar.limit)
+	oauth.GET("/clients/:client_id", ar.authController.GetOAuthClient)
+	rg.POST("/oauth/authorize", ar.authenticate, ar.limit, ar.authController.AuthorizeOAuthClient)
+	oauth.POST("/token", ar.authController.OAuthToken)
 }
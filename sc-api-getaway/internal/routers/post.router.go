@@ -3,33 +3,43 @@ package routers
 import (
 	"github.com/gin-gonic/gin"
 	"soul-connect/sc-api-getaway/internal/controllers"
+	"soul-connect/sc-api-getaway/internal/middlewares"
 )
 
 type postRouter struct {
-	controller *controllers.PostController
+	controller   *controllers.PostController
+	authenticate gin.HandlerFunc
+	// limit limits all requests, limitWrites also the writes of every user against spam
+	limit       gin.HandlerFunc
+	limitWrites gin.HandlerFunc
 }
 
-func newPostRouter(controller *controllers.PostController) *postRouter {
-	return &postRouter{controller: controller}
+func newPostRouter(controller *controllers.PostController, authenticate gin.HandlerFunc, limit gin.HandlerFunc, limitWrites gin.HandlerFunc) *postRouter {
+	return &postRouter{controller: controller, authenticate: authenticate, limit: limit, limitWrites: limitWrites}
 }
 
-func (r *postRouter) setPostRoutes(group *gin.RouterGroup) {
-	group.POST("/posts", r.controller.CreatePost)
+func (r *postRouter) setPostRoutes(rg *gin.RouterGroup) {
+	// Reading stays public and is rate limited per client IP, writes need an access token or a
+	// personal access token with posts:write and are rate limited per user
+	group := rg.Group("", r.limit)
+	write := rg.Group("", r.authenticate, middlewares.RequireScope("posts:write"), r.limit, r.limitWrites)
+
+	write.POST("/posts", r.controller.CreatePost)
 	group.GET("/posts", r.controller.ListPosts)
 	group.GET("/posts/:post_id", r.controller.GetPost)
-	group.PUT("/posts/:post_id", r.controller.UpdatePost)
-	group.DELETE("/posts/:post_id", r.controller.DeletePost)
+	write.PUT("/posts/:post_id", r.controller.UpdatePost)
+	write.DELETE("/posts/:post_id", r.controller.DeletePost)
 
-	group.POST("/posts/:post_id/comments", r.controller.AddComment)
+	write.POST("/posts/:post_id/comments", r.controller.AddComment)
 	group.GET("/posts/:post_id/comments", r.controller.ListComments)
 
-	group.POST("/posts/:post_id/likes", r.controller.LikePost)
-	group.DELETE("/posts/:post_id/likes", r.controller.UnlikePost)
+	write.POST("/posts/:post_id/likes", r.controller.LikePost)
+	write.DELETE("/posts/:post_id/likes", r.controller.UnlikePost)
 
-	group.POST("/comments/:comment_id/likes", r.controller.LikeComment)
-	group.DELETE("/comments/:comment_id/likes", r.controller.UnlikeComment)
+	write.POST("/comments/:comment_id/likes", r.controller.LikeComment)
+	write.DELETE("/comments/:comment_id/likes", r.controller.UnlikeComment)
 
 	group.GET("/labels", r.controller.ListLabels)
-	group.POST("/posts/:post_id/labels", r.controller.AddLabelToPost)
-	group.DELETE("/posts/:post_id/labels/:label_id", r.controller.RemoveLabelFromPost)
+	write.POST("/posts/:post_id/labels", r.controller.AddLabelToPost)
+	write.DELETE("/posts/:post_id/labels/:label_id", r.controller.RemoveLabelFromPost)
 }
@@ -0,0 +1,244 @@
+package routers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"soul-connect/sc-api-getaway/internal/config"
+	"soul-connect/sc-api-getaway/internal/generated"
+	postpb "soul-connect/sc-post/pkg/postpb"
+)
+
+// fakePostServer is an in-memory sc-post that keeps posts and who liked them.
+type fakePostServer struct {
+	postpb.UnimplementedPostServiceServer
+	mu       sync.Mutex
+	posts    map[string]*postpb.Post
+	comments []*postpb.Comment
+	likes    map[string]map[string]bool
+}
+
+func newFakePostServer() *fakePostServer {
+	return &fakePostServer{
+		posts: map[string]*postpb.Post{},
+		likes: map[string]map[string]bool{},
+	}
+}
+
+func (s *fakePostServer) CreatePost(_ context.Context, req *postpb.CreatePostRequest) (*postpb.CreatePostResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	post := &postpb.Post{
+		Id:          "post-" + strconv.Itoa(len(s.posts)+1),
+		UserId:      req.UserId,
+		Title:       req.Title,
+		Description: req.Description,
+	}
+	s.posts[post.Id] = post
+	return &postpb.CreatePostResponse{Post: post}, nil
+}
+
+func (s *fakePostServer) GetPost(_ context.Context, req *postpb.GetPostRequest) (*postpb.GetPostResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	post, ok := s.posts[req.Id]
+	if !ok {
+		return nil, status.Error(codes.NotFound, "post not found")
+	}
+	return &postpb.GetPostResponse{Post: post}, nil
+}
+
+func (s *fakePostServer) UpdatePost(_ context.Context, req *postpb.UpdatePostRequest) (*postpb.Post, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	post, ok := s.posts[req.Id]
+	if !ok {
+		return nil, status.Error(codes.NotFound, "post not found")
+	}
+	post.Title = req.Title
+	post.Description = req.Description
+	return post, nil
+}
+
+func (s *fakePostServer) DeletePost(_ context.Context, req *postpb.GetPostRequest) (*postpb.Empty, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.posts, req.Id)
+	return &postpb.Empty{}, nil
+}
+
+func (s *fakePostServer) AddComment(_ context.Context, req *postpb.AddCommentRequest) (*postpb.AddCommentResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	comment := &postpb.Comment{
+		Id:      "comment-" + strconv.Itoa(len(s.comments)+1),
+		PostId:  req.PostId,
+		UserId:  req.UserId,
+		Content: req.Content,
+	}
+	s.comments = append(s.comments, comment)
+	return &postpb.AddCommentResponse{Comment: comment}, nil
+}
+
+func (s *fakePostServer) LikePost(_ context.Context, req *postpb.LikePostRequest) (*postpb.LikeCountResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.likes[req.PostId] == nil {
+		s.likes[req.PostId] = map[string]bool{}
+	}
+	s.likes[req.PostId][req.UserId] = true
+	return &postpb.LikeCountResponse{LikesCount: int32(len(s.likes[req.PostId]))}, nil
+}
+
+func (s *fakePostServer) UnlikePost(_ context.Context, req *postpb.UnlikePostRequest) (*postpb.LikeCountResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.likes[req.PostId], req.UserId)
+	return &postpb.LikeCountResponse{LikesCount: int32(len(s.likes[req.PostId]))}, nil
+}
+
+// Profile ids differ from the auth ids of their users, like they do in sc-user.
+const (
+	aliceProfileID = "3b8f6d21-9c4e-4a7f-b2d5-6e1a0c9f8d37"
+	bobProfileID   = "d41e7a96-0b3c-4f28-9a6d-5c2e8b7f1a04"
+)
+
+// newPostAuthors is a sc-user that knows the profiles of alice and bob.
+func newPostAuthors() *fakeUserServer {
+	users := newFakeUserServer()
+	users.profiles[aliceProfileID] = &generated.UserProfile{Id: aliceProfileID, AuthId: aliceID, FullName: "Alice Example"}
+	users.profiles[bobProfileID] = &generated.UserProfile{Id: bobProfileID, AuthId: bobID, FullName: "Bob Example"}
+	return users
+}
+
+func TestPostRoutes_TakeTheAuthorFromTheToken(t *testing.T) {
+	fake := newFakePostServer()
+	router := newTestRouter(t, newPostAuthors(), fake)
+
+	anonymous := doJSON(t, router, http.MethodPost, "/api/posts", "", map[string]any{"user_id": aliceID, "title": "Hello"})
+	require.Equal(t, http.StatusUnauthorized, anonymous.Code, anonymous.Body.String())
+
+	impostor := doJSON(t, router, http.MethodPost, "/api/posts", "bob-token", map[string]any{"user_id": aliceID, "title": "Hello"})
+	require.Equal(t, http.StatusForbidden, impostor.Code, impostor.Body.String())
+	require.Empty(t, fake.posts)
+
+	created := doJSON(t, router, http.MethodPost, "/api/posts", "alice-token", map[string]any{"title": "Hello"})
+	require.Equal(t, http.StatusCreated, created.Code, created.Body.String())
+	post := decodeJSON(t, created)
+	require.Equal(t, aliceProfileID, post["user_id"])
+	postID := post["id"].(string)
+
+	commented := doJSON(t, router, http.MethodPost, "/api/posts/"+postID+"/comments", "bob-token", map[string]any{"content": "Nice"})
+	require.Equal(t, http.StatusCreated, commented.Code, commented.Body.String())
+	require.Equal(t, bobProfileID, decodeJSON(t, commented)["user_id"])
+
+	impostor = doJSON(t, router, http.MethodPost, "/api/posts/"+postID+"/comments", "bob-token", map[string]any{"user_id": aliceID, "content": "Nice"})
+	require.Equal(t, http.StatusForbidden, impostor.Code, impostor.Body.String())
+	require.Len(t, fake.comments, 1)
+}
+
+func TestPostRoutes_LikesCountForTheCaller(t *testing.T) {
+	fake := newFakePostServer()
+	fake.posts["post-1"] = &postpb.Post{Id: "post-1", UserId: aliceProfileID, Title: "Hello"}
+	router := newTestRouter(t, newPostAuthors(), fake)
+
+	// No body is needed, the user comes from the token
+	liked := doJSON(t, router, http.MethodPost, "/api/posts/post-1/likes", "bob-token", nil)
+	require.Equal(t, http.StatusOK, liked.Code, liked.Body.String())
+	require.Equal(t, float64(1), decodeJSON(t, liked)["likes_count"])
+
+	impostor := doJSON(t, router, http.MethodPost, "/api/posts/post-1/likes", "bob-token", map[string]any{"user_id": aliceID})
+	require.Equal(t, http.StatusForbidden, impostor.Code, impostor.Body.String())
+
+	impostor = doJSON(t, router, http.MethodDelete, "/api/posts/post-1/likes", "alice-token", map[string]any{"user_id": bobID})
+	require.Equal(t, http.StatusForbidden, impostor.Code, impostor.Body.String())
+
+	require.Equal(t, map[string]bool{bobProfileID: true}, fake.likes["post-1"])
+
+	unliked := doJSON(t, router, http.MethodDelete, "/api/posts/post-1/likes", "bob-token", map[string]any{"user_id": bobID})
+	require.Equal(t, http.StatusOK, unliked.Code, unliked.Body.String())
+	require.Empty(t, fake.likes["post-1"])
+}
+
+func TestPostRoutes_OnlyTheAuthorChangesAPost(t *testing.T) {
+	fake := newFakePostServer()
+	fake.posts["post-1"] = &postpb.Post{Id: "post-1", UserId: aliceProfileID, Title: "Hello"}
+	router := newTestRouter(t, newPostAuthors(), fake)
+
+	update := map[string]any{"title": "Edited", "description": "by someone"}
+	forbidden := doJSON(t, router, http.MethodPut, "/api/posts/post-1", "bob-token", update)
+	require.Equal(t, http.StatusForbidden, forbidden.Code, forbidden.Body.String())
+	forbidden = doJSON(t, router, http.MethodPut, "/api/posts/post-1", "moderator-token", update)
+	require.Equal(t, http.StatusForbidden, forbidden.Code, forbidden.Body.String())
+	require.Equal(t, "Hello", fake.posts["post-1"].Title)
+
+	updated := doJSON(t, router, http.MethodPut, "/api/posts/post-1", "alice-token", update)
+	require.Equal(t, http.StatusOK, updated.Code, updated.Body.String())
+	require.Equal(t, "Edited", fake.posts["post-1"].Title)
+
+	forbidden = doJSON(t, router, http.MethodDelete, "/api/posts/post-1", "bob-token", nil)
+	require.Equal(t, http.StatusForbidden, forbidden.Code, forbidden.Body.String())
+	require.Contains(t, fake.posts, "post-1")
+
+	// Moderators may take posts down
+	deleted := doJSON(t, router, http.MethodDelete, "/api/posts/post-1", "moderator-token", nil)
+	require.Equal(t, http.StatusNoContent, deleted.Code, deleted.Body.String())
+	require.Empty(t, fake.posts)
+}
+
+func TestPostRoutes_LimitWritesPerUser(t *testing.T) {
+	cfg := &config.Config{EnvType: "prod", WebappBaseUrl: "http://localhost:3000", PostWriteRateLimit: "2/1h"}
+	router := newTestRouterWithConfig(t, cfg, newPostAuthors(), newFakePostServer())
+
+	for range 2 {
+		created := doJSON(t, router, http.MethodPost, "/api/posts", "alice-token", map[string]any{"title": "Buy now"})
+		require.Equal(t, http.StatusCreated, created.Code, created.Body.String())
+	}
+
+	limited := doJSON(t, router, http.MethodPost, "/api/posts", "alice-token", map[string]any{"title": "Buy now"})
+	require.Equal(t, http.StatusTooManyRequests, limited.Code, limited.Body.String())
+	require.Equal(t, "1800", limited.Header().Get("Retry-After"))
+
+	// Reads and other users are not affected
+	read := doJSON(t, router, http.MethodGet, "/api/posts/post-1", "alice-token", nil)
+	require.Equal(t, http.StatusOK, read.Code, read.Body.String())
+	created := doJSON(t, router, http.MethodPost, "/api/posts", "bob-token", map[string]any{"title": "Hello"})
+	require.Equal(t, http.StatusCreated, created.Code, created.Body.String())
+}
+
+func TestPostRoutes_WriteAsTheProfileOfTheCaller(t *testing.T) {
+	fake := newFakePostServer()
+	router := newTestRouter(t, newPostAuthors(), fake)
+
+	// Naming oneself by profile id is fine, sc-post gets the profile id either way
+	created := doJSON(t, router, http.MethodPost, "/api/posts", "alice-token", map[string]any{"user_id": aliceProfileID, "title": "Hello"})
+	require.Equal(t, http.StatusCreated, created.Code, created.Body.String())
+	postID := decodeJSON(t, created)["id"].(string)
+	require.Equal(t, aliceProfileID, fake.posts[postID].UserId)
+
+	impostor := doJSON(t, router, http.MethodPost, "/api/posts", "bob-token", map[string]any{"user_id": aliceProfileID, "title": "Hello"})
+	require.Equal(t, http.StatusForbidden, impostor.Code, impostor.Body.String())
+
+	updated := doJSON(t, router, http.MethodPut, "/api/posts/"+postID, "alice-token", map[string]any{"title": "Edited"})
+	require.Equal(t, http.StatusOK, updated.Code, updated.Body.String())
+	require.Equal(t, "Edited", fake.posts[postID].Title)
+
+	// Users without a profile cannot write yet
+	router = newTestRouter(t, newFakeUserServer(), fake)
+	noProfile := doJSON(t, router, http.MethodPost, "/api/posts", "alice-token", map[string]any{"title": "Hello"})
+	require.Equal(t, http.StatusConflict, noProfile.Code, noProfile.Body.String())
+	require.Len(t, fake.posts, 1)
+}
@@ -6,9 +6,13 @@ import (
 	"github.com/gin-gonic/gin"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
+	"log"
 	"net/http"
 	"soul-connect/sc-api-getaway/internal/config"
 	"soul-connect/sc-api-getaway/internal/controllers"
+	"soul-connect/sc-api-getaway/internal/generated"
+	"soul-connect/sc-api-getaway/internal/middlewares"
+	"soul-connect/sc-api-getaway/internal/ratelimit"
 )
 
 type Router struct {
@@ -16,17 +20,33 @@ type Router struct {
 	config     *config.Config
 	authRouter *authRouter
 	postRouter *postRouter
+	userRouter *userRouter
 }
 
-func NewRouter(config *config.Config, controller *controllers.Controller) *Router {
+// rateLimits holds the rate limiting middlewares of the route groups.
+type rateLimits struct {
+	auth      gin.HandlerFunc
+	login     gin.HandlerFunc
+	post      gin.HandlerFunc
+	postWrite gin.HandlerFunc
+	user      gin.HandlerFunc
+	userWrite gin.HandlerFunc
+}
+
+func NewRouter(config *config.Config, controller *controllers.Controller, authClient generated.AuthServiceClient, limiter ratelimit.Limiter) *Router {
 	ginRouter := gin.Default()
+	// The client IP rate limits are keyed by is only taken from X-Forwarded-For of trusted proxies
+	if err := ginRouter.SetTrustedProxies(config.TrustedProxies); err != nil {
+		log.Fatalf("invalid TRUSTED_PROXIES: %v", err)
+	}
+	ginRouter.Use(middlewares.RequestContext())
 
 	// Apply CORS middleware with custom options
 	ginRouter.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{config.WebappBaseUrl},
 		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Accept-Language", "Authorization", middlewares.RequestIDHeader},
+		ExposeHeaders:    []string{"Content-Length", middlewares.ImpersonatedByHeader, middlewares.RequestIDHeader},
 		AllowCredentials: true,
 	}))
 
@@ -34,24 +54,48 @@ func NewRouter(config *config.Config, controller *controllers.Controller) *Route
 		ctx.JSON(http.StatusNotFound, gin.H{"status": "failed", "message": fmt.Sprintf("The specified route %s not found", ctx.Request.URL)})
 	})
 
+	authenticate := middlewares.Authenticate(authClient)
+	limits := newRateLimits(config, limiter)
+
 	return &Router{
 		Gin:        ginRouter,
 		config:     config,
-		authRouter: newAuthRouter(controller.AuthController, config),
-		postRouter: newPostRouter(controller.PostController),
-
+		authRouter: newAuthRouter(controller.AuthController, config, authenticate, limits.auth, limits.login),
+		postRouter: newPostRouter(controller.PostController, authenticate, limits.post, limits.postWrite),
+		userRouter: newUserRouter(controller.UserController, config, authenticate, limits.user, limits.userWrite),
 	}
 }
 
 func (r *Router) SetRoutes() {
 	api := r.Gin.Group("/api")
 
-	r.authRouter.setAuthRoutes(api)
-	r.postRouter.setPostRoutes(api)
-
+	// The routers apply the rate limits of their groups themselves, after Authenticate
+	// where the routes need a caller, so that users are limited per user and not per IP
+	r.authRouter.setAuthRoutes(api.Group("", middlewares.Timeout(r.config.Timeout(r.config.AuthRequestTimeout))))
+	r.postRouter.setPostRoutes(api.Group("", middlewares.Timeout(r.config.Timeout(r.config.PostRequestTimeout))))
+	r.userRouter.setUserRoutes(api.Group("", middlewares.Timeout(r.config.Timeout(r.config.UserRequestTimeout))))
 
 	if r.config.EnvType != "prod" {
 		// r.devRouter.setDevRoutes(api)
 		r.Gin.GET("/docs/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 	}
 }
+
+func newRateLimits(cfg *config.Config, limiter ratelimit.Limiter) *rateLimits {
+	rateLimit := func(name string, groupLimit string, defaultLimit string) gin.HandlerFunc {
+		policy, err := ratelimit.ParsePolicy(cfg.RateLimit(groupLimit, defaultLimit))
+		if err != nil {
+			log.Fatalf("invalid %s rate limit: %v", name, err)
+		}
+		return middlewares.RateLimit(limiter, name, policy)
+	}
+
+	return &rateLimits{
+		auth:      rateLimit("auth", cfg.AuthRateLimit, config.DefaultAuthRateLimit),
+		login:     rateLimit("login", cfg.LoginRateLimit, config.DefaultLoginRateLimit),
+		post:      rateLimit("post", cfg.PostRateLimit, config.DefaultPostRateLimit),
+		postWrite: rateLimit("post-write", cfg.PostWriteRateLimit, config.DefaultPostWriteRateLimit),
+		user:      rateLimit("user", cfg.UserRateLimit, config.DefaultUserRateLimit),
+		userWrite: rateLimit("user-write", cfg.UserWriteRateLimit, config.DefaultUserWriteRateLimit),
+	}
+}
@@ -0,0 +1,341 @@
+package routers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"soul-connect/pkg/requestctx"
+	"soul-connect/sc-api-getaway/internal/config"
+	"soul-connect/sc-api-getaway/internal/controllers"
+	"soul-connect/sc-api-getaway/internal/generated"
+	"soul-connect/sc-api-getaway/internal/ratelimit"
+	"soul-connect/sc-auth/pkg/authz"
+	postpb "soul-connect/sc-post/pkg/postpb"
+)
+
+const (
+	aliceID     = "7f1d3c52-1f7a-4b7e-9f0a-2d5b6c8e9a10"
+	bobID       = "0c9e4a8b-5d2f-4e61-8b7a-3f1d2c6e9b04"
+	moderatorID = "5a7b9c1d-2e3f-4a5b-8c6d-7e8f9a0b1c2d"
+)
+
+// fakeAuthClient answers token introspection like sc-auth would for a fixed set of tokens.
+type fakeAuthClient struct {
+	generated.AuthServiceClient
+	tokens map[string]*generated.IntrospectTokenResponse
+}
+
+func newFakeAuthClient() *fakeAuthClient {
+	return &fakeAuthClient{tokens: map[string]*generated.IntrospectTokenResponse{
+		"alice-token": {Active: true, UserId: aliceID, TokenType: "access"},
+		"bob-token":   {Active: true, UserId: bobID, TokenType: "access"},
+		"moderator-token": {
+			Active:      true,
+			UserId:      moderatorID,
+			TokenType:   "access",
+			Roles:       []string{authz.RoleModerator},
+			Permissions: []string{authz.PermissionModeratePosts},
+		},
+	}}
+}
+
+func (c *fakeAuthClient) IntrospectToken(_ context.Context, in *generated.IntrospectTokenRequest, _ ...grpc.CallOption) (*generated.IntrospectTokenResponse, error) {
+	if introspection, ok := c.tokens[in.Token]; ok {
+		return introspection, nil
+	}
+	return &generated.IntrospectTokenResponse{Active: false}, nil
+}
+
+// Login accepts no credentials at all.
+func (c *fakeAuthClient) Login(context.Context, *generated.LoginUserRequest, ...grpc.CallOption) (*generated.LoginUserResponse, error) {
+	return nil, status.Error(codes.Unauthenticated, "invalid credentials")
+}
+
+// ListSessions answers every caller with no sessions.
+func (c *fakeAuthClient) ListSessions(context.Context, *generated.ListSessionsRequest, ...grpc.CallOption) (*generated.ListSessionsResponse, error) {
+	return &generated.ListSessionsResponse{}, nil
+}
+
+// dialTestServer starts a gRPC server on an in-memory listener and connects to it.
+func dialTestServer(t *testing.T, register func(server *grpc.Server)) *grpc.ClientConn {
+	t.Helper()
+
+	listener := bufconn.Listen(1 << 20)
+	server := grpc.NewServer()
+	register(server)
+	go func() {
+		_ = server.Serve(listener)
+	}()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithUnaryInterceptor(requestctx.UnaryClientInterceptor()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn
+}
+
+// newTestRouter serves the gateway routes in front of fake backends. Backends the test
+// does not need can be nil.
+func newTestRouter(t *testing.T, userServer generated.UserServiceServer, postServer postpb.PostServiceServer) *gin.Engine {
+	t.Helper()
+	return newTestRouterWithConfig(t, &config.Config{EnvType: "prod", WebappBaseUrl: "http://localhost:3000"}, userServer, postServer)
+}
+
+func newTestRouterWithConfig(t *testing.T, cfg *config.Config, userServer generated.UserServiceServer, postServer postpb.PostServiceServer) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	var userClient generated.UserServiceClient
+	if userServer != nil {
+		userClient = generated.NewUserServiceClient(dialTestServer(t, func(server *grpc.Server) {
+			generated.RegisterUserServiceServer(server, userServer)
+		}))
+	}
+	var postClient postpb.PostServiceClient
+	if postServer != nil {
+		postClient = postpb.NewPostServiceClient(dialTestServer(t, func(server *grpc.Server) {
+			postpb.RegisterPostServiceServer(server, postServer)
+		}))
+	}
+
+	authClient := newFakeAuthClient()
+	controller := controllers.NewController(authClient, postClient, userClient)
+	router := NewRouter(cfg, controller, authClient, ratelimit.NewMemoryLimiter())
+	router.SetRoutes()
+	return router.Gin
+}
+
+// doJSON sends a request with an optional bearer token and JSON body.
+func doJSON(t *testing.T, handler http.Handler, method string, path string, token string, body any) *httptest.ResponseRecorder {
+	t.Helper()
+
+	var encoded []byte
+	if body != nil {
+		var err error
+		encoded, err = json.Marshal(body)
+		require.NoError(t, err)
+	}
+
+	req := httptest.NewRequest(method, path, bytes.NewReader(encoded))
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func decodeJSON(t *testing.T, recorder *httptest.ResponseRecorder) map[string]any {
+	t.Helper()
+
+	var decoded map[string]any
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &decoded), recorder.Body.String())
+	return decoded
+}
+
+// inspectingUserServer hands the context of profile reads and updates to inspect before
+// answering like fakeUserServer.
+type inspectingUserServer struct {
+	*fakeUserServer
+	inspect func(ctx context.Context)
+}
+
+func (s *inspectingUserServer) GetProfile(ctx context.Context, req *generated.GetProfileRequest) (*generated.UserProfile, error) {
+	s.inspect(ctx)
+	return s.fakeUserServer.GetProfile(ctx, req)
+}
+
+func (s *inspectingUserServer) UpdateProfile(ctx context.Context, req *generated.UpdateProfileRequest) (*generated.UserProfile, error) {
+	s.inspect(ctx)
+	return s.fakeUserServer.UpdateProfile(ctx, req)
+}
+
+func TestRouter_ForwardsRequestMetadataToTheBackends(t *testing.T) {
+	fake := newFakeUserServer()
+	fake.profiles["alice"] = &generated.UserProfile{Id: "alice", AuthId: aliceID, FullName: "Alice Example"}
+	var received metadata.MD
+	router := newTestRouter(t, &inspectingUserServer{fakeUserServer: fake, inspect: func(ctx context.Context) {
+		received, _ = metadata.FromIncomingContext(ctx)
+	}}, nil)
+
+	req := httptest.NewRequest(http.MethodPut, "/api/users/alice", strings.NewReader(`{"full_name":"Alice Renamed"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer alice-token")
+	req.Header.Set("X-Request-ID", "req-1234")
+	req.Header.Set("Accept-Language", "de-DE,de;q=0.9,en;q=0.8")
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+
+	require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+	require.Equal(t, "req-1234", recorder.Header().Get("X-Request-ID"))
+	require.Equal(t, []string{"req-1234"}, received.Get(requestctx.RequestIDKey))
+	require.Equal(t, []string{aliceID}, received.Get(requestctx.CallerUserIDKey))
+	require.Empty(t, received.Get(requestctx.CallerActorIDKey))
+	require.Equal(t, []string{"de-DE"}, received.Get(requestctx.LocaleKey))
+}
+
+func TestRouter_ForwardsOnlyPrintableUserAgents(t *testing.T) {
+	fake := newFakeUserServer()
+	fake.profiles["alice"] = &generated.UserProfile{Id: "alice", AuthId: aliceID, FullName: "Alice Example"}
+	var received metadata.MD
+	router := newTestRouter(t, &inspectingUserServer{fakeUserServer: fake, inspect: func(ctx context.Context) {
+		received, _ = metadata.FromIncomingContext(ctx)
+	}}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/users/alice", nil)
+	req.Header.Set("User-Agent", "Mozilla/5.0\t(Gerät) "+strings.Repeat("x", 1000))
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+
+	require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+	userAgent := received.Get(requestctx.ClientUserAgentKey)
+	require.Len(t, userAgent, 1)
+	require.True(t, strings.HasPrefix(userAgent[0], "Mozilla/5.0(Gert) xxx"), userAgent[0])
+	require.Len(t, userAgent[0], 512)
+}
+
+func TestRouter_GeneratesMissingRequestIDs(t *testing.T) {
+	router := newTestRouter(t, newFakeUserServer(), nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/users/missing", nil)
+	req.Header.Set("X-Request-ID", "not a valid id")
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+
+	requestID := recorder.Header().Get("X-Request-ID")
+	require.NotEmpty(t, requestID)
+	require.NotEqual(t, "not a valid id", requestID)
+}
+
+func TestRouter_AnswersSlowBackendsWithGatewayTimeout(t *testing.T) {
+	fake := newFakeUserServer()
+	fake.profiles["alice"] = &generated.UserProfile{Id: "alice", AuthId: aliceID, FullName: "Alice Example"}
+	cfg := &config.Config{EnvType: "prod", WebappBaseUrl: "http://localhost:3000", UserRequestTimeout: 50 * time.Millisecond}
+	router := newTestRouterWithConfig(t, cfg, &inspectingUserServer{fakeUserServer: fake, inspect: func(ctx context.Context) {
+		<-ctx.Done()
+		// The server learns about the deadline a moment before the gateway does, answering
+		// right away could still beat it
+		time.Sleep(cfg.UserRequestTimeout)
+	}}, nil)
+
+	recorder := doJSON(t, router, http.MethodGet, "/api/users/alice", "alice-token", nil)
+
+	require.Equal(t, http.StatusGatewayTimeout, recorder.Code, recorder.Body.String())
+	require.Equal(t, "deadline_exceeded", decodeJSON(t, recorder)["code"])
+}
+
+func TestRouter_LimitsLoginAttemptsPerClientIP(t *testing.T) {
+	cfg := &config.Config{
+		EnvType:        "prod",
+		WebappBaseUrl:  "http://localhost:3000",
+		TrustedProxies: []string{"192.0.2.0/24"},
+		LoginRateLimit: "2/1m",
+	}
+	router := newTestRouterWithConfig(t, cfg, nil, nil)
+
+	login := func(forwardedFor string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/auth/login", strings.NewReader(`{"email":"alice@example.com","password":"guess"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	for range 2 {
+		recorder := login("198.51.100.7")
+		require.Equal(t, http.StatusUnauthorized, recorder.Code, recorder.Body.String())
+	}
+
+	limited := login("198.51.100.7")
+	require.Equal(t, http.StatusTooManyRequests, limited.Code, limited.Body.String())
+	require.Equal(t, "30", limited.Header().Get("Retry-After"))
+	require.Equal(t, "resource_exhausted", decodeJSON(t, limited)["code"])
+
+	// nginx appends the peer to the addresses the client sent, which must not help it
+	spoofed := login("203.0.113.9, 198.51.100.7")
+	require.Equal(t, http.StatusTooManyRequests, spoofed.Code, spoofed.Body.String())
+
+	other := login("203.0.113.9")
+	require.Equal(t, http.StatusUnauthorized, other.Code, other.Body.String())
+}
+
+func TestRouter_IgnoresForwardedForFromUntrustedPeers(t *testing.T) {
+	cfg := &config.Config{EnvType: "prod", WebappBaseUrl: "http://localhost:3000", LoginRateLimit: "1/1m"}
+	router := newTestRouterWithConfig(t, cfg, nil, nil)
+
+	for i, forwardedFor := range []string{"198.51.100.7", "203.0.113.9"} {
+		req := httptest.NewRequest(http.MethodPost, "/api/auth/login", strings.NewReader(`{"email":"alice@example.com","password":"guess"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+
+		expected := http.StatusUnauthorized
+		if i > 0 {
+			expected = http.StatusTooManyRequests
+		}
+		require.Equal(t, expected, recorder.Code, recorder.Body.String())
+	}
+}
+
+func TestRouter_LimitsUsersBehindOneIPSeparately(t *testing.T) {
+	fake := newFakeUserServer()
+	fake.profiles["alice"] = &generated.UserProfile{Id: "alice", AuthId: aliceID, FullName: "Alice Example"}
+	fake.profiles["bob"] = &generated.UserProfile{Id: "bob", AuthId: bobID, FullName: "Bob Example"}
+	cfg := &config.Config{EnvType: "prod", WebappBaseUrl: "http://localhost:3000", UserRateLimit: "2/1h"}
+	router := newTestRouterWithConfig(t, cfg, fake, nil)
+
+	// All requests come from the same client IP
+	for range 2 {
+		updated := doJSON(t, router, http.MethodPut, "/api/users/alice", "alice-token", map[string]any{"bio": "Hi"})
+		require.Equal(t, http.StatusOK, updated.Code, updated.Body.String())
+	}
+	limited := doJSON(t, router, http.MethodPut, "/api/users/alice", "alice-token", map[string]any{"bio": "Hi"})
+	require.Equal(t, http.StatusTooManyRequests, limited.Code, limited.Body.String())
+
+	updated := doJSON(t, router, http.MethodPut, "/api/users/bob", "bob-token", map[string]any{"bio": "Hi"})
+	require.Equal(t, http.StatusOK, updated.Code, updated.Body.String())
+	read := doJSON(t, router, http.MethodGet, "/api/users/alice", "", nil)
+	require.Equal(t, http.StatusOK, read.Code, read.Body.String())
+}
+
+func TestRouter_LimitsAuthRoutesThatNeedATokenPerUser(t *testing.T) {
+	cfg := &config.Config{EnvType: "prod", WebappBaseUrl: "http://localhost:3000", AuthRateLimit: "2/1h"}
+	router := newTestRouterWithConfig(t, cfg, nil, nil)
+
+	// All requests come from the same client IP
+	for range 2 {
+		listed := doJSON(t, router, http.MethodGet, "/api/auth/sessions", "alice-token", nil)
+		require.Equal(t, http.StatusOK, listed.Code, listed.Body.String())
+	}
+	limited := doJSON(t, router, http.MethodGet, "/api/auth/sessions", "alice-token", nil)
+	require.Equal(t, http.StatusTooManyRequests, limited.Code, limited.Body.String())
+
+	listed := doJSON(t, router, http.MethodGet, "/api/auth/sessions", "bob-token", nil)
+	require.Equal(t, http.StatusOK, listed.Code, listed.Body.String())
+	anonymous := doJSON(t, router, http.MethodGet, "/api/auth/sessions", "", nil)
+	require.Equal(t, http.StatusUnauthorized, anonymous.Code, anonymous.Body.String())
+}
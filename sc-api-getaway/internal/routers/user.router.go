@@ -4,24 +4,33 @@ import (
 	"github.com/gin-gonic/gin"
 	"soul-connect/sc-api-getaway/internal/config"
 	"soul-connect/sc-api-getaway/internal/controllers"
+	"soul-connect/sc-api-getaway/internal/middlewares"
 )
 
 type userRouter struct {
-	controller *controllers.UserController
-	config     *config.Config
+	controller   *controllers.UserController
+	config       *config.Config
+	authenticate gin.HandlerFunc
+	// limit limits all requests, limitWrites also the profile changes and subscriptions of every user
+	limit       gin.HandlerFunc
+	limitWrites gin.HandlerFunc
 }
 
-func newUserRouter(controller *controllers.UserController, config *config.Config) *userRouter {
-	return &userRouter{controller: controller, config: config}
+func newUserRouter(controller *controllers.UserController, config *config.Config, authenticate gin.HandlerFunc, limit gin.HandlerFunc, limitWrites gin.HandlerFunc) *userRouter {
+	return &userRouter{controller: controller, config: config, authenticate: authenticate, limit: limit, limitWrites: limitWrites}
 }
 
 func (ur *userRouter) setUserRoutes(rg *gin.RouterGroup) {
-	router := rg.Group("users")
-	router.POST("", ur.controller.CreateProfile)
+	// Profiles are public and rate limited per client IP, changes are made by their owner with an access
+	// token or a personal access token with users:write and are rate limited per user
+	router := rg.Group("users", ur.limit)
+	write := rg.Group("users", ur.authenticate, middlewares.RequireScope("users:write"), ur.limit, ur.limitWrites)
+
+	write.POST("", ur.controller.CreateProfile)
 	router.GET("/:id", ur.controller.GetProfile)
-	router.PUT("/:id", ur.controller.UpdateProfile)
-	router.DELETE("/:id", ur.controller.DeleteProfile)
-	router.POST("/:id/subscriptions", ur.controller.Subscribe)
+	write.PUT("/:id", ur.controller.UpdateProfile)
+	write.DELETE("/:id", ur.controller.DeleteProfile)
+	write.POST("/:id/subscriptions", ur.controller.Subscribe)
 	router.GET("/:id/subscriptions", ur.controller.ListSubscriptions)
-	router.DELETE("/:id/subscriptions/:author_id", ur.controller.Unsubscribe)
+	write.DELETE("/:id/subscriptions/:author_id", ur.controller.Unsubscribe)
 }
@@ -0,0 +1,326 @@
+package routers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"soul-connect/sc-api-getaway/internal/config"
+	"soul-connect/sc-api-getaway/internal/generated"
+)
+
+// fakeUserServer is an in-memory sc-user. It records the requests it received.
+type fakeUserServer struct {
+	generated.UnimplementedUserServiceServer
+	mu            sync.Mutex
+	profiles      map[string]*generated.UserProfile
+	subscriptions map[string][]string
+	calls         []string
+	// createErr makes CreateProfile fail
+	createErr error
+}
+
+func newFakeUserServer() *fakeUserServer {
+	return &fakeUserServer{
+		profiles:      map[string]*generated.UserProfile{},
+		subscriptions: map[string][]string{},
+	}
+}
+
+func (s *fakeUserServer) record(call string) {
+	s.calls = append(s.calls, call)
+}
+
+func (s *fakeUserServer) CreateProfile(_ context.Context, req *generated.CreateProfileRequest) (*generated.UserProfile, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.record("CreateProfile")
+	if s.createErr != nil {
+		return nil, s.createErr
+	}
+
+	profile := &generated.UserProfile{
+		Id:        "profile-" + strconv.Itoa(len(s.profiles)+1),
+		AuthId:    req.AuthId,
+		FullName:  req.FullName,
+		Bio:       req.Bio,
+		PhotoLink: req.PhotoLink,
+		CreatedAt: "2024-01-01T00:00:00Z",
+		UpdatedAt: "2024-01-01T00:00:00Z",
+	}
+	s.profiles[profile.Id] = profile
+	return profile, nil
+}
+
+func (s *fakeUserServer) GetProfile(_ context.Context, req *generated.GetProfileRequest) (*generated.UserProfile, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.record("GetProfile")
+
+	profile, ok := s.profiles[req.Id]
+	if !ok {
+		return nil, status.Error(codes.NotFound, "profile not found")
+	}
+	return profile, nil
+}
+
+func (s *fakeUserServer) GetProfileByAuthId(_ context.Context, req *generated.GetProfileByAuthIdRequest) (*generated.UserProfile, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.record("GetProfileByAuthId")
+
+	for _, profile := range s.profiles {
+		if profile.AuthId == req.AuthId {
+			return profile, nil
+		}
+	}
+	return nil, status.Error(codes.NotFound, "profile not found")
+}
+
+func (s *fakeUserServer) UpdateProfile(_ context.Context, req *generated.UpdateProfileRequest) (*generated.UserProfile, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.record("UpdateProfile")
+
+	profile, ok := s.profiles[req.Id]
+	if !ok {
+		return nil, status.Error(codes.NotFound, "profile not found")
+	}
+	if req.FullName != nil {
+		profile.FullName = *req.FullName
+	}
+	if req.Bio != nil {
+		profile.Bio = req.Bio
+	}
+	if req.PhotoLink != nil {
+		profile.PhotoLink = req.PhotoLink
+	}
+	profile.UpdatedAt = "2024-01-02T00:00:00Z"
+	return profile, nil
+}
+
+func (s *fakeUserServer) DeleteProfile(_ context.Context, req *generated.DeleteProfileRequest) (*generated.Empty, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.record("DeleteProfile")
+
+	if _, ok := s.profiles[req.Id]; !ok {
+		return nil, status.Error(codes.NotFound, "profile not found")
+	}
+	delete(s.profiles, req.Id)
+	return &generated.Empty{}, nil
+}
+
+func (s *fakeUserServer) Subscribe(_ context.Context, req *generated.ModifySubscriptionRequest) (*generated.Empty, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.record("Subscribe")
+
+	if !slices.Contains(s.subscriptions[req.SubscriberId], req.AuthorId) {
+		s.subscriptions[req.SubscriberId] = append(s.subscriptions[req.SubscriberId], req.AuthorId)
+	}
+	return &generated.Empty{}, nil
+}
+
+func (s *fakeUserServer) Unsubscribe(_ context.Context, req *generated.ModifySubscriptionRequest) (*generated.Empty, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.record("Unsubscribe")
+
+	s.subscriptions[req.SubscriberId] = slices.DeleteFunc(s.subscriptions[req.SubscriberId], func(authorID string) bool {
+		return authorID == req.AuthorId
+	})
+	return &generated.Empty{}, nil
+}
+
+func (s *fakeUserServer) ListSubscriptions(_ context.Context, req *generated.ListSubscriptionsRequest) (*generated.ListSubscriptionsResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.record("ListSubscriptions")
+
+	return &generated.ListSubscriptionsResponse{
+		SubscriberId: req.SubscriberId,
+		AuthorIds:    slices.Clone(s.subscriptions[req.SubscriberId]),
+	}, nil
+}
+
+func TestUserRoutes_ProfileLifecycle(t *testing.T) {
+	fake := newFakeUserServer()
+	router := newTestRouter(t, fake, nil)
+
+	// The profile belongs to the authenticated account, the body does not name it
+	created := doJSON(t, router, http.MethodPost, "/api/users", "alice-token", map[string]any{
+		"full_name": "Alice Example",
+		"bio":       "hello",
+	})
+	require.Equal(t, http.StatusCreated, created.Code, created.Body.String())
+	profile := decodeJSON(t, created)
+	require.Equal(t, aliceID, profile["auth_id"])
+	require.Equal(t, "Alice Example", profile["full_name"])
+	require.Equal(t, "hello", profile["bio"])
+	require.NotContains(t, profile, "photo_link")
+	id := profile["id"].(string)
+
+	fetched := doJSON(t, router, http.MethodGet, "/api/users/"+id, "", nil)
+	require.Equal(t, http.StatusOK, fetched.Code, fetched.Body.String())
+	require.Equal(t, aliceID, decodeJSON(t, fetched)["auth_id"])
+
+	update := map[string]any{"photo_link": "https://cdn.example.com/alice.png"}
+	forbidden := doJSON(t, router, http.MethodPut, "/api/users/"+id, "bob-token", update)
+	require.Equal(t, http.StatusForbidden, forbidden.Code, forbidden.Body.String())
+
+	// Fields left out of the update stay as they are
+	updated := doJSON(t, router, http.MethodPut, "/api/users/"+id, "alice-token", update)
+	require.Equal(t, http.StatusOK, updated.Code, updated.Body.String())
+	profile = decodeJSON(t, updated)
+	require.Equal(t, "Alice Example", profile["full_name"])
+	require.Equal(t, "hello", profile["bio"])
+	require.Equal(t, "https://cdn.example.com/alice.png", profile["photo_link"])
+
+	forbidden = doJSON(t, router, http.MethodDelete, "/api/users/"+id, "bob-token", nil)
+	require.Equal(t, http.StatusForbidden, forbidden.Code, forbidden.Body.String())
+	require.Len(t, fake.profiles, 1)
+
+	deleted := doJSON(t, router, http.MethodDelete, "/api/users/"+id, "alice-token", nil)
+	require.Equal(t, http.StatusNoContent, deleted.Code, deleted.Body.String())
+	require.Empty(t, fake.profiles)
+}
+
+func TestUserRoutes_ProfilesCannotBeCreatedForOthers(t *testing.T) {
+	fake := newFakeUserServer()
+	router := newTestRouter(t, fake, nil)
+
+	anonymous := doJSON(t, router, http.MethodPost, "/api/users", "", map[string]any{"auth_id": aliceID, "full_name": "Alice Example"})
+	require.Equal(t, http.StatusUnauthorized, anonymous.Code, anonymous.Body.String())
+
+	mismatch := doJSON(t, router, http.MethodPost, "/api/users", "bob-token", map[string]any{"auth_id": aliceID, "full_name": "Alice Example"})
+	require.Equal(t, http.StatusForbidden, mismatch.Code, mismatch.Body.String())
+
+	// Naming yourself is still accepted
+	matching := doJSON(t, router, http.MethodPost, "/api/users", "alice-token", map[string]any{"auth_id": aliceID, "full_name": "Alice Example"})
+	require.Equal(t, http.StatusCreated, matching.Code, matching.Body.String())
+
+	require.Equal(t, []string{"CreateProfile"}, fake.calls)
+}
+
+func TestUserRoutes_Subscriptions(t *testing.T) {
+	fake := newFakeUserServer()
+	fake.profiles["reader"] = &generated.UserProfile{Id: "reader", AuthId: aliceID, FullName: "Alice Example"}
+	router := newTestRouter(t, fake, nil)
+
+	for _, authorID := range []string{"author-1", "author-2"} {
+		subscribed := doJSON(t, router, http.MethodPost, "/api/users/reader/subscriptions", "alice-token", map[string]any{"author_id": authorID})
+		require.Equal(t, http.StatusCreated, subscribed.Code, subscribed.Body.String())
+	}
+
+	// Nobody can subscribe or unsubscribe someone else
+	forbidden := doJSON(t, router, http.MethodPost, "/api/users/reader/subscriptions", "bob-token", map[string]any{"author_id": "author-3"})
+	require.Equal(t, http.StatusForbidden, forbidden.Code, forbidden.Body.String())
+	forbidden = doJSON(t, router, http.MethodDelete, "/api/users/reader/subscriptions/author-2", "bob-token", nil)
+	require.Equal(t, http.StatusForbidden, forbidden.Code, forbidden.Body.String())
+
+	listed := doJSON(t, router, http.MethodGet, "/api/users/reader/subscriptions", "", nil)
+	require.Equal(t, http.StatusOK, listed.Code, listed.Body.String())
+	require.Equal(t, map[string]any{
+		"subscriber_id": "reader",
+		"author_ids":    []any{"author-1", "author-2"},
+	}, decodeJSON(t, listed))
+
+	unsubscribed := doJSON(t, router, http.MethodDelete, "/api/users/reader/subscriptions/author-1", "alice-token", nil)
+	require.Equal(t, http.StatusNoContent, unsubscribed.Code, unsubscribed.Body.String())
+
+	listed = doJSON(t, router, http.MethodGet, "/api/users/reader/subscriptions", "", nil)
+	require.Equal(t, http.StatusOK, listed.Code, listed.Body.String())
+	require.Equal(t, []any{"author-2"}, decodeJSON(t, listed)["author_ids"])
+}
+
+func TestUserRoutes_LimitWritesPerUser(t *testing.T) {
+	fake := newFakeUserServer()
+	fake.profiles["reader"] = &generated.UserProfile{Id: "reader", AuthId: aliceID, FullName: "Alice Example"}
+	fake.profiles["bob"] = &generated.UserProfile{Id: "bob", AuthId: bobID, FullName: "Bob Example"}
+	cfg := &config.Config{EnvType: "prod", WebappBaseUrl: "http://localhost:3000", UserWriteRateLimit: "1/1h"}
+	router := newTestRouterWithConfig(t, cfg, fake, nil)
+
+	subscribed := doJSON(t, router, http.MethodPost, "/api/users/reader/subscriptions", "alice-token", map[string]any{"author_id": "author-1"})
+	require.Equal(t, http.StatusCreated, subscribed.Code, subscribed.Body.String())
+
+	limited := doJSON(t, router, http.MethodPost, "/api/users/reader/subscriptions", "alice-token", map[string]any{"author_id": "author-2"})
+	require.Equal(t, http.StatusTooManyRequests, limited.Code, limited.Body.String())
+	require.Equal(t, "3600", limited.Header().Get("Retry-After"))
+
+	// Reads and other users are not affected
+	listed := doJSON(t, router, http.MethodGet, "/api/users/reader/subscriptions", "", nil)
+	require.Equal(t, http.StatusOK, listed.Code, listed.Body.String())
+	subscribed = doJSON(t, router, http.MethodPost, "/api/users/bob/subscriptions", "bob-token", map[string]any{"author_id": "author-1"})
+	require.Equal(t, http.StatusCreated, subscribed.Code, subscribed.Body.String())
+}
+
+func TestUserRoutes_AnswerErrorsWithTheEnvelope(t *testing.T) {
+	fake := newFakeUserServer()
+	router := newTestRouter(t, fake, nil)
+
+	missing := doJSON(t, router, http.MethodGet, "/api/users/unknown", "", nil)
+	require.Equal(t, http.StatusNotFound, missing.Code, missing.Body.String())
+	require.Equal(t, map[string]any{"code": "not_found", "message": "profile not found"}, decodeJSON(t, missing))
+
+	// Field violations of the backend are passed on
+	invalid, err := status.New(codes.InvalidArgument, "auth_id must be a UUID").WithDetails(&errdetails.BadRequest{
+		FieldViolations: []*errdetails.BadRequest_FieldViolation{{Field: "auth_id", Description: "auth_id must be a UUID"}},
+	})
+	require.NoError(t, err)
+	fake.createErr = invalid.Err()
+	rejected := doJSON(t, router, http.MethodPost, "/api/users", "alice-token", map[string]any{"full_name": "Alice Example"})
+	require.Equal(t, http.StatusBadRequest, rejected.Code, rejected.Body.String())
+	require.Equal(t, map[string]any{
+		"code":    "invalid_argument",
+		"message": "auth_id must be a UUID",
+		"fields":  []any{map[string]any{"field": "auth_id", "description": "auth_id must be a UUID"}},
+	}, decodeJSON(t, rejected))
+
+	fake.createErr = status.Error(codes.AlreadyExists, "the account already has a profile")
+	conflict := doJSON(t, router, http.MethodPost, "/api/users", "alice-token", map[string]any{"full_name": "Alice Example"})
+	require.Equal(t, http.StatusConflict, conflict.Code, conflict.Body.String())
+	require.Equal(t, "already_exists", decodeJSON(t, conflict)["code"])
+
+	// Unexpected failures do not leak what went wrong
+	fake.createErr = errors.New("pq: connection refused")
+	failed := doJSON(t, router, http.MethodPost, "/api/users", "alice-token", map[string]any{"full_name": "Alice Example"})
+	require.Equal(t, http.StatusInternalServerError, failed.Code, failed.Body.String())
+	require.Equal(t, map[string]any{"code": "unknown", "message": "internal server error"}, decodeJSON(t, failed))
+
+	// Requests the gateway rejects itself name the field too
+	incomplete := doJSON(t, router, http.MethodPost, "/api/users", "alice-token", map[string]any{"bio": "hello"})
+	require.Equal(t, http.StatusBadRequest, incomplete.Code, incomplete.Body.String())
+	require.Equal(t, map[string]any{
+		"code":    "invalid_argument",
+		"message": "full_name is required",
+		"fields":  []any{map[string]any{"field": "full_name", "description": "full_name is required"}},
+	}, decodeJSON(t, incomplete))
+}
+
+func TestUserRoutes_RejectInvalidRequestsBeforeCallingTheService(t *testing.T) {
+	fake := newFakeUserServer()
+	router := newTestRouter(t, fake, nil)
+
+	for name, request := range map[string]struct {
+		method string
+		path   string
+		body   any
+	}{
+		"profile without full name":   {http.MethodPost, "/api/users", map[string]any{"bio": "hello"}},
+		"subscription without author": {http.MethodPost, "/api/users/reader/subscriptions", map[string]any{}},
+	} {
+		recorder := doJSON(t, router, request.method, request.path, "alice-token", request.body)
+		require.Equal(t, http.StatusBadRequest, recorder.Code, name)
+	}
+
+	require.Empty(t, fake.calls)
+}
@@ -1,17 +1,29 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"github.com/go-redis/redis/v8"
+	"golang.org/x/crypto/bcrypt"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/reflection"
 	"log"
 	"net"
 	"os"
+	"soul-connect/pkg/requestctx"
 	"soul-connect/sc-auth/internal/config"
+	db "soul-connect/sc-auth/internal/db/sqlc"
+	"soul-connect/sc-auth/internal/events"
 	"soul-connect/sc-auth/internal/generated"
+	"soul-connect/sc-auth/internal/mailer"
+	"soul-connect/sc-auth/internal/revocation"
 	"soul-connect/sc-auth/internal/server"
 	"soul-connect/sc-auth/internal/services"
+	"soul-connect/sc-auth/internal/utils"
 	"soul-connect/sc-auth/pkg/database"
+	"soul-connect/sc-auth/pkg/kafka"
+	"strings"
+	"time"
 )
 
 func main() {
@@ -21,6 +33,26 @@ func main() {
 		log.Fatal("cannot load config:", err)
 	}
 
+	keys, err := loadSigningKeys(&newConfig)
+	if err != nil {
+		log.Fatalf("cannot load JWT signing keys: %v", err)
+	}
+
+	mailSender, err := newMailSender(&newConfig)
+	if err != nil {
+		log.Fatalf("cannot create mail sender: %v", err)
+	}
+
+	passwordHasher, err := newPasswordHasher(&newConfig)
+	if err != nil {
+		log.Fatalf("cannot create password hasher: %v", err)
+	}
+
+	revocations, err := newRevocationList(&newConfig)
+	if err != nil {
+		log.Fatalf("cannot connect to redis: %v", err)
+	}
+
 	newPool, err := database.NewPostgresDB(&newConfig)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Unable to create connection pool: %v\n", err)
@@ -33,12 +65,29 @@ func main() {
 		log.Fatalf("failed to listen on gRPC port %s: %v", newConfig.ServerPort, err)
 	}
 
-	grpcServer := grpc.NewServer()
+	grpcServer := grpc.NewServer(grpc.UnaryInterceptor(requestctx.UnaryServerInterceptor()))
 	reflection.Register(grpcServer)
 
-	newService := services.NewService(newPool)
+	newService := services.NewService(newPool, keys, mailSender, revocations, passwordHasher, &newConfig)
 	newServer := server.NewAuthServer(newService)
 
+	go cleanUpLoginAttempts(newService.AuthService, newConfig.LoginAttemptsCleanupInterval)
+
+	// Events are always written to the outbox, the relay only publishes them
+	if brokers := parseBrokers(newConfig.KafkaBrokers); len(brokers) == 0 {
+		log.Println("KAFKA_BROKERS is not set, auth events are kept in the outbox")
+	} else {
+		producer := kafka.NewProducer(brokers)
+		defer producer.Close()
+
+		relay := events.NewRelay(db.New(newPool), producer, events.RelayPolicy{
+			BatchSize:    newConfig.OutboxBatchSize,
+			PollInterval: newConfig.OutboxPollInterval,
+			Retention:    newConfig.OutboxRetention,
+		})
+		go relay.Run(context.Background())
+	}
+
 	generated.RegisterAuthServiceServer(grpcServer, newServer)
 
 	// Start serving gRPC
@@ -47,3 +96,98 @@ func main() {
 		log.Fatalf("failed to serve gRPC: %v", err)
 	}
 }
+
+func loadSigningKeys(cfg *config.Config) (*utils.KeySet, error) {
+	if cfg.JWTKeysDir == "" {
+		log.Println("JWT_KEYS_DIR is not set, signing tokens with an ephemeral key")
+		return utils.GenerateKeySet()
+	}
+	return utils.LoadKeySet(cfg.JWTKeysDir, cfg.JWTActiveKeyID)
+}
+
+// newRevocationList shares revoked access tokens through Redis. Without REDIS_URL they
+// are kept in memory, which only works as long as a single instance is running.
+func newRevocationList(cfg *config.Config) (revocation.List, error) {
+	if cfg.RedisURL == "" {
+		log.Println("REDIS_URL is not set, keeping revoked access tokens in memory")
+		return revocation.NewMemoryList(), nil
+	}
+
+	options, err := redis.ParseURL(cfg.RedisURL)
+	if err != nil {
+		return nil, err
+	}
+	client := redis.NewClient(options)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		return nil, err
+	}
+	return revocation.NewRedisList(client), nil
+}
+
+func newMailSender(cfg *config.Config) (mailer.Sender, error) {
+	switch cfg.MailSender {
+	case "log":
+		return mailer.NewLogSender(), nil
+	case "file":
+		return mailer.NewFileSender(cfg.MailDir)
+	default:
+		return nil, fmt.Errorf("unknown mail sender %q", cfg.MailSender)
+	}
+}
+
+// newPasswordHasher picks the algorithm new passwords are hashed with. Existing hashes
+// of the other algorithm keep working and are upgraded at login when they are weaker.
+func newPasswordHasher(cfg *config.Config) (utils.PasswordHasher, error) {
+	switch cfg.PasswordHasher {
+	case "argon2id":
+		if cfg.Argon2Iterations < 1 || cfg.Argon2Parallelism < 1 {
+			return nil, fmt.Errorf("argon2id needs at least one iteration and one thread")
+		}
+		params := utils.DefaultArgon2idParams
+		params.Memory = cfg.Argon2Memory
+		params.Iterations = cfg.Argon2Iterations
+		params.Parallelism = cfg.Argon2Parallelism
+		return utils.NewArgon2idHasher(params), nil
+	case "bcrypt":
+		if cfg.BcryptCost < bcrypt.MinCost || cfg.BcryptCost > bcrypt.MaxCost {
+			return nil, fmt.Errorf("bcrypt cost must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+		}
+		return utils.NewBcryptHasher(cfg.BcryptCost), nil
+	default:
+		return nil, fmt.Errorf("unknown password hasher %q", cfg.PasswordHasher)
+	}
+}
+
+func parseBrokers(value string) []string {
+	if value == "" {
+		return nil
+	}
+	items := strings.Split(value, ",")
+	brokers := make([]string, 0, len(items))
+	for _, item := range items {
+		trimmed := strings.TrimSpace(item)
+		if trimmed != "" {
+			brokers = append(brokers, trimmed)
+		}
+	}
+	return brokers
+}
+
+// cleanUpLoginAttempts periodically removes login attempts that no longer matter for lockouts.
+func cleanUpLoginAttempts(authService *services.AuthService, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if err := authService.CleanUpLoginAttempts(context.Background()); err != nil {
+			log.Printf("failed to clean up login attempts: %v", err)
+		}
+	}
+}
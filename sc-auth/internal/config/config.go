@@ -3,19 +3,84 @@ package config
 import (
 	"errors"
 	"log"
+	"time"
 
 	"github.com/spf13/viper"
 )
 
 type Config struct {
-	EnvType    string `mapstructure:"ENV_TYPE"`
-	ServerPort string `mapstructure:"SERVER_PORT"`
-	DBSource   string `mapstructure:"DB_SOURCE"`
+	EnvType                       string        `mapstructure:"ENV_TYPE"`
+	ServerPort                    string        `mapstructure:"SERVER_PORT"`
+	DBSource                      string        `mapstructure:"DB_SOURCE"`
+	JWTKeysDir                    string        `mapstructure:"JWT_KEYS_DIR"`
+	JWTActiveKeyID                string        `mapstructure:"JWT_ACTIVE_KEY_ID"`
+	LockoutMaxAttempts            int           `mapstructure:"LOCKOUT_MAX_ATTEMPTS"`
+	LockoutWindow                 time.Duration `mapstructure:"LOCKOUT_WINDOW"`
+	LockoutDuration               time.Duration `mapstructure:"LOCKOUT_DURATION"`
+	LockoutMaxDuration            time.Duration `mapstructure:"LOCKOUT_MAX_DURATION"`
+	LoginAttemptsCleanupInterval  time.Duration `mapstructure:"LOGIN_ATTEMPTS_CLEANUP_INTERVAL"`
+	PasswordResetTokenTTL         time.Duration `mapstructure:"PASSWORD_RESET_TOKEN_TTL"`
+	PasswordResetURL              string        `mapstructure:"PASSWORD_RESET_URL"`
+	RequireEmailVerification      bool          `mapstructure:"REQUIRE_EMAIL_VERIFICATION"`
+	EmailVerificationTokenTTL     time.Duration `mapstructure:"EMAIL_VERIFICATION_TOKEN_TTL"`
+	EmailVerificationURL          string        `mapstructure:"EMAIL_VERIFICATION_URL"`
+	EmailVerificationResendLimit  int           `mapstructure:"EMAIL_VERIFICATION_RESEND_LIMIT"`
+	EmailVerificationResendWindow time.Duration `mapstructure:"EMAIL_VERIFICATION_RESEND_WINDOW"`
+	TOTPIssuer                    string        `mapstructure:"TOTP_ISSUER"`
+	TwoFactorChallengeTTL         time.Duration `mapstructure:"TWO_FACTOR_CHALLENGE_TTL"`
+	MagicLinkTokenTTL             time.Duration `mapstructure:"MAGIC_LINK_TOKEN_TTL"`
+	MagicLinkURL                  string        `mapstructure:"MAGIC_LINK_URL"`
+	MagicLinkRequestLimit         int           `mapstructure:"MAGIC_LINK_REQUEST_LIMIT"`
+	MagicLinkRequestWindow        time.Duration `mapstructure:"MAGIC_LINK_REQUEST_WINDOW"`
+	OAuthCodeTTL                  time.Duration `mapstructure:"OAUTH_CODE_TTL"`
+	ImpersonationTokenTTL         time.Duration `mapstructure:"IMPERSONATION_TOKEN_TTL"`
+	MailSender                    string        `mapstructure:"MAIL_SENDER"`
+	MailDir                       string        `mapstructure:"MAIL_DIR"`
+	RedisURL                      string        `mapstructure:"REDIS_URL"`
+	PasswordHasher                string        `mapstructure:"PASSWORD_HASHER"`
+	Argon2Memory                  uint32        `mapstructure:"ARGON2_MEMORY"`
+	Argon2Iterations              uint32        `mapstructure:"ARGON2_ITERATIONS"`
+	Argon2Parallelism             uint8         `mapstructure:"ARGON2_PARALLELISM"`
+	BcryptCost                    int           `mapstructure:"BCRYPT_COST"`
+	KafkaBrokers                  string        `mapstructure:"KAFKA_BROKERS"`
+	OutboxPollInterval            time.Duration `mapstructure:"OUTBOX_POLL_INTERVAL"`
+	OutboxBatchSize               int           `mapstructure:"OUTBOX_BATCH_SIZE"`
+	OutboxRetention               time.Duration `mapstructure:"OUTBOX_RETENTION"`
 }
 
 func LoadConfig(path string) (config Config, err error) {
 	viper.SetConfigFile(path + ".env")
 
+	viper.SetDefault("LOCKOUT_MAX_ATTEMPTS", 5)
+	viper.SetDefault("LOCKOUT_WINDOW", 15*time.Minute)
+	viper.SetDefault("LOCKOUT_DURATION", 5*time.Minute)
+	viper.SetDefault("LOCKOUT_MAX_DURATION", 24*time.Hour)
+	viper.SetDefault("LOGIN_ATTEMPTS_CLEANUP_INTERVAL", 24*time.Hour)
+	viper.SetDefault("PASSWORD_RESET_TOKEN_TTL", 30*time.Minute)
+	viper.SetDefault("REQUIRE_EMAIL_VERIFICATION", false)
+	viper.SetDefault("EMAIL_VERIFICATION_TOKEN_TTL", 24*time.Hour)
+	viper.SetDefault("EMAIL_VERIFICATION_RESEND_LIMIT", 3)
+	viper.SetDefault("EMAIL_VERIFICATION_RESEND_WINDOW", time.Hour)
+	viper.SetDefault("TOTP_ISSUER", "Soul Connect")
+	viper.SetDefault("TWO_FACTOR_CHALLENGE_TTL", 5*time.Minute)
+	viper.SetDefault("MAGIC_LINK_TOKEN_TTL", 15*time.Minute)
+	viper.SetDefault("MAGIC_LINK_REQUEST_LIMIT", 3)
+	viper.SetDefault("MAGIC_LINK_REQUEST_WINDOW", time.Hour)
+	viper.SetDefault("OAUTH_CODE_TTL", time.Minute)
+	viper.SetDefault("IMPERSONATION_TOKEN_TTL", 15*time.Minute)
+	viper.SetDefault("MAIL_SENDER", "log")
+	viper.SetDefault("MAIL_DIR", "./mail")
+	viper.SetDefault("REDIS_URL", "")
+	viper.SetDefault("PASSWORD_HASHER", "argon2id")
+	viper.SetDefault("ARGON2_MEMORY", 64*1024)
+	viper.SetDefault("ARGON2_ITERATIONS", 3)
+	viper.SetDefault("ARGON2_PARALLELISM", 2)
+	viper.SetDefault("BCRYPT_COST", 10)
+	viper.SetDefault("KAFKA_BROKERS", "")
+	viper.SetDefault("OUTBOX_POLL_INTERVAL", time.Second)
+	viper.SetDefault("OUTBOX_BATCH_SIZE", 100)
+	viper.SetDefault("OUTBOX_RETENTION", 7*24*time.Hour)
+
 	viper.AutomaticEnv()
 
 	err = viper.ReadInConfig()
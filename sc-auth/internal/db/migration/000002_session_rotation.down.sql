@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS rotated_session_tokens_session_token_idx;
+DROP INDEX IF EXISTS sessions_session_token_idx;
+
+DROP TABLE IF EXISTS rotated_session_tokens;
@@ -0,0 +1,11 @@
+-- Refresh tokens are rotated on every use, so previously issued tokens of a
+-- session are kept to detect replays of an already rotated token.
+CREATE TABLE IF NOT EXISTS rotated_session_tokens (
+    id UUID PRIMARY KEY DEFAULT (uuid_generate_v4()),
+    session_id UUID NOT NULL REFERENCES sessions(id) ON DELETE CASCADE,
+    session_token VARCHAR(255) NOT NULL,
+    rotated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS sessions_session_token_idx ON sessions (session_token);
+CREATE INDEX IF NOT EXISTS rotated_session_tokens_session_token_idx ON rotated_session_tokens (session_token);
@@ -0,0 +1,2 @@
+ALTER TABLE rotated_session_tokens ALTER COLUMN session_token TYPE VARCHAR(255);
+ALTER TABLE sessions ALTER COLUMN session_token TYPE VARCHAR(255);
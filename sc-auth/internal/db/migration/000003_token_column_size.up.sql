@@ -0,0 +1,3 @@
+-- Asymmetrically signed tokens no longer fit into VARCHAR(255)
+ALTER TABLE sessions ALTER COLUMN session_token TYPE TEXT;
+ALTER TABLE rotated_session_tokens ALTER COLUMN session_token TYPE TEXT;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS login_attempts_username_attempt_time_idx;
+
+DROP TABLE IF EXISTS account_lockouts;
@@ -0,0 +1,10 @@
+-- Accounts locked after too many failed logins. lockout_count drives the
+-- exponential backoff and updated_at marks the point failures are counted from.
+CREATE TABLE IF NOT EXISTS account_lockouts (
+    username VARCHAR(255) PRIMARY KEY,
+    lockout_count INT NOT NULL DEFAULT 0,
+    locked_until TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS login_attempts_username_attempt_time_idx ON login_attempts (username, attempt_time DESC);
@@ -0,0 +1,12 @@
+-- Password reset tokens are stored as SHA-256 hashes, the plain token only
+-- ever leaves the service in the reset email. used_at makes them single-use.
+CREATE TABLE IF NOT EXISTS password_reset_tokens (
+    id UUID PRIMARY KEY DEFAULT (uuid_generate_v4()),
+    user_id UUID NOT NULL REFERENCES auth(id) ON DELETE CASCADE,
+    token_hash VARCHAR(64) UNIQUE NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS password_reset_tokens_user_id_idx ON password_reset_tokens (user_id);
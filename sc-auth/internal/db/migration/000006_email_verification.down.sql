@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS email_verification_tokens_user_id_created_at_idx;
+
+DROP TABLE IF EXISTS email_verification_tokens;
+
+ALTER TABLE auth DROP COLUMN IF EXISTS email_verified_at;
+ALTER TABLE auth DROP COLUMN IF EXISTS email_verified;
@@ -0,0 +1,17 @@
+ALTER TABLE auth ADD COLUMN IF NOT EXISTS email_verified BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE auth ADD COLUMN IF NOT EXISTS email_verified_at TIMESTAMP;
+
+-- Accounts created before verification existed keep working
+UPDATE auth SET email_verified = TRUE, email_verified_at = NOW();
+
+-- Verification tokens are stored as SHA-256 hashes, like password reset tokens
+CREATE TABLE IF NOT EXISTS email_verification_tokens (
+    id UUID PRIMARY KEY DEFAULT (uuid_generate_v4()),
+    user_id UUID NOT NULL REFERENCES auth(id) ON DELETE CASCADE,
+    token_hash VARCHAR(64) UNIQUE NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS email_verification_tokens_user_id_created_at_idx ON email_verification_tokens (user_id, created_at DESC);
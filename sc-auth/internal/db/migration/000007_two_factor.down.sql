@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS totp_recovery_codes_user_id_idx;
+
+DROP TABLE IF EXISTS totp_recovery_codes;
+
+DROP TABLE IF EXISTS user_totp;
@@ -0,0 +1,19 @@
+-- TOTP enrollment per user. confirmed_at stays NULL until the user proves the
+-- authenticator works, last_used_step stops a code from being replayed.
+CREATE TABLE IF NOT EXISTS user_totp (
+    user_id UUID PRIMARY KEY REFERENCES auth(id) ON DELETE CASCADE,
+    secret VARCHAR(64) NOT NULL,
+    confirmed_at TIMESTAMP,
+    last_used_step BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- Recovery codes are stored as SHA-256 hashes like the other one-time tokens
+CREATE TABLE IF NOT EXISTS totp_recovery_codes (
+    id UUID PRIMARY KEY DEFAULT (uuid_generate_v4()),
+    user_id UUID NOT NULL REFERENCES auth(id) ON DELETE CASCADE,
+    code_hash VARCHAR(255) NOT NULL,
+    used_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS totp_recovery_codes_user_id_idx ON totp_recovery_codes (user_id);
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS sessions_user_id_idx;
+
+ALTER TABLE sessions DROP COLUMN IF EXISTS last_used_at;
+ALTER TABLE sessions DROP COLUMN IF EXISTS created_at;
+ALTER TABLE sessions DROP COLUMN IF EXISTS ip_address;
+ALTER TABLE sessions DROP COLUMN IF EXISTS user_agent;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS auth_email_lower_key;
+DROP INDEX IF EXISTS auth_username_lower_key;
@@ -0,0 +1,4 @@
+-- Usernames and emails are matched case-insensitively at login, so they also have to be unique that way.
+-- The migration fails if existing accounts differ only in case; those have to be merged by hand first.
+CREATE UNIQUE INDEX IF NOT EXISTS auth_username_lower_key ON auth (LOWER(username));
+CREATE UNIQUE INDEX IF NOT EXISTS auth_email_lower_key ON auth (LOWER(email));
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS personal_access_tokens_user_id_idx;
+
+DROP TABLE IF EXISTS personal_access_tokens;
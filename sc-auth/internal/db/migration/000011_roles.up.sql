@@ -0,0 +1,36 @@
+-- Roles bundle permissions. Access tokens carry both the roles and the resolved
+-- permissions of the user, so services can authorize without asking sc-auth.
+CREATE TABLE IF NOT EXISTS roles (
+    name VARCHAR(50) PRIMARY KEY,
+    description TEXT NOT NULL DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS role_permissions (
+    role VARCHAR(50) NOT NULL REFERENCES roles(name) ON DELETE CASCADE,
+    permission VARCHAR(100) NOT NULL,
+    PRIMARY KEY (role, permission)
+);
+
+CREATE TABLE IF NOT EXISTS user_roles (
+    user_id UUID NOT NULL REFERENCES auth(id) ON DELETE CASCADE,
+    role VARCHAR(50) NOT NULL REFERENCES roles(name) ON DELETE CASCADE,
+    granted_by UUID REFERENCES auth(id) ON DELETE SET NULL,
+    granted_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, role)
+);
+
+INSERT INTO roles (name, description) VALUES
+    ('admin', 'Manages users and their roles'),
+    ('moderator', 'Moderates posts and comments')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO role_permissions (role, permission) VALUES
+    ('admin', 'roles:manage'),
+    ('admin', 'users:delete'),
+    ('admin', 'users:unlock'),
+    ('admin', 'posts:moderate'),
+    ('moderator', 'posts:moderate')
+ON CONFLICT (role, permission) DO NOTHING;
+
+-- The first admin has to be granted by hand, e.g.
+-- INSERT INTO user_roles (user_id, role) SELECT id, 'admin' FROM auth WHERE username = 'alice';
@@ -0,0 +1,6 @@
+DELETE FROM role_permissions WHERE permission = 'audit:read';
+
+DROP INDEX IF EXISTS login_attempts_attempt_time_idx;
+
+ALTER TABLE login_attempts DROP COLUMN IF EXISTS user_agent;
+ALTER TABLE login_attempts DROP COLUMN IF EXISTS ip_address;
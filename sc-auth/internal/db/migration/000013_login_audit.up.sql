@@ -0,0 +1,10 @@
+-- Device details shown in the sign-in history and the audit export
+ALTER TABLE login_attempts ADD COLUMN IF NOT EXISTS ip_address VARCHAR(45) NOT NULL DEFAULT '';
+ALTER TABLE login_attempts ADD COLUMN IF NOT EXISTS user_agent TEXT NOT NULL DEFAULT '';
+
+-- Exports select a time range across all users
+CREATE INDEX IF NOT EXISTS login_attempts_attempt_time_idx ON login_attempts (attempt_time);
+
+INSERT INTO role_permissions (role, permission) VALUES
+    ('admin', 'audit:read')
+ON CONFLICT (role, permission) DO NOTHING;
@@ -0,0 +1,12 @@
+-- Magic link tokens sign a user in without a password. Like password reset tokens
+-- they are stored as SHA-256 hashes and used_at makes them single-use.
+CREATE TABLE IF NOT EXISTS magic_link_tokens (
+    id UUID PRIMARY KEY DEFAULT (uuid_generate_v4()),
+    user_id UUID NOT NULL REFERENCES auth(id) ON DELETE CASCADE,
+    token_hash VARCHAR(64) UNIQUE NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS magic_link_tokens_user_id_idx ON magic_link_tokens (user_id);
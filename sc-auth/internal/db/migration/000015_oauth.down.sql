@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS oauth_authorization_codes;
+
+DROP TABLE IF EXISTS oauth_consents;
+
+DROP INDEX IF EXISTS oauth_clients_owner_id_idx;
+
+DROP TABLE IF EXISTS oauth_clients;
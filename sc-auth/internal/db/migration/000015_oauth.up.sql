@@ -0,0 +1,38 @@
+-- Third-party apps registered for the OAuth2 authorization code flow. Public clients
+-- (SPAs, mobile apps) have no secret and rely on PKCE alone.
+CREATE TABLE IF NOT EXISTS oauth_clients (
+    id UUID PRIMARY KEY DEFAULT (uuid_generate_v4()),
+    owner_id UUID NOT NULL REFERENCES auth(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    client_secret_hash VARCHAR(64) NOT NULL DEFAULT '',
+    redirect_uris TEXT[] NOT NULL,
+    scopes TEXT[] NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS oauth_clients_owner_id_idx ON oauth_clients (owner_id);
+
+-- The scopes a user allowed an app to use. Tokens of the app stop working once the consent is revoked.
+CREATE TABLE IF NOT EXISTS oauth_consents (
+    user_id UUID NOT NULL REFERENCES auth(id) ON DELETE CASCADE,
+    client_id UUID NOT NULL REFERENCES oauth_clients(id) ON DELETE CASCADE,
+    scopes TEXT[] NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, client_id)
+);
+
+-- Authorization codes are single-use and short-lived. Like other one-time tokens only
+-- their SHA-256 hash is stored, next to the PKCE challenge they were requested with.
+CREATE TABLE IF NOT EXISTS oauth_authorization_codes (
+    id UUID PRIMARY KEY DEFAULT (uuid_generate_v4()),
+    code_hash VARCHAR(64) UNIQUE NOT NULL,
+    client_id UUID NOT NULL REFERENCES oauth_clients(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES auth(id) ON DELETE CASCADE,
+    redirect_uri TEXT NOT NULL,
+    scopes TEXT[] NOT NULL,
+    code_challenge VARCHAR(128) NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
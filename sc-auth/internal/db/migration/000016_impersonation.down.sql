@@ -0,0 +1,5 @@
+DELETE FROM role_permissions WHERE permission = 'users:impersonate';
+
+DROP INDEX IF EXISTS impersonation_sessions_started_at_idx;
+
+DROP TABLE IF EXISTS impersonation_sessions;
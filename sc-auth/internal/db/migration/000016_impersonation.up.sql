@@ -0,0 +1,21 @@
+-- Audit trail of support staff acting as a user. Usernames are copied, so the
+-- record stays readable after either account is deleted.
+CREATE TABLE IF NOT EXISTS impersonation_sessions (
+    id UUID PRIMARY KEY DEFAULT (uuid_generate_v4()),
+    actor_id UUID NOT NULL,
+    actor_username VARCHAR(255) NOT NULL,
+    target_id UUID NOT NULL,
+    target_username VARCHAR(255) NOT NULL,
+    reason TEXT NOT NULL,
+    token_id VARCHAR(36) NOT NULL,
+    ip_address VARCHAR(45) NOT NULL DEFAULT '',
+    user_agent TEXT NOT NULL DEFAULT '',
+    started_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS impersonation_sessions_started_at_idx ON impersonation_sessions (started_at);
+
+INSERT INTO role_permissions (role, permission) VALUES
+    ('admin', 'users:impersonate')
+ON CONFLICT (role, permission) DO NOTHING;
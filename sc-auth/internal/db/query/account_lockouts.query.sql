@@ -0,0 +1,23 @@
+-- name: GetAccountLockout :one
+SELECT username, lockout_count, locked_until, updated_at
+FROM account_lockouts
+WHERE username = @username
+LIMIT 1;
+
+-- name: LockAccount :one
+INSERT INTO account_lockouts (username, lockout_count, locked_until, updated_at)
+VALUES (@username, 1, @locked_until, NOW())
+ON CONFLICT (username) DO UPDATE
+SET lockout_count = account_lockouts.lockout_count + 1,
+    locked_until = EXCLUDED.locked_until,
+    updated_at = NOW()
+    RETURNING username, lockout_count, locked_until, updated_at;
+
+-- name: UnlockAccount :execrows
+UPDATE account_lockouts
+SET lockout_count = 0, locked_until = NOW(), updated_at = NOW()
+WHERE username = @username;
+
+-- name: DeleteAccountLockout :exec
+DELETE FROM account_lockouts
+WHERE username = @username;
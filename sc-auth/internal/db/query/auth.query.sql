@@ -4,19 +4,19 @@ VALUES (@username, @email, @password)
     RETURNING id, username, email, password;
 
 -- name: GetUserByUsername :one
-SELECT id, username, email, password
+SELECT id, username, email, password, email_verified
 FROM auth
-WHERE username = @username
+WHERE LOWER(username) = LOWER(@username)
 LIMIT 1;
 
 -- name: GetUserByEmail :one
-SELECT id, username, email, password
+SELECT id, username, email, password, email_verified
 FROM auth
-WHERE email = @email
+WHERE LOWER(email) = LOWER(@email)
     LIMIT 1;
 
 -- name: GetUserById :one
-SELECT id, username, email, password, last_login
+SELECT id, username, email, password, last_login, email_verified
 FROM auth
 WHERE id = @id
     LIMIT 1;
@@ -26,11 +26,21 @@ UPDATE auth
 SET password = @new_password, updated_at = NOW()
 WHERE id = @id;
 
+-- name: RehashUserPassword :execrows
+UPDATE auth
+SET password = @new_password
+WHERE id = @id AND password = @old_password;
+
+-- name: MarkEmailVerified :exec
+UPDATE auth
+SET email_verified = TRUE, email_verified_at = NOW(), updated_at = NOW()
+WHERE id = @id;
+
 -- name: UpdateLastLogin :exec
 UPDATE auth
 SET last_login = NOW()
 WHERE id = @id;
 
--- name: DeleteUser :exec
+-- name: DeleteUser :execrows
 DELETE FROM auth
 WHERE id = @id;
\ No newline at end of file
@@ -0,0 +1,23 @@
+-- name: CreateEmailVerificationToken :exec
+INSERT INTO email_verification_tokens (user_id, token_hash, expires_at)
+VALUES (@user_id, @token_hash, @expires_at);
+
+-- name: ConsumeEmailVerificationToken :one
+UPDATE email_verification_tokens
+SET used_at = NOW()
+WHERE token_hash = @token_hash
+  AND used_at IS NULL
+  AND expires_at > NOW()
+    RETURNING user_id;
+
+-- name: InvalidateEmailVerificationTokens :exec
+UPDATE email_verification_tokens
+SET used_at = NOW()
+WHERE user_id = @user_id
+  AND used_at IS NULL;
+
+-- name: CountEmailVerificationTokensSince :one
+SELECT COUNT(*)
+FROM email_verification_tokens
+WHERE user_id = @user_id
+  AND created_at > @since;
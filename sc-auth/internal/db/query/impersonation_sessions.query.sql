@@ -0,0 +1,10 @@
+-- name: CreateImpersonationSession :one
+INSERT INTO impersonation_sessions (actor_id, actor_username, target_id, target_username, reason, token_id, ip_address, user_agent, expires_at)
+VALUES (@actor_id, @actor_username, @target_id, @target_username, @reason, @token_id, @ip_address, @user_agent, @expires_at)
+RETURNING id, actor_id, actor_username, target_id, target_username, reason, token_id, ip_address, user_agent, started_at, expires_at;
+
+-- name: ListImpersonationSessions :many
+SELECT id, actor_id, actor_username, target_id, target_username, reason, token_id, ip_address, user_agent, started_at, expires_at
+FROM impersonation_sessions
+ORDER BY started_at DESC
+LIMIT @request_limit;
\ No newline at end of file
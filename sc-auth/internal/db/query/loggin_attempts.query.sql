@@ -1,26 +1,35 @@
 -- name: LogSuccessfulLogin :exec
-INSERT INTO login_attempts (username, success)
-VALUES (@username, TRUE);
+INSERT INTO login_attempts (username, success, ip_address, user_agent)
+VALUES (@username, TRUE, @ip_address, @user_agent);
 
 -- name: LogFailedLogin :exec
-INSERT INTO login_attempts (username, success)
-VALUES (@username, FALSE);
+INSERT INTO login_attempts (username, success, ip_address, user_agent)
+VALUES (@username, FALSE, @ip_address, @user_agent);
 
 -- name: GetLoginAttemptsByUsername :many
-SELECT id, username, success, attempt_time
+SELECT id, username, success, attempt_time, ip_address, user_agent
 FROM login_attempts
 WHERE username = @username
 ORDER BY attempt_time DESC
 LIMIT @request_limit;
 
 -- name: GetFailedLoginAttemptsByUsername :many
-SELECT id, username, success, attempt_time
+SELECT id, username, success, attempt_time, ip_address, user_agent
 FROM login_attempts
 WHERE username = @username
   AND success = FALSE
 ORDER BY attempt_time DESC
 LIMIT @request_limit;
 
+-- name: ExportLoginAttempts :many
+SELECT id, username, success, attempt_time, ip_address, user_agent
+FROM login_attempts
+WHERE attempt_time >= @from_time
+  AND attempt_time < @to_time
+  AND (@username::varchar = '' OR username = @username)
+ORDER BY attempt_time, id
+LIMIT @row_limit;
+
 -- name: DeleteOldLoginAttempts :exec
 DELETE FROM login_attempts
 WHERE attempt_time < NOW() - INTERVAL '30 days';
\ No newline at end of file
@@ -0,0 +1,23 @@
+-- name: CreateMagicLinkToken :exec
+INSERT INTO magic_link_tokens (user_id, token_hash, expires_at)
+VALUES (@user_id, @token_hash, @expires_at);
+
+-- name: ConsumeMagicLinkToken :one
+UPDATE magic_link_tokens
+SET used_at = NOW()
+WHERE token_hash = @token_hash
+  AND used_at IS NULL
+  AND expires_at > NOW()
+RETURNING user_id;
+
+-- name: InvalidateMagicLinkTokens :exec
+UPDATE magic_link_tokens
+SET used_at = NOW()
+WHERE user_id = @user_id
+  AND used_at IS NULL;
+
+-- name: CountMagicLinkTokensSince :one
+SELECT COUNT(*)
+FROM magic_link_tokens
+WHERE user_id = @user_id
+  AND created_at > @since;
\ No newline at end of file
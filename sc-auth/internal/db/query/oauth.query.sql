@@ -0,0 +1,59 @@
+-- name: CreateOAuthClient :one
+INSERT INTO oauth_clients (owner_id, name, client_secret_hash, redirect_uris, scopes)
+VALUES (@owner_id, @name, @client_secret_hash, @redirect_uris, @scopes)
+RETURNING id, owner_id, name, client_secret_hash, redirect_uris, scopes, created_at;
+
+-- name: GetOAuthClient :one
+SELECT id, owner_id, name, client_secret_hash, redirect_uris, scopes, created_at
+FROM oauth_clients
+WHERE id = @id
+LIMIT 1;
+
+-- name: ListOAuthClientsByOwner :many
+SELECT id, owner_id, name, client_secret_hash, redirect_uris, scopes, created_at
+FROM oauth_clients
+WHERE owner_id = @owner_id
+ORDER BY created_at DESC;
+
+-- name: DeleteOAuthClient :execrows
+DELETE FROM oauth_clients
+WHERE id = @id
+  AND owner_id = @owner_id;
+
+-- name: UpsertOAuthConsent :exec
+INSERT INTO oauth_consents (user_id, client_id, scopes)
+VALUES (@user_id, @client_id, @scopes)
+ON CONFLICT (user_id, client_id) DO UPDATE
+SET scopes = EXCLUDED.scopes,
+    updated_at = NOW();
+
+-- name: GetOAuthConsent :one
+SELECT user_id, client_id, scopes, created_at, updated_at
+FROM oauth_consents
+WHERE user_id = @user_id
+  AND client_id = @client_id
+LIMIT 1;
+
+-- name: ListOAuthConsents :many
+SELECT oauth_consents.client_id, oauth_clients.name, oauth_consents.scopes, oauth_consents.created_at, oauth_consents.updated_at
+FROM oauth_consents
+JOIN oauth_clients ON oauth_clients.id = oauth_consents.client_id
+WHERE oauth_consents.user_id = @user_id
+ORDER BY oauth_consents.updated_at DESC;
+
+-- name: DeleteOAuthConsent :execrows
+DELETE FROM oauth_consents
+WHERE user_id = @user_id
+  AND client_id = @client_id;
+
+-- name: CreateOAuthAuthorizationCode :exec
+INSERT INTO oauth_authorization_codes (code_hash, client_id, user_id, redirect_uri, scopes, code_challenge, expires_at)
+VALUES (@code_hash, @client_id, @user_id, @redirect_uri, @scopes, @code_challenge, @expires_at);
+
+-- name: ConsumeOAuthAuthorizationCode :one
+UPDATE oauth_authorization_codes
+SET used_at = NOW()
+WHERE code_hash = @code_hash
+  AND used_at IS NULL
+  AND expires_at > NOW()
+RETURNING client_id, user_id, redirect_uri, scopes, code_challenge;
\ No newline at end of file
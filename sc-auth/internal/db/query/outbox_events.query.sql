@@ -0,0 +1,36 @@
+-- name: CreateOutboxEvent :exec
+INSERT INTO outbox_events (id, event_type, aggregate_id, payload)
+VALUES (@id, @event_type, @aggregate_id, @payload);
+
+-- name: ClaimOutboxEvents :many
+UPDATE outbox_events
+SET locked_until = @locked_until
+WHERE id IN (
+    SELECT id
+    FROM outbox_events
+    WHERE published_at IS NULL
+      AND (locked_until IS NULL OR locked_until < NOW())
+    ORDER BY created_at
+    LIMIT @batch_size
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING id, event_type, aggregate_id, payload, created_at, locked_until, attempts, last_error, published_at;
+
+-- name: MarkOutboxEventPublished :exec
+UPDATE outbox_events
+SET published_at = NOW(), locked_until = NULL
+WHERE id = @id;
+
+-- name: MarkOutboxEventFailed :exec
+UPDATE outbox_events
+SET attempts = attempts + 1, last_error = @last_error, locked_until = NULL
+WHERE id = @id;
+
+-- name: ReleaseOutboxEvents :exec
+UPDATE outbox_events
+SET locked_until = NULL
+WHERE id = ANY(@ids::uuid[]) AND published_at IS NULL;
+
+-- name: DeletePublishedOutboxEvents :exec
+DELETE FROM outbox_events
+WHERE published_at < @published_before;
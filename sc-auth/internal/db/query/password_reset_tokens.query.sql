@@ -0,0 +1,17 @@
+-- name: CreatePasswordResetToken :exec
+INSERT INTO password_reset_tokens (user_id, token_hash, expires_at)
+VALUES (@user_id, @token_hash, @expires_at);
+
+-- name: ConsumePasswordResetToken :one
+UPDATE password_reset_tokens
+SET used_at = NOW()
+WHERE token_hash = @token_hash
+  AND used_at IS NULL
+  AND expires_at > NOW()
+    RETURNING user_id;
+
+-- name: InvalidatePasswordResetTokens :exec
+UPDATE password_reset_tokens
+SET used_at = NOW()
+WHERE user_id = @user_id
+  AND used_at IS NULL;
@@ -0,0 +1,26 @@
+-- name: CreatePersonalAccessToken :one
+INSERT INTO personal_access_tokens (user_id, name, token_hash, scopes, expires_at)
+VALUES (@user_id, @name, @token_hash, @scopes, @expires_at)
+RETURNING id, user_id, name, token_hash, scopes, expires_at, last_used_at, created_at;
+
+-- name: GetPersonalAccessTokenByHash :one
+SELECT id, user_id, name, token_hash, scopes, expires_at, last_used_at, created_at
+FROM personal_access_tokens
+WHERE token_hash = @token_hash
+LIMIT 1;
+
+-- name: ListPersonalAccessTokens :many
+SELECT id, user_id, name, token_hash, scopes, expires_at, last_used_at, created_at
+FROM personal_access_tokens
+WHERE user_id = @user_id
+ORDER BY created_at DESC;
+
+-- name: TouchPersonalAccessToken :exec
+UPDATE personal_access_tokens
+SET last_used_at = NOW()
+WHERE id = @id;
+
+-- name: DeletePersonalAccessToken :execrows
+DELETE FROM personal_access_tokens
+WHERE id = @id
+  AND user_id = @user_id;
@@ -0,0 +1,27 @@
+-- name: GetUserRoles :many
+SELECT role
+FROM user_roles
+WHERE user_id = @user_id
+ORDER BY role;
+
+-- name: GetUserPermissions :many
+SELECT DISTINCT rp.permission
+FROM role_permissions rp
+JOIN user_roles ur ON ur.role = rp.role
+WHERE ur.user_id = @user_id
+ORDER BY rp.permission;
+
+-- name: RoleExists :one
+SELECT EXISTS (
+    SELECT 1 FROM roles WHERE name = @name
+);
+
+-- name: GrantRole :exec
+INSERT INTO user_roles (user_id, role, granted_by)
+VALUES (@user_id, @role, @granted_by)
+ON CONFLICT (user_id, role) DO NOTHING;
+
+-- name: RevokeRole :execrows
+DELETE FROM user_roles
+WHERE user_id = @user_id
+  AND role = @role;
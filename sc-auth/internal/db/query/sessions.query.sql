@@ -1,10 +1,17 @@
 -- name: CreateSession :one
-INSERT INTO sessions (user_id, session_token, session_expires_at)
-VALUES (@user_id, @session_token, @session_expires_at)
-    RETURNING id, user_id, session_token, session_expires_at;
+INSERT INTO sessions (id, user_id, session_token, session_expires_at, user_agent, ip_address)
+VALUES (@id, @user_id, @session_token, @session_expires_at, @user_agent, @ip_address)
+    RETURNING id, user_id, session_token, session_expires_at, user_agent, ip_address, created_at, last_used_at;
+
+-- name: GetSessionByID :one
+SELECT id, user_id, session_token, session_expires_at, user_agent, ip_address, created_at, last_used_at
+FROM sessions
+WHERE id = @id
+  AND session_expires_at > NOW()
+    LIMIT 1;
 
 -- name: GetSessionByToken :one
-SELECT user_id, session_token, session_expires_at
+SELECT id, user_id, session_token, session_expires_at, user_agent, ip_address, created_at, last_used_at
 FROM sessions
 WHERE session_token = @session_token
   AND session_expires_at > NOW()
@@ -16,15 +23,54 @@ FROM sessions
 WHERE user_id = @user_id
   AND session_expires_at > NOW();
 
+-- name: ListSessionsForUser :many
+SELECT id, user_id, session_token, session_expires_at, user_agent, ip_address, created_at, last_used_at
+FROM sessions
+WHERE user_id = @user_id
+  AND session_expires_at > NOW()
+ORDER BY last_used_at DESC;
+
 -- name: UpdateSessionExpiry :exec
 UPDATE sessions
 SET session_expires_at = @new_expiry
 WHERE session_token = @session_token;
 
+-- name: RotateSessionToken :execrows
+WITH rotated AS (
+    UPDATE sessions
+    SET session_token = @new_token, session_expires_at = @new_expiry, last_used_at = NOW()
+    WHERE id = @id
+      AND session_token = @old_token
+    RETURNING id
+)
+INSERT INTO rotated_session_tokens (session_id, session_token)
+SELECT id, @old_token
+FROM rotated;
+
+-- name: GetRotatedSessionToken :one
+SELECT session_id, session_token, rotated_at
+FROM rotated_session_tokens
+WHERE session_token = @session_token
+    LIMIT 1;
+
+-- name: DeleteSessionByID :exec
+DELETE FROM sessions
+WHERE id = @id;
+
+-- name: DeleteSessionForUser :execrows
+DELETE FROM sessions
+WHERE id = @id
+  AND user_id = @user_id;
+
 -- name: DeleteSessionByToken :exec
 DELETE FROM sessions
 WHERE session_token = @session_token;
 
 -- name: DeleteAllSessionsForUser :exec
 DELETE FROM sessions
-WHERE user_id = @user_id;
\ No newline at end of file
+WHERE user_id = @user_id;
+
+-- name: DeleteOtherSessionsForUser :exec
+DELETE FROM sessions
+WHERE user_id = @user_id
+  AND id <> @session_id;
@@ -0,0 +1,44 @@
+-- name: UpsertUserTotp :exec
+INSERT INTO user_totp (user_id, secret)
+VALUES (@user_id, @secret)
+ON CONFLICT (user_id) DO UPDATE
+SET secret = EXCLUDED.secret,
+    confirmed_at = NULL,
+    last_used_step = 0,
+    created_at = NOW();
+
+-- name: GetUserTotp :one
+SELECT user_id, secret, confirmed_at, last_used_step, created_at
+FROM user_totp
+WHERE user_id = @user_id
+LIMIT 1;
+
+-- name: ConfirmUserTotp :exec
+UPDATE user_totp
+SET confirmed_at = NOW()
+WHERE user_id = @user_id;
+
+-- name: UseTotpStep :execrows
+UPDATE user_totp
+SET last_used_step = @step
+WHERE user_id = @user_id
+  AND last_used_step < @step;
+
+-- name: DeleteUserTotp :exec
+DELETE FROM user_totp
+WHERE user_id = @user_id;
+
+-- name: CreateRecoveryCode :exec
+INSERT INTO totp_recovery_codes (user_id, code_hash)
+VALUES (@user_id, @code_hash);
+
+-- name: UseRecoveryCode :execrows
+UPDATE totp_recovery_codes
+SET used_at = NOW()
+WHERE user_id = @user_id
+  AND code_hash = @code_hash
+  AND used_at IS NULL;
+
+-- name: DeleteRecoveryCodes :exec
+DELETE FROM totp_recovery_codes
+WHERE user_id = @user_id;
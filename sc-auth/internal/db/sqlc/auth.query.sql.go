@@ -42,28 +42,32 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (CreateU
 	return i, err
 }
 
-const deleteUser = `-- name: DeleteUser :exec
+const deleteUser = `-- name: DeleteUser :execrows
 DELETE FROM auth
 WHERE id = $1
 `
 
-func (q *Queries) DeleteUser(ctx context.Context, id pgtype.UUID) error {
-	_, err := q.db.Exec(ctx, deleteUser, id)
-	return err
+func (q *Queries) DeleteUser(ctx context.Context, id pgtype.UUID) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteUser, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
 }
 
 const getUserByEmail = `-- name: GetUserByEmail :one
-SELECT id, username, email, password
+SELECT id, username, email, password, email_verified
 FROM auth
-WHERE email = $1
+WHERE LOWER(email) = LOWER($1)
     LIMIT 1
 `
 
 type GetUserByEmailRow struct {
-	ID       pgtype.UUID `json:"id"`
-	Username string      `json:"username"`
-	Email    string      `json:"email"`
-	Password string      `json:"password"`
+	ID            pgtype.UUID `json:"id"`
+	Username      string      `json:"username"`
+	Email         string      `json:"email"`
+	Password      string      `json:"password"`
+	EmailVerified bool        `json:"email_verified"`
 }
 
 func (q *Queries) GetUserByEmail(ctx context.Context, email string) (GetUserByEmailRow, error) {
@@ -74,23 +78,25 @@ func (q *Queries) GetUserByEmail(ctx context.Context, email string) (GetUserByEm
 		&i.Username,
 		&i.Email,
 		&i.Password,
+		&i.EmailVerified,
 	)
 	return i, err
 }
 
 const getUserById = `-- name: GetUserById :one
-SELECT id, username, email, password, last_login
+SELECT id, username, email, password, last_login, email_verified
 FROM auth
 WHERE id = $1
     LIMIT 1
 `
 
 type GetUserByIdRow struct {
-	ID        pgtype.UUID      `json:"id"`
-	Username  string           `json:"username"`
-	Email     string           `json:"email"`
-	Password  string           `json:"password"`
-	LastLogin pgtype.Timestamp `json:"last_login"`
+	ID            pgtype.UUID      `json:"id"`
+	Username      string           `json:"username"`
+	Email         string           `json:"email"`
+	Password      string           `json:"password"`
+	LastLogin     pgtype.Timestamp `json:"last_login"`
+	EmailVerified bool             `json:"email_verified"`
 }
 
 func (q *Queries) GetUserById(ctx context.Context, id pgtype.UUID) (GetUserByIdRow, error) {
@@ -102,22 +108,24 @@ func (q *Queries) GetUserById(ctx context.Context, id pgtype.UUID) (GetUserByIdR
 		&i.Email,
 		&i.Password,
 		&i.LastLogin,
+		&i.EmailVerified,
 	)
 	return i, err
 }
 
 const getUserByUsername = `-- name: GetUserByUsername :one
-SELECT id, username, email, password
+SELECT id, username, email, password, email_verified
 FROM auth
-WHERE username = $1
+WHERE LOWER(username) = LOWER($1)
 LIMIT 1
 `
 
 type GetUserByUsernameRow struct {
-	ID       pgtype.UUID `json:"id"`
-	Username string      `json:"username"`
-	Email    string      `json:"email"`
-	Password string      `json:"password"`
+	ID            pgtype.UUID `json:"id"`
+	Username      string      `json:"username"`
+	Email         string      `json:"email"`
+	Password      string      `json:"password"`
+	EmailVerified bool        `json:"email_verified"`
 }
 
 func (q *Queries) GetUserByUsername(ctx context.Context, username string) (GetUserByUsernameRow, error) {
@@ -128,10 +136,42 @@ func (q *Queries) GetUserByUsername(ctx context.Context, username string) (GetUs
 		&i.Username,
 		&i.Email,
 		&i.Password,
+		&i.EmailVerified,
 	)
 	return i, err
 }
 
+const markEmailVerified = `-- name: MarkEmailVerified :exec
+UPDATE auth
+SET email_verified = TRUE, email_verified_at = NOW(), updated_at = NOW()
+WHERE id = $1
+`
+
+func (q *Queries) MarkEmailVerified(ctx context.Context, id pgtype.UUID) error {
+	_, err := q.db.Exec(ctx, markEmailVerified, id)
+	return err
+}
+
+const rehashUserPassword = `-- name: RehashUserPassword :execrows
+UPDATE auth
+SET password = $1
+WHERE id = $2 AND password = $3
+`
+
+type RehashUserPasswordParams struct {
+	NewPassword string      `json:"new_password"`
+	ID          pgtype.UUID `json:"id"`
+	OldPassword string      `json:"old_password"`
+}
+
+func (q *Queries) RehashUserPassword(ctx context.Context, arg RehashUserPasswordParams) (int64, error) {
+	result, err := q.db.Exec(ctx, rehashUserPassword, arg.NewPassword, arg.ID, arg.OldPassword)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const updateLastLogin = `-- name: UpdateLastLogin :exec
 UPDATE auth
 SET last_login = NOW()
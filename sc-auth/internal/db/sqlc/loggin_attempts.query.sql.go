@@ -7,6 +7,8 @@ package db
 
 import (
 	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 const deleteOldLoginAttempts = `-- name: DeleteOldLoginAttempts :exec
@@ -19,8 +21,57 @@ func (q *Queries) DeleteOldLoginAttempts(ctx context.Context) error {
 	return err
 }
 
+const exportLoginAttempts = `-- name: ExportLoginAttempts :many
+SELECT id, username, success, attempt_time, ip_address, user_agent
+FROM login_attempts
+WHERE attempt_time >= $1
+  AND attempt_time < $2
+  AND ($3::varchar = '' OR username = $3)
+ORDER BY attempt_time, id
+LIMIT $4
+`
+
+type ExportLoginAttemptsParams struct {
+	FromTime pgtype.Timestamp `json:"from_time"`
+	ToTime   pgtype.Timestamp `json:"to_time"`
+	Username string           `json:"username"`
+	RowLimit int32            `json:"row_limit"`
+}
+
+func (q *Queries) ExportLoginAttempts(ctx context.Context, arg ExportLoginAttemptsParams) ([]LoginAttempt, error) {
+	rows, err := q.db.Query(ctx, exportLoginAttempts,
+		arg.FromTime,
+		arg.ToTime,
+		arg.Username,
+		arg.RowLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []LoginAttempt{}
+	for rows.Next() {
+		var i LoginAttempt
+		if err := rows.Scan(
+			&i.ID,
+			&i.Username,
+			&i.Success,
+			&i.AttemptTime,
+			&i.IpAddress,
+			&i.UserAgent,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getFailedLoginAttemptsByUsername = `-- name: GetFailedLoginAttemptsByUsername :many
-SELECT id, username, success, attempt_time
+SELECT id, username, success, attempt_time, ip_address, user_agent
 FROM login_attempts
 WHERE username = $1
   AND success = FALSE
@@ -47,6 +98,8 @@ func (q *Queries) GetFailedLoginAttemptsByUsername(ctx context.Context, arg GetF
 			&i.Username,
 			&i.Success,
 			&i.AttemptTime,
+			&i.IpAddress,
+			&i.UserAgent,
 		); err != nil {
 			return nil, err
 		}
@@ -59,7 +112,7 @@ func (q *Queries) GetFailedLoginAttemptsByUsername(ctx context.Context, arg GetF
 }
 
 const getLoginAttemptsByUsername = `-- name: GetLoginAttemptsByUsername :many
-SELECT id, username, success, attempt_time
+SELECT id, username, success, attempt_time, ip_address, user_agent
 FROM login_attempts
 WHERE username = $1
 ORDER BY attempt_time DESC
@@ -85,6 +138,8 @@ func (q *Queries) GetLoginAttemptsByUsername(ctx context.Context, arg GetLoginAt
 			&i.Username,
 			&i.Success,
 			&i.AttemptTime,
+			&i.IpAddress,
+			&i.UserAgent,
 		); err != nil {
 			return nil, err
 		}
@@ -97,21 +152,33 @@ func (q *Queries) GetLoginAttemptsByUsername(ctx context.Context, arg GetLoginAt
 }
 
 const logFailedLogin = `-- name: LogFailedLogin :exec
-INSERT INTO login_attempts (username, success)
-VALUES ($1, FALSE)
+INSERT INTO login_attempts (username, success, ip_address, user_agent)
+VALUES ($1, FALSE, $2, $3)
 `
 
-func (q *Queries) LogFailedLogin(ctx context.Context, username string) error {
-	_, err := q.db.Exec(ctx, logFailedLogin, username)
+type LogFailedLoginParams struct {
+	Username  string `json:"username"`
+	IpAddress string `json:"ip_address"`
+	UserAgent string `json:"user_agent"`
+}
+
+func (q *Queries) LogFailedLogin(ctx context.Context, arg LogFailedLoginParams) error {
+	_, err := q.db.Exec(ctx, logFailedLogin, arg.Username, arg.IpAddress, arg.UserAgent)
 	return err
 }
 
 const logSuccessfulLogin = `-- name: LogSuccessfulLogin :exec
-INSERT INTO login_attempts (username, success)
-VALUES ($1, TRUE)
+INSERT INTO login_attempts (username, success, ip_address, user_agent)
+VALUES ($1, TRUE, $2, $3)
 `
 
-func (q *Queries) LogSuccessfulLogin(ctx context.Context, username string) error {
-	_, err := q.db.Exec(ctx, logSuccessfulLogin, username)
+type LogSuccessfulLoginParams struct {
+	Username  string `json:"username"`
+	IpAddress string `json:"ip_address"`
+	UserAgent string `json:"user_agent"`
+}
+
+func (q *Queries) LogSuccessfulLogin(ctx context.Context, arg LogSuccessfulLoginParams) error {
+	_, err := q.db.Exec(ctx, logSuccessfulLogin, arg.Username, arg.IpAddress, arg.UserAgent)
 	return err
 }
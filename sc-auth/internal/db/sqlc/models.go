@@ -8,13 +8,45 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+type AccountLockout struct {
+	Username     string           `json:"username"`
+	LockoutCount int32            `json:"lockout_count"`
+	LockedUntil  pgtype.Timestamp `json:"locked_until"`
+	UpdatedAt    pgtype.Timestamp `json:"updated_at"`
+}
+
 type Auth struct {
+	ID              pgtype.UUID      `json:"id"`
+	Username        string           `json:"username"`
+	Email           string           `json:"email"`
+	Password        string           `json:"password"`
+	UpdatedAt       pgtype.Timestamp `json:"updated_at"`
+	LastLogin       pgtype.Timestamp `json:"last_login"`
+	EmailVerified   bool             `json:"email_verified"`
+	EmailVerifiedAt pgtype.Timestamp `json:"email_verified_at"`
+}
+
+type EmailVerificationToken struct {
 	ID        pgtype.UUID      `json:"id"`
-	Username  string           `json:"username"`
-	Email     string           `json:"email"`
-	Password  string           `json:"password"`
-	UpdatedAt pgtype.Timestamp `json:"updated_at"`
-	LastLogin pgtype.Timestamp `json:"last_login"`
+	UserID    pgtype.UUID      `json:"user_id"`
+	TokenHash string           `json:"token_hash"`
+	ExpiresAt pgtype.Timestamp `json:"expires_at"`
+	UsedAt    pgtype.Timestamp `json:"used_at"`
+	CreatedAt pgtype.Timestamp `json:"created_at"`
+}
+
+type ImpersonationSession struct {
+	ID             pgtype.UUID      `json:"id"`
+	ActorID        pgtype.UUID      `json:"actor_id"`
+	ActorUsername  string           `json:"actor_username"`
+	TargetID       pgtype.UUID      `json:"target_id"`
+	TargetUsername string           `json:"target_username"`
+	Reason         string           `json:"reason"`
+	TokenID        string           `json:"token_id"`
+	IpAddress      string           `json:"ip_address"`
+	UserAgent      string           `json:"user_agent"`
+	StartedAt      pgtype.Timestamp `json:"started_at"`
+	ExpiresAt      pgtype.Timestamp `json:"expires_at"`
 }
 
 type LoginAttempt struct {
@@ -22,6 +54,97 @@ type LoginAttempt struct {
 	Username    string           `json:"username"`
 	Success     bool             `json:"success"`
 	AttemptTime pgtype.Timestamp `json:"attempt_time"`
+	IpAddress   string           `json:"ip_address"`
+	UserAgent   string           `json:"user_agent"`
+}
+
+type MagicLinkToken struct {
+	ID        pgtype.UUID      `json:"id"`
+	UserID    pgtype.UUID      `json:"user_id"`
+	TokenHash string           `json:"token_hash"`
+	ExpiresAt pgtype.Timestamp `json:"expires_at"`
+	UsedAt    pgtype.Timestamp `json:"used_at"`
+	CreatedAt pgtype.Timestamp `json:"created_at"`
+}
+
+type OauthAuthorizationCode struct {
+	ID            pgtype.UUID      `json:"id"`
+	CodeHash      string           `json:"code_hash"`
+	ClientID      pgtype.UUID      `json:"client_id"`
+	UserID        pgtype.UUID      `json:"user_id"`
+	RedirectUri   string           `json:"redirect_uri"`
+	Scopes        []string         `json:"scopes"`
+	CodeChallenge string           `json:"code_challenge"`
+	ExpiresAt     pgtype.Timestamp `json:"expires_at"`
+	UsedAt        pgtype.Timestamp `json:"used_at"`
+	CreatedAt     pgtype.Timestamp `json:"created_at"`
+}
+
+type OauthClient struct {
+	ID               pgtype.UUID      `json:"id"`
+	OwnerID          pgtype.UUID      `json:"owner_id"`
+	Name             string           `json:"name"`
+	ClientSecretHash string           `json:"client_secret_hash"`
+	RedirectUris     []string         `json:"redirect_uris"`
+	Scopes           []string         `json:"scopes"`
+	CreatedAt        pgtype.Timestamp `json:"created_at"`
+}
+
+type OauthConsent struct {
+	UserID    pgtype.UUID      `json:"user_id"`
+	ClientID  pgtype.UUID      `json:"client_id"`
+	Scopes    []string         `json:"scopes"`
+	CreatedAt pgtype.Timestamp `json:"created_at"`
+	UpdatedAt pgtype.Timestamp `json:"updated_at"`
+}
+
+type OutboxEvent struct {
+	ID          pgtype.UUID      `json:"id"`
+	EventType   string           `json:"event_type"`
+	AggregateID pgtype.UUID      `json:"aggregate_id"`
+	Payload     []byte           `json:"payload"`
+	CreatedAt   pgtype.Timestamp `json:"created_at"`
+	LockedUntil pgtype.Timestamp `json:"locked_until"`
+	Attempts    int32            `json:"attempts"`
+	LastError   pgtype.Text      `json:"last_error"`
+	PublishedAt pgtype.Timestamp `json:"published_at"`
+}
+
+type PasswordResetToken struct {
+	ID        pgtype.UUID      `json:"id"`
+	UserID    pgtype.UUID      `json:"user_id"`
+	TokenHash string           `json:"token_hash"`
+	ExpiresAt pgtype.Timestamp `json:"expires_at"`
+	UsedAt    pgtype.Timestamp `json:"used_at"`
+	CreatedAt pgtype.Timestamp `json:"created_at"`
+}
+
+type PersonalAccessToken struct {
+	ID         pgtype.UUID      `json:"id"`
+	UserID     pgtype.UUID      `json:"user_id"`
+	Name       string           `json:"name"`
+	TokenHash  string           `json:"token_hash"`
+	Scopes     []string         `json:"scopes"`
+	ExpiresAt  pgtype.Timestamp `json:"expires_at"`
+	LastUsedAt pgtype.Timestamp `json:"last_used_at"`
+	CreatedAt  pgtype.Timestamp `json:"created_at"`
+}
+
+type Role struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+type RolePermission struct {
+	Role       string `json:"role"`
+	Permission string `json:"permission"`
+}
+
+type RotatedSessionToken struct {
+	ID           pgtype.UUID      `json:"id"`
+	SessionID    pgtype.UUID      `json:"session_id"`
+	SessionToken string           `json:"session_token"`
+	RotatedAt    pgtype.Timestamp `json:"rotated_at"`
 }
 
 type Session struct {
@@ -29,4 +152,30 @@ type Session struct {
 	UserID           pgtype.UUID      `json:"user_id"`
 	SessionToken     string           `json:"session_token"`
 	SessionExpiresAt pgtype.Timestamp `json:"session_expires_at"`
+	UserAgent        string           `json:"user_agent"`
+	IpAddress        string           `json:"ip_address"`
+	CreatedAt        pgtype.Timestamp `json:"created_at"`
+	LastUsedAt       pgtype.Timestamp `json:"last_used_at"`
+}
+
+type TotpRecoveryCode struct {
+	ID       pgtype.UUID      `json:"id"`
+	UserID   pgtype.UUID      `json:"user_id"`
+	CodeHash string           `json:"code_hash"`
+	UsedAt   pgtype.Timestamp `json:"used_at"`
+}
+
+type UserRole struct {
+	UserID    pgtype.UUID      `json:"user_id"`
+	Role      string           `json:"role"`
+	GrantedBy pgtype.UUID      `json:"granted_by"`
+	GrantedAt pgtype.Timestamp `json:"granted_at"`
+}
+
+type UserTotp struct {
+	UserID       pgtype.UUID      `json:"user_id"`
+	Secret       string           `json:"secret"`
+	ConfirmedAt  pgtype.Timestamp `json:"confirmed_at"`
+	LastUsedStep int64            `json:"last_used_step"`
+	CreatedAt    pgtype.Timestamp `json:"created_at"`
 }
@@ -11,24 +11,85 @@ import (
 )
 
 type Querier interface {
+	ClaimOutboxEvents(ctx context.Context, arg ClaimOutboxEventsParams) ([]OutboxEvent, error)
+	ConfirmUserTotp(ctx context.Context, userID pgtype.UUID) error
+	ConsumeEmailVerificationToken(ctx context.Context, tokenHash string) (pgtype.UUID, error)
+	ConsumeMagicLinkToken(ctx context.Context, tokenHash string) (pgtype.UUID, error)
+	ConsumeOAuthAuthorizationCode(ctx context.Context, codeHash string) (ConsumeOAuthAuthorizationCodeRow, error)
+	ConsumePasswordResetToken(ctx context.Context, tokenHash string) (pgtype.UUID, error)
+	CountEmailVerificationTokensSince(ctx context.Context, arg CountEmailVerificationTokensSinceParams) (int64, error)
+	CountMagicLinkTokensSince(ctx context.Context, arg CountMagicLinkTokensSinceParams) (int64, error)
+	CreateEmailVerificationToken(ctx context.Context, arg CreateEmailVerificationTokenParams) error
+	CreateImpersonationSession(ctx context.Context, arg CreateImpersonationSessionParams) (ImpersonationSession, error)
+	CreateMagicLinkToken(ctx context.Context, arg CreateMagicLinkTokenParams) error
+	CreateOAuthAuthorizationCode(ctx context.Context, arg CreateOAuthAuthorizationCodeParams) error
+	CreateOAuthClient(ctx context.Context, arg CreateOAuthClientParams) (OauthClient, error)
+	CreateOutboxEvent(ctx context.Context, arg CreateOutboxEventParams) error
+	CreatePasswordResetToken(ctx context.Context, arg CreatePasswordResetTokenParams) error
+	CreatePersonalAccessToken(ctx context.Context, arg CreatePersonalAccessTokenParams) (PersonalAccessToken, error)
+	CreateRecoveryCode(ctx context.Context, arg CreateRecoveryCodeParams) error
 	CreateSession(ctx context.Context, arg CreateSessionParams) (Session, error)
 	CreateUser(ctx context.Context, arg CreateUserParams) (CreateUserRow, error)
+	DeleteAccountLockout(ctx context.Context, username string) error
 	DeleteAllSessionsForUser(ctx context.Context, userID pgtype.UUID) error
+	DeleteOAuthClient(ctx context.Context, arg DeleteOAuthClientParams) (int64, error)
+	DeleteOAuthConsent(ctx context.Context, arg DeleteOAuthConsentParams) (int64, error)
 	DeleteOldLoginAttempts(ctx context.Context) error
+	DeleteOtherSessionsForUser(ctx context.Context, arg DeleteOtherSessionsForUserParams) error
+	DeletePersonalAccessToken(ctx context.Context, arg DeletePersonalAccessTokenParams) (int64, error)
+	DeletePublishedOutboxEvents(ctx context.Context, publishedBefore pgtype.Timestamp) error
+	DeleteRecoveryCodes(ctx context.Context, userID pgtype.UUID) error
+	DeleteSessionByID(ctx context.Context, id pgtype.UUID) error
 	DeleteSessionByToken(ctx context.Context, sessionToken string) error
-	DeleteUser(ctx context.Context, id pgtype.UUID) error
+	DeleteSessionForUser(ctx context.Context, arg DeleteSessionForUserParams) (int64, error)
+	DeleteUser(ctx context.Context, id pgtype.UUID) (int64, error)
+	DeleteUserTotp(ctx context.Context, userID pgtype.UUID) error
+	ExportLoginAttempts(ctx context.Context, arg ExportLoginAttemptsParams) ([]LoginAttempt, error)
+	GetAccountLockout(ctx context.Context, username string) (AccountLockout, error)
 	GetFailedLoginAttemptsByUsername(ctx context.Context, arg GetFailedLoginAttemptsByUsernameParams) ([]LoginAttempt, error)
 	GetLoginAttemptsByUsername(ctx context.Context, arg GetLoginAttemptsByUsernameParams) ([]LoginAttempt, error)
-	GetSessionByToken(ctx context.Context, sessionToken string) (GetSessionByTokenRow, error)
+	GetOAuthClient(ctx context.Context, id pgtype.UUID) (OauthClient, error)
+	GetOAuthConsent(ctx context.Context, arg GetOAuthConsentParams) (OauthConsent, error)
+	GetPersonalAccessTokenByHash(ctx context.Context, tokenHash string) (PersonalAccessToken, error)
+	GetRotatedSessionToken(ctx context.Context, sessionToken string) (GetRotatedSessionTokenRow, error)
+	GetSessionByID(ctx context.Context, id pgtype.UUID) (Session, error)
+	GetSessionByToken(ctx context.Context, sessionToken string) (Session, error)
 	GetSessionByUserId(ctx context.Context, userID pgtype.UUID) ([]GetSessionByUserIdRow, error)
 	GetUserByEmail(ctx context.Context, email string) (GetUserByEmailRow, error)
 	GetUserById(ctx context.Context, id pgtype.UUID) (GetUserByIdRow, error)
 	GetUserByUsername(ctx context.Context, username string) (GetUserByUsernameRow, error)
-	LogFailedLogin(ctx context.Context, username string) error
-	LogSuccessfulLogin(ctx context.Context, username string) error
+	GetUserPermissions(ctx context.Context, userID pgtype.UUID) ([]string, error)
+	GetUserRoles(ctx context.Context, userID pgtype.UUID) ([]string, error)
+	GetUserTotp(ctx context.Context, userID pgtype.UUID) (UserTotp, error)
+	GrantRole(ctx context.Context, arg GrantRoleParams) error
+	InvalidateEmailVerificationTokens(ctx context.Context, userID pgtype.UUID) error
+	InvalidateMagicLinkTokens(ctx context.Context, userID pgtype.UUID) error
+	InvalidatePasswordResetTokens(ctx context.Context, userID pgtype.UUID) error
+	ListImpersonationSessions(ctx context.Context, requestLimit int32) ([]ImpersonationSession, error)
+	ListOAuthClientsByOwner(ctx context.Context, ownerID pgtype.UUID) ([]OauthClient, error)
+	ListOAuthConsents(ctx context.Context, userID pgtype.UUID) ([]ListOAuthConsentsRow, error)
+	ListPersonalAccessTokens(ctx context.Context, userID pgtype.UUID) ([]PersonalAccessToken, error)
+	ListSessionsForUser(ctx context.Context, userID pgtype.UUID) ([]Session, error)
+	LockAccount(ctx context.Context, arg LockAccountParams) (AccountLockout, error)
+	LogFailedLogin(ctx context.Context, arg LogFailedLoginParams) error
+	LogSuccessfulLogin(ctx context.Context, arg LogSuccessfulLoginParams) error
+	MarkEmailVerified(ctx context.Context, id pgtype.UUID) error
+	MarkOutboxEventFailed(ctx context.Context, arg MarkOutboxEventFailedParams) error
+	MarkOutboxEventPublished(ctx context.Context, id pgtype.UUID) error
+	RehashUserPassword(ctx context.Context, arg RehashUserPasswordParams) (int64, error)
+	ReleaseOutboxEvents(ctx context.Context, ids []pgtype.UUID) error
+	RevokeRole(ctx context.Context, arg RevokeRoleParams) (int64, error)
+	RoleExists(ctx context.Context, name string) (bool, error)
+	RotateSessionToken(ctx context.Context, arg RotateSessionTokenParams) (int64, error)
+	TouchPersonalAccessToken(ctx context.Context, id pgtype.UUID) error
+	UnlockAccount(ctx context.Context, username string) (int64, error)
 	UpdateLastLogin(ctx context.Context, id pgtype.UUID) error
 	UpdateSessionExpiry(ctx context.Context, arg UpdateSessionExpiryParams) error
 	UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) error
+	UpsertOAuthConsent(ctx context.Context, arg UpsertOAuthConsentParams) error
+	UpsertUserTotp(ctx context.Context, arg UpsertUserTotpParams) error
+	UseRecoveryCode(ctx context.Context, arg UseRecoveryCodeParams) (int64, error)
+	UseTotpStep(ctx context.Context, arg UseTotpStepParams) (int64, error)
 }
 
 var _ Querier = (*Queries)(nil)
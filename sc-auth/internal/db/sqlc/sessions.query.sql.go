@@ -12,25 +12,39 @@ import (
 )
 
 const createSession = `-- name: CreateSession :one
-INSERT INTO sessions (user_id, session_token, session_expires_at)
-VALUES ($1, $2, $3)
-    RETURNING id, user_id, session_token, session_expires_at
+INSERT INTO sessions (id, user_id, session_token, session_expires_at, user_agent, ip_address)
+VALUES ($1, $2, $3, $4, $5, $6)
+    RETURNING id, user_id, session_token, session_expires_at, user_agent, ip_address, created_at, last_used_at
 `
 
 type CreateSessionParams struct {
+	ID               pgtype.UUID      `json:"id"`
 	UserID           pgtype.UUID      `json:"user_id"`
 	SessionToken     string           `json:"session_token"`
 	SessionExpiresAt pgtype.Timestamp `json:"session_expires_at"`
+	UserAgent        string           `json:"user_agent"`
+	IpAddress        string           `json:"ip_address"`
 }
 
 func (q *Queries) CreateSession(ctx context.Context, arg CreateSessionParams) (Session, error) {
-	row := q.db.QueryRow(ctx, createSession, arg.UserID, arg.SessionToken, arg.SessionExpiresAt)
+	row := q.db.QueryRow(ctx, createSession,
+		arg.ID,
+		arg.UserID,
+		arg.SessionToken,
+		arg.SessionExpiresAt,
+		arg.UserAgent,
+		arg.IpAddress,
+	)
 	var i Session
 	err := row.Scan(
 		&i.ID,
 		&i.UserID,
 		&i.SessionToken,
 		&i.SessionExpiresAt,
+		&i.UserAgent,
+		&i.IpAddress,
+		&i.CreatedAt,
+		&i.LastUsedAt,
 	)
 	return i, err
 }
@@ -45,6 +59,32 @@ func (q *Queries) DeleteAllSessionsForUser(ctx context.Context, userID pgtype.UU
 	return err
 }
 
+const deleteOtherSessionsForUser = `-- name: DeleteOtherSessionsForUser :exec
+DELETE FROM sessions
+WHERE user_id = $1
+  AND id <> $2
+`
+
+type DeleteOtherSessionsForUserParams struct {
+	UserID    pgtype.UUID `json:"user_id"`
+	SessionID pgtype.UUID `json:"session_id"`
+}
+
+func (q *Queries) DeleteOtherSessionsForUser(ctx context.Context, arg DeleteOtherSessionsForUserParams) error {
+	_, err := q.db.Exec(ctx, deleteOtherSessionsForUser, arg.UserID, arg.SessionID)
+	return err
+}
+
+const deleteSessionByID = `-- name: DeleteSessionByID :exec
+DELETE FROM sessions
+WHERE id = $1
+`
+
+func (q *Queries) DeleteSessionByID(ctx context.Context, id pgtype.UUID) error {
+	_, err := q.db.Exec(ctx, deleteSessionByID, id)
+	return err
+}
+
 const deleteSessionByToken = `-- name: DeleteSessionByToken :exec
 DELETE FROM sessions
 WHERE session_token = $1
@@ -55,24 +95,90 @@ func (q *Queries) DeleteSessionByToken(ctx context.Context, sessionToken string)
 	return err
 }
 
-const getSessionByToken = `-- name: GetSessionByToken :one
-SELECT user_id, session_token, session_expires_at
-FROM sessions
+const deleteSessionForUser = `-- name: DeleteSessionForUser :execrows
+DELETE FROM sessions
+WHERE id = $1
+  AND user_id = $2
+`
+
+type DeleteSessionForUserParams struct {
+	ID     pgtype.UUID `json:"id"`
+	UserID pgtype.UUID `json:"user_id"`
+}
+
+func (q *Queries) DeleteSessionForUser(ctx context.Context, arg DeleteSessionForUserParams) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteSessionForUser, arg.ID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const getRotatedSessionToken = `-- name: GetRotatedSessionToken :one
+SELECT session_id, session_token, rotated_at
+FROM rotated_session_tokens
 WHERE session_token = $1
+    LIMIT 1
+`
+
+type GetRotatedSessionTokenRow struct {
+	SessionID    pgtype.UUID      `json:"session_id"`
+	SessionToken string           `json:"session_token"`
+	RotatedAt    pgtype.Timestamp `json:"rotated_at"`
+}
+
+func (q *Queries) GetRotatedSessionToken(ctx context.Context, sessionToken string) (GetRotatedSessionTokenRow, error) {
+	row := q.db.QueryRow(ctx, getRotatedSessionToken, sessionToken)
+	var i GetRotatedSessionTokenRow
+	err := row.Scan(&i.SessionID, &i.SessionToken, &i.RotatedAt)
+	return i, err
+}
+
+const getSessionByID = `-- name: GetSessionByID :one
+SELECT id, user_id, session_token, session_expires_at, user_agent, ip_address, created_at, last_used_at
+FROM sessions
+WHERE id = $1
   AND session_expires_at > NOW()
     LIMIT 1
 `
 
-type GetSessionByTokenRow struct {
-	UserID           pgtype.UUID      `json:"user_id"`
-	SessionToken     string           `json:"session_token"`
-	SessionExpiresAt pgtype.Timestamp `json:"session_expires_at"`
+func (q *Queries) GetSessionByID(ctx context.Context, id pgtype.UUID) (Session, error) {
+	row := q.db.QueryRow(ctx, getSessionByID, id)
+	var i Session
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.SessionToken,
+		&i.SessionExpiresAt,
+		&i.UserAgent,
+		&i.IpAddress,
+		&i.CreatedAt,
+		&i.LastUsedAt,
+	)
+	return i, err
 }
 
-func (q *Queries) GetSessionByToken(ctx context.Context, sessionToken string) (GetSessionByTokenRow, error) {
+const getSessionByToken = `-- name: GetSessionByToken :one
+SELECT id, user_id, session_token, session_expires_at, user_agent, ip_address, created_at, last_used_at
+FROM sessions
+WHERE session_token = $1
+  AND session_expires_at > NOW()
+    LIMIT 1
+`
+
+func (q *Queries) GetSessionByToken(ctx context.Context, sessionToken string) (Session, error) {
 	row := q.db.QueryRow(ctx, getSessionByToken, sessionToken)
-	var i GetSessionByTokenRow
-	err := row.Scan(&i.UserID, &i.SessionToken, &i.SessionExpiresAt)
+	var i Session
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.SessionToken,
+		&i.SessionExpiresAt,
+		&i.UserAgent,
+		&i.IpAddress,
+		&i.CreatedAt,
+		&i.LastUsedAt,
+	)
 	return i, err
 }
 
@@ -109,6 +215,76 @@ func (q *Queries) GetSessionByUserId(ctx context.Context, userID pgtype.UUID) ([
 	return items, nil
 }
 
+const listSessionsForUser = `-- name: ListSessionsForUser :many
+SELECT id, user_id, session_token, session_expires_at, user_agent, ip_address, created_at, last_used_at
+FROM sessions
+WHERE user_id = $1
+  AND session_expires_at > NOW()
+ORDER BY last_used_at DESC
+`
+
+func (q *Queries) ListSessionsForUser(ctx context.Context, userID pgtype.UUID) ([]Session, error) {
+	rows, err := q.db.Query(ctx, listSessionsForUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Session{}
+	for rows.Next() {
+		var i Session
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.SessionToken,
+			&i.SessionExpiresAt,
+			&i.UserAgent,
+			&i.IpAddress,
+			&i.CreatedAt,
+			&i.LastUsedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const rotateSessionToken = `-- name: RotateSessionToken :execrows
+WITH rotated AS (
+    UPDATE sessions
+    SET session_token = $1, session_expires_at = $2, last_used_at = NOW()
+    WHERE id = $3
+      AND session_token = $4
+    RETURNING id
+)
+INSERT INTO rotated_session_tokens (session_id, session_token)
+SELECT id, $4
+FROM rotated
+`
+
+type RotateSessionTokenParams struct {
+	NewToken  string           `json:"new_token"`
+	NewExpiry pgtype.Timestamp `json:"new_expiry"`
+	ID        pgtype.UUID      `json:"id"`
+	OldToken  string           `json:"old_token"`
+}
+
+func (q *Queries) RotateSessionToken(ctx context.Context, arg RotateSessionTokenParams) (int64, error) {
+	result, err := q.db.Exec(ctx, rotateSessionToken,
+		arg.NewToken,
+		arg.NewExpiry,
+		arg.ID,
+		arg.OldToken,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const updateSessionExpiry = `-- name: UpdateSessionExpiry :exec
 UPDATE sessions
 SET session_expires_at = $1
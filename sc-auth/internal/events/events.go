@@ -0,0 +1,105 @@
+package events
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Event types of the auth lifecycle. They double as the Kafka topic names.
+const (
+	UserRegisteredType      = "user.registered"
+	UserDeletedType         = "user.deleted"
+	UserPasswordChangedType = "user.password_changed"
+	UserLoggedInType        = "user.logged_in"
+)
+
+// Reasons reported by UserPasswordChangedEvent.
+const (
+	PasswordChangedByUser  = "changed"
+	PasswordChangedByReset = "reset"
+)
+
+// Event is a domain event that is written to the outbox and published to Kafka.
+type Event interface {
+	EventID() string
+	EventType() string
+	// AggregateID is the user the event is about. It is the Kafka message key,
+	// so all events of one user keep their order.
+	AggregateID() string
+}
+
+// UserRegisteredEvent is emitted once an account was created.
+type UserRegisteredEvent struct {
+	ID         string    `json:"id"`
+	UserID     string    `json:"user_id"`
+	Username   string    `json:"username"`
+	Email      string    `json:"email"`
+	OccurredAt time.Time `json:"occurred_at"`
+}
+
+func NewUserRegisteredEvent(userID string, username string, email string) UserRegisteredEvent {
+	return UserRegisteredEvent{ID: uuid.NewString(), UserID: userID, Username: username, Email: email, OccurredAt: time.Now().UTC()}
+}
+
+func (e UserRegisteredEvent) EventID() string     { return e.ID }
+func (e UserRegisteredEvent) EventType() string   { return UserRegisteredType }
+func (e UserRegisteredEvent) AggregateID() string { return e.UserID }
+
+// UserDeletedEvent is emitted once an account was deleted. Services holding data
+// of the user are expected to remove it.
+type UserDeletedEvent struct {
+	ID         string    `json:"id"`
+	UserID     string    `json:"user_id"`
+	OccurredAt time.Time `json:"occurred_at"`
+}
+
+func NewUserDeletedEvent(userID string) UserDeletedEvent {
+	return UserDeletedEvent{ID: uuid.NewString(), UserID: userID, OccurredAt: time.Now().UTC()}
+}
+
+func (e UserDeletedEvent) EventID() string     { return e.ID }
+func (e UserDeletedEvent) EventType() string   { return UserDeletedType }
+func (e UserDeletedEvent) AggregateID() string { return e.UserID }
+
+// UserPasswordChangedEvent is emitted when a password was changed by its owner or
+// through a reset link. Transparent rehashing at login does not count as a change.
+type UserPasswordChangedEvent struct {
+	ID         string    `json:"id"`
+	UserID     string    `json:"user_id"`
+	Reason     string    `json:"reason"`
+	OccurredAt time.Time `json:"occurred_at"`
+}
+
+func NewUserPasswordChangedEvent(userID string, reason string) UserPasswordChangedEvent {
+	return UserPasswordChangedEvent{ID: uuid.NewString(), UserID: userID, Reason: reason, OccurredAt: time.Now().UTC()}
+}
+
+func (e UserPasswordChangedEvent) EventID() string     { return e.ID }
+func (e UserPasswordChangedEvent) EventType() string   { return UserPasswordChangedType }
+func (e UserPasswordChangedEvent) AggregateID() string { return e.UserID }
+
+// UserLoggedInEvent is emitted for every completed login, after the second factor if there is one.
+type UserLoggedInEvent struct {
+	ID         string    `json:"id"`
+	UserID     string    `json:"user_id"`
+	SessionID  string    `json:"session_id"`
+	UserAgent  string    `json:"user_agent,omitempty"`
+	IPAddress  string    `json:"ip_address,omitempty"`
+	OccurredAt time.Time `json:"occurred_at"`
+}
+
+func NewUserLoggedInEvent(userID string, sessionID string, userAgent string, ipAddress string) UserLoggedInEvent {
+	return UserLoggedInEvent{
+		ID:         uuid.NewString(),
+		UserID:     userID,
+		SessionID:  sessionID,
+		UserAgent:  userAgent,
+		IPAddress:  ipAddress,
+		OccurredAt: time.Now().UTC(),
+	}
+}
+
+func (e UserLoggedInEvent) EventID() string     { return e.ID }
+func (e UserLoggedInEvent) EventType() string   { return UserLoggedInType }
+func (e UserLoggedInEvent) AggregateID() string { return e.UserID }
@@ -0,0 +1,134 @@
+package events
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"log"
+	"slices"
+	db "soul-connect/sc-auth/internal/db/sqlc"
+	"time"
+)
+
+// OutboxStore is the part of the repository the relay works with.
+type OutboxStore interface {
+	ClaimOutboxEvents(ctx context.Context, arg db.ClaimOutboxEventsParams) ([]db.OutboxEvent, error)
+	MarkOutboxEventPublished(ctx context.Context, id pgtype.UUID) error
+	MarkOutboxEventFailed(ctx context.Context, arg db.MarkOutboxEventFailedParams) error
+	ReleaseOutboxEvents(ctx context.Context, ids []pgtype.UUID) error
+	DeletePublishedOutboxEvents(ctx context.Context, publishedBefore pgtype.Timestamp) error
+}
+
+// Publisher delivers a message to a Kafka topic.
+type Publisher interface {
+	Publish(ctx context.Context, topic string, key string, payload []byte) error
+}
+
+// RelayPolicy configures how often the outbox is polled and how long published events are kept.
+type RelayPolicy struct {
+	BatchSize    int
+	PollInterval time.Duration
+	Retention    time.Duration
+}
+
+// outboxLease is how long claimed events stay hidden from other relays. A relay that
+// dies while publishing leaves the events to be picked up again after the lease.
+const outboxLease = 30 * time.Second
+
+// Relay publishes outbox events to Kafka. Delivery is at least once: an event can be
+// published twice when marking it as published fails, so consumers deduplicate by event id.
+type Relay struct {
+	store     OutboxStore
+	publisher Publisher
+	policy    RelayPolicy
+}
+
+func NewRelay(store OutboxStore, publisher Publisher, policy RelayPolicy) *Relay {
+	return &Relay{store: store, publisher: publisher, policy: policy}
+}
+
+// Run polls the outbox until the context is cancelled.
+func (r *Relay) Run(ctx context.Context) {
+	if r.policy.PollInterval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(r.policy.PollInterval)
+	defer ticker.Stop()
+
+	lastCleanUp := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if _, err := r.PublishPending(ctx); err != nil {
+			log.Printf("failed to publish outbox events: %v", err)
+		}
+
+		if r.policy.Retention > 0 && time.Since(lastCleanUp) >= time.Hour {
+			lastCleanUp = time.Now()
+			publishedBefore := pgtype.Timestamp{Time: time.Now().UTC().Add(-r.policy.Retention), Valid: true}
+			if err := r.store.DeletePublishedOutboxEvents(ctx, publishedBefore); err != nil {
+				log.Printf("failed to clean up outbox events: %v", err)
+			}
+		}
+	}
+}
+
+// PublishPending publishes one batch of pending events in the order they were written
+// and returns how many were sent. It stops at the first failure and releases the rest
+// of the batch, so the next run starts over at the failed event and later events of
+// the same user never overtake it.
+func (r *Relay) PublishPending(ctx context.Context) (int, error) {
+	pending, err := r.store.ClaimOutboxEvents(ctx, db.ClaimOutboxEventsParams{
+		LockedUntil: pgtype.Timestamp{Time: time.Now().UTC().Add(outboxLease), Valid: true},
+		BatchSize:   int32(r.policy.BatchSize),
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	// UPDATE ... RETURNING does not keep the order of the subquery
+	slices.SortFunc(pending, func(a, b db.OutboxEvent) int {
+		return a.CreatedAt.Time.Compare(b.CreatedAt.Time)
+	})
+
+	for i, event := range pending {
+		key := uuid.UUID(event.AggregateID.Bytes).String()
+		if err := r.publisher.Publish(ctx, event.EventType, key, event.Payload); err != nil {
+			if markErr := r.store.MarkOutboxEventFailed(ctx, db.MarkOutboxEventFailedParams{
+				LastError: pgtype.Text{String: err.Error(), Valid: true},
+				ID:        event.ID,
+			}); markErr != nil {
+				log.Printf("failed to record outbox publish error: %v", markErr)
+			}
+			r.release(ctx, pending[i+1:])
+			return i, err
+		}
+
+		// The event is published again on the next run, ahead of the ones after it
+		if err := r.store.MarkOutboxEventPublished(ctx, event.ID); err != nil {
+			r.release(ctx, pending[i:])
+			return i, err
+		}
+	}
+	return len(pending), nil
+}
+
+// release hands claimed events back before their lease ends. Events that cannot be
+// released are picked up again once the lease expired.
+func (r *Relay) release(ctx context.Context, events []db.OutboxEvent) {
+	if len(events) == 0 {
+		return
+	}
+	ids := make([]pgtype.UUID, 0, len(events))
+	for _, event := range events {
+		ids = append(ids, event.ID)
+	}
+	if err := r.store.ReleaseOutboxEvents(ctx, ids); err != nil {
+		log.Printf("failed to release outbox events: %v", err)
+	}
+}
@@ -0,0 +1,118 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+	db "soul-connect/sc-auth/internal/db/sqlc"
+)
+
+type stubOutboxStore struct {
+	pending   []db.OutboxEvent
+	published []pgtype.UUID
+	failed    []db.MarkOutboxEventFailedParams
+	released  []pgtype.UUID
+}
+
+func (s *stubOutboxStore) ClaimOutboxEvents(_ context.Context, arg db.ClaimOutboxEventsParams) ([]db.OutboxEvent, error) {
+	claimed := s.pending
+	if len(claimed) > int(arg.BatchSize) {
+		claimed = claimed[:arg.BatchSize]
+	}
+	return claimed, nil
+}
+
+func (s *stubOutboxStore) MarkOutboxEventPublished(_ context.Context, id pgtype.UUID) error {
+	s.published = append(s.published, id)
+	return nil
+}
+
+func (s *stubOutboxStore) MarkOutboxEventFailed(_ context.Context, arg db.MarkOutboxEventFailedParams) error {
+	s.failed = append(s.failed, arg)
+	return nil
+}
+
+func (s *stubOutboxStore) ReleaseOutboxEvents(_ context.Context, ids []pgtype.UUID) error {
+	s.released = append(s.released, ids...)
+	return nil
+}
+
+func (s *stubOutboxStore) DeletePublishedOutboxEvents(context.Context, pgtype.Timestamp) error {
+	return nil
+}
+
+type publishedMessage struct {
+	topic string
+	key   string
+}
+
+type stubPublisher struct {
+	messages []publishedMessage
+	failOn   string
+}
+
+func (p *stubPublisher) Publish(_ context.Context, topic string, key string, _ []byte) error {
+	if topic == p.failOn {
+		return errors.New("broker unavailable")
+	}
+	p.messages = append(p.messages, publishedMessage{topic: topic, key: key})
+	return nil
+}
+
+func outboxEvent(eventType string, userID uuid.UUID, createdAt time.Time) db.OutboxEvent {
+	return db.OutboxEvent{
+		ID:          pgtype.UUID{Bytes: uuid.New(), Valid: true},
+		EventType:   eventType,
+		AggregateID: pgtype.UUID{Bytes: userID, Valid: true},
+		Payload:     []byte(`{}`),
+		CreatedAt:   pgtype.Timestamp{Time: createdAt, Valid: true},
+	}
+}
+
+func TestRelay_PublishesEventsInOrder(t *testing.T) {
+	userID := uuid.New()
+	now := time.Now()
+
+	registered := outboxEvent(UserRegisteredType, userID, now)
+	loggedIn := outboxEvent(UserLoggedInType, userID, now.Add(time.Second))
+
+	// Claimed rows come back in any order
+	store := &stubOutboxStore{pending: []db.OutboxEvent{loggedIn, registered}}
+	publisher := &stubPublisher{}
+
+	published, err := NewRelay(store, publisher, RelayPolicy{BatchSize: 10}).PublishPending(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 2, published)
+	require.Equal(t, []publishedMessage{
+		{topic: UserRegisteredType, key: userID.String()},
+		{topic: UserLoggedInType, key: userID.String()},
+	}, publisher.messages)
+	require.Equal(t, []pgtype.UUID{registered.ID, loggedIn.ID}, store.published)
+}
+
+func TestRelay_StopsAtTheFirstFailure(t *testing.T) {
+	userID := uuid.New()
+	now := time.Now()
+
+	store := &stubOutboxStore{pending: []db.OutboxEvent{
+		outboxEvent(UserRegisteredType, userID, now),
+		outboxEvent(UserPasswordChangedType, userID, now.Add(time.Second)),
+		outboxEvent(UserDeletedType, userID, now.Add(2*time.Second)),
+	}}
+	publisher := &stubPublisher{failOn: UserPasswordChangedType}
+
+	published, err := NewRelay(store, publisher, RelayPolicy{BatchSize: 10}).PublishPending(context.Background())
+	require.EqualError(t, err, "broker unavailable")
+	require.Equal(t, 1, published)
+	require.Equal(t, []pgtype.UUID{store.pending[0].ID}, store.published)
+	require.Len(t, store.failed, 1)
+	require.Equal(t, store.pending[1].ID, store.failed[0].ID)
+	require.Equal(t, "broker unavailable", store.failed[0].LastError.String)
+	// The rest of the batch is handed back, so the next run starts at the failed event
+	require.Equal(t, []pgtype.UUID{store.pending[2].ID}, store.released)
+}
@@ -0,0 +1,64 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Sender delivers emails on behalf of the auth service.
+type Sender interface {
+	Send(ctx context.Context, message Message) error
+}
+
+// LogSender writes emails to the service log, meant for local development only.
+type LogSender struct{}
+
+func NewLogSender() *LogSender {
+	return &LogSender{}
+}
+
+func (s *LogSender) Send(_ context.Context, message Message) error {
+	log.Printf("mail to %s: %s\n%s", message.To, message.Subject, message.Body)
+	return nil
+}
+
+// FileSender writes every email as a separate .eml file into a directory.
+type FileSender struct {
+	dir string
+}
+
+func NewFileSender(dir string) (*FileSender, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("create mail directory %s: %w", dir, err)
+	}
+	return &FileSender{dir: dir}, nil
+}
+
+func (s *FileSender) Send(_ context.Context, message Message) error {
+	name := fmt.Sprintf("%d-%s.eml", time.Now().UnixNano(), sanitizeFileName(message.To))
+
+	content := fmt.Sprintf("To: %s\r\nSubject: %s\r\nDate: %s\r\n\r\n%s\r\n",
+		message.To, message.Subject, time.Now().UTC().Format(time.RFC1123Z), message.Body)
+
+	return os.WriteFile(filepath.Join(s.dir, name), []byte(content), 0o600)
+}
+
+func sanitizeFileName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r == os.PathSeparator {
+			return '_'
+		}
+		return r
+	}, name)
+}
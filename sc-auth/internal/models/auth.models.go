@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 type User struct {
 	ID        string `json:"id"`
 	Username  string `json:"username"`
@@ -20,15 +22,192 @@ type CreateUserResponse struct {
 	Email    string `json:"email"`
 }
 
+// UserLoginRequest identifies the account by either its username or its email.
 type UserLoginRequest struct {
-	Username string `json:"username" validate:"required"`
-	Password string `json:"password" validate:"required,min=6"`
+	Identifier string     `json:"identifier" validate:"required"`
+	Password   string     `json:"password" validate:"required,min=6"`
+	Client     ClientInfo `json:"-"`
+}
+
+// ClientInfo describes the device a session is opened from.
+type ClientInfo struct {
+	UserAgent string `json:"user_agent"`
+	IPAddress string `json:"ip_address"`
 }
 
 type UserLoginResponseDTO struct {
+	ID                string `json:"id"`
+	Username          string `json:"username"`
+	Email             string `json:"email"`
+	AccessToken       string `json:"access_token"`
+	RefreshToken      string `json:"refresh_token"`
+	TwoFactorRequired bool   `json:"two_factor_required"`
+	ChallengeToken    string `json:"challenge_token"`
+}
+
+type RefreshTokenResponseDTO struct {
 	ID           string `json:"id"`
-	Username     string `json:"username"`
-	Email        string `json:"email"`
 	AccessToken  string `json:"access_token"`
 	RefreshToken string `json:"refresh_token"`
 }
+
+type TokenIntrospectionDTO struct {
+	Active        bool      `json:"active"`
+	UserID        string    `json:"user_id"`
+	SessionID     string    `json:"session_id"`
+	TokenType     string    `json:"token_type"`
+	IssuedAt      time.Time `json:"issued_at"`
+	ExpiresAt     time.Time `json:"expires_at"`
+	Revoked       bool      `json:"revoked"`
+	EmailVerified bool      `json:"email_verified"`
+	Scopes        []string  `json:"scopes"`
+	Roles         []string  `json:"roles"`
+	Permissions   []string  `json:"permissions"`
+	ClientID      string    `json:"client_id"`
+	ActorID       string    `json:"actor_id"`
+}
+
+type TwoFactorEnrollmentDTO struct {
+	Secret          string   `json:"secret"`
+	ProvisioningURI string   `json:"provisioning_uri"`
+	RecoveryCodes   []string `json:"recovery_codes"`
+}
+
+type SessionDTO struct {
+	ID         string    `json:"id"`
+	UserAgent  string    `json:"user_agent"`
+	IPAddress  string    `json:"ip_address"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	Current    bool      `json:"current"`
+}
+
+type CreatePersonalAccessTokenRequest struct {
+	Name      string        `json:"name" validate:"required,max=100"`
+	Scopes    []string      `json:"scopes" validate:"required,min=1"`
+	ExpiresIn time.Duration `json:"expires_in"`
+}
+
+// PersonalAccessTokenDTO describes a personal access token. Token is only set right after creation.
+type PersonalAccessTokenDTO struct {
+	ID         string    `json:"id"`
+	Name       string    `json:"name"`
+	Scopes     []string  `json:"scopes"`
+	Token      string    `json:"token,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// LoginAttemptDTO is one entry of the sign-in history kept in login_attempts.
+type LoginAttemptDTO struct {
+	ID          string    `json:"id"`
+	Username    string    `json:"username"`
+	Success     bool      `json:"success"`
+	IPAddress   string    `json:"ip_address"`
+	UserAgent   string    `json:"user_agent"`
+	AttemptedAt time.Time `json:"attempted_at"`
+}
+
+// LoginAttemptExportRequest selects the attempts of an audit export. An empty Username exports all users.
+type LoginAttemptExportRequest struct {
+	Format   string
+	Username string
+	From     time.Time
+	To       time.Time
+}
+
+type LoginAttemptExport struct {
+	ContentType string
+	Filename    string
+	Data        []byte
+	Count       int
+}
+
+// RegisterOAuthClientRequest describes a third-party app. Confidential clients get a
+// client secret, public clients (single page and mobile apps) rely on PKCE alone.
+type RegisterOAuthClientRequest struct {
+	Name         string
+	RedirectURIs []string
+	Scopes       []string
+	Confidential bool
+}
+
+// OAuthClientDTO describes a registered app. ClientSecret is only set right after registration.
+type OAuthClientDTO struct {
+	ClientID     string    `json:"client_id"`
+	ClientSecret string    `json:"client_secret,omitempty"`
+	Name         string    `json:"name"`
+	RedirectURIs []string  `json:"redirect_uris"`
+	Scopes       []string  `json:"scopes"`
+	Confidential bool      `json:"confidential"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// OAuthAuthorizationRequest is an authorization request the signed-in user approved.
+// Without Scopes the client gets all scopes it was registered with.
+type OAuthAuthorizationRequest struct {
+	ClientID            string
+	RedirectURI         string
+	Scopes              []string
+	State               string
+	CodeChallenge       string
+	CodeChallengeMethod string
+}
+
+// OAuthAuthorizationDTO carries the issued code. RedirectTo is the redirect URI with
+// the code and state appended, where the user agent is sent next.
+type OAuthAuthorizationDTO struct {
+	Code       string   `json:"code"`
+	RedirectTo string   `json:"redirect_to"`
+	Scopes     []string `json:"scopes"`
+}
+
+// OAuthTokenRequest is a token endpoint request for the authorization_code or refresh_token grant.
+type OAuthTokenRequest struct {
+	GrantType    string
+	ClientID     string
+	ClientSecret string
+	Code         string
+	RedirectURI  string
+	CodeVerifier string
+	RefreshToken string
+}
+
+type OAuthTokenDTO struct {
+	AccessToken  string        `json:"access_token"`
+	RefreshToken string        `json:"refresh_token"`
+	ExpiresIn    time.Duration `json:"expires_in"`
+	Scopes       []string      `json:"scopes"`
+}
+
+// OAuthConsentDTO is an app the user allowed to act on their behalf.
+type OAuthConsentDTO struct {
+	ClientID   string    `json:"client_id"`
+	ClientName string    `json:"client_name"`
+	Scopes     []string  `json:"scopes"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// ImpersonationDTO is the token an admin received to act as another user.
+type ImpersonationDTO struct {
+	SessionID   string    `json:"session_id"`
+	AccessToken string    `json:"access_token"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// ImpersonationSessionDTO is an entry of the impersonation audit trail.
+type ImpersonationSessionDTO struct {
+	ID             string    `json:"id"`
+	ActorID        string    `json:"actor_id"`
+	ActorUsername  string    `json:"actor_username"`
+	TargetID       string    `json:"target_id"`
+	TargetUsername string    `json:"target_username"`
+	Reason         string    `json:"reason"`
+	IPAddress      string    `json:"ip_address"`
+	UserAgent      string    `json:"user_agent"`
+	StartedAt      time.Time `json:"started_at"`
+	ExpiresAt      time.Time `json:"expires_at"`
+}
@@ -0,0 +1,149 @@
+package revocation
+
+import (
+	"context"
+	"errors"
+	"github.com/go-redis/redis/v8"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// List remembers revoked access tokens until they would have expired anyway.
+// Single tokens are revoked by their jti, all tokens of a user by the time they were issued.
+type List interface {
+	// RevokeToken revokes the token with the jti until expiresAt.
+	RevokeToken(ctx context.Context, jti string, expiresAt time.Time) error
+	// RevokeUser revokes every token of the user issued before now. ttl has to cover
+	// the lifetime of the longest living token.
+	RevokeUser(ctx context.Context, userID string, ttl time.Duration) error
+	// IsRevoked reports whether a token was revoked by either of the above.
+	IsRevoked(ctx context.Context, jti string, userID string, issuedAt time.Time) (bool, error)
+}
+
+const (
+	tokenKeyPrefix = "sc-auth:revoked:jti:"
+	userKeyPrefix  = "sc-auth:revoked:user:"
+)
+
+// RedisList shares revocations between all sc-auth instances. Entries expire through Redis TTLs.
+type RedisList struct {
+	client *redis.Client
+}
+
+func NewRedisList(client *redis.Client) *RedisList {
+	return &RedisList{client: client}
+}
+
+func (l *RedisList) RevokeToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	ttl := time.Until(expiresAt)
+	if jti == "" || ttl <= 0 {
+		return nil
+	}
+	return l.client.Set(ctx, tokenKeyPrefix+jti, 1, ttl).Err()
+}
+
+func (l *RedisList) RevokeUser(ctx context.Context, userID string, ttl time.Duration) error {
+	return l.client.Set(ctx, userKeyPrefix+userID, time.Now().UnixMicro(), ttl).Err()
+}
+
+func (l *RedisList) IsRevoked(ctx context.Context, jti string, userID string, issuedAt time.Time) (bool, error) {
+	if jti != "" {
+		revoked, err := l.client.Exists(ctx, tokenKeyPrefix+jti).Result()
+		if err != nil {
+			return false, err
+		}
+		if revoked > 0 {
+			return true, nil
+		}
+	}
+
+	value, err := l.client.Get(ctx, userKeyPrefix+userID).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return false, nil
+		}
+		return false, err
+	}
+	revokedAt, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return false, err
+	}
+	return issuedBefore(issuedAt, revokedAt), nil
+}
+
+// MemoryList keeps revocations in process memory. It is meant for local development
+// and tests, revocations are lost on restart and not shared between instances.
+type MemoryList struct {
+	mu     sync.Mutex
+	tokens map[string]time.Time
+	users  map[string]memoryUserRevocation
+}
+
+type memoryUserRevocation struct {
+	// revokedAt is in microseconds since the epoch
+	revokedAt int64
+	expiresAt time.Time
+}
+
+func NewMemoryList() *MemoryList {
+	return &MemoryList{
+		tokens: map[string]time.Time{},
+		users:  map[string]memoryUserRevocation{},
+	}
+}
+
+func (l *MemoryList) RevokeToken(_ context.Context, jti string, expiresAt time.Time) error {
+	if jti == "" || !time.Now().Before(expiresAt) {
+		return nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.removeExpired()
+	l.tokens[jti] = expiresAt
+	return nil
+}
+
+func (l *MemoryList) RevokeUser(_ context.Context, userID string, ttl time.Duration) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.removeExpired()
+	now := time.Now()
+	l.users[userID] = memoryUserRevocation{revokedAt: now.UnixMicro(), expiresAt: now.Add(ttl)}
+	return nil
+}
+
+func (l *MemoryList) IsRevoked(_ context.Context, jti string, userID string, issuedAt time.Time) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.removeExpired()
+
+	if _, ok := l.tokens[jti]; ok && jti != "" {
+		return true, nil
+	}
+	if user, ok := l.users[userID]; ok {
+		return issuedBefore(issuedAt, user.revokedAt), nil
+	}
+	return false, nil
+}
+
+func (l *MemoryList) removeExpired() {
+	now := time.Now()
+	for jti, expiresAt := range l.tokens {
+		if !now.Before(expiresAt) {
+			delete(l.tokens, jti)
+		}
+	}
+	for userID, user := range l.users {
+		if !now.Before(user.expiresAt) {
+			delete(l.users, userID)
+		}
+	}
+}
+
+// issuedBefore compares in microseconds, the precision of the iat claim, so tokens
+// issued right after the revocation stay valid.
+func issuedBefore(issuedAt time.Time, revokedAt int64) bool {
+	return issuedAt.UnixMicro() < revokedAt
+}
@@ -0,0 +1,46 @@
+package revocation
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryList_RevokesTokensUntilTheyExpire(t *testing.T) {
+	ctx := context.Background()
+	list := NewMemoryList()
+
+	require.NoError(t, list.RevokeToken(ctx, "revoked", time.Now().Add(time.Minute)))
+	require.NoError(t, list.RevokeToken(ctx, "expired", time.Now().Add(-time.Minute)))
+
+	revoked, err := list.IsRevoked(ctx, "revoked", "user", time.Now())
+	require.NoError(t, err)
+	require.True(t, revoked)
+
+	revoked, err = list.IsRevoked(ctx, "expired", "user", time.Now())
+	require.NoError(t, err)
+	require.False(t, revoked)
+	require.NotContains(t, list.tokens, "expired")
+}
+
+func TestMemoryList_RevokesTokensIssuedBeforeUserRevocation(t *testing.T) {
+	ctx := context.Background()
+	list := NewMemoryList()
+
+	require.NoError(t, list.RevokeUser(ctx, "user", time.Minute))
+
+	revoked, err := list.IsRevoked(ctx, "jti", "user", time.Now().Add(-time.Minute))
+	require.NoError(t, err)
+	require.True(t, revoked)
+
+	// Tokens issued right after the revocation, within the same second, stay valid
+	revoked, err = list.IsRevoked(ctx, "jti", "user", time.Now())
+	require.NoError(t, err)
+	require.False(t, revoked)
+
+	revoked, err = list.IsRevoked(ctx, "jti", "other-user", time.Now().Add(-time.Minute))
+	require.NoError(t, err)
+	require.False(t, revoked)
+}
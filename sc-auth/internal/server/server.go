@@ -2,14 +2,29 @@ package server
 
 import (
 	"context"
+	"errors"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgtype"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
 	"google.golang.org/protobuf/types/known/emptypb"
+	"net"
+	"soul-connect/pkg/requestctx"
 	"soul-connect/sc-auth/internal/generated"
 	"soul-connect/sc-auth/internal/models"
 	"soul-connect/sc-auth/internal/services"
+	"soul-connect/sc-auth/pkg/authz"
+	"strings"
+	"time"
+	"unicode/utf8"
 )
 
+const maxUserAgentLength = 512
+
 type AuthServer struct {
 	generated.UnimplementedAuthServiceServer
 	authService *services.AuthService
@@ -30,6 +45,12 @@ func (s *AuthServer) Register(ctx context.Context, request *generated.RegisterUs
 
 	registeredUser, err := s.authService.Register(ctx, createUserReq)
 	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrEmailTaken):
+			return nil, fieldStatus(codes.AlreadyExists, "email", err)
+		case errors.Is(err, services.ErrUsernameTaken):
+			return nil, fieldStatus(codes.AlreadyExists, "username", err)
+		}
 		return nil, err
 	}
 
@@ -43,13 +64,55 @@ func (s *AuthServer) Register(ctx context.Context, request *generated.RegisterUs
 }
 
 func (s *AuthServer) Login(ctx context.Context, request *generated.LoginUserRequest) (*generated.LoginUserResponse, error) {
+	identifier := request.Username
+	if identifier == "" {
+		identifier = request.Email
+	}
+
 	loginReq := models.UserLoginRequest{
-		Username: request.Username,
-		Password: request.Password,
+		Identifier: identifier,
+		Password:   request.Password,
+		Client:     clientInfoFromContext(ctx),
 	}
 
 	loginUser, err := s.authService.Login(ctx, loginReq)
 	if err != nil {
+		var lockedErr *services.AccountLockedError
+		if errors.As(err, &lockedErr) {
+			return nil, accountLockedStatus(lockedErr)
+		}
+		switch {
+		case errors.Is(err, services.ErrInvalidCredentials):
+			return nil, status.Error(codes.Unauthenticated, err.Error())
+		case errors.Is(err, services.ErrEmailNotVerified):
+			return nil, status.Error(codes.FailedPrecondition, err.Error())
+		}
+		return nil, err
+	}
+
+	response := &generated.LoginUserResponse{
+		Id:                loginUser.ID,
+		Username:          loginUser.Username,
+		Email:             loginUser.Email,
+		AccessToken:       loginUser.AccessToken,
+		RefreshToken:      loginUser.RefreshToken,
+		TwoFactorRequired: loginUser.TwoFactorRequired,
+		ChallengeToken:    loginUser.ChallengeToken,
+	}
+
+	return response, nil
+}
+
+func (s *AuthServer) VerifySecondFactor(ctx context.Context, request *generated.VerifySecondFactorRequest) (*generated.LoginUserResponse, error) {
+	loginUser, err := s.authService.VerifySecondFactor(ctx, request.ChallengeToken, request.Code, clientInfoFromContext(ctx))
+	if err != nil {
+		var lockedErr *services.AccountLockedError
+		if errors.As(err, &lockedErr) {
+			return nil, accountLockedStatus(lockedErr)
+		}
+		if errors.Is(err, services.ErrInvalidChallengeToken) || errors.Is(err, services.ErrInvalidTwoFactorCode) {
+			return nil, status.Error(codes.Unauthenticated, err.Error())
+		}
 		return nil, err
 	}
 
@@ -64,6 +127,106 @@ func (s *AuthServer) Login(ctx context.Context, request *generated.LoginUserRequ
 	return response, nil
 }
 
+func (s *AuthServer) RequestMagicLink(ctx context.Context, request *generated.RequestMagicLinkRequest) (*emptypb.Empty, error) {
+	if err := s.authService.RequestMagicLink(ctx, request.Email); err != nil {
+		return nil, err
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (s *AuthServer) ConsumeMagicLink(ctx context.Context, request *generated.ConsumeMagicLinkRequest) (*generated.LoginUserResponse, error) {
+	loginUser, err := s.authService.ConsumeMagicLink(ctx, request.Token, clientInfoFromContext(ctx))
+	if err != nil {
+		var lockedErr *services.AccountLockedError
+		if errors.As(err, &lockedErr) {
+			return nil, accountLockedStatus(lockedErr)
+		}
+		if errors.Is(err, services.ErrInvalidMagicLinkToken) {
+			return nil, status.Error(codes.Unauthenticated, err.Error())
+		}
+		return nil, err
+	}
+
+	response := &generated.LoginUserResponse{
+		Id:                loginUser.ID,
+		Username:          loginUser.Username,
+		Email:             loginUser.Email,
+		AccessToken:       loginUser.AccessToken,
+		RefreshToken:      loginUser.RefreshToken,
+		TwoFactorRequired: loginUser.TwoFactorRequired,
+		ChallengeToken:    loginUser.ChallengeToken,
+	}
+
+	return response, nil
+}
+
+func (s *AuthServer) RefreshToken(ctx context.Context, request *generated.RefreshTokenRequest) (*generated.RefreshTokenResponse, error) {
+	tokens, err := s.authService.RefreshToken(ctx, request.RefreshToken)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidRefreshToken) || errors.Is(err, services.ErrRefreshTokenReused) {
+			return nil, status.Error(codes.Unauthenticated, err.Error())
+		}
+		return nil, err
+	}
+
+	response := &generated.RefreshTokenResponse{
+		Id:           tokens.ID,
+		AccessToken:  tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+	}
+
+	return response, nil
+}
+
+func (s *AuthServer) IntrospectToken(ctx context.Context, request *generated.IntrospectTokenRequest) (*generated.IntrospectTokenResponse, error) {
+	introspection, err := s.authService.IntrospectToken(ctx, request.Token)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &generated.IntrospectTokenResponse{
+		Active:        introspection.Active,
+		UserId:        introspection.UserID,
+		SessionId:     introspection.SessionID,
+		TokenType:     introspection.TokenType,
+		Revoked:       introspection.Revoked,
+		EmailVerified: introspection.EmailVerified,
+		Scopes:        introspection.Scopes,
+		Roles:         introspection.Roles,
+		Permissions:   introspection.Permissions,
+		ClientId:      introspection.ClientID,
+		ActorId:       introspection.ActorID,
+	}
+	if !introspection.IssuedAt.IsZero() {
+		response.IssuedAt = introspection.IssuedAt.Unix()
+	}
+	if !introspection.ExpiresAt.IsZero() {
+		response.ExpiresAt = introspection.ExpiresAt.Unix()
+	}
+
+	return response, nil
+}
+
+func (s *AuthServer) GetJWKS(_ context.Context, _ *emptypb.Empty) (*generated.JWKSResponse, error) {
+	jwks := s.authService.JWKS()
+
+	keys := make([]*generated.JsonWebKey, 0, len(jwks))
+	for _, jwk := range jwks {
+		keys = append(keys, &generated.JsonWebKey{
+			Kty: jwk.Kty,
+			Kid: jwk.Kid,
+			Use: jwk.Use,
+			Alg: jwk.Alg,
+			N:   jwk.N,
+			E:   jwk.E,
+			Crv: jwk.Crv,
+			X:   jwk.X,
+		})
+	}
+
+	return &generated.JWKSResponse{Keys: keys}, nil
+}
+
 func (s *AuthServer) Logout(ctx context.Context, request *generated.LogoutUserRequest) (*emptypb.Empty, error) {
 	if err := s.authService.Logout(ctx, request.Token); err != nil {
 		return nil, err
@@ -72,25 +235,644 @@ func (s *AuthServer) Logout(ctx context.Context, request *generated.LogoutUserRe
 }
 
 func (s *AuthServer) LogoutFromAllDevices(ctx context.Context, request *generated.LogoutUserFromAllDevicesRequest) (*emptypb.Empty, error) {
+	if err := s.authService.LogoutFromAllDevices(ctx, request.AccessToken); err != nil {
+		if errors.Is(err, services.ErrInvalidAccessToken) {
+			return nil, status.Error(codes.Unauthenticated, err.Error())
+		}
+		return nil, err
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (s *AuthServer) DeleteUser(ctx context.Context, request *generated.DeleteUserRequest) (*emptypb.Empty, error) {
 	userUUID, err := uuid.Parse(request.UserId)
 	if err != nil {
-		return nil, err
+		return nil, fieldStatus(codes.InvalidArgument, "user_id", errors.New("user id must be a UUID"))
 	}
 	userID := pgtype.UUID{Bytes: [16]byte(userUUID[:]), Valid: true}
-	if err := s.authService.LogoutFromAllDevices(ctx, userID); err != nil {
+	if err := s.authService.DeleteUser(ctx, request.AccessToken, userID); err != nil {
+		if errors.Is(err, services.ErrUserNotFound) {
+			return nil, status.Error(codes.NotFound, err.Error())
+		}
+		return nil, authorizationStatus(err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (s *AuthServer) UnlockAccount(ctx context.Context, request *generated.UnlockAccountRequest) (*emptypb.Empty, error) {
+	if err := s.authService.UnlockAccount(ctx, request.AccessToken, request.Username); err != nil {
+		if errors.Is(err, services.ErrAccountNotLocked) {
+			return nil, status.Error(codes.NotFound, err.Error())
+		}
+		return nil, authorizationStatus(err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (s *AuthServer) GrantRole(ctx context.Context, request *generated.GrantRoleRequest) (*emptypb.Empty, error) {
+	if err := s.authService.GrantRole(ctx, request.AccessToken, request.UserId, request.Role); err != nil {
+		return nil, roleStatus(err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (s *AuthServer) RevokeRole(ctx context.Context, request *generated.RevokeRoleRequest) (*emptypb.Empty, error) {
+	if err := s.authService.RevokeRole(ctx, request.AccessToken, request.UserId, request.Role); err != nil {
+		return nil, roleStatus(err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (s *AuthServer) Impersonate(ctx context.Context, request *generated.ImpersonateRequest) (*generated.ImpersonateResponse, error) {
+	impersonation, err := s.authService.Impersonate(ctx, request.AccessToken, request.UserId, request.Reason, clientInfoFromContext(ctx))
+	if err != nil {
+		return nil, impersonationStatus(err)
+	}
+
+	return &generated.ImpersonateResponse{
+		SessionId:   impersonation.SessionID,
+		AccessToken: impersonation.AccessToken,
+		ExpiresAt:   impersonation.ExpiresAt.Unix(),
+	}, nil
+}
+
+func (s *AuthServer) RequestPasswordReset(ctx context.Context, request *generated.RequestPasswordResetRequest) (*emptypb.Empty, error) {
+	if err := s.authService.RequestPasswordReset(ctx, request.Email); err != nil {
 		return nil, err
 	}
 	return &emptypb.Empty{}, nil
 }
 
-func (s *AuthServer) DeleteUser(ctx context.Context, request *generated.DeleteUserRequest) (*emptypb.Empty, error) {
-	userUUID, err := uuid.Parse(request.UserId)
+func (s *AuthServer) ResetPassword(ctx context.Context, request *generated.ResetPasswordRequest) (*emptypb.Empty, error) {
+	if err := s.authService.ResetPassword(ctx, request.Token, request.NewPassword); err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidResetToken):
+			return nil, fieldStatus(codes.InvalidArgument, "token", err)
+		case isPasswordPolicyError(err):
+			return nil, fieldStatus(codes.InvalidArgument, "new_password", err)
+		}
+		return nil, err
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (s *AuthServer) ChangePassword(ctx context.Context, request *generated.ChangePasswordRequest) (*emptypb.Empty, error) {
+	err := s.authService.ChangePassword(ctx, request.AccessToken, request.OldPassword, request.NewPassword, request.RevokeOtherSessions)
 	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidAccessToken):
+			return nil, status.Error(codes.Unauthenticated, err.Error())
+		case errors.Is(err, services.ErrIncorrectPassword):
+			return nil, status.Error(codes.PermissionDenied, err.Error())
+		case isPasswordPolicyError(err):
+			return nil, fieldStatus(codes.InvalidArgument, "new_password", err)
+		}
 		return nil, err
 	}
-	userID := pgtype.UUID{Bytes: [16]byte(userUUID[:]), Valid: true}
-	if err := s.authService.DeleteUser(ctx, userID); err != nil {
+	return &emptypb.Empty{}, nil
+}
+
+func (s *AuthServer) VerifyEmail(ctx context.Context, request *generated.VerifyEmailRequest) (*emptypb.Empty, error) {
+	if err := s.authService.VerifyEmail(ctx, request.Token); err != nil {
+		if errors.Is(err, services.ErrInvalidVerificationToken) {
+			return nil, fieldStatus(codes.InvalidArgument, "token", err)
+		}
+		return nil, err
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (s *AuthServer) ResendVerificationEmail(ctx context.Context, request *generated.ResendVerificationEmailRequest) (*emptypb.Empty, error) {
+	if err := s.authService.ResendVerificationEmail(ctx, request.Email); err != nil {
+		if errors.Is(err, services.ErrVerificationResendLimited) {
+			return nil, status.Error(codes.ResourceExhausted, err.Error())
+		}
 		return nil, err
 	}
 	return &emptypb.Empty{}, nil
 }
+
+func (s *AuthServer) EnrollTwoFactor(ctx context.Context, request *generated.EnrollTwoFactorRequest) (*generated.EnrollTwoFactorResponse, error) {
+	enrollment, err := s.authService.EnrollTwoFactor(ctx, request.AccessToken)
+	if err != nil {
+		return nil, twoFactorStatus(err)
+	}
+
+	response := &generated.EnrollTwoFactorResponse{
+		Secret:          enrollment.Secret,
+		ProvisioningUri: enrollment.ProvisioningURI,
+		RecoveryCodes:   enrollment.RecoveryCodes,
+	}
+
+	return response, nil
+}
+
+func (s *AuthServer) ConfirmTwoFactor(ctx context.Context, request *generated.ConfirmTwoFactorRequest) (*emptypb.Empty, error) {
+	if err := s.authService.ConfirmTwoFactor(ctx, request.AccessToken, request.Code); err != nil {
+		return nil, twoFactorStatus(err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (s *AuthServer) DisableTwoFactor(ctx context.Context, request *generated.DisableTwoFactorRequest) (*emptypb.Empty, error) {
+	if err := s.authService.DisableTwoFactor(ctx, request.AccessToken, request.Code); err != nil {
+		return nil, twoFactorStatus(err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (s *AuthServer) ListSessions(ctx context.Context, request *generated.ListSessionsRequest) (*generated.ListSessionsResponse, error) {
+	sessions, err := s.authService.ListSessions(ctx, request.AccessToken)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidAccessToken) {
+			return nil, status.Error(codes.Unauthenticated, err.Error())
+		}
+		return nil, err
+	}
+
+	response := &generated.ListSessionsResponse{
+		Sessions: make([]*generated.SessionInfo, 0, len(sessions)),
+	}
+	for _, session := range sessions {
+		response.Sessions = append(response.Sessions, &generated.SessionInfo{
+			Id:         session.ID,
+			UserAgent:  session.UserAgent,
+			IpAddress:  session.IPAddress,
+			CreatedAt:  session.CreatedAt.Unix(),
+			LastUsedAt: session.LastUsedAt.Unix(),
+			ExpiresAt:  session.ExpiresAt.Unix(),
+			Current:    session.Current,
+		})
+	}
+
+	return response, nil
+}
+
+func (s *AuthServer) RevokeSession(ctx context.Context, request *generated.RevokeSessionRequest) (*emptypb.Empty, error) {
+	if err := s.authService.RevokeSession(ctx, request.AccessToken, request.SessionId); err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidAccessToken):
+			return nil, status.Error(codes.Unauthenticated, err.Error())
+		case errors.Is(err, services.ErrSessionNotFound):
+			return nil, status.Error(codes.NotFound, err.Error())
+		}
+		return nil, err
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (s *AuthServer) GetLoginHistory(ctx context.Context, request *generated.GetLoginHistoryRequest) (*generated.GetLoginHistoryResponse, error) {
+	attempts, err := s.authService.GetLoginHistory(ctx, request.AccessToken, int(request.Limit))
+	if err != nil {
+		return nil, authorizationStatus(err)
+	}
+
+	response := &generated.GetLoginHistoryResponse{
+		Attempts: make([]*generated.LoginAttemptInfo, 0, len(attempts)),
+	}
+	for _, attempt := range attempts {
+		response.Attempts = append(response.Attempts, &generated.LoginAttemptInfo{
+			Id:          attempt.ID,
+			Username:    attempt.Username,
+			Success:     attempt.Success,
+			IpAddress:   attempt.IPAddress,
+			UserAgent:   attempt.UserAgent,
+			AttemptedAt: attempt.AttemptedAt.Unix(),
+		})
+	}
+
+	return response, nil
+}
+
+func (s *AuthServer) ExportLoginAttempts(ctx context.Context, request *generated.ExportLoginAttemptsRequest) (*generated.ExportLoginAttemptsResponse, error) {
+	exportRequest := models.LoginAttemptExportRequest{
+		Format:   request.Format,
+		Username: request.Username,
+	}
+	if request.From > 0 {
+		exportRequest.From = time.Unix(request.From, 0)
+	}
+	if request.To > 0 {
+		exportRequest.To = time.Unix(request.To, 0)
+	}
+
+	export, err := s.authService.ExportLoginAttempts(ctx, request.AccessToken, exportRequest)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrUnsupportedExportFormat),
+			errors.Is(err, services.ErrInvalidExportRange),
+			errors.Is(err, services.ErrExportTooLarge):
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		return nil, authorizationStatus(err)
+	}
+
+	response := &generated.ExportLoginAttemptsResponse{
+		Content:     export.Data,
+		ContentType: export.ContentType,
+		Filename:    export.Filename,
+		Count:       int32(export.Count),
+	}
+
+	return response, nil
+}
+
+func (s *AuthServer) ListImpersonationSessions(ctx context.Context, request *generated.ListImpersonationSessionsRequest) (*generated.ListImpersonationSessionsResponse, error) {
+	sessions, err := s.authService.ListImpersonationSessions(ctx, request.AccessToken, int(request.Limit))
+	if err != nil {
+		return nil, authorizationStatus(err)
+	}
+
+	response := &generated.ListImpersonationSessionsResponse{
+		Sessions: make([]*generated.ImpersonationSessionInfo, 0, len(sessions)),
+	}
+	for _, session := range sessions {
+		response.Sessions = append(response.Sessions, &generated.ImpersonationSessionInfo{
+			Id:             session.ID,
+			ActorId:        session.ActorID,
+			ActorUsername:  session.ActorUsername,
+			TargetId:       session.TargetID,
+			TargetUsername: session.TargetUsername,
+			Reason:         session.Reason,
+			IpAddress:      session.IPAddress,
+			UserAgent:      session.UserAgent,
+			StartedAt:      session.StartedAt.Unix(),
+			ExpiresAt:      session.ExpiresAt.Unix(),
+		})
+	}
+
+	return response, nil
+}
+
+func (s *AuthServer) CreatePersonalAccessToken(ctx context.Context, request *generated.CreatePersonalAccessTokenRequest) (*generated.CreatePersonalAccessTokenResponse, error) {
+	created, err := s.authService.CreatePersonalAccessToken(ctx, request.AccessToken, models.CreatePersonalAccessTokenRequest{
+		Name:      request.Name,
+		Scopes:    request.Scopes,
+		ExpiresIn: time.Duration(request.ExpiresInSeconds) * time.Second,
+	})
+	if err != nil {
+		return nil, personalAccessTokenStatus(err)
+	}
+
+	response := &generated.CreatePersonalAccessTokenResponse{
+		TokenInfo: toPersonalAccessTokenInfo(*created),
+		Token:     created.Token,
+	}
+
+	return response, nil
+}
+
+func (s *AuthServer) ListPersonalAccessTokens(ctx context.Context, request *generated.ListPersonalAccessTokensRequest) (*generated.ListPersonalAccessTokensResponse, error) {
+	tokens, err := s.authService.ListPersonalAccessTokens(ctx, request.AccessToken)
+	if err != nil {
+		return nil, personalAccessTokenStatus(err)
+	}
+
+	response := &generated.ListPersonalAccessTokensResponse{
+		Tokens: make([]*generated.PersonalAccessTokenInfo, 0, len(tokens)),
+	}
+	for _, token := range tokens {
+		response.Tokens = append(response.Tokens, toPersonalAccessTokenInfo(token))
+	}
+
+	return response, nil
+}
+
+func (s *AuthServer) RevokePersonalAccessToken(ctx context.Context, request *generated.RevokePersonalAccessTokenRequest) (*emptypb.Empty, error) {
+	if err := s.authService.RevokePersonalAccessToken(ctx, request.AccessToken, request.TokenId); err != nil {
+		return nil, personalAccessTokenStatus(err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (s *AuthServer) RegisterOAuthClient(ctx context.Context, request *generated.RegisterOAuthClientRequest) (*generated.RegisterOAuthClientResponse, error) {
+	client, err := s.authService.RegisterOAuthClient(ctx, request.AccessToken, models.RegisterOAuthClientRequest{
+		Name:         request.Name,
+		RedirectURIs: request.RedirectUris,
+		Scopes:       request.Scopes,
+		Confidential: request.Confidential,
+	})
+	if err != nil {
+		return nil, oauthStatus(err)
+	}
+
+	response := &generated.RegisterOAuthClientResponse{
+		Client:       toOAuthClientInfo(*client),
+		ClientSecret: client.ClientSecret,
+	}
+
+	return response, nil
+}
+
+func (s *AuthServer) ListOAuthClients(ctx context.Context, request *generated.ListOAuthClientsRequest) (*generated.ListOAuthClientsResponse, error) {
+	clients, err := s.authService.ListOAuthClients(ctx, request.AccessToken)
+	if err != nil {
+		return nil, oauthStatus(err)
+	}
+
+	response := &generated.ListOAuthClientsResponse{
+		Clients: make([]*generated.OAuthClientInfo, 0, len(clients)),
+	}
+	for _, client := range clients {
+		response.Clients = append(response.Clients, toOAuthClientInfo(client))
+	}
+
+	return response, nil
+}
+
+func (s *AuthServer) DeleteOAuthClient(ctx context.Context, request *generated.DeleteOAuthClientRequest) (*emptypb.Empty, error) {
+	if err := s.authService.DeleteOAuthClient(ctx, request.AccessToken, request.ClientId); err != nil {
+		return nil, oauthStatus(err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (s *AuthServer) GetOAuthClient(ctx context.Context, request *generated.GetOAuthClientRequest) (*generated.OAuthClientInfo, error) {
+	client, err := s.authService.GetOAuthClient(ctx, request.ClientId)
+	if err != nil {
+		return nil, oauthStatus(err)
+	}
+	return toOAuthClientInfo(*client), nil
+}
+
+func (s *AuthServer) AuthorizeOAuthClient(ctx context.Context, request *generated.AuthorizeOAuthClientRequest) (*generated.AuthorizeOAuthClientResponse, error) {
+	authorization, err := s.authService.AuthorizeOAuthClient(ctx, request.AccessToken, models.OAuthAuthorizationRequest{
+		ClientID:            request.ClientId,
+		RedirectURI:         request.RedirectUri,
+		Scopes:              request.Scopes,
+		State:               request.State,
+		CodeChallenge:       request.CodeChallenge,
+		CodeChallengeMethod: request.CodeChallengeMethod,
+	})
+	if err != nil {
+		return nil, oauthStatus(err)
+	}
+
+	response := &generated.AuthorizeOAuthClientResponse{
+		Code:       authorization.Code,
+		RedirectTo: authorization.RedirectTo,
+		Scopes:     authorization.Scopes,
+	}
+
+	return response, nil
+}
+
+func (s *AuthServer) ExchangeOAuthToken(ctx context.Context, request *generated.ExchangeOAuthTokenRequest) (*generated.ExchangeOAuthTokenResponse, error) {
+	tokens, err := s.authService.ExchangeOAuthToken(ctx, models.OAuthTokenRequest{
+		GrantType:    request.GrantType,
+		ClientID:     request.ClientId,
+		ClientSecret: request.ClientSecret,
+		Code:         request.Code,
+		RedirectURI:  request.RedirectUri,
+		CodeVerifier: request.CodeVerifier,
+		RefreshToken: request.RefreshToken,
+	}, clientInfoFromContext(ctx))
+	if err != nil {
+		return nil, oauthStatus(err)
+	}
+
+	response := &generated.ExchangeOAuthTokenResponse{
+		AccessToken:  tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresIn:    int64(tokens.ExpiresIn.Seconds()),
+		Scopes:       tokens.Scopes,
+	}
+
+	return response, nil
+}
+
+func (s *AuthServer) ListOAuthConsents(ctx context.Context, request *generated.ListOAuthConsentsRequest) (*generated.ListOAuthConsentsResponse, error) {
+	consents, err := s.authService.ListOAuthConsents(ctx, request.AccessToken)
+	if err != nil {
+		return nil, oauthStatus(err)
+	}
+
+	response := &generated.ListOAuthConsentsResponse{
+		Consents: make([]*generated.OAuthConsentInfo, 0, len(consents)),
+	}
+	for _, consent := range consents {
+		response.Consents = append(response.Consents, &generated.OAuthConsentInfo{
+			ClientId:   consent.ClientID,
+			ClientName: consent.ClientName,
+			Scopes:     consent.Scopes,
+			CreatedAt:  consent.CreatedAt.Unix(),
+			UpdatedAt:  consent.UpdatedAt.Unix(),
+		})
+	}
+
+	return response, nil
+}
+
+func (s *AuthServer) RevokeOAuthConsent(ctx context.Context, request *generated.RevokeOAuthConsentRequest) (*emptypb.Empty, error) {
+	if err := s.authService.RevokeOAuthConsent(ctx, request.AccessToken, request.ClientId); err != nil {
+		return nil, oauthStatus(err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+// toPersonalAccessTokenInfo converts a token to its wire format, leaving unset times at 0.
+func toPersonalAccessTokenInfo(token models.PersonalAccessTokenDTO) *generated.PersonalAccessTokenInfo {
+	info := &generated.PersonalAccessTokenInfo{
+		Id:        token.ID,
+		Name:      token.Name,
+		Scopes:    token.Scopes,
+		CreatedAt: token.CreatedAt.Unix(),
+	}
+	if !token.LastUsedAt.IsZero() {
+		info.LastUsedAt = token.LastUsedAt.Unix()
+	}
+	if !token.ExpiresAt.IsZero() {
+		info.ExpiresAt = token.ExpiresAt.Unix()
+	}
+	return info
+}
+
+// personalAccessTokenStatus maps the errors of the personal access token RPCs to gRPC status codes.
+func personalAccessTokenStatus(err error) error {
+	switch {
+	case errors.Is(err, services.ErrInvalidAccessToken):
+		return status.Error(codes.Unauthenticated, err.Error())
+	case errors.Is(err, services.ErrInvalidTokenName):
+		return fieldStatus(codes.InvalidArgument, "name", err)
+	case errors.Is(err, services.ErrInvalidTokenScope):
+		return fieldStatus(codes.InvalidArgument, "scopes", err)
+	case errors.Is(err, services.ErrInvalidTokenExpiry):
+		return fieldStatus(codes.InvalidArgument, "expires_in_seconds", err)
+	case errors.Is(err, services.ErrPersonalAccessTokenNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	}
+	return err
+}
+
+func toOAuthClientInfo(client models.OAuthClientDTO) *generated.OAuthClientInfo {
+	return &generated.OAuthClientInfo{
+		ClientId:     client.ClientID,
+		Name:         client.Name,
+		RedirectUris: client.RedirectURIs,
+		Scopes:       client.Scopes,
+		Confidential: client.Confidential,
+		CreatedAt:    client.CreatedAt.Unix(),
+	}
+}
+
+// oauthStatus maps the errors of the OAuth RPCs to gRPC status codes. Protocol errors
+// carry their RFC 6749 error code as ErrorInfo reason, which the gateway answers clients with.
+func oauthStatus(err error) error {
+	var oauthErr *services.OAuthError
+	if errors.As(err, &oauthErr) {
+		code := codes.InvalidArgument
+		if oauthErr.Code == services.ErrInvalidOAuthClient.Code {
+			code = codes.Unauthenticated
+		}
+		st := status.New(code, oauthErr.Error())
+		detailed, detailsErr := st.WithDetails(&errdetails.ErrorInfo{
+			Reason: oauthErr.Code,
+			Domain: "oauth",
+		})
+		if detailsErr != nil {
+			return st.Err()
+		}
+		return detailed.Err()
+	}
+
+	switch {
+	case errors.Is(err, services.ErrInvalidOAuthClientName):
+		return fieldStatus(codes.InvalidArgument, "name", err)
+	case errors.Is(err, services.ErrInvalidRedirectURIs):
+		return fieldStatus(codes.InvalidArgument, "redirect_uris", err)
+	case errors.Is(err, services.ErrInvalidTokenScope):
+		return fieldStatus(codes.InvalidArgument, "scopes", err)
+	case errors.Is(err, services.ErrOAuthClientNotFound), errors.Is(err, services.ErrOAuthConsentNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	}
+	return authorizationStatus(err)
+}
+
+// roleStatus maps the errors of the role management RPCs to gRPC status codes.
+func roleStatus(err error) error {
+	switch {
+	case errors.Is(err, services.ErrUserNotFound), errors.Is(err, services.ErrRoleNotGranted):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, services.ErrUnknownRole):
+		return fieldStatus(codes.InvalidArgument, "role", err)
+	case errors.Is(err, services.ErrCannotDemoteSelf):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	}
+	return authorizationStatus(err)
+}
+
+// impersonationStatus maps the errors of the Impersonate RPC to gRPC status codes.
+func impersonationStatus(err error) error {
+	switch {
+	case errors.Is(err, services.ErrUserNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, services.ErrImpersonationReasonRequired), errors.Is(err, services.ErrImpersonationReasonTooLong):
+		return fieldStatus(codes.InvalidArgument, "reason", err)
+	case errors.Is(err, services.ErrCannotImpersonateSelf), errors.Is(err, services.ErrCannotImpersonateStaff):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	}
+	return authorizationStatus(err)
+}
+
+// authorizationStatus reports a missing or invalid access token as Unauthenticated
+// and a missing permission as PermissionDenied.
+func authorizationStatus(err error) error {
+	switch {
+	case errors.Is(err, services.ErrInvalidAccessToken):
+		return status.Error(codes.Unauthenticated, err.Error())
+	case errors.Is(err, authz.ErrPermissionDenied):
+		return status.Error(codes.PermissionDenied, err.Error())
+	}
+	return err
+}
+
+// twoFactorStatus maps the errors of the 2FA management RPCs to gRPC status codes.
+func twoFactorStatus(err error) error {
+	switch {
+	case errors.Is(err, services.ErrInvalidAccessToken):
+		return status.Error(codes.Unauthenticated, err.Error())
+	case errors.Is(err, services.ErrInvalidTwoFactorCode):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, services.ErrTwoFactorAlreadyEnabled), errors.Is(err, services.ErrTwoFactorNotEnrolled):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	}
+	return err
+}
+
+// fieldStatus reports err with a BadRequest detail naming the request field it is about,
+// which the gateway passes on to clients.
+func fieldStatus(code codes.Code, field string, err error) error {
+	st := status.New(code, err.Error())
+	detailed, detailsErr := st.WithDetails(&errdetails.BadRequest{
+		FieldViolations: []*errdetails.BadRequest_FieldViolation{{
+			Field:       field,
+			Description: err.Error(),
+		}},
+	})
+	if detailsErr != nil {
+		return st.Err()
+	}
+	return detailed.Err()
+}
+
+func isPasswordPolicyError(err error) bool {
+	return errors.Is(err, services.ErrPasswordRequired) || errors.Is(err, services.ErrPasswordTooShort)
+}
+
+// accountLockedStatus reports a lockout as ResourceExhausted with a RetryInfo
+// detail, so clients can tell it apart from bad credentials and know when to retry.
+func accountLockedStatus(lockedErr *services.AccountLockedError) error {
+	st := status.New(codes.ResourceExhausted, lockedErr.Error())
+	detailed, err := st.WithDetails(&errdetails.RetryInfo{
+		RetryDelay: durationpb.New(lockedErr.RetryAfter()),
+	})
+	if err != nil {
+		return st.Err()
+	}
+	return detailed.Err()
+}
+
+// clientInfoFromContext reads the device a request comes from, which the gateway
+// forwards since the gRPC peer seen here is the gateway itself. Without forwarded
+// metadata the address of the gRPC peer is used.
+func clientInfoFromContext(ctx context.Context) models.ClientInfo {
+	var client models.ClientInfo
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(requestctx.ClientUserAgentKey); len(values) > 0 {
+			client.UserAgent = values[0]
+		}
+		if values := md.Get(requestctx.ClientIPKey); len(values) > 0 {
+			client.IPAddress = values[0]
+		}
+	}
+
+	if client.IPAddress == "" {
+		if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+			client.IPAddress = p.Addr.String()
+			if host, _, err := net.SplitHostPort(client.IPAddress); err == nil {
+				client.IPAddress = host
+			}
+		}
+	}
+
+	// Anything that is not a plain address would not fit the sessions.ip_address column
+	if net.ParseIP(client.IPAddress) == nil {
+		client.IPAddress = ""
+	}
+	client.UserAgent = truncateUTF8(client.UserAgent, maxUserAgentLength)
+	return client
+}
+
+// truncateUTF8 drops invalid UTF-8, which Postgres would reject, and shortens s to at
+// most maxBytes without splitting a character.
+func truncateUTF8(s string, maxBytes int) string {
+	s = strings.ToValidUTF8(s, "")
+	if len(s) <= maxBytes {
+		return s
+	}
+	end := maxBytes
+	for end > 0 && !utf8.RuneStart(s[end]) {
+		end--
+	}
+	return s[:end]
+}
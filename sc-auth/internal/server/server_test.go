@@ -0,0 +1,26 @@
+package server
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/metadata"
+	"soul-connect/pkg/requestctx"
+)
+
+func TestClientInfoFromContext_CutsUserAgentsOnCharacters(t *testing.T) {
+	// 511 bytes of ASCII put the 512th byte into the middle of the two-byte ü
+	userAgent := strings.Repeat("a", maxUserAgentLength-1) + "über"
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(requestctx.ClientUserAgentKey, userAgent, requestctx.ClientIPKey, "198.51.100.7"))
+
+	client := clientInfoFromContext(ctx)
+	require.True(t, utf8.ValidString(client.UserAgent))
+	require.Equal(t, strings.Repeat("a", maxUserAgentLength-1), client.UserAgent)
+	require.Equal(t, "198.51.100.7", client.IPAddress)
+
+	ctx = metadata.NewIncomingContext(context.Background(), metadata.Pairs(requestctx.ClientUserAgentKey, "curl\xff/8.0"))
+	require.Equal(t, "curl/8.0", clientInfoFromContext(ctx).UserAgent)
+}
@@ -4,11 +4,18 @@ import (
 	"context"
 	"errors"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
-	"golang.org/x/crypto/bcrypt"
+	"log"
 	db "soul-connect/sc-auth/internal/db/sqlc"
+	"soul-connect/sc-auth/internal/events"
+	"soul-connect/sc-auth/internal/mailer"
 	"soul-connect/sc-auth/internal/models"
+	"soul-connect/sc-auth/internal/revocation"
 	"soul-connect/sc-auth/internal/utils"
+	"soul-connect/sc-auth/pkg/authz"
+	"strings"
+	"sync"
 	"time"
 )
 
@@ -16,58 +23,173 @@ type IAuthRepository interface {
 	CreateUser(ctx context.Context, params db.CreateUserParams) (db.CreateUserRow, error)
 	GetUserByEmail(ctx context.Context, email string) (db.GetUserByEmailRow, error)
 	GetUserByUsername(ctx context.Context, username string) (db.GetUserByUsernameRow, error)
-	DeleteUser(ctx context.Context, id pgtype.UUID) error
+	GetUserById(ctx context.Context, id pgtype.UUID) (db.GetUserByIdRow, error)
+	MarkEmailVerified(ctx context.Context, id pgtype.UUID) error
+	DeleteUser(ctx context.Context, id pgtype.UUID) (int64, error)
 	UpdateUserPassword(ctx context.Context, params db.UpdateUserPasswordParams) error
+	RehashUserPassword(ctx context.Context, arg db.RehashUserPasswordParams) (int64, error)
+	CreatePasswordResetToken(ctx context.Context, arg db.CreatePasswordResetTokenParams) error
+	ConsumePasswordResetToken(ctx context.Context, tokenHash string) (pgtype.UUID, error)
+	InvalidatePasswordResetTokens(ctx context.Context, userID pgtype.UUID) error
+	CreateEmailVerificationToken(ctx context.Context, arg db.CreateEmailVerificationTokenParams) error
+	ConsumeEmailVerificationToken(ctx context.Context, tokenHash string) (pgtype.UUID, error)
+	InvalidateEmailVerificationTokens(ctx context.Context, userID pgtype.UUID) error
+	CreateMagicLinkToken(ctx context.Context, arg db.CreateMagicLinkTokenParams) error
+	ConsumeMagicLinkToken(ctx context.Context, tokenHash string) (pgtype.UUID, error)
+	InvalidateMagicLinkTokens(ctx context.Context, userID pgtype.UUID) error
+	CountMagicLinkTokensSince(ctx context.Context, arg db.CountMagicLinkTokensSinceParams) (int64, error)
+	CountEmailVerificationTokensSince(ctx context.Context, arg db.CountEmailVerificationTokensSinceParams) (int64, error)
 	UpdateLastLogin(ctx context.Context, id pgtype.UUID) error
-	LogFailedLogin(ctx context.Context, username string) error
-	LogSuccessfulLogin(ctx context.Context, username string) error
+	LogFailedLogin(ctx context.Context, arg db.LogFailedLoginParams) error
+	LogSuccessfulLogin(ctx context.Context, arg db.LogSuccessfulLoginParams) error
+	GetLoginAttemptsByUsername(ctx context.Context, arg db.GetLoginAttemptsByUsernameParams) ([]db.LoginAttempt, error)
+	ExportLoginAttempts(ctx context.Context, arg db.ExportLoginAttemptsParams) ([]db.LoginAttempt, error)
+	DeleteOldLoginAttempts(ctx context.Context) error
+	GetAccountLockout(ctx context.Context, username string) (db.AccountLockout, error)
+	LockAccount(ctx context.Context, arg db.LockAccountParams) (db.AccountLockout, error)
+	UnlockAccount(ctx context.Context, username string) (int64, error)
+	DeleteAccountLockout(ctx context.Context, username string) error
 	GetSessionByUserId(ctx context.Context, userID pgtype.UUID) ([]db.GetSessionByUserIdRow, error)
+	GetSessionByID(ctx context.Context, id pgtype.UUID) (db.Session, error)
+	GetSessionByToken(ctx context.Context, sessionToken string) (db.Session, error)
+	GetRotatedSessionToken(ctx context.Context, sessionToken string) (db.GetRotatedSessionTokenRow, error)
 	CreateSession(ctx context.Context, arg db.CreateSessionParams) (db.Session, error)
 	UpdateSessionExpiry(ctx context.Context, arg db.UpdateSessionExpiryParams) error
+	RotateSessionToken(ctx context.Context, arg db.RotateSessionTokenParams) (int64, error)
+	DeleteSessionByID(ctx context.Context, id pgtype.UUID) error
 	DeleteSessionByToken(ctx context.Context, sessionToken string) error
+	DeleteSessionForUser(ctx context.Context, arg db.DeleteSessionForUserParams) (int64, error)
+	ListSessionsForUser(ctx context.Context, userID pgtype.UUID) ([]db.Session, error)
 	DeleteAllSessionsForUser(ctx context.Context, userID pgtype.UUID) error
+	DeleteOtherSessionsForUser(ctx context.Context, arg db.DeleteOtherSessionsForUserParams) error
+	UpsertUserTotp(ctx context.Context, arg db.UpsertUserTotpParams) error
+	GetUserTotp(ctx context.Context, userID pgtype.UUID) (db.UserTotp, error)
+	ConfirmUserTotp(ctx context.Context, userID pgtype.UUID) error
+	UseTotpStep(ctx context.Context, arg db.UseTotpStepParams) (int64, error)
+	DeleteUserTotp(ctx context.Context, userID pgtype.UUID) error
+	CreateRecoveryCode(ctx context.Context, arg db.CreateRecoveryCodeParams) error
+	UseRecoveryCode(ctx context.Context, arg db.UseRecoveryCodeParams) (int64, error)
+	DeleteRecoveryCodes(ctx context.Context, userID pgtype.UUID) error
+	CreatePersonalAccessToken(ctx context.Context, arg db.CreatePersonalAccessTokenParams) (db.PersonalAccessToken, error)
+	GetPersonalAccessTokenByHash(ctx context.Context, tokenHash string) (db.PersonalAccessToken, error)
+	ListPersonalAccessTokens(ctx context.Context, userID pgtype.UUID) ([]db.PersonalAccessToken, error)
+	TouchPersonalAccessToken(ctx context.Context, id pgtype.UUID) error
+	DeletePersonalAccessToken(ctx context.Context, arg db.DeletePersonalAccessTokenParams) (int64, error)
+	CreateOAuthClient(ctx context.Context, arg db.CreateOAuthClientParams) (db.OauthClient, error)
+	GetOAuthClient(ctx context.Context, id pgtype.UUID) (db.OauthClient, error)
+	ListOAuthClientsByOwner(ctx context.Context, ownerID pgtype.UUID) ([]db.OauthClient, error)
+	DeleteOAuthClient(ctx context.Context, arg db.DeleteOAuthClientParams) (int64, error)
+	UpsertOAuthConsent(ctx context.Context, arg db.UpsertOAuthConsentParams) error
+	GetOAuthConsent(ctx context.Context, arg db.GetOAuthConsentParams) (db.OauthConsent, error)
+	ListOAuthConsents(ctx context.Context, userID pgtype.UUID) ([]db.ListOAuthConsentsRow, error)
+	DeleteOAuthConsent(ctx context.Context, arg db.DeleteOAuthConsentParams) (int64, error)
+	CreateOAuthAuthorizationCode(ctx context.Context, arg db.CreateOAuthAuthorizationCodeParams) error
+	ConsumeOAuthAuthorizationCode(ctx context.Context, codeHash string) (db.ConsumeOAuthAuthorizationCodeRow, error)
+	GetUserRoles(ctx context.Context, userID pgtype.UUID) ([]string, error)
+	GetUserPermissions(ctx context.Context, userID pgtype.UUID) ([]string, error)
+	RoleExists(ctx context.Context, name string) (bool, error)
+	GrantRole(ctx context.Context, arg db.GrantRoleParams) error
+	RevokeRole(ctx context.Context, arg db.RevokeRoleParams) (int64, error)
+	CreateImpersonationSession(ctx context.Context, arg db.CreateImpersonationSessionParams) (db.ImpersonationSession, error)
+	ListImpersonationSessions(ctx context.Context, requestLimit int32) ([]db.ImpersonationSession, error)
+	CreateOutboxEvent(ctx context.Context, arg db.CreateOutboxEventParams) error
+	// InTx runs fn with a repository bound to a single transaction
+	InTx(ctx context.Context, fn func(repo IAuthRepository) error) error
 }
 
 type AuthService struct {
-	authRepo IAuthRepository
+	authRepo    IAuthRepository
+	keys        *utils.KeySet
+	mailSender  mailer.Sender
+	revocations revocation.List
+	policy      AuthPolicy
+	// unknownAccountHash is verified for logins to unknown accounts, so they take as long
+	// to reject as a wrong password. It is hashed on first use.
+	unknownAccountHash     string
+	unknownAccountHashOnce sync.Once
 }
 
-func NewAuthService(authRepository IAuthRepository) *AuthService {
+// AuthPolicy groups the configurable rules the auth service enforces.
+type AuthPolicy struct {
+	// PasswordHasher hashes new passwords, argon2id with the default parameters when nil
+	PasswordHasher    utils.PasswordHasher
+	Lockout           LockoutPolicy
+	PasswordReset     PasswordResetPolicy
+	EmailVerification EmailVerificationPolicy
+	TwoFactor         TwoFactorPolicy
+	MagicLink         MagicLinkPolicy
+	OAuth             OAuthPolicy
+	Impersonation     ImpersonationPolicy
+}
+
+// NewAuthService creates the service. Without a revocation list revoked access tokens
+// are only remembered in memory, which is enough for a single instance and for tests.
+func NewAuthService(authRepository IAuthRepository, keys *utils.KeySet, mailSender mailer.Sender, revocations revocation.List, policy AuthPolicy) *AuthService {
+	if revocations == nil {
+		revocations = revocation.NewMemoryList()
+	}
+	if policy.PasswordHasher == nil {
+		policy.PasswordHasher = utils.NewArgon2idHasher(utils.DefaultArgon2idParams)
+	}
 	return &AuthService{
-		authRepo: authRepository,
+		authRepo:    authRepository,
+		keys:        keys,
+		mailSender:  mailSender,
+		revocations: revocations,
+		policy:      policy,
 	}
 }
 
-const (
-	secret = "SECRET_JWT_KEY"
+var (
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token reuse detected")
+	ErrInvalidAccessToken  = errors.New("invalid access token")
+	ErrInvalidCredentials  = errors.New("invalid credentials")
+	ErrIncorrectPassword   = errors.New("current password is incorrect")
+	ErrPasswordRequired    = errors.New("password is required")
+	ErrPasswordTooShort    = errors.New("password must be at least 6 characters long")
+	ErrSessionNotFound     = errors.New("session not found")
+	ErrEmailTaken          = errors.New("user with this email already exists")
+	ErrUsernameTaken       = errors.New("user with this username already exists")
 )
 
 func (s *AuthService) Register(ctx context.Context, params models.CreateUserRequest) (*models.CreateUserResponse, error) {
 	_, err := s.authRepo.GetUserByEmail(ctx, params.Email)
 	if err == nil {
-		return nil, errors.New("user with this email already exists")
+		return nil, ErrEmailTaken
 	}
 
 	_, err = s.authRepo.GetUserByUsername(ctx, params.Username)
 	if err == nil {
-		return nil, errors.New("user with this username already exists")
+		return nil, ErrUsernameTaken
 	}
 
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(params.Password), bcrypt.DefaultCost)
+	hashedPassword, err := s.policy.PasswordHasher.Hash(params.Password)
 	if err != nil {
 		return nil, errors.New("failed to hash password")
 	}
 
-	newUser, err := s.authRepo.CreateUser(ctx, db.CreateUserParams{
-		Username: params.Username,
-		Email:    params.Email,
-		Password: string(hashedPassword),
+	var newUser db.CreateUserRow
+	err = s.authRepo.InTx(ctx, func(repo IAuthRepository) error {
+		newUser, err = repo.CreateUser(ctx, db.CreateUserParams{
+			Username: params.Username,
+			Email:    params.Email,
+			Password: hashedPassword,
+		})
+		if err != nil {
+			return err
+		}
+		return enqueueEvent(ctx, repo, events.NewUserRegisteredEvent(uuid.UUID(newUser.ID.Bytes).String(), newUser.Username, newUser.Email))
 	})
-
 	if err != nil {
 		return nil, err
 	}
 
+	// The account already exists at this point, a lost email can be requested again
+	if err := s.sendVerificationEmail(ctx, newUser.ID, newUser.Username, newUser.Email); err != nil {
+		log.Printf("failed to send verification email to user %s: %v", newUser.Username, err)
+	}
+
 	userID := uuid.UUID(newUser.ID.Bytes[:])
 	return &models.CreateUserResponse{
 		ID:       userID.String(),
@@ -77,70 +199,564 @@ func (s *AuthService) Register(ctx context.Context, params models.CreateUserRequ
 }
 
 func (s *AuthService) Login(ctx context.Context, loginCredentials models.UserLoginRequest) (*models.UserLoginResponseDTO, error) {
-	user, err := s.authRepo.GetUserByUsername(ctx, loginCredentials.Username)
+	user, err := s.findUserByIdentifier(ctx, loginCredentials.Identifier)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// Unknown accounts are answered like a wrong password and locked like existing
+		// ones, so neither the answer nor a lockout tells whether an account exists
+		return nil, s.rejectUnknownAccount(ctx, loginCredentials)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Locked accounts are rejected before the password is even checked. Lockouts and
+	// attempts are tracked by the stored username, whichever identifier was used.
+	lockout, err := s.checkAccountLockout(ctx, user.Username)
 	if err != nil {
 		return nil, err
 	}
 
 	// Comparing passwords
-	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(loginCredentials.Password)); err != nil {
-		if err := s.authRepo.LogFailedLogin(ctx, loginCredentials.Username); err != nil {
+	match, err := s.policy.PasswordHasher.Verify(loginCredentials.Password, user.Password)
+	if err != nil {
+		return nil, err
+	}
+	if !match {
+		return nil, s.failLogin(ctx, user.Username, lockout, loginCredentials.Client)
+	}
+
+	s.upgradePasswordHash(ctx, user.ID, user.Password, loginCredentials.Password)
+
+	return s.continueLogin(ctx, user, lockout, loginCredentials.Client)
+}
+
+// rejectUnknownAccount answers a login for an account that does not exist. Its attempts
+// are tracked under the normalized identifier, so it locks after as many failures as an
+// existing account would.
+func (s *AuthService) rejectUnknownAccount(ctx context.Context, loginCredentials models.UserLoginRequest) error {
+	username := strings.ToLower(strings.TrimSpace(loginCredentials.Identifier))
+	lockout, err := s.checkAccountLockout(ctx, username)
+	if err != nil {
+		return err
+	}
+
+	s.unknownAccountHashOnce.Do(func() {
+		s.unknownAccountHash, err = s.policy.PasswordHasher.Hash("unknown-account")
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := s.policy.PasswordHasher.Verify(loginCredentials.Password, s.unknownAccountHash); err != nil {
+		return err
+	}
+	return s.failLogin(ctx, username, lockout, loginCredentials.Client)
+}
+
+// failLogin records a failed attempt and returns ErrInvalidCredentials, or an
+// AccountLockedError when the attempt locked the account.
+func (s *AuthService) failLogin(ctx context.Context, username string, lockout *db.AccountLockout, client models.ClientInfo) error {
+	if err := s.authRepo.LogFailedLogin(ctx, db.LogFailedLoginParams{
+		Username:  username,
+		IpAddress: client.IPAddress,
+		UserAgent: client.UserAgent,
+	}); err != nil {
+		return err
+	}
+	if err := s.registerFailedLogin(ctx, username, lockout); err != nil {
+		return err
+	}
+	return ErrInvalidCredentials
+}
+
+// continueLogin runs the steps every first factor shares once it succeeded: the email
+// verification requirement, the two-factor challenge and opening the session.
+func (s *AuthService) continueLogin(
+	ctx context.Context,
+	user db.GetUserByUsernameRow,
+	lockout *db.AccountLockout,
+	client models.ClientInfo,
+) (*models.UserLoginResponseDTO, error) {
+	if s.policy.EmailVerification.Required && !user.EmailVerified {
+		return nil, ErrEmailNotVerified
+	}
+
+	// Accounts with two-factor authentication only get a challenge here. Nothing counts
+	// as a successful login until VerifySecondFactor accepts the code.
+	twoFactorEnabled, err := s.twoFactorEnabled(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if twoFactorEnabled {
+		userID := uuid.UUID(user.ID.Bytes[:])
+		challengeToken, err := utils.GenerateChallengeToken(userID.String(), s.policy.TwoFactor.ChallengeTTL, s.keys)
+		if err != nil {
 			return nil, err
 		}
-		return nil, errors.New("invalid credentials")
+		return &models.UserLoginResponseDTO{
+			ID:                userID.String(),
+			Username:          user.Username,
+			Email:             user.Email,
+			TwoFactorRequired: true,
+			ChallengeToken:    challengeToken,
+		}, nil
 	}
 
+	// A successful login resets the backoff
+	if lockout != nil {
+		if err := s.authRepo.DeleteAccountLockout(ctx, user.Username); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.completeLogin(ctx, user.ID, user.Username, user.Email, user.EmailVerified, client)
+}
+
+// findUserByIdentifier resolves a login identifier as a username first and then as an email.
+// Both lookups are case-insensitive.
+func (s *AuthService) findUserByIdentifier(ctx context.Context, identifier string) (db.GetUserByUsernameRow, error) {
+	identifier = strings.TrimSpace(identifier)
+
+	user, err := s.authRepo.GetUserByUsername(ctx, identifier)
+	if err == nil || !errors.Is(err, pgx.ErrNoRows) || !strings.Contains(identifier, "@") {
+		return user, err
+	}
+
+	userByEmail, err := s.authRepo.GetUserByEmail(ctx, identifier)
+	if err != nil {
+		return db.GetUserByUsernameRow{}, err
+	}
+	return db.GetUserByUsernameRow(userByEmail), nil
+}
+
+// completeLogin records a successful login and opens a new session for the device.
+func (s *AuthService) completeLogin(
+	ctx context.Context,
+	id pgtype.UUID,
+	username string,
+	email string,
+	emailVerified bool,
+	client models.ClientInfo,
+) (*models.UserLoginResponseDTO, error) {
 	// Log successful login
-	if err := s.authRepo.LogSuccessfulLogin(ctx, loginCredentials.Username); err != nil {
+	if err := s.authRepo.LogSuccessfulLogin(ctx, db.LogSuccessfulLoginParams{
+		Username:  username,
+		IpAddress: client.IPAddress,
+		UserAgent: client.UserAgent,
+	}); err != nil {
 		return nil, err
 	}
 
 	// Update last date of login
-	if err := s.authRepo.UpdateLastLogin(ctx, user.ID); err != nil {
+	if err := s.authRepo.UpdateLastLogin(ctx, id); err != nil {
 		return nil, err
 	}
 
 	// Clean up sessions
-	sessions, err := s.authRepo.GetSessionByUserId(ctx, user.ID)
+	sessions, err := s.authRepo.GetSessionByUserId(ctx, id)
 	if err != nil {
 		return nil, err
 	}
-	activeSessions, err := s.cleanUpSessions(ctx, sessions)
-	if err != nil {
+	if _, err := s.cleanUpSessions(ctx, sessions); err != nil {
 		return nil, err
 	}
 
 	// Generation token
-	userID := uuid.UUID(user.ID.Bytes[:])
-	tokens, err := utils.GenerateToken(userID.String(), secret)
+	userID := uuid.UUID(id.Bytes[:])
+	sessionID := uuid.New()
+	subject, err := s.tokenSubject(ctx, id, sessionID, emailVerified)
+	if err != nil {
+		return nil, err
+	}
+	tokens, err := utils.GenerateToken(subject, s.keys)
 	if err != nil {
 		return nil, errors.New("failed to generate tokens")
 	}
 
-	var refreshToken string
-	if len(activeSessions) > 0 {
-		refreshToken = activeSessions[0].SessionToken
-	} else {
-		_, err = s.authRepo.CreateSession(ctx, db.CreateSessionParams{
-			UserID:           user.ID,
+	// Every login opens its own session: refresh tokens are rotated on use,
+	// so sharing one token between devices would look like a replay
+	err = s.authRepo.InTx(ctx, func(repo IAuthRepository) error {
+		_, err := repo.CreateSession(ctx, db.CreateSessionParams{
+			ID:               pgtype.UUID{Bytes: sessionID, Valid: true},
+			UserID:           id,
 			SessionToken:     tokens.RefreshToken,
-			SessionExpiresAt: pgtype.Timestamp{Time: tokens.ExpiresAt, Valid: true},
+			SessionExpiresAt: pgtype.Timestamp{Time: tokens.RefreshExpiresAt, Valid: true},
+			UserAgent:        client.UserAgent,
+			IpAddress:        client.IPAddress,
 		})
 		if err != nil {
-			return nil, errors.New("failed to generate session")
+			return err
 		}
-		refreshToken = tokens.RefreshToken
+		return enqueueEvent(ctx, repo, events.NewUserLoggedInEvent(userID.String(), sessionID.String(), client.UserAgent, client.IPAddress))
+	})
+	if err != nil {
+		return nil, errors.New("failed to generate session")
 	}
 
 	return &models.UserLoginResponseDTO{
 		ID:           userID.String(),
-		Username:     user.Username,
-		Email:        user.Email,
+		Username:     username,
+		Email:        email,
 		AccessToken:  tokens.AccessToken,
-		RefreshToken: refreshToken,
+		RefreshToken: tokens.RefreshToken,
 	}, nil
 }
 
+// upgradePasswordHash stores a new hash when the current one uses an older algorithm
+// or weaker parameters. It needs the plain password, so it can only run at login.
+// Failures are logged, the user already proved the password and can still log in.
+func (s *AuthService) upgradePasswordHash(ctx context.Context, userID pgtype.UUID, currentHash string, password string) {
+	if !s.policy.PasswordHasher.NeedsRehash(currentHash) {
+		return
+	}
+
+	hashedPassword, err := s.policy.PasswordHasher.Hash(password)
+	if err != nil {
+		log.Printf("failed to rehash password of user %s: %v", uuid.UUID(userID.Bytes), err)
+		return
+	}
+
+	// The update is conditional on the old hash, so a password changed in the meantime is kept
+	if _, err := s.authRepo.RehashUserPassword(ctx, db.RehashUserPasswordParams{
+		NewPassword: hashedPassword,
+		ID:          userID,
+		OldPassword: currentHash,
+	}); err != nil {
+		log.Printf("failed to store rehashed password of user %s: %v", uuid.UUID(userID.Bytes), err)
+	}
+}
+
+// ChangePassword replaces the password of the user the access token belongs to.
+// With revokeOtherSessions every session except the caller's one is signed out.
+func (s *AuthService) ChangePassword(ctx context.Context, accessToken string, oldPassword string, newPassword string, revokeOtherSessions bool) error {
+	caller, err := s.authenticate(ctx, accessToken)
+	if err != nil {
+		return err
+	}
+
+	user, err := s.authRepo.GetUserById(ctx, caller.UserID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrInvalidAccessToken
+		}
+		return err
+	}
+
+	match, err := s.policy.PasswordHasher.Verify(oldPassword, user.Password)
+	if err != nil {
+		return err
+	}
+	if !match {
+		return ErrIncorrectPassword
+	}
+
+	if err := validatePassword(newPassword); err != nil {
+		return err
+	}
+
+	hashedPassword, err := s.policy.PasswordHasher.Hash(newPassword)
+	if err != nil {
+		return errors.New("failed to hash password")
+	}
+
+	err = s.authRepo.InTx(ctx, func(repo IAuthRepository) error {
+		if err := repo.UpdateUserPassword(ctx, db.UpdateUserPasswordParams{
+			NewPassword: hashedPassword,
+			ID:          user.ID,
+		}); err != nil {
+			return err
+		}
+
+		// Reset links requested with the old password must not override the new one
+		if err := repo.InvalidatePasswordResetTokens(ctx, user.ID); err != nil {
+			return err
+		}
+
+		return enqueueEvent(ctx, repo, events.NewUserPasswordChangedEvent(uuid.UUID(user.ID.Bytes).String(), events.PasswordChangedByUser))
+	})
+	if err != nil {
+		return err
+	}
+
+	if !revokeOtherSessions {
+		return nil
+	}
+	if !caller.SessionID.Valid {
+		return s.authRepo.DeleteAllSessionsForUser(ctx, user.ID)
+	}
+	return s.authRepo.DeleteOtherSessionsForUser(ctx, db.DeleteOtherSessionsForUserParams{
+		UserID:    user.ID,
+		SessionID: caller.SessionID,
+	})
+}
+
+// authenticatedCaller is the user and session an access token was issued for.
+type authenticatedCaller struct {
+	UserID    pgtype.UUID
+	SessionID pgtype.UUID
+	Principal authz.Principal
+}
+
+// authenticate validates an access token for RPCs that act on behalf of the caller.
+// Tokens that were revoked, directly or through their session, are rejected even before they expire.
+// Tokens issued to OAuth clients are rejected too, apps only reach the API routes their scopes cover.
+// So are impersonation tokens: an admin acting as a user cannot manage the user's account.
+func (s *AuthService) authenticate(ctx context.Context, accessToken string) (*authenticatedCaller, error) {
+	claims, err := utils.ValidateJWT(accessToken, s.keys)
+	if err != nil || claims.TokenType != utils.AccessTokenType || claims.ClientID != "" || claims.Actor != nil {
+		return nil, ErrInvalidAccessToken
+	}
+
+	revoked, err := s.isRevoked(ctx, claims)
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return nil, ErrInvalidAccessToken
+	}
+
+	userUUID, err := uuid.Parse(claims.UserID)
+	if err != nil {
+		return nil, ErrInvalidAccessToken
+	}
+	caller := &authenticatedCaller{
+		UserID: pgtype.UUID{Bytes: userUUID, Valid: true},
+		Principal: authz.Principal{
+			UserID:      claims.UserID,
+			Roles:       claims.Roles,
+			Permissions: claims.Permissions,
+		},
+	}
+
+	if claims.SessionID == "" {
+		return caller, nil
+	}
+
+	sessionUUID, err := uuid.Parse(claims.SessionID)
+	if err != nil {
+		return nil, ErrInvalidAccessToken
+	}
+	session, err := s.authRepo.GetSessionByID(ctx, pgtype.UUID{Bytes: sessionUUID, Valid: true})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrInvalidAccessToken
+		}
+		return nil, err
+	}
+	if session.UserID != caller.UserID {
+		return nil, ErrInvalidAccessToken
+	}
+	caller.SessionID = session.ID
+
+	return caller, nil
+}
+
+func (s *AuthService) RefreshToken(ctx context.Context, refreshToken string) (*models.RefreshTokenResponseDTO, error) {
+	tokens, subject, err := s.rotateSession(ctx, refreshToken, "")
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.RefreshTokenResponseDTO{
+		ID:           subject.UserID,
+		AccessToken:  tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+	}, nil
+}
+
+// rotateSession issues a new token pair for a refresh token and rotates the session.
+// clientID is empty for first-party sessions and names the client for OAuth grants,
+// refresh tokens are only accepted from the party they were issued to. Tokens of any
+// other type are rejected, including types added later.
+func (s *AuthService) rotateSession(ctx context.Context, refreshToken string, clientID string) (*utils.Token, utils.TokenSubject, error) {
+	claims, err := utils.ValidateJWT(refreshToken, s.keys)
+	if err != nil || claims.TokenType != utils.RefreshTokenType || claims.ClientID != clientID {
+		return nil, utils.TokenSubject{}, ErrInvalidRefreshToken
+	}
+
+	session, err := s.authRepo.GetSessionByToken(ctx, refreshToken)
+	if err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return nil, utils.TokenSubject{}, err
+		}
+		return nil, utils.TokenSubject{}, s.detectRefreshTokenReuse(ctx, refreshToken)
+	}
+
+	userID := uuid.UUID(session.UserID.Bytes[:])
+	if userID.String() != claims.UserID {
+		return nil, utils.TokenSubject{}, ErrInvalidRefreshToken
+	}
+
+	// The verification state and the roles are read again, so a refreshed
+	// token picks up a confirmed email or a role change
+	user, err := s.authRepo.GetUserById(ctx, session.UserID)
+	if err != nil {
+		return nil, utils.TokenSubject{}, err
+	}
+
+	var subject utils.TokenSubject
+	if clientID == "" {
+		subject, err = s.tokenSubject(ctx, session.UserID, uuid.UUID(session.ID.Bytes), user.EmailVerified)
+	} else {
+		subject, err = s.oauthClientSubject(ctx, claims, session, user.EmailVerified)
+	}
+	if err != nil {
+		return nil, utils.TokenSubject{}, err
+	}
+	tokens, err := utils.GenerateToken(subject, s.keys)
+	if err != nil {
+		return nil, utils.TokenSubject{}, errors.New("failed to generate tokens")
+	}
+
+	rotated, err := s.authRepo.RotateSessionToken(ctx, db.RotateSessionTokenParams{
+		NewToken:  tokens.RefreshToken,
+		NewExpiry: pgtype.Timestamp{Time: tokens.RefreshExpiresAt, Valid: true},
+		ID:        session.ID,
+		OldToken:  refreshToken,
+	})
+	if err != nil {
+		return nil, utils.TokenSubject{}, err
+	}
+
+	// Another request rotated the same token first, so it was presented twice
+	if rotated == 0 {
+		if err := s.authRepo.DeleteSessionByID(ctx, session.ID); err != nil {
+			return nil, utils.TokenSubject{}, err
+		}
+		return nil, utils.TokenSubject{}, ErrRefreshTokenReused
+	}
+
+	return tokens, subject, nil
+}
+
+// IntrospectToken reports whether a token is currently usable and who it belongs to.
+// Invalid or expired tokens are not an error: they are reported as inactive.
+// Besides JWTs it understands personal access tokens, recognised by their prefix.
+func (s *AuthService) IntrospectToken(ctx context.Context, token string) (*models.TokenIntrospectionDTO, error) {
+	if strings.HasPrefix(token, PersonalAccessTokenPrefix) {
+		return s.introspectPersonalAccessToken(ctx, token)
+	}
+
+	claims, err := utils.ValidateJWT(token, s.keys)
+	if err != nil {
+		return &models.TokenIntrospectionDTO{Active: false}, nil
+	}
+
+	introspection := &models.TokenIntrospectionDTO{
+		Active:        true,
+		UserID:        claims.UserID,
+		SessionID:     claims.SessionID,
+		TokenType:     claims.TokenType,
+		EmailVerified: claims.EmailVerified,
+		Roles:         claims.Roles,
+		Permissions:   claims.Permissions,
+	}
+	if claims.Actor != nil {
+		introspection.ActorID = claims.Actor.Subject
+	}
+	if claims.IssuedAt != nil {
+		introspection.IssuedAt = claims.IssuedAt.Time
+	}
+	if claims.ExpiresAt != nil {
+		introspection.ExpiresAt = claims.ExpiresAt.Time
+	}
+
+	revoked, err := s.isRevoked(ctx, claims)
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		introspection.Active = false
+		introspection.Revoked = true
+		return introspection, nil
+	}
+
+	// Tokens of OAuth clients only cover the scopes the user still consents to
+	if claims.ClientID != "" {
+		clientUUID, err := uuid.Parse(claims.ClientID)
+		if err != nil {
+			return &models.TokenIntrospectionDTO{Active: false}, nil
+		}
+		userUUID, err := uuid.Parse(claims.UserID)
+		if err != nil {
+			return &models.TokenIntrospectionDTO{Active: false}, nil
+		}
+
+		scopes, err := s.consentedScopes(ctx, pgtype.UUID{Bytes: userUUID, Valid: true}, pgtype.UUID{Bytes: clientUUID, Valid: true}, claims.Scopes)
+		if err != nil {
+			if !errors.Is(err, ErrOAuthConsentNotFound) {
+				return nil, err
+			}
+			introspection.Active = false
+			introspection.Revoked = true
+			return introspection, nil
+		}
+		if claims.TokenType == utils.AccessTokenType {
+			introspection.TokenType = OAuthAccessTokenType
+		}
+		introspection.ClientID = claims.ClientID
+		introspection.Scopes = scopes
+	}
+
+	// Tokens issued before sessions were embedded in the claims cannot be revoked
+	if claims.SessionID == "" {
+		return introspection, nil
+	}
+
+	sessionUUID, err := uuid.Parse(claims.SessionID)
+	if err != nil {
+		return &models.TokenIntrospectionDTO{Active: false}, nil
+	}
+
+	session, err := s.authRepo.GetSessionByID(ctx, pgtype.UUID{Bytes: sessionUUID, Valid: true})
+	if err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return nil, err
+		}
+		introspection.Active = false
+		introspection.Revoked = true
+		return introspection, nil
+	}
+
+	if uuid.UUID(session.UserID.Bytes[:]).String() != claims.UserID {
+		return &models.TokenIntrospectionDTO{Active: false}, nil
+	}
+
+	return introspection, nil
+}
+
+// JWKS exposes the public signing keys so other services can verify tokens offline.
+func (s *AuthService) JWKS() []utils.JSONWebKey {
+	return s.keys.JWKS()
+}
+
+// detectRefreshTokenReuse revokes the session a rotated refresh token belonged to.
+// Presenting an already rotated token means it leaked, so the whole token family is dropped.
+func (s *AuthService) detectRefreshTokenReuse(ctx context.Context, refreshToken string) error {
+	rotated, err := s.authRepo.GetRotatedSessionToken(ctx, refreshToken)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrInvalidRefreshToken
+		}
+		return err
+	}
+
+	if err := s.authRepo.DeleteSessionByID(ctx, rotated.SessionID); err != nil {
+		return err
+	}
+	return ErrRefreshTokenReused
+}
+
+// validatePassword applies the same rules as the CreateUserRequest validation tags.
+func validatePassword(password string) error {
+	if password == "" {
+		return ErrPasswordRequired
+	}
+	if len(password) < 6 {
+		return ErrPasswordTooShort
+	}
+	return nil
+}
+
 func (s *AuthService) cleanUpSessions(ctx context.Context, sessions []db.GetSessionByUserIdRow) ([]db.GetSessionByUserIdRow, error) {
 	activeSessions := make([]db.GetSessionByUserIdRow, 0)
 	for _, session := range sessions {
@@ -155,23 +771,128 @@ func (s *AuthService) cleanUpSessions(ctx context.Context, sessions []db.GetSess
 	return activeSessions, nil
 }
 
+// Logout ends the session of a refresh token or an access token. An access token is
+// revoked right away instead of staying usable until it expires.
 func (s *AuthService) Logout(ctx context.Context, token string) error {
-	if err := s.authRepo.DeleteSessionByToken(ctx, token); err != nil {
+	claims, err := utils.ValidateJWT(token, s.keys)
+	if err != nil || claims.TokenType != utils.AccessTokenType {
+		return s.authRepo.DeleteSessionByToken(ctx, token)
+	}
+
+	if claims.ExpiresAt != nil {
+		if err := s.revocations.RevokeToken(ctx, claims.ID, claims.ExpiresAt.Time); err != nil {
+			return err
+		}
+	}
+
+	sessionUUID, err := uuid.Parse(claims.SessionID)
+	if err != nil {
+		return nil
+	}
+	return s.authRepo.DeleteSessionByID(ctx, pgtype.UUID{Bytes: sessionUUID, Valid: true})
+}
+
+// ListSessions returns the active sessions of the caller, most recently used first.
+func (s *AuthService) ListSessions(ctx context.Context, accessToken string) ([]models.SessionDTO, error) {
+	caller, err := s.authenticate(ctx, accessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	sessions, err := s.authRepo.ListSessionsForUser(ctx, caller.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]models.SessionDTO, 0, len(sessions))
+	for _, session := range sessions {
+		result = append(result, models.SessionDTO{
+			ID:         uuid.UUID(session.ID.Bytes).String(),
+			UserAgent:  session.UserAgent,
+			IPAddress:  session.IpAddress,
+			CreatedAt:  session.CreatedAt.Time,
+			LastUsedAt: session.LastUsedAt.Time,
+			ExpiresAt:  session.SessionExpiresAt.Time,
+			Current:    caller.SessionID.Valid && session.ID == caller.SessionID,
+		})
+	}
+	return result, nil
+}
+
+// RevokeSession signs out a single device of the caller. Revoking the current session works like Logout.
+func (s *AuthService) RevokeSession(ctx context.Context, accessToken string, sessionID string) error {
+	caller, err := s.authenticate(ctx, accessToken)
+	if err != nil {
 		return err
 	}
+
+	sessionUUID, err := uuid.Parse(sessionID)
+	if err != nil {
+		return ErrSessionNotFound
+	}
+
+	// Scoping the delete to the caller keeps users from revoking sessions of other accounts
+	deleted, err := s.authRepo.DeleteSessionForUser(ctx, db.DeleteSessionForUserParams{
+		ID:     pgtype.UUID{Bytes: sessionUUID, Valid: true},
+		UserID: caller.UserID,
+	})
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return ErrSessionNotFound
+	}
 	return nil
 }
 
-func (s *AuthService) LogoutFromAllDevices(ctx context.Context, userID pgtype.UUID) error {
-	if err := s.authRepo.DeleteAllSessionsForUser(ctx, userID); err != nil {
+// LogoutFromAllDevices ends every session of the caller and revokes all access tokens
+// issued to them so far, including the one used for this call.
+func (s *AuthService) LogoutFromAllDevices(ctx context.Context, accessToken string) error {
+	caller, err := s.authenticate(ctx, accessToken)
+	if err != nil {
 		return err
 	}
-	return nil
+
+	if err := s.authRepo.DeleteAllSessionsForUser(ctx, caller.UserID); err != nil {
+		return err
+	}
+	return s.revokeUserTokens(ctx, caller.UserID)
+}
+
+// revokeUserTokens revokes every access token issued to the user up to now.
+// The entry only has to outlive the longest living access token.
+func (s *AuthService) revokeUserTokens(ctx context.Context, userID pgtype.UUID) error {
+	return s.revocations.RevokeUser(ctx, uuid.UUID(userID.Bytes).String(), utils.MaxAge)
 }
 
-func (s *AuthService) DeleteUser(ctx context.Context, userID pgtype.UUID) error {
-	if err := s.authRepo.DeleteUser(ctx, userID); err != nil {
+// isRevoked checks the revocation list for the token itself and for its user.
+func (s *AuthService) isRevoked(ctx context.Context, claims *utils.Claims) (bool, error) {
+	var issuedAt time.Time
+	if claims.IssuedAt != nil {
+		issuedAt = claims.IssuedAt.Time
+	}
+	return s.revocations.IsRevoked(ctx, claims.ID, claims.UserID, issuedAt)
+}
+
+// DeleteUser removes an account. Only callers with the users:delete permission may do so.
+// Unknown or already deleted accounts return ErrUserNotFound without announcing anything.
+func (s *AuthService) DeleteUser(ctx context.Context, accessToken string, userID pgtype.UUID) error {
+	if _, err := s.authorize(ctx, accessToken, authz.PermissionDeleteUsers); err != nil {
 		return err
 	}
-	return nil
+
+	err := s.authRepo.InTx(ctx, func(repo IAuthRepository) error {
+		deleted, err := repo.DeleteUser(ctx, userID)
+		if err != nil {
+			return err
+		}
+		if deleted == 0 {
+			return ErrUserNotFound
+		}
+		return enqueueEvent(ctx, repo, events.NewUserDeletedEvent(uuid.UUID(userID.Bytes).String()))
+	})
+	if err != nil {
+		return err
+	}
+	return s.revokeUserTokens(ctx, userID)
 }
@@ -0,0 +1,2032 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+	db "soul-connect/sc-auth/internal/db/sqlc"
+	"soul-connect/sc-auth/internal/events"
+	"soul-connect/sc-auth/internal/mailer"
+	"soul-connect/sc-auth/internal/models"
+	"soul-connect/sc-auth/internal/utils"
+	"soul-connect/sc-auth/pkg/authz"
+)
+
+type stubAuthRepo struct {
+	IAuthRepository
+	GetSessionByIDFn         func(ctx context.Context, id pgtype.UUID) (db.Session, error)
+	GetSessionByTokenFn      func(ctx context.Context, sessionToken string) (db.Session, error)
+	GetRotatedSessionTokenFn func(ctx context.Context, sessionToken string) (db.GetRotatedSessionTokenRow, error)
+	RotateSessionTokenFn     func(ctx context.Context, arg db.RotateSessionTokenParams) (int64, error)
+	GetUserByUsernameFn      func(ctx context.Context, username string) (db.GetUserByUsernameRow, error)
+	GetAccountLockoutFn      func(ctx context.Context, username string) (db.AccountLockout, error)
+	LockAccountFn            func(ctx context.Context, arg db.LockAccountParams) (db.AccountLockout, error)
+	GetUserByEmailFn         func(ctx context.Context, email string) (db.GetUserByEmailRow, error)
+	GetUserByIdFn            func(ctx context.Context, id pgtype.UUID) (db.GetUserByIdRow, error)
+	deletedSessions          []pgtype.UUID
+	loginAttempts            []db.LoginAttempt
+	resetTokens              []db.PasswordResetToken
+	magicLinkTokens          []db.MagicLinkToken
+	updatedPasswords         []db.UpdateUserPasswordParams
+	rehashedPasswords        []db.RehashUserPasswordParams
+	signedOutUsers           []pgtype.UUID
+	revokedOtherSessions     []db.DeleteOtherSessionsForUserParams
+	verificationTokens       []db.EmailVerificationToken
+	verifiedUsers            []pgtype.UUID
+	totp                     *db.UserTotp
+	recoveryCodes            []db.TotpRecoveryCode
+	createdSessions          []db.CreateSessionParams
+	sessions                 []db.Session
+	personalTokens           []db.PersonalAccessToken
+	oauthClients             []db.OauthClient
+	oauthConsents            []db.OauthConsent
+	oauthCodes               []db.OauthAuthorizationCode
+	impersonationSessions    []db.ImpersonationSession
+	userRoles                map[pgtype.UUID][]string
+	deletedUsers             []pgtype.UUID
+	outboxEvents             []db.CreateOutboxEventParams
+	createdUsers             []db.CreateUserParams
+}
+
+// testRolePermissions mirrors the roles seeded by the migrations.
+var testRolePermissions = map[string][]string{
+	authz.RoleAdmin:     {authz.PermissionReadAuditLog, authz.PermissionDeleteUsers, authz.PermissionImpersonateUsers, authz.PermissionManageRoles, authz.PermissionModeratePosts, authz.PermissionUnlockUsers},
+	authz.RoleModerator: {authz.PermissionModeratePosts},
+}
+
+func (s *stubAuthRepo) GetSessionByID(ctx context.Context, id pgtype.UUID) (db.Session, error) {
+	return s.GetSessionByIDFn(ctx, id)
+}
+
+func (s *stubAuthRepo) GetSessionByToken(ctx context.Context, sessionToken string) (db.Session, error) {
+	return s.GetSessionByTokenFn(ctx, sessionToken)
+}
+
+func (s *stubAuthRepo) GetRotatedSessionToken(ctx context.Context, sessionToken string) (db.GetRotatedSessionTokenRow, error) {
+	return s.GetRotatedSessionTokenFn(ctx, sessionToken)
+}
+
+func (s *stubAuthRepo) RotateSessionToken(ctx context.Context, arg db.RotateSessionTokenParams) (int64, error) {
+	return s.RotateSessionTokenFn(ctx, arg)
+}
+
+func (s *stubAuthRepo) DeleteSessionByID(_ context.Context, id pgtype.UUID) error {
+	s.deletedSessions = append(s.deletedSessions, id)
+	return nil
+}
+
+func (s *stubAuthRepo) GetUserByUsername(ctx context.Context, username string) (db.GetUserByUsernameRow, error) {
+	return s.GetUserByUsernameFn(ctx, username)
+}
+
+func (s *stubAuthRepo) GetAccountLockout(ctx context.Context, username string) (db.AccountLockout, error) {
+	return s.GetAccountLockoutFn(ctx, username)
+}
+
+func (s *stubAuthRepo) LockAccount(ctx context.Context, arg db.LockAccountParams) (db.AccountLockout, error) {
+	return s.LockAccountFn(ctx, arg)
+}
+
+func (s *stubAuthRepo) LogFailedLogin(_ context.Context, arg db.LogFailedLoginParams) error {
+	s.logLoginAttempt(arg.Username, false, arg.IpAddress, arg.UserAgent)
+	return nil
+}
+
+func (s *stubAuthRepo) LogSuccessfulLogin(_ context.Context, arg db.LogSuccessfulLoginParams) error {
+	s.logLoginAttempt(arg.Username, true, arg.IpAddress, arg.UserAgent)
+	return nil
+}
+
+// logLoginAttempt keeps loginAttempts newest first, like the queries return them.
+func (s *stubAuthRepo) logLoginAttempt(username string, success bool, ipAddress string, userAgent string) {
+	attempt := db.LoginAttempt{
+		ID:          pgtype.UUID{Bytes: uuid.New(), Valid: true},
+		Username:    username,
+		Success:     success,
+		AttemptTime: pgtype.Timestamp{Time: time.Now(), Valid: true},
+		IpAddress:   ipAddress,
+		UserAgent:   userAgent,
+	}
+	s.loginAttempts = append([]db.LoginAttempt{attempt}, s.loginAttempts...)
+}
+
+func (s *stubAuthRepo) GetLoginAttemptsByUsername(_ context.Context, arg db.GetLoginAttemptsByUsernameParams) ([]db.LoginAttempt, error) {
+	var attempts []db.LoginAttempt
+	for _, attempt := range s.loginAttempts {
+		if attempt.Username == arg.Username && len(attempts) < int(arg.RequestLimit) {
+			attempts = append(attempts, attempt)
+		}
+	}
+	return attempts, nil
+}
+
+func (s *stubAuthRepo) ExportLoginAttempts(_ context.Context, arg db.ExportLoginAttemptsParams) ([]db.LoginAttempt, error) {
+	var attempts []db.LoginAttempt
+	for i := len(s.loginAttempts) - 1; i >= 0 && len(attempts) < int(arg.RowLimit); i-- {
+		attempt := s.loginAttempts[i]
+		if attempt.AttemptTime.Time.Before(arg.FromTime.Time) || !attempt.AttemptTime.Time.Before(arg.ToTime.Time) {
+			continue
+		}
+		if arg.Username != "" && attempt.Username != arg.Username {
+			continue
+		}
+		attempts = append(attempts, attempt)
+	}
+	return attempts, nil
+}
+
+func (s *stubAuthRepo) GetUserByEmail(ctx context.Context, email string) (db.GetUserByEmailRow, error) {
+	return s.GetUserByEmailFn(ctx, email)
+}
+
+func (s *stubAuthRepo) CreatePasswordResetToken(_ context.Context, arg db.CreatePasswordResetTokenParams) error {
+	s.resetTokens = append(s.resetTokens, db.PasswordResetToken{
+		UserID:    arg.UserID,
+		TokenHash: arg.TokenHash,
+		ExpiresAt: arg.ExpiresAt,
+	})
+	return nil
+}
+
+func (s *stubAuthRepo) ConsumePasswordResetToken(_ context.Context, tokenHash string) (pgtype.UUID, error) {
+	for i, token := range s.resetTokens {
+		if token.TokenHash == tokenHash && !token.UsedAt.Valid && token.ExpiresAt.Time.After(time.Now()) {
+			s.resetTokens[i].UsedAt = pgtype.Timestamp{Time: time.Now(), Valid: true}
+			return token.UserID, nil
+		}
+	}
+	return pgtype.UUID{}, pgx.ErrNoRows
+}
+
+func (s *stubAuthRepo) InvalidatePasswordResetTokens(_ context.Context, userID pgtype.UUID) error {
+	for i, token := range s.resetTokens {
+		if token.UserID == userID && !token.UsedAt.Valid {
+			s.resetTokens[i].UsedAt = pgtype.Timestamp{Time: time.Now(), Valid: true}
+		}
+	}
+	return nil
+}
+
+func (s *stubAuthRepo) CreateMagicLinkToken(_ context.Context, arg db.CreateMagicLinkTokenParams) error {
+	s.magicLinkTokens = append(s.magicLinkTokens, db.MagicLinkToken{
+		UserID:    arg.UserID,
+		TokenHash: arg.TokenHash,
+		ExpiresAt: arg.ExpiresAt,
+	})
+	return nil
+}
+
+func (s *stubAuthRepo) ConsumeMagicLinkToken(_ context.Context, tokenHash string) (pgtype.UUID, error) {
+	for i, token := range s.magicLinkTokens {
+		if token.TokenHash == tokenHash && !token.UsedAt.Valid && token.ExpiresAt.Time.After(time.Now()) {
+			s.magicLinkTokens[i].UsedAt = pgtype.Timestamp{Time: time.Now(), Valid: true}
+			return token.UserID, nil
+		}
+	}
+	return pgtype.UUID{}, pgx.ErrNoRows
+}
+
+func (s *stubAuthRepo) InvalidateMagicLinkTokens(_ context.Context, userID pgtype.UUID) error {
+	for i, token := range s.magicLinkTokens {
+		if token.UserID == userID && !token.UsedAt.Valid {
+			s.magicLinkTokens[i].UsedAt = pgtype.Timestamp{Time: time.Now(), Valid: true}
+		}
+	}
+	return nil
+}
+
+func (s *stubAuthRepo) CountMagicLinkTokensSince(_ context.Context, arg db.CountMagicLinkTokensSinceParams) (int64, error) {
+	var count int64
+	for _, token := range s.magicLinkTokens {
+		if token.UserID == arg.UserID {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (s *stubAuthRepo) UpdateUserPassword(_ context.Context, arg db.UpdateUserPasswordParams) error {
+	s.updatedPasswords = append(s.updatedPasswords, arg)
+	return nil
+}
+
+func (s *stubAuthRepo) CreateUser(_ context.Context, arg db.CreateUserParams) (db.CreateUserRow, error) {
+	s.createdUsers = append(s.createdUsers, arg)
+	return db.CreateUserRow{ID: toPgUUID(uuid.New()), Username: arg.Username, Email: arg.Email, Password: arg.Password}, nil
+}
+
+// InTx runs fn on the stub itself, the tests do not roll anything back.
+func (s *stubAuthRepo) InTx(_ context.Context, fn func(repo IAuthRepository) error) error {
+	return fn(s)
+}
+
+func (s *stubAuthRepo) CreateOutboxEvent(_ context.Context, arg db.CreateOutboxEventParams) error {
+	s.outboxEvents = append(s.outboxEvents, arg)
+	return nil
+}
+
+// outboxEventTypes lists the types of the enqueued events in order.
+func (s *stubAuthRepo) outboxEventTypes() []string {
+	types := make([]string, 0, len(s.outboxEvents))
+	for _, event := range s.outboxEvents {
+		types = append(types, event.EventType)
+	}
+	return types
+}
+
+func (s *stubAuthRepo) RehashUserPassword(_ context.Context, arg db.RehashUserPasswordParams) (int64, error) {
+	s.rehashedPasswords = append(s.rehashedPasswords, arg)
+	return 1, nil
+}
+
+func (s *stubAuthRepo) DeleteAllSessionsForUser(_ context.Context, userID pgtype.UUID) error {
+	s.signedOutUsers = append(s.signedOutUsers, userID)
+	return nil
+}
+
+func (s *stubAuthRepo) GetUserById(ctx context.Context, id pgtype.UUID) (db.GetUserByIdRow, error) {
+	return s.GetUserByIdFn(ctx, id)
+}
+
+func (s *stubAuthRepo) DeleteOtherSessionsForUser(_ context.Context, arg db.DeleteOtherSessionsForUserParams) error {
+	s.revokedOtherSessions = append(s.revokedOtherSessions, arg)
+	return nil
+}
+
+func (s *stubAuthRepo) CreateEmailVerificationToken(_ context.Context, arg db.CreateEmailVerificationTokenParams) error {
+	s.verificationTokens = append(s.verificationTokens, db.EmailVerificationToken{
+		UserID:    arg.UserID,
+		TokenHash: arg.TokenHash,
+		ExpiresAt: arg.ExpiresAt,
+	})
+	return nil
+}
+
+func (s *stubAuthRepo) ConsumeEmailVerificationToken(_ context.Context, tokenHash string) (pgtype.UUID, error) {
+	for i, token := range s.verificationTokens {
+		if token.TokenHash == tokenHash && !token.UsedAt.Valid && token.ExpiresAt.Time.After(time.Now()) {
+			s.verificationTokens[i].UsedAt = pgtype.Timestamp{Time: time.Now(), Valid: true}
+			return token.UserID, nil
+		}
+	}
+	return pgtype.UUID{}, pgx.ErrNoRows
+}
+
+func (s *stubAuthRepo) InvalidateEmailVerificationTokens(_ context.Context, userID pgtype.UUID) error {
+	for i, token := range s.verificationTokens {
+		if token.UserID == userID && !token.UsedAt.Valid {
+			s.verificationTokens[i].UsedAt = pgtype.Timestamp{Time: time.Now(), Valid: true}
+		}
+	}
+	return nil
+}
+
+func (s *stubAuthRepo) CountEmailVerificationTokensSince(_ context.Context, arg db.CountEmailVerificationTokensSinceParams) (int64, error) {
+	var count int64
+	for _, token := range s.verificationTokens {
+		if token.UserID == arg.UserID {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (s *stubAuthRepo) MarkEmailVerified(_ context.Context, id pgtype.UUID) error {
+	s.verifiedUsers = append(s.verifiedUsers, id)
+	return nil
+}
+
+func (s *stubAuthRepo) UpsertUserTotp(_ context.Context, arg db.UpsertUserTotpParams) error {
+	s.totp = &db.UserTotp{UserID: arg.UserID, Secret: arg.Secret}
+	return nil
+}
+
+func (s *stubAuthRepo) GetUserTotp(_ context.Context, userID pgtype.UUID) (db.UserTotp, error) {
+	if s.totp == nil || s.totp.UserID != userID {
+		return db.UserTotp{}, pgx.ErrNoRows
+	}
+	return *s.totp, nil
+}
+
+func (s *stubAuthRepo) ConfirmUserTotp(context.Context, pgtype.UUID) error {
+	s.totp.ConfirmedAt = pgtype.Timestamp{Time: time.Now(), Valid: true}
+	return nil
+}
+
+func (s *stubAuthRepo) UseTotpStep(_ context.Context, arg db.UseTotpStepParams) (int64, error) {
+	if s.totp.LastUsedStep >= arg.Step {
+		return 0, nil
+	}
+	s.totp.LastUsedStep = arg.Step
+	return 1, nil
+}
+
+func (s *stubAuthRepo) DeleteUserTotp(context.Context, pgtype.UUID) error {
+	s.totp = nil
+	return nil
+}
+
+func (s *stubAuthRepo) CreateRecoveryCode(_ context.Context, arg db.CreateRecoveryCodeParams) error {
+	s.recoveryCodes = append(s.recoveryCodes, db.TotpRecoveryCode{
+		ID:       toPgUUID(uuid.New()),
+		UserID:   arg.UserID,
+		CodeHash: arg.CodeHash,
+	})
+	return nil
+}
+
+func (s *stubAuthRepo) UseRecoveryCode(_ context.Context, arg db.UseRecoveryCodeParams) (int64, error) {
+	for i, code := range s.recoveryCodes {
+		if code.UserID == arg.UserID && code.CodeHash == arg.CodeHash && !code.UsedAt.Valid {
+			s.recoveryCodes[i].UsedAt = pgtype.Timestamp{Time: time.Now(), Valid: true}
+			return 1, nil
+		}
+	}
+	return 0, nil
+}
+
+func (s *stubAuthRepo) DeleteRecoveryCodes(context.Context, pgtype.UUID) error {
+	s.recoveryCodes = nil
+	return nil
+}
+
+func (s *stubAuthRepo) UpdateLastLogin(context.Context, pgtype.UUID) error {
+	return nil
+}
+
+func (s *stubAuthRepo) GetSessionByUserId(context.Context, pgtype.UUID) ([]db.GetSessionByUserIdRow, error) {
+	return nil, nil
+}
+
+func (s *stubAuthRepo) CreateSession(_ context.Context, arg db.CreateSessionParams) (db.Session, error) {
+	s.createdSessions = append(s.createdSessions, arg)
+	session := db.Session{
+		ID:               arg.ID,
+		UserID:           arg.UserID,
+		SessionToken:     arg.SessionToken,
+		SessionExpiresAt: arg.SessionExpiresAt,
+		UserAgent:        arg.UserAgent,
+		IpAddress:        arg.IpAddress,
+	}
+	s.sessions = append(s.sessions, session)
+	return session, nil
+}
+
+func (s *stubAuthRepo) ListSessionsForUser(_ context.Context, userID pgtype.UUID) ([]db.Session, error) {
+	var sessions []db.Session
+	for _, session := range s.sessions {
+		if session.UserID == userID {
+			sessions = append(sessions, session)
+		}
+	}
+	return sessions, nil
+}
+
+func (s *stubAuthRepo) DeleteSessionForUser(_ context.Context, arg db.DeleteSessionForUserParams) (int64, error) {
+	for i, session := range s.sessions {
+		if session.ID == arg.ID && session.UserID == arg.UserID {
+			s.sessions = append(s.sessions[:i], s.sessions[i+1:]...)
+			return 1, nil
+		}
+	}
+	return 0, nil
+}
+
+func (s *stubAuthRepo) CreatePersonalAccessToken(_ context.Context, arg db.CreatePersonalAccessTokenParams) (db.PersonalAccessToken, error) {
+	token := db.PersonalAccessToken{
+		ID:        toPgUUID(uuid.New()),
+		UserID:    arg.UserID,
+		Name:      arg.Name,
+		TokenHash: arg.TokenHash,
+		Scopes:    arg.Scopes,
+		ExpiresAt: arg.ExpiresAt,
+		CreatedAt: pgtype.Timestamp{Time: time.Now(), Valid: true},
+	}
+	s.personalTokens = append(s.personalTokens, token)
+	return token, nil
+}
+
+func (s *stubAuthRepo) GetPersonalAccessTokenByHash(_ context.Context, tokenHash string) (db.PersonalAccessToken, error) {
+	for _, token := range s.personalTokens {
+		if token.TokenHash == tokenHash {
+			return token, nil
+		}
+	}
+	return db.PersonalAccessToken{}, pgx.ErrNoRows
+}
+
+func (s *stubAuthRepo) ListPersonalAccessTokens(_ context.Context, userID pgtype.UUID) ([]db.PersonalAccessToken, error) {
+	var tokens []db.PersonalAccessToken
+	for _, token := range s.personalTokens {
+		if token.UserID == userID {
+			tokens = append(tokens, token)
+		}
+	}
+	return tokens, nil
+}
+
+func (s *stubAuthRepo) TouchPersonalAccessToken(_ context.Context, id pgtype.UUID) error {
+	for i := range s.personalTokens {
+		if s.personalTokens[i].ID == id {
+			s.personalTokens[i].LastUsedAt = pgtype.Timestamp{Time: time.Now(), Valid: true}
+		}
+	}
+	return nil
+}
+
+func (s *stubAuthRepo) DeletePersonalAccessToken(_ context.Context, arg db.DeletePersonalAccessTokenParams) (int64, error) {
+	for i, token := range s.personalTokens {
+		if token.ID == arg.ID && token.UserID == arg.UserID {
+			s.personalTokens = append(s.personalTokens[:i], s.personalTokens[i+1:]...)
+			return 1, nil
+		}
+	}
+	return 0, nil
+}
+
+func (s *stubAuthRepo) CreateOAuthClient(_ context.Context, arg db.CreateOAuthClientParams) (db.OauthClient, error) {
+	client := db.OauthClient{
+		ID:               toPgUUID(uuid.New()),
+		OwnerID:          arg.OwnerID,
+		Name:             arg.Name,
+		ClientSecretHash: arg.ClientSecretHash,
+		RedirectUris:     arg.RedirectUris,
+		Scopes:           arg.Scopes,
+		CreatedAt:        pgtype.Timestamp{Time: time.Now(), Valid: true},
+	}
+	s.oauthClients = append(s.oauthClients, client)
+	return client, nil
+}
+
+func (s *stubAuthRepo) GetOAuthClient(_ context.Context, id pgtype.UUID) (db.OauthClient, error) {
+	for _, client := range s.oauthClients {
+		if client.ID == id {
+			return client, nil
+		}
+	}
+	return db.OauthClient{}, pgx.ErrNoRows
+}
+
+func (s *stubAuthRepo) ListOAuthClientsByOwner(_ context.Context, ownerID pgtype.UUID) ([]db.OauthClient, error) {
+	var clients []db.OauthClient
+	for _, client := range s.oauthClients {
+		if client.OwnerID == ownerID {
+			clients = append(clients, client)
+		}
+	}
+	return clients, nil
+}
+
+func (s *stubAuthRepo) DeleteOAuthClient(_ context.Context, arg db.DeleteOAuthClientParams) (int64, error) {
+	for i, client := range s.oauthClients {
+		if client.ID == arg.ID && client.OwnerID == arg.OwnerID {
+			s.oauthClients = append(s.oauthClients[:i], s.oauthClients[i+1:]...)
+			return 1, nil
+		}
+	}
+	return 0, nil
+}
+
+func (s *stubAuthRepo) UpsertOAuthConsent(_ context.Context, arg db.UpsertOAuthConsentParams) error {
+	for i, consent := range s.oauthConsents {
+		if consent.UserID == arg.UserID && consent.ClientID == arg.ClientID {
+			s.oauthConsents[i].Scopes = arg.Scopes
+			return nil
+		}
+	}
+	s.oauthConsents = append(s.oauthConsents, db.OauthConsent{UserID: arg.UserID, ClientID: arg.ClientID, Scopes: arg.Scopes})
+	return nil
+}
+
+func (s *stubAuthRepo) GetOAuthConsent(_ context.Context, arg db.GetOAuthConsentParams) (db.OauthConsent, error) {
+	for _, consent := range s.oauthConsents {
+		if consent.UserID == arg.UserID && consent.ClientID == arg.ClientID {
+			return consent, nil
+		}
+	}
+	return db.OauthConsent{}, pgx.ErrNoRows
+}
+
+func (s *stubAuthRepo) ListOAuthConsents(_ context.Context, userID pgtype.UUID) ([]db.ListOAuthConsentsRow, error) {
+	var consents []db.ListOAuthConsentsRow
+	for _, consent := range s.oauthConsents {
+		if consent.UserID != userID {
+			continue
+		}
+		client, _ := s.GetOAuthClient(context.Background(), consent.ClientID)
+		consents = append(consents, db.ListOAuthConsentsRow{ClientID: consent.ClientID, Name: client.Name, Scopes: consent.Scopes})
+	}
+	return consents, nil
+}
+
+func (s *stubAuthRepo) DeleteOAuthConsent(_ context.Context, arg db.DeleteOAuthConsentParams) (int64, error) {
+	for i, consent := range s.oauthConsents {
+		if consent.UserID == arg.UserID && consent.ClientID == arg.ClientID {
+			s.oauthConsents = append(s.oauthConsents[:i], s.oauthConsents[i+1:]...)
+			return 1, nil
+		}
+	}
+	return 0, nil
+}
+
+func (s *stubAuthRepo) CreateOAuthAuthorizationCode(_ context.Context, arg db.CreateOAuthAuthorizationCodeParams) error {
+	s.oauthCodes = append(s.oauthCodes, db.OauthAuthorizationCode{
+		ID:            toPgUUID(uuid.New()),
+		CodeHash:      arg.CodeHash,
+		ClientID:      arg.ClientID,
+		UserID:        arg.UserID,
+		RedirectUri:   arg.RedirectUri,
+		Scopes:        arg.Scopes,
+		CodeChallenge: arg.CodeChallenge,
+		ExpiresAt:     arg.ExpiresAt,
+	})
+	return nil
+}
+
+func (s *stubAuthRepo) ConsumeOAuthAuthorizationCode(_ context.Context, codeHash string) (db.ConsumeOAuthAuthorizationCodeRow, error) {
+	for i, code := range s.oauthCodes {
+		if code.CodeHash == codeHash && !code.UsedAt.Valid && code.ExpiresAt.Time.After(time.Now()) {
+			s.oauthCodes[i].UsedAt = pgtype.Timestamp{Time: time.Now(), Valid: true}
+			return db.ConsumeOAuthAuthorizationCodeRow{
+				ClientID:      code.ClientID,
+				UserID:        code.UserID,
+				RedirectUri:   code.RedirectUri,
+				Scopes:        code.Scopes,
+				CodeChallenge: code.CodeChallenge,
+			}, nil
+		}
+	}
+	return db.ConsumeOAuthAuthorizationCodeRow{}, pgx.ErrNoRows
+}
+
+func (s *stubAuthRepo) GetUserRoles(_ context.Context, userID pgtype.UUID) ([]string, error) {
+	return s.userRoles[userID], nil
+}
+
+func (s *stubAuthRepo) GetUserPermissions(_ context.Context, userID pgtype.UUID) ([]string, error) {
+	var permissions []string
+	for _, role := range s.userRoles[userID] {
+		for _, permission := range testRolePermissions[role] {
+			if !slices.Contains(permissions, permission) {
+				permissions = append(permissions, permission)
+			}
+		}
+	}
+	slices.Sort(permissions)
+	return permissions, nil
+}
+
+func (s *stubAuthRepo) RoleExists(_ context.Context, name string) (bool, error) {
+	_, ok := testRolePermissions[name]
+	return ok, nil
+}
+
+func (s *stubAuthRepo) GrantRole(_ context.Context, arg db.GrantRoleParams) error {
+	if s.userRoles == nil {
+		s.userRoles = map[pgtype.UUID][]string{}
+	}
+	if !slices.Contains(s.userRoles[arg.UserID], arg.Role) {
+		s.userRoles[arg.UserID] = append(s.userRoles[arg.UserID], arg.Role)
+	}
+	return nil
+}
+
+func (s *stubAuthRepo) RevokeRole(_ context.Context, arg db.RevokeRoleParams) (int64, error) {
+	roles := s.userRoles[arg.UserID]
+	index := slices.Index(roles, arg.Role)
+	if index < 0 {
+		return 0, nil
+	}
+	s.userRoles[arg.UserID] = slices.Delete(roles, index, index+1)
+	return 1, nil
+}
+
+func (s *stubAuthRepo) CreateImpersonationSession(_ context.Context, arg db.CreateImpersonationSessionParams) (db.ImpersonationSession, error) {
+	session := db.ImpersonationSession{
+		ID:             toPgUUID(uuid.New()),
+		ActorID:        arg.ActorID,
+		ActorUsername:  arg.ActorUsername,
+		TargetID:       arg.TargetID,
+		TargetUsername: arg.TargetUsername,
+		Reason:         arg.Reason,
+		TokenID:        arg.TokenID,
+		IpAddress:      arg.IpAddress,
+		UserAgent:      arg.UserAgent,
+		StartedAt:      pgtype.Timestamp{Time: time.Now().UTC(), Valid: true},
+		ExpiresAt:      arg.ExpiresAt,
+	}
+	s.impersonationSessions = append(s.impersonationSessions, session)
+	return session, nil
+}
+
+func (s *stubAuthRepo) ListImpersonationSessions(_ context.Context, requestLimit int32) ([]db.ImpersonationSession, error) {
+	sessions := slices.Clone(s.impersonationSessions)
+	slices.Reverse(sessions)
+	if len(sessions) > int(requestLimit) {
+		sessions = sessions[:requestLimit]
+	}
+	return sessions, nil
+}
+
+func (s *stubAuthRepo) DeleteUser(_ context.Context, id pgtype.UUID) (int64, error) {
+	if slices.Contains(s.deletedUsers, id) {
+		return 0, nil
+	}
+	s.deletedUsers = append(s.deletedUsers, id)
+	return 1, nil
+}
+
+type stubMailSender struct {
+	sent []mailer.Message
+}
+
+func (s *stubMailSender) Send(_ context.Context, message mailer.Message) error {
+	s.sent = append(s.sent, message)
+	return nil
+}
+
+func toPgUUID(id uuid.UUID) pgtype.UUID {
+	return pgtype.UUID{Bytes: id, Valid: true}
+}
+
+// requirePasswordHash checks that a stored password is an argon2id hash of the expected password.
+func requirePasswordHash(t *testing.T, hash string, password string) {
+	t.Helper()
+	require.True(t, strings.HasPrefix(hash, "$argon2id$"), hash)
+	match, err := utils.NewArgon2idHasher(utils.DefaultArgon2idParams).Verify(password, hash)
+	require.NoError(t, err)
+	require.True(t, match)
+}
+
+func newTestKeySet(t *testing.T) *utils.KeySet {
+	keys, err := utils.GenerateKeySet()
+	require.NoError(t, err)
+	return keys
+}
+
+func TestAuthService_RefreshTokenRotatesSession(t *testing.T) {
+	ctx := context.Background()
+	userID := uuid.New()
+	sessionID := toPgUUID(uuid.New())
+
+	keys := newTestKeySet(t)
+	tokens, err := utils.GenerateToken(utils.TokenSubject{UserID: userID.String(), SessionID: uuid.UUID(sessionID.Bytes).String()}, keys)
+	require.NoError(t, err)
+
+	var rotateCalls []db.RotateSessionTokenParams
+	repo := &stubAuthRepo{
+		GetSessionByTokenFn: func(_ context.Context, token string) (db.Session, error) {
+			require.Equal(t, tokens.RefreshToken, token)
+			return db.Session{ID: sessionID, UserID: toPgUUID(userID), SessionToken: token}, nil
+		},
+		RotateSessionTokenFn: func(_ context.Context, arg db.RotateSessionTokenParams) (int64, error) {
+			rotateCalls = append(rotateCalls, arg)
+			return 1, nil
+		},
+		GetUserByIdFn: func(_ context.Context, id pgtype.UUID) (db.GetUserByIdRow, error) {
+			return db.GetUserByIdRow{ID: id, EmailVerified: true}, nil
+		},
+	}
+
+	service := NewAuthService(repo, keys, nil, nil, AuthPolicy{})
+
+	refreshed, err := service.RefreshToken(ctx, tokens.RefreshToken)
+	require.NoError(t, err)
+	require.Equal(t, userID.String(), refreshed.ID)
+	require.NotEqual(t, tokens.RefreshToken, refreshed.RefreshToken)
+	require.Len(t, rotateCalls, 1)
+	require.Equal(t, sessionID, rotateCalls[0].ID)
+	require.Equal(t, tokens.RefreshToken, rotateCalls[0].OldToken)
+	require.Equal(t, refreshed.RefreshToken, rotateCalls[0].NewToken)
+	require.Empty(t, repo.deletedSessions)
+}
+
+func TestAuthService_RefreshTokenReuseRevokesSession(t *testing.T) {
+	ctx := context.Background()
+	userID := uuid.New()
+	sessionID := toPgUUID(uuid.New())
+
+	keys := newTestKeySet(t)
+	tokens, err := utils.GenerateToken(utils.TokenSubject{UserID: userID.String(), SessionID: uuid.UUID(sessionID.Bytes).String()}, keys)
+	require.NoError(t, err)
+
+	repo := &stubAuthRepo{
+		GetSessionByTokenFn: func(context.Context, string) (db.Session, error) {
+			return db.Session{}, pgx.ErrNoRows
+		},
+		GetRotatedSessionTokenFn: func(_ context.Context, token string) (db.GetRotatedSessionTokenRow, error) {
+			return db.GetRotatedSessionTokenRow{SessionID: sessionID, SessionToken: token}, nil
+		},
+	}
+
+	service := NewAuthService(repo, keys, nil, nil, AuthPolicy{})
+
+	_, err = service.RefreshToken(ctx, tokens.RefreshToken)
+	require.ErrorIs(t, err, ErrRefreshTokenReused)
+	require.Equal(t, []pgtype.UUID{sessionID}, repo.deletedSessions)
+}
+
+func TestAuthService_RefreshTokenUnknownTokenIsRejected(t *testing.T) {
+	ctx := context.Background()
+
+	keys := newTestKeySet(t)
+	tokens, err := utils.GenerateToken(utils.TokenSubject{UserID: uuid.NewString(), SessionID: uuid.NewString()}, keys)
+	require.NoError(t, err)
+
+	repo := &stubAuthRepo{
+		GetSessionByTokenFn: func(context.Context, string) (db.Session, error) {
+			return db.Session{}, pgx.ErrNoRows
+		},
+		GetRotatedSessionTokenFn: func(context.Context, string) (db.GetRotatedSessionTokenRow, error) {
+			return db.GetRotatedSessionTokenRow{}, pgx.ErrNoRows
+		},
+	}
+
+	service := NewAuthService(repo, keys, nil, nil, AuthPolicy{})
+
+	_, err = service.RefreshToken(ctx, tokens.RefreshToken)
+	require.ErrorIs(t, err, ErrInvalidRefreshToken)
+	require.Empty(t, repo.deletedSessions)
+
+	_, err = service.RefreshToken(ctx, "not-a-jwt")
+	require.ErrorIs(t, err, ErrInvalidRefreshToken)
+
+	// Only refresh tokens rotate a session
+	challenge, err := utils.GenerateChallengeToken(uuid.NewString(), time.Minute, keys)
+	require.NoError(t, err)
+	for _, token := range []string{tokens.AccessToken, challenge} {
+		_, err = service.RefreshToken(ctx, token)
+		require.ErrorIs(t, err, ErrInvalidRefreshToken)
+	}
+}
+
+func TestAuthService_IntrospectTokenReportsRevokedSession(t *testing.T) {
+	ctx := context.Background()
+	userID := uuid.New()
+	activeSession := uuid.New()
+	revokedSession := uuid.New()
+
+	keys := newTestKeySet(t)
+	activeTokens, err := utils.GenerateToken(utils.TokenSubject{UserID: userID.String(), SessionID: activeSession.String()}, keys)
+	require.NoError(t, err)
+	revokedTokens, err := utils.GenerateToken(utils.TokenSubject{UserID: userID.String(), SessionID: revokedSession.String()}, keys)
+	require.NoError(t, err)
+
+	repo := &stubAuthRepo{
+		GetSessionByIDFn: func(_ context.Context, id pgtype.UUID) (db.Session, error) {
+			if id == toPgUUID(activeSession) {
+				return db.Session{ID: id, UserID: toPgUUID(userID)}, nil
+			}
+			return db.Session{}, pgx.ErrNoRows
+		},
+	}
+
+	service := NewAuthService(repo, keys, nil, nil, AuthPolicy{})
+
+	introspection, err := service.IntrospectToken(ctx, activeTokens.AccessToken)
+	require.NoError(t, err)
+	require.True(t, introspection.Active)
+	require.False(t, introspection.Revoked)
+	require.Equal(t, userID.String(), introspection.UserID)
+	require.Equal(t, activeSession.String(), introspection.SessionID)
+	require.Equal(t, utils.AccessTokenType, introspection.TokenType)
+	require.WithinDuration(t, activeTokens.ExpiresAt, introspection.ExpiresAt, time.Second)
+
+	introspection, err = service.IntrospectToken(ctx, revokedTokens.AccessToken)
+	require.NoError(t, err)
+	require.False(t, introspection.Active)
+	require.True(t, introspection.Revoked)
+
+	introspection, err = service.IntrospectToken(ctx, "not-a-jwt")
+	require.NoError(t, err)
+	require.False(t, introspection.Active)
+}
+
+func TestAuthService_LoginLocksAccountAfterRepeatedFailures(t *testing.T) {
+	ctx := context.Background()
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte("correct-password"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	var lockout *db.AccountLockout
+	repo := &stubAuthRepo{
+		GetUserByUsernameFn: func(_ context.Context, username string) (db.GetUserByUsernameRow, error) {
+			return db.GetUserByUsernameRow{ID: toPgUUID(uuid.New()), Username: username, Password: string(hashedPassword)}, nil
+		},
+		GetAccountLockoutFn: func(context.Context, string) (db.AccountLockout, error) {
+			if lockout == nil {
+				return db.AccountLockout{}, pgx.ErrNoRows
+			}
+			return *lockout, nil
+		},
+		LockAccountFn: func(_ context.Context, arg db.LockAccountParams) (db.AccountLockout, error) {
+			lockout = &db.AccountLockout{
+				Username:     arg.Username,
+				LockoutCount: 1,
+				LockedUntil:  arg.LockedUntil,
+				UpdatedAt:    pgtype.Timestamp{Time: time.Now(), Valid: true},
+			}
+			return *lockout, nil
+		},
+	}
+
+	policy := LockoutPolicy{MaxAttempts: 3, Window: time.Minute, Duration: time.Minute, MaxDuration: time.Hour}
+	service := NewAuthService(repo, newTestKeySet(t), nil, nil, AuthPolicy{Lockout: policy})
+
+	credentials := models.UserLoginRequest{Identifier: "alice", Password: "wrong-password"}
+	for i := 0; i < policy.MaxAttempts-1; i++ {
+		_, err = service.Login(ctx, credentials)
+		require.EqualError(t, err, "invalid credentials")
+	}
+
+	var lockedErr *AccountLockedError
+	_, err = service.Login(ctx, credentials)
+	require.True(t, errors.As(err, &lockedErr))
+	require.WithinDuration(t, time.Now().Add(policy.Duration), lockedErr.Until, time.Second)
+
+	// The correct password is not even checked while the account is locked
+	credentials.Password = "correct-password"
+	_, err = service.Login(ctx, credentials)
+	require.True(t, errors.As(err, &lockedErr))
+	require.Len(t, repo.loginAttempts, policy.MaxAttempts)
+}
+
+func TestAuthService_LoginLocksUnknownAccountsLikeExistingOnes(t *testing.T) {
+	ctx := context.Background()
+
+	lockouts := map[string]db.AccountLockout{}
+	repo := &stubAuthRepo{
+		GetUserByUsernameFn: func(context.Context, string) (db.GetUserByUsernameRow, error) {
+			return db.GetUserByUsernameRow{}, pgx.ErrNoRows
+		},
+		GetUserByEmailFn: func(context.Context, string) (db.GetUserByEmailRow, error) {
+			return db.GetUserByEmailRow{}, pgx.ErrNoRows
+		},
+		GetAccountLockoutFn: func(_ context.Context, username string) (db.AccountLockout, error) {
+			lockout, ok := lockouts[username]
+			if !ok {
+				return db.AccountLockout{}, pgx.ErrNoRows
+			}
+			return lockout, nil
+		},
+		LockAccountFn: func(_ context.Context, arg db.LockAccountParams) (db.AccountLockout, error) {
+			lockouts[arg.Username] = db.AccountLockout{
+				Username:     arg.Username,
+				LockoutCount: 1,
+				LockedUntil:  arg.LockedUntil,
+				UpdatedAt:    pgtype.Timestamp{Time: time.Now(), Valid: true},
+			}
+			return lockouts[arg.Username], nil
+		},
+	}
+
+	policy := LockoutPolicy{MaxAttempts: 3, Window: time.Minute, Duration: time.Minute, MaxDuration: time.Hour}
+	hasher := &countingHasher{PasswordHasher: utils.NewArgon2idHasher(utils.Argon2idParams{Memory: 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32})}
+	service := NewAuthService(repo, newTestKeySet(t), nil, nil, AuthPolicy{Lockout: policy, PasswordHasher: hasher})
+
+	credentials := models.UserLoginRequest{Identifier: "Nobody@Example.com", Password: "guess"}
+	for i := 0; i < policy.MaxAttempts-1; i++ {
+		_, err := service.Login(ctx, credentials)
+		require.ErrorIs(t, err, ErrInvalidCredentials)
+	}
+
+	var lockedErr *AccountLockedError
+	_, err := service.Login(ctx, credentials)
+	require.True(t, errors.As(err, &lockedErr))
+	_, err = service.Login(ctx, models.UserLoginRequest{Identifier: "nobody@example.com", Password: "guess"})
+	require.True(t, errors.As(err, &lockedErr))
+	require.Len(t, repo.loginAttempts, policy.MaxAttempts)
+	require.Equal(t, "nobody@example.com", repo.loginAttempts[0].Username)
+	// A password is verified for every attempt that was not locked out, like for existing accounts
+	require.Equal(t, policy.MaxAttempts, hasher.verified)
+}
+
+// countingHasher counts the passwords it verified.
+type countingHasher struct {
+	utils.PasswordHasher
+	verified int
+}
+
+func (h *countingHasher) Verify(password string, encodedHash string) (bool, error) {
+	h.verified++
+	return h.PasswordHasher.Verify(password, encodedHash)
+}
+
+func TestAuthService_LoginUpgradesWeakerPasswordHashes(t *testing.T) {
+	ctx := context.Background()
+	userID := toPgUUID(uuid.New())
+
+	legacyHash, err := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	require.NoError(t, err)
+	storedHash := string(legacyHash)
+
+	repo := &stubAuthRepo{
+		GetUserByUsernameFn: func(_ context.Context, username string) (db.GetUserByUsernameRow, error) {
+			return db.GetUserByUsernameRow{ID: userID, Username: username, Password: storedHash}, nil
+		},
+	}
+	service := NewAuthService(repo, newTestKeySet(t), nil, nil, AuthPolicy{})
+
+	// A wrong password never triggers a rehash
+	_, err = service.Login(ctx, models.UserLoginRequest{Identifier: "alice", Password: "wrong-password"})
+	require.EqualError(t, err, "invalid credentials")
+	require.Empty(t, repo.rehashedPasswords)
+
+	_, err = service.Login(ctx, models.UserLoginRequest{Identifier: "alice", Password: "password"})
+	require.NoError(t, err)
+	require.Len(t, repo.rehashedPasswords, 1)
+	require.Equal(t, userID, repo.rehashedPasswords[0].ID)
+	require.Equal(t, storedHash, repo.rehashedPasswords[0].OldPassword)
+	requirePasswordHash(t, repo.rehashedPasswords[0].NewPassword, "password")
+
+	// The upgraded hash is current and is left alone on the next login
+	storedHash = repo.rehashedPasswords[0].NewPassword
+	_, err = service.Login(ctx, models.UserLoginRequest{Identifier: "alice", Password: "password"})
+	require.NoError(t, err)
+	require.Len(t, repo.rehashedPasswords, 1)
+}
+
+func TestAuthService_LoginAcceptsUsernameOrEmail(t *testing.T) {
+	ctx := context.Background()
+	userID := toPgUUID(uuid.New())
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	// The stubs mimic the case-insensitive lookups of the queries
+	repo := &stubAuthRepo{
+		GetUserByUsernameFn: func(_ context.Context, username string) (db.GetUserByUsernameRow, error) {
+			if !strings.EqualFold(username, "alice") {
+				return db.GetUserByUsernameRow{}, pgx.ErrNoRows
+			}
+			return db.GetUserByUsernameRow{ID: userID, Username: "alice", Email: "alice@example.com", Password: string(hashedPassword)}, nil
+		},
+		GetUserByEmailFn: func(_ context.Context, email string) (db.GetUserByEmailRow, error) {
+			if !strings.EqualFold(email, "alice@example.com") {
+				return db.GetUserByEmailRow{}, pgx.ErrNoRows
+			}
+			return db.GetUserByEmailRow{ID: userID, Username: "alice", Email: "alice@example.com", Password: string(hashedPassword)}, nil
+		},
+	}
+	service := NewAuthService(repo, newTestKeySet(t), nil, nil, AuthPolicy{})
+
+	for _, identifier := range []string{"alice", "ALICE", "Alice@Example.com", " alice@example.com "} {
+		login, err := service.Login(ctx, models.UserLoginRequest{Identifier: identifier, Password: "password"})
+		require.NoError(t, err, identifier)
+		require.Equal(t, "alice", login.Username)
+	}
+
+	// Failed attempts count against the stored username, whatever identifier was used
+	_, err = service.Login(ctx, models.UserLoginRequest{Identifier: "ALICE@example.com", Password: "wrong-password"})
+	require.EqualError(t, err, "invalid credentials")
+	require.Equal(t, "alice", repo.loginAttempts[0].Username)
+
+	// Unknown accounts look like a wrong password
+	_, err = service.Login(ctx, models.UserLoginRequest{Identifier: "bob@example.com", Password: "password"})
+	require.ErrorIs(t, err, ErrInvalidCredentials)
+}
+
+func TestLockoutPolicy_LockoutDurationBacksOffExponentially(t *testing.T) {
+	policy := LockoutPolicy{Duration: time.Minute, MaxDuration: 10 * time.Minute}
+
+	require.Equal(t, time.Minute, policy.lockoutDuration(0))
+	require.Equal(t, 2*time.Minute, policy.lockoutDuration(1))
+	require.Equal(t, 8*time.Minute, policy.lockoutDuration(3))
+	require.Equal(t, 10*time.Minute, policy.lockoutDuration(4))
+	require.Equal(t, 10*time.Minute, policy.lockoutDuration(30))
+}
+
+func TestAuthService_PasswordResetTokenIsSingleUse(t *testing.T) {
+	ctx := context.Background()
+	userID := toPgUUID(uuid.New())
+
+	repo := &stubAuthRepo{
+		GetUserByEmailFn: func(_ context.Context, email string) (db.GetUserByEmailRow, error) {
+			if email != "alice@example.com" {
+				return db.GetUserByEmailRow{}, pgx.ErrNoRows
+			}
+			return db.GetUserByEmailRow{ID: userID, Username: "alice", Email: email}, nil
+		},
+	}
+	sender := &stubMailSender{}
+	policy := PasswordResetPolicy{TokenTTL: time.Hour, ResetURL: "https://soul-connect.test/reset?token="}
+	service := NewAuthService(repo, newTestKeySet(t), sender, nil, AuthPolicy{PasswordReset: policy})
+
+	// Unknown emails succeed silently and send nothing
+	require.NoError(t, service.RequestPasswordReset(ctx, "nobody@example.com"))
+	require.Empty(t, sender.sent)
+
+	require.NoError(t, service.RequestPasswordReset(ctx, "alice@example.com"))
+	require.Len(t, sender.sent, 1)
+	require.Len(t, repo.resetTokens, 1)
+
+	_, token, found := strings.Cut(sender.sent[0].Body, policy.ResetURL)
+	require.True(t, found)
+	token = strings.Fields(token)[0]
+	require.NotEqual(t, token, repo.resetTokens[0].TokenHash)
+
+	require.NoError(t, service.ResetPassword(ctx, token, "new-password"))
+	require.Len(t, repo.updatedPasswords, 1)
+	require.Equal(t, userID, repo.updatedPasswords[0].ID)
+	requirePasswordHash(t, repo.updatedPasswords[0].NewPassword, "new-password")
+	require.Equal(t, []pgtype.UUID{userID}, repo.signedOutUsers)
+
+	require.ErrorIs(t, service.ResetPassword(ctx, token, "another-password"), ErrInvalidResetToken)
+	require.Len(t, repo.updatedPasswords, 1)
+}
+
+func TestAuthService_ChangePasswordRevokesOtherSessions(t *testing.T) {
+	ctx := context.Background()
+	userID := toPgUUID(uuid.New())
+	sessionID := toPgUUID(uuid.New())
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte("old-password"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	keys := newTestKeySet(t)
+	tokens, err := utils.GenerateToken(utils.TokenSubject{UserID: uuid.UUID(userID.Bytes).String(), SessionID: uuid.UUID(sessionID.Bytes).String()}, keys)
+	require.NoError(t, err)
+
+	repo := &stubAuthRepo{
+		GetSessionByIDFn: func(_ context.Context, id pgtype.UUID) (db.Session, error) {
+			return db.Session{ID: id, UserID: userID}, nil
+		},
+		GetUserByIdFn: func(_ context.Context, id pgtype.UUID) (db.GetUserByIdRow, error) {
+			return db.GetUserByIdRow{ID: id, Password: string(hashedPassword)}, nil
+		},
+	}
+	service := NewAuthService(repo, keys, nil, nil, AuthPolicy{})
+
+	err = service.ChangePassword(ctx, tokens.AccessToken, "wrong-password", "new-password", true)
+	require.ErrorIs(t, err, ErrIncorrectPassword)
+
+	err = service.ChangePassword(ctx, tokens.AccessToken, "old-password", "short", true)
+	require.ErrorIs(t, err, ErrPasswordTooShort)
+
+	err = service.ChangePassword(ctx, tokens.RefreshToken, "old-password", "new-password", true)
+	require.ErrorIs(t, err, ErrInvalidAccessToken)
+	require.Empty(t, repo.updatedPasswords)
+
+	require.NoError(t, service.ChangePassword(ctx, tokens.AccessToken, "old-password", "new-password", true))
+	require.Len(t, repo.updatedPasswords, 1)
+	requirePasswordHash(t, repo.updatedPasswords[0].NewPassword, "new-password")
+	require.Equal(t, []db.DeleteOtherSessionsForUserParams{{UserID: userID, SessionID: sessionID}}, repo.revokedOtherSessions)
+	require.Empty(t, repo.signedOutUsers)
+}
+
+func TestAuthService_EmailVerification(t *testing.T) {
+	ctx := context.Background()
+	userID := toPgUUID(uuid.New())
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	repo := &stubAuthRepo{
+		GetUserByUsernameFn: func(_ context.Context, username string) (db.GetUserByUsernameRow, error) {
+			return db.GetUserByUsernameRow{ID: userID, Username: username, Password: string(hashedPassword)}, nil
+		},
+		GetUserByEmailFn: func(_ context.Context, email string) (db.GetUserByEmailRow, error) {
+			return db.GetUserByEmailRow{ID: userID, Username: "alice", Email: email}, nil
+		},
+	}
+	sender := &stubMailSender{}
+	policy := EmailVerificationPolicy{
+		Required:     true,
+		TokenTTL:     time.Hour,
+		VerifyURL:    "https://soul-connect.test/verify?token=",
+		ResendLimit:  2,
+		ResendWindow: time.Hour,
+	}
+	service := NewAuthService(repo, newTestKeySet(t), sender, nil, AuthPolicy{EmailVerification: policy})
+
+	_, err = service.Login(ctx, models.UserLoginRequest{Identifier: "alice", Password: "password"})
+	require.ErrorIs(t, err, ErrEmailNotVerified)
+
+	require.NoError(t, service.ResendVerificationEmail(ctx, "alice@example.com"))
+	require.NoError(t, service.ResendVerificationEmail(ctx, "alice@example.com"))
+	require.ErrorIs(t, service.ResendVerificationEmail(ctx, "alice@example.com"), ErrVerificationResendLimited)
+	require.Len(t, sender.sent, 2)
+
+	// Only the latest emailed token is still valid
+	tokens := make([]string, 0, len(sender.sent))
+	for _, message := range sender.sent {
+		_, token, found := strings.Cut(message.Body, policy.VerifyURL)
+		require.True(t, found)
+		tokens = append(tokens, strings.Fields(token)[0])
+	}
+	require.ErrorIs(t, service.VerifyEmail(ctx, tokens[0]), ErrInvalidVerificationToken)
+	require.NoError(t, service.VerifyEmail(ctx, tokens[1]))
+	require.Equal(t, []pgtype.UUID{userID}, repo.verifiedUsers)
+	require.ErrorIs(t, service.VerifyEmail(ctx, tokens[1]), ErrInvalidVerificationToken)
+}
+
+func TestAuthService_TwoFactorLogin(t *testing.T) {
+	ctx := context.Background()
+	userID := toPgUUID(uuid.New())
+	sessionID := toPgUUID(uuid.New())
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	keys := newTestKeySet(t)
+	tokens, err := utils.GenerateToken(utils.TokenSubject{UserID: uuid.UUID(userID.Bytes).String(), SessionID: uuid.UUID(sessionID.Bytes).String()}, keys)
+	require.NoError(t, err)
+
+	repo := &stubAuthRepo{
+		GetSessionByIDFn: func(_ context.Context, id pgtype.UUID) (db.Session, error) {
+			return db.Session{ID: id, UserID: userID}, nil
+		},
+		GetUserByIdFn: func(_ context.Context, id pgtype.UUID) (db.GetUserByIdRow, error) {
+			return db.GetUserByIdRow{ID: id, Username: "alice", Email: "alice@example.com"}, nil
+		},
+		GetUserByUsernameFn: func(_ context.Context, username string) (db.GetUserByUsernameRow, error) {
+			return db.GetUserByUsernameRow{ID: userID, Username: username, Password: string(hashedPassword)}, nil
+		},
+	}
+	service := NewAuthService(repo, keys, nil, nil, AuthPolicy{TwoFactor: TwoFactorPolicy{Issuer: "Soul Connect", ChallengeTTL: time.Minute}})
+
+	enrollment, err := service.EnrollTwoFactor(ctx, tokens.AccessToken)
+	require.NoError(t, err)
+	require.Contains(t, enrollment.ProvisioningURI, "otpauth://totp/Soul%20Connect:alice?")
+	require.Len(t, enrollment.RecoveryCodes, recoveryCodeCount)
+	for i, code := range repo.recoveryCodes {
+		require.Equal(t, hashOneTimeToken(normalizeRecoveryCode(enrollment.RecoveryCodes[i])), code.CodeHash)
+	}
+
+	// Until the enrollment is confirmed the password alone is enough
+	login, err := service.Login(ctx, models.UserLoginRequest{Identifier: "alice", Password: "password"})
+	require.NoError(t, err)
+	require.False(t, login.TwoFactorRequired)
+
+	previousCode, err := utils.TOTPCode(enrollment.Secret, utils.TOTPStep(time.Now())-1)
+	require.NoError(t, err)
+	require.NoError(t, service.ConfirmTwoFactor(ctx, tokens.AccessToken, previousCode))
+
+	login, err = service.Login(ctx, models.UserLoginRequest{Identifier: "alice", Password: "password"})
+	require.NoError(t, err)
+	require.True(t, login.TwoFactorRequired)
+	require.Empty(t, login.AccessToken)
+	require.NotEmpty(t, login.ChallengeToken)
+
+	// A challenge is not an access or refresh token
+	_, err = service.EnrollTwoFactor(ctx, login.ChallengeToken)
+	require.ErrorIs(t, err, ErrInvalidAccessToken)
+	_, err = service.RefreshToken(ctx, login.ChallengeToken)
+	require.ErrorIs(t, err, ErrInvalidRefreshToken)
+
+	// The code used for confirmation cannot be replayed
+	_, err = service.VerifySecondFactor(ctx, login.ChallengeToken, previousCode, models.ClientInfo{})
+	require.ErrorIs(t, err, ErrInvalidTwoFactorCode)
+
+	sessionsBefore := len(repo.createdSessions)
+	client := models.ClientInfo{UserAgent: "Authenticator test", IPAddress: "203.0.113.7"}
+	verified, err := service.VerifySecondFactor(ctx, login.ChallengeToken, strings.ToUpper(enrollment.RecoveryCodes[0]), client)
+	require.NoError(t, err)
+	require.NotEmpty(t, verified.AccessToken)
+	require.Len(t, repo.createdSessions, sessionsBefore+1)
+	require.Equal(t, client.UserAgent, repo.createdSessions[sessionsBefore].UserAgent)
+
+	// The challenge completes a single login
+	_, err = service.VerifySecondFactor(ctx, login.ChallengeToken, enrollment.RecoveryCodes[2], models.ClientInfo{})
+	require.ErrorIs(t, err, ErrInvalidChallengeToken)
+
+	// Recovery codes are single-use
+	login, err = service.Login(ctx, models.UserLoginRequest{Identifier: "alice", Password: "password"})
+	require.NoError(t, err)
+	_, err = service.VerifySecondFactor(ctx, login.ChallengeToken, enrollment.RecoveryCodes[0], models.ClientInfo{})
+	require.ErrorIs(t, err, ErrInvalidTwoFactorCode)
+
+	require.ErrorIs(t, service.DisableTwoFactor(ctx, tokens.AccessToken, "000000"), ErrInvalidTwoFactorCode)
+	require.NoError(t, service.DisableTwoFactor(ctx, tokens.AccessToken, enrollment.RecoveryCodes[1]))
+	require.Nil(t, repo.totp)
+}
+
+func TestAuthService_ListAndRevokeSessions(t *testing.T) {
+	ctx := context.Background()
+	userID := toPgUUID(uuid.New())
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	repo := &stubAuthRepo{
+		GetUserByUsernameFn: func(_ context.Context, username string) (db.GetUserByUsernameRow, error) {
+			return db.GetUserByUsernameRow{ID: userID, Username: username, Password: string(hashedPassword)}, nil
+		},
+	}
+	repo.GetSessionByIDFn = func(_ context.Context, id pgtype.UUID) (db.Session, error) {
+		for _, session := range repo.sessions {
+			if session.ID == id {
+				return session, nil
+			}
+		}
+		return db.Session{}, pgx.ErrNoRows
+	}
+	service := NewAuthService(repo, newTestKeySet(t), nil, nil, AuthPolicy{})
+
+	laptop, err := service.Login(ctx, models.UserLoginRequest{
+		Identifier: "alice",
+		Password:   "password",
+		Client:     models.ClientInfo{UserAgent: "Firefox", IPAddress: "198.51.100.1"},
+	})
+	require.NoError(t, err)
+	phone, err := service.Login(ctx, models.UserLoginRequest{
+		Identifier: "alice",
+		Password:   "password",
+		Client:     models.ClientInfo{UserAgent: "Soul Connect iOS", IPAddress: "198.51.100.2"},
+	})
+	require.NoError(t, err)
+
+	sessions, err := service.ListSessions(ctx, laptop.AccessToken)
+	require.NoError(t, err)
+	require.Len(t, sessions, 2)
+
+	var phoneSessionID string
+	for _, session := range sessions {
+		switch session.UserAgent {
+		case "Firefox":
+			require.True(t, session.Current)
+			require.Equal(t, "198.51.100.1", session.IPAddress)
+		case "Soul Connect iOS":
+			require.False(t, session.Current)
+			phoneSessionID = session.ID
+		}
+	}
+	require.NotEmpty(t, phoneSessionID)
+
+	// Sessions of other users and unknown ids are reported the same way
+	require.ErrorIs(t, service.RevokeSession(ctx, laptop.AccessToken, uuid.NewString()), ErrSessionNotFound)
+	require.ErrorIs(t, service.RevokeSession(ctx, laptop.AccessToken, "not-a-uuid"), ErrSessionNotFound)
+
+	require.NoError(t, service.RevokeSession(ctx, laptop.AccessToken, phoneSessionID))
+	_, err = service.ListSessions(ctx, phone.AccessToken)
+	require.ErrorIs(t, err, ErrInvalidAccessToken)
+
+	sessions, err = service.ListSessions(ctx, laptop.AccessToken)
+	require.NoError(t, err)
+	require.Len(t, sessions, 1)
+}
+
+func TestAuthService_PersonalAccessTokens(t *testing.T) {
+	ctx := context.Background()
+	userID := toPgUUID(uuid.New())
+	sessionID := toPgUUID(uuid.New())
+
+	keys := newTestKeySet(t)
+	tokens, err := utils.GenerateToken(utils.TokenSubject{UserID: uuid.UUID(userID.Bytes).String(), SessionID: uuid.UUID(sessionID.Bytes).String()}, keys)
+	require.NoError(t, err)
+
+	repo := &stubAuthRepo{
+		GetSessionByIDFn: func(_ context.Context, id pgtype.UUID) (db.Session, error) {
+			return db.Session{ID: id, UserID: userID}, nil
+		},
+	}
+	service := NewAuthService(repo, keys, nil, nil, AuthPolicy{})
+
+	_, err = service.CreatePersonalAccessToken(ctx, tokens.AccessToken, models.CreatePersonalAccessTokenRequest{Name: "ci", Scopes: []string{"admin"}})
+	require.ErrorIs(t, err, ErrInvalidTokenScope)
+	_, err = service.CreatePersonalAccessToken(ctx, tokens.AccessToken, models.CreatePersonalAccessTokenRequest{Name: " ", Scopes: []string{"posts:write"}})
+	require.ErrorIs(t, err, ErrInvalidTokenName)
+
+	created, err := service.CreatePersonalAccessToken(ctx, tokens.AccessToken, models.CreatePersonalAccessTokenRequest{
+		Name:      "moderation bot",
+		Scopes:    []string{"posts:write", "posts:read", "posts:write"},
+		ExpiresIn: time.Hour,
+	})
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(created.Token, PersonalAccessTokenPrefix))
+	require.Equal(t, []string{"posts:write", "posts:read"}, created.Scopes)
+	require.NotEqual(t, created.Token, repo.personalTokens[0].TokenHash)
+
+	// A personal access token cannot be used to manage tokens
+	_, err = service.ListPersonalAccessTokens(ctx, created.Token)
+	require.ErrorIs(t, err, ErrInvalidAccessToken)
+
+	introspection, err := service.IntrospectToken(ctx, created.Token)
+	require.NoError(t, err)
+	require.True(t, introspection.Active)
+	require.Equal(t, PersonalAccessTokenType, introspection.TokenType)
+	require.Equal(t, uuid.UUID(userID.Bytes).String(), introspection.UserID)
+	require.Equal(t, created.Scopes, introspection.Scopes)
+
+	listed, err := service.ListPersonalAccessTokens(ctx, tokens.AccessToken)
+	require.NoError(t, err)
+	require.Len(t, listed, 1)
+	require.Empty(t, listed[0].Token)
+	require.False(t, listed[0].LastUsedAt.IsZero())
+
+	require.ErrorIs(t, service.RevokePersonalAccessToken(ctx, tokens.AccessToken, uuid.NewString()), ErrPersonalAccessTokenNotFound)
+	require.NoError(t, service.RevokePersonalAccessToken(ctx, tokens.AccessToken, created.ID))
+
+	introspection, err = service.IntrospectToken(ctx, created.Token)
+	require.NoError(t, err)
+	require.False(t, introspection.Active)
+}
+
+func TestAuthService_ExpiredPersonalAccessTokenIsInactive(t *testing.T) {
+	token := PersonalAccessTokenPrefix + "expired"
+	repo := &stubAuthRepo{personalTokens: []db.PersonalAccessToken{{
+		ID:        toPgUUID(uuid.New()),
+		UserID:    toPgUUID(uuid.New()),
+		TokenHash: hashOneTimeToken(token),
+		ExpiresAt: pgtype.Timestamp{Time: time.Now().Add(-time.Minute), Valid: true},
+	}}}
+	service := NewAuthService(repo, newTestKeySet(t), nil, nil, AuthPolicy{})
+
+	introspection, err := service.IntrospectToken(context.Background(), token)
+	require.NoError(t, err)
+	require.False(t, introspection.Active)
+}
+
+func TestAuthService_RoleManagement(t *testing.T) {
+	ctx := context.Background()
+	adminID := toPgUUID(uuid.New())
+	userID := toPgUUID(uuid.New())
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	repo := &stubAuthRepo{
+		userRoles: map[pgtype.UUID][]string{adminID: {authz.RoleAdmin}},
+		GetUserByUsernameFn: func(_ context.Context, username string) (db.GetUserByUsernameRow, error) {
+			id := userID
+			if username == "admin" {
+				id = adminID
+			}
+			return db.GetUserByUsernameRow{ID: id, Username: username, Password: string(hashedPassword)}, nil
+		},
+		GetUserByIdFn: func(_ context.Context, id pgtype.UUID) (db.GetUserByIdRow, error) {
+			if id != adminID && id != userID {
+				return db.GetUserByIdRow{}, pgx.ErrNoRows
+			}
+			return db.GetUserByIdRow{ID: id}, nil
+		},
+	}
+	repo.GetSessionByIDFn = func(_ context.Context, id pgtype.UUID) (db.Session, error) {
+		for _, session := range repo.sessions {
+			if session.ID == id {
+				return session, nil
+			}
+		}
+		return db.Session{}, pgx.ErrNoRows
+	}
+	repo.GetSessionByTokenFn = func(_ context.Context, sessionToken string) (db.Session, error) {
+		for _, session := range repo.sessions {
+			if session.SessionToken == sessionToken {
+				return session, nil
+			}
+		}
+		return db.Session{}, pgx.ErrNoRows
+	}
+	repo.RotateSessionTokenFn = func(context.Context, db.RotateSessionTokenParams) (int64, error) {
+		return 1, nil
+	}
+	keys := newTestKeySet(t)
+	service := NewAuthService(repo, keys, nil, nil, AuthPolicy{})
+
+	admin, err := service.Login(ctx, models.UserLoginRequest{Identifier: "admin", Password: "password"})
+	require.NoError(t, err)
+	user, err := service.Login(ctx, models.UserLoginRequest{Identifier: "bob", Password: "password"})
+	require.NoError(t, err)
+
+	// Roles and their permissions are embedded in the access token
+	claims, err := utils.ValidateJWT(admin.AccessToken, keys)
+	require.NoError(t, err)
+	require.Equal(t, []string{authz.RoleAdmin}, claims.Roles)
+	require.Contains(t, claims.Permissions, authz.PermissionManageRoles)
+
+	userIDString := uuid.UUID(userID.Bytes).String()
+	require.ErrorIs(t, service.GrantRole(ctx, user.AccessToken, userIDString, authz.RoleAdmin), authz.ErrPermissionDenied)
+	require.ErrorIs(t, service.GrantRole(ctx, admin.AccessToken, userIDString, "superuser"), ErrUnknownRole)
+	require.ErrorIs(t, service.GrantRole(ctx, admin.AccessToken, uuid.NewString(), authz.RoleModerator), ErrUserNotFound)
+	require.NoError(t, service.GrantRole(ctx, admin.AccessToken, userIDString, authz.RoleModerator))
+
+	// The new role shows up once the user refreshes
+	refreshed, err := service.RefreshToken(ctx, user.RefreshToken)
+	require.NoError(t, err)
+	claims, err = utils.ValidateJWT(refreshed.AccessToken, keys)
+	require.NoError(t, err)
+	require.Equal(t, []string{authz.RoleModerator}, claims.Roles)
+	require.Equal(t, []string{authz.PermissionModeratePosts}, claims.Permissions)
+
+	require.ErrorIs(t, service.RevokeRole(ctx, admin.AccessToken, uuid.UUID(adminID.Bytes).String(), authz.RoleAdmin), ErrCannotDemoteSelf)
+	require.NoError(t, service.RevokeRole(ctx, admin.AccessToken, userIDString, authz.RoleModerator))
+	require.ErrorIs(t, service.RevokeRole(ctx, admin.AccessToken, userIDString, authz.RoleModerator), ErrRoleNotGranted)
+
+	require.ErrorIs(t, service.DeleteUser(ctx, refreshed.AccessToken, adminID), authz.ErrPermissionDenied)
+	require.NoError(t, service.DeleteUser(ctx, admin.AccessToken, userID))
+	require.Equal(t, []pgtype.UUID{userID}, repo.deletedUsers)
+}
+
+func TestAuthService_Impersonation(t *testing.T) {
+	ctx := context.Background()
+	adminID := toPgUUID(uuid.New())
+	moderatorID := toPgUUID(uuid.New())
+	userID := toPgUUID(uuid.New())
+	usernames := map[pgtype.UUID]string{adminID: "admin", moderatorID: "mod", userID: "bob"}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	repo := &stubAuthRepo{
+		userRoles: map[pgtype.UUID][]string{adminID: {authz.RoleAdmin}, moderatorID: {authz.RoleModerator}},
+		GetUserByUsernameFn: func(_ context.Context, username string) (db.GetUserByUsernameRow, error) {
+			for id, name := range usernames {
+				if name == username {
+					return db.GetUserByUsernameRow{ID: id, Username: username, Password: string(hashedPassword)}, nil
+				}
+			}
+			return db.GetUserByUsernameRow{}, pgx.ErrNoRows
+		},
+		GetUserByIdFn: func(_ context.Context, id pgtype.UUID) (db.GetUserByIdRow, error) {
+			username, ok := usernames[id]
+			if !ok {
+				return db.GetUserByIdRow{}, pgx.ErrNoRows
+			}
+			return db.GetUserByIdRow{ID: id, Username: username, EmailVerified: true}, nil
+		},
+	}
+	repo.GetSessionByIDFn = func(_ context.Context, id pgtype.UUID) (db.Session, error) {
+		for _, session := range repo.sessions {
+			if session.ID == id {
+				return session, nil
+			}
+		}
+		return db.Session{}, pgx.ErrNoRows
+	}
+	keys := newTestKeySet(t)
+	service := NewAuthService(repo, keys, nil, nil, AuthPolicy{Impersonation: ImpersonationPolicy{TokenTTL: 5 * time.Minute}})
+
+	admin, err := service.Login(ctx, models.UserLoginRequest{Identifier: "admin", Password: "password"})
+	require.NoError(t, err)
+	user, err := service.Login(ctx, models.UserLoginRequest{Identifier: "bob", Password: "password"})
+	require.NoError(t, err)
+
+	userIDString := uuid.UUID(userID.Bytes).String()
+	adminIDString := uuid.UUID(adminID.Bytes).String()
+	client := models.ClientInfo{IPAddress: "10.0.0.7", UserAgent: "support-console"}
+
+	_, err = service.Impersonate(ctx, user.AccessToken, adminIDString, "curious", client)
+	require.ErrorIs(t, err, authz.ErrPermissionDenied)
+	_, err = service.Impersonate(ctx, admin.AccessToken, userIDString, "  ", client)
+	require.ErrorIs(t, err, ErrImpersonationReasonRequired)
+	_, err = service.Impersonate(ctx, admin.AccessToken, userIDString, strings.Repeat("a", 501), client)
+	require.ErrorIs(t, err, ErrImpersonationReasonTooLong)
+	_, err = service.Impersonate(ctx, admin.AccessToken, uuid.NewString(), "ticket #42", client)
+	require.ErrorIs(t, err, ErrUserNotFound)
+	_, err = service.Impersonate(ctx, admin.AccessToken, adminIDString, "ticket #42", client)
+	require.ErrorIs(t, err, ErrCannotImpersonateSelf)
+	_, err = service.Impersonate(ctx, admin.AccessToken, uuid.UUID(moderatorID.Bytes).String(), "ticket #42", client)
+	require.ErrorIs(t, err, ErrCannotImpersonateStaff)
+	require.Empty(t, repo.impersonationSessions)
+
+	impersonation, err := service.Impersonate(ctx, admin.AccessToken, userIDString, "ticket #42", client)
+	require.NoError(t, err)
+	require.WithinDuration(t, time.Now().Add(5*time.Minute), impersonation.ExpiresAt, 5*time.Second)
+
+	// The token is issued for the user and names the admin as the actor, without any roles
+	claims, err := utils.ValidateJWT(impersonation.AccessToken, keys)
+	require.NoError(t, err)
+	require.Equal(t, userIDString, claims.UserID)
+	require.Equal(t, &utils.ActorClaim{Subject: adminIDString}, claims.Actor)
+	require.Empty(t, claims.Roles)
+	require.Empty(t, claims.Permissions)
+
+	introspection, err := service.IntrospectToken(ctx, impersonation.AccessToken)
+	require.NoError(t, err)
+	require.True(t, introspection.Active)
+	require.Equal(t, userIDString, introspection.UserID)
+	require.Equal(t, adminIDString, introspection.ActorID)
+
+	// Account management stays with the user
+	require.ErrorIs(t, service.ChangePassword(ctx, impersonation.AccessToken, "password", "new-password", false), ErrInvalidAccessToken)
+	_, err = service.RefreshToken(ctx, impersonation.AccessToken)
+	require.ErrorIs(t, err, ErrInvalidRefreshToken)
+
+	require.Len(t, repo.impersonationSessions, 1)
+	recorded := repo.impersonationSessions[0]
+	require.Equal(t, claims.ID, recorded.TokenID)
+
+	_, err = service.ListImpersonationSessions(ctx, user.AccessToken, 0)
+	require.ErrorIs(t, err, authz.ErrPermissionDenied)
+	sessions, err := service.ListImpersonationSessions(ctx, admin.AccessToken, 0)
+	require.NoError(t, err)
+	require.Equal(t, []models.ImpersonationSessionDTO{{
+		ID:             impersonation.SessionID,
+		ActorID:        adminIDString,
+		ActorUsername:  "admin",
+		TargetID:       userIDString,
+		TargetUsername: "bob",
+		Reason:         "ticket #42",
+		IPAddress:      "10.0.0.7",
+		UserAgent:      "support-console",
+		StartedAt:      recorded.StartedAt.Time,
+		ExpiresAt:      recorded.ExpiresAt.Time,
+	}}, sessions)
+}
+
+func TestAuthService_LogoutRevokesAccessTokensImmediately(t *testing.T) {
+	ctx := context.Background()
+	userID := toPgUUID(uuid.New())
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	// The stub keeps deleted sessions around, so only the revocation list can reject the tokens
+	repo := &stubAuthRepo{
+		GetUserByUsernameFn: func(_ context.Context, username string) (db.GetUserByUsernameRow, error) {
+			return db.GetUserByUsernameRow{ID: userID, Username: username, Password: string(hashedPassword)}, nil
+		},
+	}
+	repo.GetSessionByIDFn = func(_ context.Context, id pgtype.UUID) (db.Session, error) {
+		for _, session := range repo.sessions {
+			if session.ID == id {
+				return session, nil
+			}
+		}
+		return db.Session{}, pgx.ErrNoRows
+	}
+	service := NewAuthService(repo, newTestKeySet(t), nil, nil, AuthPolicy{})
+
+	login := func() *models.UserLoginResponseDTO {
+		response, err := service.Login(ctx, models.UserLoginRequest{Identifier: "alice", Password: "password"})
+		require.NoError(t, err)
+		return response
+	}
+	laptop, phone, tablet := login(), login(), login()
+
+	require.NoError(t, service.Logout(ctx, laptop.AccessToken))
+	require.Len(t, repo.deletedSessions, 1)
+
+	introspection, err := service.IntrospectToken(ctx, laptop.AccessToken)
+	require.NoError(t, err)
+	require.False(t, introspection.Active)
+	require.True(t, introspection.Revoked)
+	_, err = service.ListSessions(ctx, laptop.AccessToken)
+	require.ErrorIs(t, err, ErrInvalidAccessToken)
+
+	introspection, err = service.IntrospectToken(ctx, phone.AccessToken)
+	require.NoError(t, err)
+	require.True(t, introspection.Active)
+
+	require.NoError(t, service.LogoutFromAllDevices(ctx, phone.AccessToken))
+	require.Equal(t, []pgtype.UUID{userID}, repo.signedOutUsers)
+
+	for _, token := range []string{phone.AccessToken, tablet.AccessToken} {
+		introspection, err = service.IntrospectToken(ctx, token)
+		require.NoError(t, err)
+		require.False(t, introspection.Active)
+		require.True(t, introspection.Revoked)
+	}
+	require.ErrorIs(t, service.LogoutFromAllDevices(ctx, tablet.AccessToken), ErrInvalidAccessToken)
+
+	// Signing in again right away, within the same second, works
+	introspection, err = service.IntrospectToken(ctx, login().AccessToken)
+	require.NoError(t, err)
+	require.True(t, introspection.Active)
+}
+
+func TestAuthService_LifecycleEventsAreWrittenToTheOutbox(t *testing.T) {
+	ctx := context.Background()
+
+	// Assigned once Register created the user
+	var userID pgtype.UUID
+
+	repo := &stubAuthRepo{}
+	repo.GetUserByEmailFn = func(context.Context, string) (db.GetUserByEmailRow, error) {
+		return db.GetUserByEmailRow{}, pgx.ErrNoRows
+	}
+	repo.GetUserByUsernameFn = func(_ context.Context, username string) (db.GetUserByUsernameRow, error) {
+		if len(repo.createdUsers) == 0 {
+			return db.GetUserByUsernameRow{}, pgx.ErrNoRows
+		}
+		return db.GetUserByUsernameRow{ID: userID, Username: username, Password: repo.createdUsers[0].Password}, nil
+	}
+	repo.GetUserByIdFn = func(_ context.Context, id pgtype.UUID) (db.GetUserByIdRow, error) {
+		return db.GetUserByIdRow{ID: id, Password: repo.createdUsers[0].Password}, nil
+	}
+	repo.GetSessionByIDFn = func(_ context.Context, id pgtype.UUID) (db.Session, error) {
+		for _, session := range repo.sessions {
+			if session.ID == id {
+				return session, nil
+			}
+		}
+		return db.Session{}, pgx.ErrNoRows
+	}
+	keys := newTestKeySet(t)
+	service := NewAuthService(repo, keys, &stubMailSender{}, nil, AuthPolicy{})
+
+	registered, err := service.Register(ctx, models.CreateUserRequest{Username: "alice", Email: "alice@example.com", Password: "password"})
+	require.NoError(t, err)
+	require.Equal(t, []string{events.UserRegisteredType}, repo.outboxEventTypes())
+	userID = toPgUUID(uuid.MustParse(registered.ID))
+
+	var registeredEvent events.UserRegisteredEvent
+	require.NoError(t, json.Unmarshal(repo.outboxEvents[0].Payload, &registeredEvent))
+	require.Equal(t, registered.ID, registeredEvent.UserID)
+	require.Equal(t, "alice", registeredEvent.Username)
+	require.Equal(t, registeredEvent.ID, uuid.UUID(repo.outboxEvents[0].ID.Bytes).String())
+	require.Equal(t, registered.ID, uuid.UUID(repo.outboxEvents[0].AggregateID.Bytes).String())
+
+	// A failed login does not produce an event
+	_, err = service.Login(ctx, models.UserLoginRequest{Identifier: "alice", Password: "wrong-password"})
+	require.Error(t, err)
+	login, err := service.Login(ctx, models.UserLoginRequest{Identifier: "alice", Password: "password", Client: models.ClientInfo{UserAgent: "Firefox"}})
+	require.NoError(t, err)
+
+	var loggedInEvent events.UserLoggedInEvent
+	require.NoError(t, json.Unmarshal(repo.outboxEvents[1].Payload, &loggedInEvent))
+	require.Equal(t, uuid.UUID(repo.sessions[0].ID.Bytes).String(), loggedInEvent.SessionID)
+	require.Equal(t, "Firefox", loggedInEvent.UserAgent)
+
+	require.NoError(t, service.ChangePassword(ctx, login.AccessToken, "password", "new-password", false))
+
+	var passwordEvent events.UserPasswordChangedEvent
+	require.NoError(t, json.Unmarshal(repo.outboxEvents[2].Payload, &passwordEvent))
+	require.Equal(t, events.PasswordChangedByUser, passwordEvent.Reason)
+
+	admin, err := utils.GenerateToken(utils.TokenSubject{UserID: uuid.NewString(), Permissions: []string{authz.PermissionDeleteUsers}}, keys)
+	require.NoError(t, err)
+	require.NoError(t, service.DeleteUser(ctx, admin.AccessToken, userID))
+	require.ErrorIs(t, service.DeleteUser(ctx, admin.AccessToken, userID), ErrUserNotFound)
+
+	require.Equal(t, []string{
+		events.UserRegisteredType,
+		events.UserLoggedInType,
+		events.UserPasswordChangedType,
+		events.UserDeletedType,
+	}, repo.outboxEventTypes())
+}
+
+func TestAuthService_LoginHistoryAndAuditExport(t *testing.T) {
+	ctx := context.Background()
+	adminID := toPgUUID(uuid.New())
+	userID := toPgUUID(uuid.New())
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	repo := &stubAuthRepo{
+		userRoles: map[pgtype.UUID][]string{adminID: {authz.RoleAdmin}},
+		GetUserByUsernameFn: func(_ context.Context, username string) (db.GetUserByUsernameRow, error) {
+			id := userID
+			if username == "admin" {
+				id = adminID
+			}
+			return db.GetUserByUsernameRow{ID: id, Username: username, Password: string(hashedPassword)}, nil
+		},
+		GetUserByIdFn: func(_ context.Context, id pgtype.UUID) (db.GetUserByIdRow, error) {
+			username := "alice"
+			if id == adminID {
+				username = "admin"
+			}
+			return db.GetUserByIdRow{ID: id, Username: username}, nil
+		},
+	}
+	repo.GetSessionByIDFn = func(_ context.Context, id pgtype.UUID) (db.Session, error) {
+		for _, session := range repo.sessions {
+			if session.ID == id {
+				return session, nil
+			}
+		}
+		return db.Session{}, pgx.ErrNoRows
+	}
+	service := NewAuthService(repo, newTestKeySet(t), nil, nil, AuthPolicy{})
+
+	_, err = service.Login(ctx, models.UserLoginRequest{
+		Identifier: "alice",
+		Password:   "wrong",
+		Client:     models.ClientInfo{UserAgent: "=HYPERLINK(\"evil\")", IPAddress: "203.0.113.9"},
+	})
+	require.Error(t, err)
+	alice, err := service.Login(ctx, models.UserLoginRequest{
+		Identifier: "alice",
+		Password:   "password",
+		Client:     models.ClientInfo{UserAgent: "Firefox", IPAddress: "198.51.100.1"},
+	})
+	require.NoError(t, err)
+	admin, err := service.Login(ctx, models.UserLoginRequest{Identifier: "admin", Password: "password"})
+	require.NoError(t, err)
+
+	// The history only covers the caller, newest first
+	history, err := service.GetLoginHistory(ctx, alice.AccessToken, 0)
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	require.True(t, history[0].Success)
+	require.Equal(t, "198.51.100.1", history[0].IPAddress)
+	require.Equal(t, "Firefox", history[0].UserAgent)
+	require.False(t, history[1].Success)
+	require.Equal(t, "203.0.113.9", history[1].IPAddress)
+
+	history, err = service.GetLoginHistory(ctx, alice.AccessToken, 1)
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+
+	request := models.LoginAttemptExportRequest{Format: ExportFormatCSV, Username: "alice"}
+	_, err = service.ExportLoginAttempts(ctx, alice.AccessToken, request)
+	require.ErrorIs(t, err, authz.ErrPermissionDenied)
+
+	export, err := service.ExportLoginAttempts(ctx, admin.AccessToken, request)
+	require.NoError(t, err)
+	require.Equal(t, "text/csv", export.ContentType)
+	require.Equal(t, 2, export.Count)
+	rows, err := csv.NewReader(bytes.NewReader(export.Data)).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, rows, 3)
+	require.Equal(t, []string{"id", "username", "success", "ip_address", "user_agent", "attempted_at"}, rows[0])
+	require.Equal(t, "false", rows[1][2])
+	require.Equal(t, "'=HYPERLINK(\"evil\")", rows[1][4])
+	require.Equal(t, "true", rows[2][2])
+
+	export, err = service.ExportLoginAttempts(ctx, admin.AccessToken, models.LoginAttemptExportRequest{Format: ExportFormatJSONL})
+	require.NoError(t, err)
+	require.Equal(t, 3, export.Count)
+	lines := strings.Split(strings.TrimSpace(string(export.Data)), "\n")
+	require.Len(t, lines, 3)
+	var entry models.LoginAttemptDTO
+	require.NoError(t, json.Unmarshal([]byte(lines[2]), &entry))
+	require.Equal(t, "admin", entry.Username)
+
+	// Attempts outside the range are left out
+	export, err = service.ExportLoginAttempts(ctx, admin.AccessToken, models.LoginAttemptExportRequest{
+		Format: ExportFormatJSONL,
+		From:   time.Now().Add(-2 * time.Hour),
+		To:     time.Now().Add(-time.Hour),
+	})
+	require.NoError(t, err)
+	require.Zero(t, export.Count)
+	require.Empty(t, export.Data)
+
+	_, err = service.ExportLoginAttempts(ctx, admin.AccessToken, models.LoginAttemptExportRequest{Format: "xlsx"})
+	require.ErrorIs(t, err, ErrUnsupportedExportFormat)
+	_, err = service.ExportLoginAttempts(ctx, admin.AccessToken, models.LoginAttemptExportRequest{
+		Format: ExportFormatCSV,
+		From:   time.Now(),
+		To:     time.Now().Add(-time.Hour),
+	})
+	require.ErrorIs(t, err, ErrInvalidExportRange)
+}
+
+func TestAuthService_MagicLinkLogin(t *testing.T) {
+	ctx := context.Background()
+	userID := toPgUUID(uuid.New())
+
+	repo := &stubAuthRepo{
+		GetUserByEmailFn: func(_ context.Context, email string) (db.GetUserByEmailRow, error) {
+			if email != "alice@example.com" {
+				return db.GetUserByEmailRow{}, pgx.ErrNoRows
+			}
+			return db.GetUserByEmailRow{ID: userID, Username: "alice", Email: email}, nil
+		},
+		GetUserByIdFn: func(_ context.Context, id pgtype.UUID) (db.GetUserByIdRow, error) {
+			return db.GetUserByIdRow{ID: id, Username: "alice", Email: "alice@example.com"}, nil
+		},
+	}
+	repo.GetSessionByIDFn = func(_ context.Context, id pgtype.UUID) (db.Session, error) {
+		for _, session := range repo.sessions {
+			if session.ID == id {
+				return session, nil
+			}
+		}
+		return db.Session{}, pgx.ErrNoRows
+	}
+	sender := &stubMailSender{}
+	policy := AuthPolicy{
+		EmailVerification: EmailVerificationPolicy{Required: true},
+		MagicLink: MagicLinkPolicy{
+			TokenTTL:      15 * time.Minute,
+			LoginURL:      "https://soul-connect.test/magic-link?token=",
+			RequestLimit:  2,
+			RequestWindow: time.Hour,
+		},
+	}
+	service := NewAuthService(repo, newTestKeySet(t), sender, nil, policy)
+
+	// Unknown emails succeed silently and send nothing
+	require.NoError(t, service.RequestMagicLink(ctx, "nobody@example.com"))
+	require.Empty(t, sender.sent)
+
+	require.NoError(t, service.RequestMagicLink(ctx, "alice@example.com"))
+	require.NoError(t, service.RequestMagicLink(ctx, "alice@example.com"))
+	require.Len(t, sender.sent, 2)
+
+	// Over the limit nothing is sent, but the caller cannot tell
+	require.NoError(t, service.RequestMagicLink(ctx, "alice@example.com"))
+	require.Len(t, sender.sent, 2)
+
+	linkToken := func(message mailer.Message) string {
+		_, token, found := strings.Cut(message.Body, policy.MagicLink.LoginURL)
+		require.True(t, found)
+		return strings.Fields(token)[0]
+	}
+
+	// Requesting a new link invalidates the previous one
+	_, err := service.ConsumeMagicLink(ctx, linkToken(sender.sent[0]), models.ClientInfo{})
+	require.ErrorIs(t, err, ErrInvalidMagicLinkToken)
+
+	client := models.ClientInfo{UserAgent: "Firefox", IPAddress: "198.51.100.1"}
+	response, err := service.ConsumeMagicLink(ctx, linkToken(sender.sent[1]), client)
+	require.NoError(t, err)
+	require.NotEmpty(t, response.AccessToken)
+	require.NotEmpty(t, response.RefreshToken)
+	require.Equal(t, []pgtype.UUID{userID}, repo.verifiedUsers)
+	require.Len(t, repo.createdSessions, 1)
+	require.Equal(t, "Firefox", repo.createdSessions[0].UserAgent)
+	require.Contains(t, repo.outboxEventTypes(), events.UserLoggedInType)
+
+	claims, err := service.IntrospectToken(ctx, response.AccessToken)
+	require.NoError(t, err)
+	require.True(t, claims.Active)
+	require.True(t, claims.EmailVerified)
+
+	_, err = service.ConsumeMagicLink(ctx, linkToken(sender.sent[1]), client)
+	require.ErrorIs(t, err, ErrInvalidMagicLinkToken)
+}
+
+func TestAuthService_OAuthAuthorizationCodeFlow(t *testing.T) {
+	ctx := context.Background()
+	userID := toPgUUID(uuid.New())
+	sessionID := toPgUUID(uuid.New())
+
+	keys := newTestKeySet(t)
+	userTokens, err := utils.GenerateToken(utils.TokenSubject{UserID: uuid.UUID(userID.Bytes).String(), SessionID: uuid.UUID(sessionID.Bytes).String()}, keys)
+	require.NoError(t, err)
+
+	repo := &stubAuthRepo{
+		sessions: []db.Session{{ID: sessionID, UserID: userID, SessionToken: userTokens.RefreshToken}},
+		GetUserByIdFn: func(_ context.Context, id pgtype.UUID) (db.GetUserByIdRow, error) {
+			return db.GetUserByIdRow{ID: id, Username: "alice", Email: "alice@example.com", EmailVerified: true}, nil
+		},
+	}
+	repo.RotateSessionTokenFn = func(_ context.Context, arg db.RotateSessionTokenParams) (int64, error) {
+		for i := range repo.sessions {
+			if repo.sessions[i].ID == arg.ID && repo.sessions[i].SessionToken == arg.OldToken {
+				repo.sessions[i].SessionToken = arg.NewToken
+				return 1, nil
+			}
+		}
+		return 0, nil
+	}
+	repo.GetSessionByIDFn = func(_ context.Context, id pgtype.UUID) (db.Session, error) {
+		for _, session := range repo.sessions {
+			if session.ID == id {
+				return session, nil
+			}
+		}
+		return db.Session{}, pgx.ErrNoRows
+	}
+	repo.GetSessionByTokenFn = func(_ context.Context, sessionToken string) (db.Session, error) {
+		for _, session := range repo.sessions {
+			if session.SessionToken == sessionToken {
+				return session, nil
+			}
+		}
+		return db.Session{}, pgx.ErrNoRows
+	}
+	repo.GetRotatedSessionTokenFn = func(context.Context, string) (db.GetRotatedSessionTokenRow, error) {
+		return db.GetRotatedSessionTokenRow{}, pgx.ErrNoRows
+	}
+	service := NewAuthService(repo, keys, nil, nil, AuthPolicy{OAuth: OAuthPolicy{CodeTTL: time.Minute}})
+
+	_, err = service.RegisterOAuthClient(ctx, userTokens.AccessToken, models.RegisterOAuthClientRequest{
+		Name:         "Planner",
+		RedirectURIs: []string{"http://planner.example/callback"},
+		Scopes:       []string{"posts:read"},
+	})
+	require.ErrorIs(t, err, ErrInvalidRedirectURIs)
+
+	client, err := service.RegisterOAuthClient(ctx, userTokens.AccessToken, models.RegisterOAuthClientRequest{
+		Name:         "Planner",
+		RedirectURIs: []string{"https://planner.example/callback"},
+		Scopes:       []string{"posts:read", "posts:write"},
+		Confidential: true,
+	})
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(client.ClientSecret, OAuthClientSecretPrefix))
+	require.NotEqual(t, client.ClientSecret, repo.oauthClients[0].ClientSecretHash)
+
+	verifier := strings.Repeat("verifier-", 6)
+	challengeSum := sha256.Sum256([]byte(verifier))
+	authorization := models.OAuthAuthorizationRequest{
+		ClientID:            client.ClientID,
+		RedirectURI:         "https://planner.example/callback",
+		Scopes:              []string{"posts:write"},
+		State:               "xyz",
+		CodeChallenge:       base64.RawURLEncoding.EncodeToString(challengeSum[:]),
+		CodeChallengeMethod: CodeChallengeMethodS256,
+	}
+
+	for name, tc := range map[string]struct {
+		mutate func(request *models.OAuthAuthorizationRequest)
+		err    error
+	}{
+		"unknown client":        {func(r *models.OAuthAuthorizationRequest) { r.ClientID = uuid.NewString() }, ErrInvalidOAuthClient},
+		"unregistered redirect": {func(r *models.OAuthAuthorizationRequest) { r.RedirectURI = "https://evil.example/callback" }, ErrInvalidRedirectURI},
+		"plain challenge":       {func(r *models.OAuthAuthorizationRequest) { r.CodeChallengeMethod = "plain" }, ErrInvalidCodeChallenge},
+		"unregistered scope":    {func(r *models.OAuthAuthorizationRequest) { r.Scopes = []string{"users:write"} }, ErrInvalidOAuthScope},
+	} {
+		request := authorization
+		tc.mutate(&request)
+		_, err := service.AuthorizeOAuthClient(ctx, userTokens.AccessToken, request)
+		require.ErrorIs(t, err, tc.err, name)
+	}
+
+	authorize := func() string {
+		granted, err := service.AuthorizeOAuthClient(ctx, userTokens.AccessToken, authorization)
+		require.NoError(t, err)
+		redirectTo, err := url.Parse(granted.RedirectTo)
+		require.NoError(t, err)
+		require.Equal(t, "xyz", redirectTo.Query().Get("state"))
+		require.Equal(t, granted.Code, redirectTo.Query().Get("code"))
+		return granted.Code
+	}
+	exchange := models.OAuthTokenRequest{
+		GrantType:    GrantTypeAuthorizationCode,
+		ClientID:     client.ClientID,
+		ClientSecret: client.ClientSecret,
+		Code:         authorize(),
+		RedirectURI:  authorization.RedirectURI,
+		CodeVerifier: verifier,
+	}
+
+	wrongSecret := exchange
+	wrongSecret.ClientSecret = "scs_wrong"
+	_, err = service.ExchangeOAuthToken(ctx, wrongSecret, models.ClientInfo{})
+	require.ErrorIs(t, err, ErrInvalidOAuthClient)
+
+	// A wrong verifier burns the code
+	wrongVerifier := exchange
+	wrongVerifier.CodeVerifier = strings.Repeat("x", 43)
+	_, err = service.ExchangeOAuthToken(ctx, wrongVerifier, models.ClientInfo{})
+	require.ErrorIs(t, err, ErrInvalidGrant)
+	_, err = service.ExchangeOAuthToken(ctx, exchange, models.ClientInfo{})
+	require.ErrorIs(t, err, ErrInvalidGrant)
+
+	exchange.Code = authorize()
+	tokens, err := service.ExchangeOAuthToken(ctx, exchange, models.ClientInfo{IPAddress: "203.0.113.7"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"posts:write"}, tokens.Scopes)
+	require.Equal(t, utils.MaxAge, tokens.ExpiresIn)
+	require.Equal(t, "OAuth app: Planner", repo.createdSessions[0].UserAgent)
+
+	_, err = service.ExchangeOAuthToken(ctx, exchange, models.ClientInfo{})
+	require.ErrorIs(t, err, ErrInvalidGrant)
+
+	introspection, err := service.IntrospectToken(ctx, tokens.AccessToken)
+	require.NoError(t, err)
+	require.True(t, introspection.Active)
+	require.Equal(t, OAuthAccessTokenType, introspection.TokenType)
+	require.Equal(t, client.ClientID, introspection.ClientID)
+	require.Equal(t, []string{"posts:write"}, introspection.Scopes)
+	require.Empty(t, introspection.Permissions)
+
+	// Apps reach the API only, not the account of the user
+	_, err = service.ListSessions(ctx, tokens.AccessToken)
+	require.ErrorIs(t, err, ErrInvalidAccessToken)
+	_, err = service.RefreshToken(ctx, tokens.RefreshToken)
+	require.ErrorIs(t, err, ErrInvalidRefreshToken)
+
+	refreshed, err := service.ExchangeOAuthToken(ctx, models.OAuthTokenRequest{
+		GrantType:    GrantTypeRefreshToken,
+		ClientID:     client.ClientID,
+		ClientSecret: client.ClientSecret,
+		RefreshToken: tokens.RefreshToken,
+	}, models.ClientInfo{})
+	require.NoError(t, err)
+	require.Equal(t, []string{"posts:write"}, refreshed.Scopes)
+
+	consents, err := service.ListOAuthConsents(ctx, userTokens.AccessToken)
+	require.NoError(t, err)
+	require.Len(t, consents, 1)
+	require.Equal(t, "Planner", consents[0].ClientName)
+
+	require.NoError(t, service.RevokeOAuthConsent(ctx, userTokens.AccessToken, client.ClientID))
+
+	introspection, err = service.IntrospectToken(ctx, refreshed.AccessToken)
+	require.NoError(t, err)
+	require.False(t, introspection.Active)
+
+	// The session of the grant ends with the consent
+	_, err = service.ExchangeOAuthToken(ctx, models.OAuthTokenRequest{
+		GrantType:    GrantTypeRefreshToken,
+		ClientID:     client.ClientID,
+		ClientSecret: client.ClientSecret,
+		RefreshToken: refreshed.RefreshToken,
+	}, models.ClientInfo{})
+	require.ErrorIs(t, err, ErrInvalidGrant)
+	require.Equal(t, []pgtype.UUID{repo.createdSessions[0].ID}, repo.deletedSessions)
+}
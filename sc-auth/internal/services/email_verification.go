@@ -0,0 +1,105 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	db "soul-connect/sc-auth/internal/db/sqlc"
+	"soul-connect/sc-auth/internal/mailer"
+	"time"
+)
+
+// EmailVerificationPolicy configures verification tokens and whether Login requires a verified email.
+// When Required is false unverified users can still log in and the state is only reported in the token claims.
+type EmailVerificationPolicy struct {
+	Required     bool
+	TokenTTL     time.Duration
+	VerifyURL    string
+	ResendLimit  int
+	ResendWindow time.Duration
+}
+
+var (
+	ErrEmailNotVerified          = errors.New("email address is not verified")
+	ErrInvalidVerificationToken  = errors.New("invalid or expired email verification token")
+	ErrVerificationResendLimited = errors.New("too many verification emails requested, try again later")
+)
+
+// VerifyEmail confirms the email address the verification token was sent to.
+func (s *AuthService) VerifyEmail(ctx context.Context, token string) error {
+	userID, err := s.authRepo.ConsumeEmailVerificationToken(ctx, hashOneTimeToken(token))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrInvalidVerificationToken
+		}
+		return err
+	}
+
+	if err := s.authRepo.MarkEmailVerified(ctx, userID); err != nil {
+		return err
+	}
+
+	return s.authRepo.InvalidateEmailVerificationTokens(ctx, userID)
+}
+
+// ResendVerificationEmail sends a new verification token, limited to ResendLimit emails per ResendWindow.
+// Unknown and already verified addresses are not reported.
+func (s *AuthService) ResendVerificationEmail(ctx context.Context, email string) error {
+	user, err := s.authRepo.GetUserByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil
+		}
+		return err
+	}
+	if user.EmailVerified {
+		return nil
+	}
+
+	policy := s.policy.EmailVerification
+	if policy.ResendLimit > 0 {
+		sent, err := s.authRepo.CountEmailVerificationTokensSince(ctx, db.CountEmailVerificationTokensSinceParams{
+			UserID: user.ID,
+			Since:  pgtype.Timestamp{Time: time.Now().UTC().Add(-policy.ResendWindow), Valid: true},
+		})
+		if err != nil {
+			return err
+		}
+		if sent >= int64(policy.ResendLimit) {
+			return ErrVerificationResendLimited
+		}
+	}
+
+	return s.sendVerificationEmail(ctx, user.ID, user.Username, user.Email)
+}
+
+// sendVerificationEmail issues a new verification token, replacing any earlier one.
+func (s *AuthService) sendVerificationEmail(ctx context.Context, userID pgtype.UUID, username string, email string) error {
+	token, tokenHash, err := generateOneTimeToken()
+	if err != nil {
+		return errors.New("failed to generate email verification token")
+	}
+
+	if err := s.authRepo.InvalidateEmailVerificationTokens(ctx, userID); err != nil {
+		return err
+	}
+
+	expiresAt := time.Now().UTC().Add(s.policy.EmailVerification.TokenTTL)
+	err = s.authRepo.CreateEmailVerificationToken(ctx, db.CreateEmailVerificationTokenParams{
+		UserID:    userID,
+		TokenHash: tokenHash,
+		ExpiresAt: pgtype.Timestamp{Time: expiresAt, Valid: true},
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.mailSender.Send(ctx, mailer.Message{
+		To:      email,
+		Subject: "Confirm your Soul Connect email",
+		Body: fmt.Sprintf("Hi %s,\n\nPlease confirm your email address using the link below. It expires in %s.\n\n%s%s",
+			username, s.policy.EmailVerification.TokenTTL, s.policy.EmailVerification.VerifyURL, token),
+	})
+}
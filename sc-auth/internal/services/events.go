@@ -0,0 +1,35 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	db "soul-connect/sc-auth/internal/db/sqlc"
+	"soul-connect/sc-auth/internal/events"
+)
+
+// enqueueEvent writes an event to the outbox. It has to get the repository of the
+// transaction making the change, so the event is published exactly when the change commits.
+func enqueueEvent(ctx context.Context, repo IAuthRepository, event events.Event) error {
+	eventID, err := uuid.Parse(event.EventID())
+	if err != nil {
+		return err
+	}
+	aggregateID, err := uuid.Parse(event.AggregateID())
+	if err != nil {
+		return err
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	return repo.CreateOutboxEvent(ctx, db.CreateOutboxEventParams{
+		ID:          pgtype.UUID{Bytes: eventID, Valid: true},
+		EventType:   event.EventType(),
+		AggregateID: pgtype.UUID{Bytes: aggregateID, Valid: true},
+		Payload:     payload,
+	})
+}
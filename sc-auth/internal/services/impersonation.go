@@ -0,0 +1,152 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"log"
+	db "soul-connect/sc-auth/internal/db/sqlc"
+	"soul-connect/sc-auth/internal/models"
+	"soul-connect/sc-auth/internal/utils"
+	"soul-connect/sc-auth/pkg/authz"
+	"strings"
+	"time"
+)
+
+// ImpersonationPolicy configures the tokens admins receive to act as another user.
+// TokenTTL is capped at the access token lifetime, so revoking all tokens of a user
+// also covers the impersonation tokens issued for them.
+type ImpersonationPolicy struct {
+	TokenTTL time.Duration
+}
+
+const (
+	maxImpersonationReasonLength = 500
+
+	defaultImpersonationSessionsLimit = 50
+	maxImpersonationSessionsLimit     = 500
+)
+
+var (
+	ErrImpersonationReasonRequired = errors.New("a reason is required to impersonate a user")
+	ErrImpersonationReasonTooLong  = errors.New("impersonation reason must be at most 500 characters long")
+	ErrCannotImpersonateSelf       = errors.New("admins cannot impersonate themselves")
+	ErrCannotImpersonateStaff      = errors.New("users with a role cannot be impersonated")
+)
+
+// Impersonate issues a short-lived access token for the user that names the calling admin
+// in its act claim. Every impersonation is recorded with its reason before the token is
+// returned. The token carries no roles and cannot be refreshed, and sc-auth does not accept
+// it for account management, so it is only good for the API routes the user can reach.
+func (s *AuthService) Impersonate(ctx context.Context, accessToken string, userID string, reason string, client models.ClientInfo) (*models.ImpersonationDTO, error) {
+	caller, err := s.authorize(ctx, accessToken, authz.PermissionImpersonateUsers)
+	if err != nil {
+		return nil, err
+	}
+
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return nil, ErrImpersonationReasonRequired
+	}
+	if len([]rune(reason)) > maxImpersonationReasonLength {
+		return nil, ErrImpersonationReasonTooLong
+	}
+
+	targetID, err := s.existingUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if targetID == caller.UserID {
+		return nil, ErrCannotImpersonateSelf
+	}
+
+	// Acting as another admin or moderator would hand out permissions through the back door
+	roles, err := s.authRepo.GetUserRoles(ctx, targetID)
+	if err != nil {
+		return nil, err
+	}
+	if len(roles) > 0 {
+		return nil, ErrCannotImpersonateStaff
+	}
+
+	actor, err := s.authRepo.GetUserById(ctx, caller.UserID)
+	if err != nil {
+		return nil, err
+	}
+	target, err := s.authRepo.GetUserById(ctx, targetID)
+	if err != nil {
+		return nil, err
+	}
+
+	ttl := s.policy.Impersonation.TokenTTL
+	if ttl <= 0 || ttl > utils.MaxAge {
+		ttl = utils.MaxAge
+	}
+
+	token, claims, err := utils.GenerateImpersonationToken(utils.TokenSubject{
+		UserID:        uuid.UUID(target.ID.Bytes).String(),
+		EmailVerified: target.EmailVerified,
+		ActorID:       caller.Principal.UserID,
+	}, ttl, s.keys)
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := s.authRepo.CreateImpersonationSession(ctx, db.CreateImpersonationSessionParams{
+		ActorID:        actor.ID,
+		ActorUsername:  actor.Username,
+		TargetID:       target.ID,
+		TargetUsername: target.Username,
+		Reason:         reason,
+		TokenID:        claims.ID,
+		IpAddress:      client.IPAddress,
+		UserAgent:      client.UserAgent,
+		ExpiresAt:      pgtype.Timestamp{Time: claims.ExpiresAt.Time.UTC(), Valid: true},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("user %s started impersonating user %s: %s", actor.Username, target.Username, reason)
+
+	return &models.ImpersonationDTO{
+		SessionID:   uuid.UUID(session.ID.Bytes).String(),
+		AccessToken: token,
+		ExpiresAt:   claims.ExpiresAt.Time,
+	}, nil
+}
+
+// ListImpersonationSessions returns the most recent impersonations, newest first.
+// A limit outside 1..500 falls back to the default of 50.
+func (s *AuthService) ListImpersonationSessions(ctx context.Context, accessToken string, limit int) ([]models.ImpersonationSessionDTO, error) {
+	if _, err := s.authorize(ctx, accessToken, authz.PermissionReadAuditLog); err != nil {
+		return nil, err
+	}
+
+	if limit <= 0 || limit > maxImpersonationSessionsLimit {
+		limit = defaultImpersonationSessionsLimit
+	}
+
+	sessions, err := s.authRepo.ListImpersonationSessions(ctx, int32(limit))
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]models.ImpersonationSessionDTO, 0, len(sessions))
+	for _, session := range sessions {
+		result = append(result, models.ImpersonationSessionDTO{
+			ID:             uuid.UUID(session.ID.Bytes).String(),
+			ActorID:        uuid.UUID(session.ActorID.Bytes).String(),
+			ActorUsername:  session.ActorUsername,
+			TargetID:       uuid.UUID(session.TargetID.Bytes).String(),
+			TargetUsername: session.TargetUsername,
+			Reason:         session.Reason,
+			IPAddress:      session.IpAddress,
+			UserAgent:      session.UserAgent,
+			StartedAt:      session.StartedAt.Time.UTC(),
+			ExpiresAt:      session.ExpiresAt.Time.UTC(),
+		})
+	}
+	return result, nil
+}
@@ -0,0 +1,144 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	db "soul-connect/sc-auth/internal/db/sqlc"
+	"soul-connect/sc-auth/pkg/authz"
+	"time"
+)
+
+// LockoutPolicy configures how many failed logins lock an account and for how long.
+// Every lockout that follows another without a successful login in between doubles
+// the duration, up to MaxDuration.
+type LockoutPolicy struct {
+	MaxAttempts int
+	Window      time.Duration
+	Duration    time.Duration
+	MaxDuration time.Duration
+}
+
+var ErrAccountNotLocked = errors.New("account is not locked")
+
+// AccountLockedError is returned by Login while an account is locked.
+type AccountLockedError struct {
+	Until time.Time
+}
+
+func (e *AccountLockedError) Error() string {
+	return fmt.Sprintf("account is locked until %s", e.Until.UTC().Format(time.RFC3339))
+}
+
+// RetryAfter is how long the caller has to wait before the account accepts logins again.
+func (e *AccountLockedError) RetryAfter() time.Duration {
+	return time.Until(e.Until)
+}
+
+func (p LockoutPolicy) enabled() bool {
+	return p.MaxAttempts > 0 && p.Duration > 0
+}
+
+// lockoutDuration doubles the base duration for every earlier lockout.
+func (p LockoutPolicy) lockoutDuration(previousLockouts int32) time.Duration {
+	duration := p.Duration
+	for i := int32(0); i < previousLockouts; i++ {
+		duration *= 2
+		if p.MaxDuration > 0 && duration >= p.MaxDuration {
+			return p.MaxDuration
+		}
+	}
+	return duration
+}
+
+// checkAccountLockout returns an AccountLockedError while the account is locked,
+// along with the current lockout row when there is one.
+func (s *AuthService) checkAccountLockout(ctx context.Context, username string) (*db.AccountLockout, error) {
+	if !s.policy.Lockout.enabled() {
+		return nil, nil
+	}
+
+	lockout, err := s.authRepo.GetAccountLockout(ctx, username)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	if time.Now().Before(lockout.LockedUntil.Time) {
+		return &lockout, &AccountLockedError{Until: lockout.LockedUntil.Time}
+	}
+	return &lockout, nil
+}
+
+// registerFailedLogin locks the account once the latest attempts are all failures
+// within the window. Failures from before the previous lockout or unlock are not counted.
+func (s *AuthService) registerFailedLogin(ctx context.Context, username string, lockout *db.AccountLockout) error {
+	if !s.policy.Lockout.enabled() {
+		return nil
+	}
+
+	attempts, err := s.authRepo.GetLoginAttemptsByUsername(ctx, db.GetLoginAttemptsByUsernameParams{
+		Username:     username,
+		RequestLimit: int32(s.policy.Lockout.MaxAttempts),
+	})
+	if err != nil {
+		return err
+	}
+
+	since := time.Now().Add(-s.policy.Lockout.Window)
+	if lockout != nil && lockout.UpdatedAt.Time.After(since) {
+		since = lockout.UpdatedAt.Time
+	}
+
+	failures := 0
+	for _, attempt := range attempts {
+		if attempt.Success || !attempt.AttemptTime.Time.After(since) {
+			break
+		}
+		failures++
+	}
+	if failures < s.policy.Lockout.MaxAttempts {
+		return nil
+	}
+
+	var previousLockouts int32
+	if lockout != nil {
+		previousLockouts = lockout.LockoutCount
+	}
+
+	lockedUntil := time.Now().UTC().Add(s.policy.Lockout.lockoutDuration(previousLockouts))
+	locked, err := s.authRepo.LockAccount(ctx, db.LockAccountParams{
+		Username:    username,
+		LockedUntil: pgtype.Timestamp{Time: lockedUntil, Valid: true},
+	})
+	if err != nil {
+		return err
+	}
+	return &AccountLockedError{Until: locked.LockedUntil.Time}
+}
+
+// UnlockAccount lifts a lockout and resets the backoff for the account.
+// Only callers with the users:unlock permission may do so.
+func (s *AuthService) UnlockAccount(ctx context.Context, accessToken string, username string) error {
+	if _, err := s.authorize(ctx, accessToken, authz.PermissionUnlockUsers); err != nil {
+		return err
+	}
+
+	unlocked, err := s.authRepo.UnlockAccount(ctx, username)
+	if err != nil {
+		return err
+	}
+	if unlocked == 0 {
+		return ErrAccountNotLocked
+	}
+	return nil
+}
+
+// CleanUpLoginAttempts drops login attempts that are too old to matter for lockouts.
+func (s *AuthService) CleanUpLoginAttempts(ctx context.Context) error {
+	return s.authRepo.DeleteOldLoginAttempts(ctx)
+}
@@ -0,0 +1,189 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	db "soul-connect/sc-auth/internal/db/sqlc"
+	"soul-connect/sc-auth/internal/models"
+	"soul-connect/sc-auth/pkg/authz"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Formats of the login audit export.
+const (
+	ExportFormatCSV   = "csv"
+	ExportFormatJSONL = "jsonl"
+)
+
+const (
+	defaultLoginHistoryLimit = 20
+	maxLoginHistoryLimit     = 100
+
+	defaultExportRange = 7 * 24 * time.Hour
+	// maxExportRows keeps an export within a single gRPC message
+	maxExportRows = 5000
+)
+
+var (
+	ErrUnsupportedExportFormat = errors.New("unsupported export format, use csv or jsonl")
+	ErrInvalidExportRange      = errors.New("export range must end after it starts")
+	ErrExportTooLarge          = fmt.Errorf("export is limited to %d login attempts, narrow the time range", maxExportRows)
+)
+
+// GetLoginHistory returns the most recent sign-in attempts of the caller, newest first.
+// A limit outside 1..100 falls back to the default of 20.
+func (s *AuthService) GetLoginHistory(ctx context.Context, accessToken string, limit int) ([]models.LoginAttemptDTO, error) {
+	caller, err := s.authenticate(ctx, accessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.authRepo.GetUserById(ctx, caller.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	if limit <= 0 || limit > maxLoginHistoryLimit {
+		limit = defaultLoginHistoryLimit
+	}
+
+	attempts, err := s.authRepo.GetLoginAttemptsByUsername(ctx, db.GetLoginAttemptsByUsernameParams{
+		Username:     user.Username,
+		RequestLimit: int32(limit),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return toLoginAttemptDTOs(attempts), nil
+}
+
+// ExportLoginAttempts renders the login attempts in [From, To) as CSV or JSON lines,
+// oldest first. Only callers with the audit:read permission may export. A zero To
+// means now and a zero From means seven days before To.
+func (s *AuthService) ExportLoginAttempts(ctx context.Context, accessToken string, request models.LoginAttemptExportRequest) (*models.LoginAttemptExport, error) {
+	if _, err := s.authorize(ctx, accessToken, authz.PermissionReadAuditLog); err != nil {
+		return nil, err
+	}
+
+	if request.Format != ExportFormatCSV && request.Format != ExportFormatJSONL {
+		return nil, ErrUnsupportedExportFormat
+	}
+
+	to := request.To
+	if to.IsZero() {
+		to = time.Now()
+	}
+	from := request.From
+	if from.IsZero() {
+		from = to.Add(-defaultExportRange)
+	}
+	if !to.After(from) {
+		return nil, ErrInvalidExportRange
+	}
+
+	// One extra row tells a complete export apart from a truncated one
+	attempts, err := s.authRepo.ExportLoginAttempts(ctx, db.ExportLoginAttemptsParams{
+		FromTime: pgtype.Timestamp{Time: from.UTC(), Valid: true},
+		ToTime:   pgtype.Timestamp{Time: to.UTC(), Valid: true},
+		Username: request.Username,
+		RowLimit: maxExportRows + 1,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(attempts) > maxExportRows {
+		return nil, ErrExportTooLarge
+	}
+
+	entries := toLoginAttemptDTOs(attempts)
+	filename := fmt.Sprintf("login-attempts-%s-%s.%s", from.UTC().Format("20060102T150405Z"), to.UTC().Format("20060102T150405Z"), request.Format)
+
+	var data []byte
+	var contentType string
+	switch request.Format {
+	case ExportFormatCSV:
+		data, err = encodeLoginAttemptsCSV(entries)
+		contentType = "text/csv"
+	default:
+		data, err = encodeLoginAttemptsJSONL(entries)
+		contentType = "application/x-ndjson"
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.LoginAttemptExport{
+		ContentType: contentType,
+		Filename:    filename,
+		Data:        data,
+		Count:       len(entries),
+	}, nil
+}
+
+func toLoginAttemptDTOs(attempts []db.LoginAttempt) []models.LoginAttemptDTO {
+	result := make([]models.LoginAttemptDTO, 0, len(attempts))
+	for _, attempt := range attempts {
+		result = append(result, models.LoginAttemptDTO{
+			ID:          uuid.UUID(attempt.ID.Bytes).String(),
+			Username:    attempt.Username,
+			Success:     attempt.Success,
+			IPAddress:   attempt.IpAddress,
+			UserAgent:   attempt.UserAgent,
+			AttemptedAt: attempt.AttemptTime.Time.UTC(),
+		})
+	}
+	return result
+}
+
+func encodeLoginAttemptsCSV(entries []models.LoginAttemptDTO) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+
+	if err := writer.Write([]string{"id", "username", "success", "ip_address", "user_agent", "attempted_at"}); err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		record := []string{
+			entry.ID,
+			csvSafe(entry.Username),
+			strconv.FormatBool(entry.Success),
+			entry.IPAddress,
+			csvSafe(entry.UserAgent),
+			entry.AttemptedAt.Format(time.RFC3339),
+		}
+		if err := writer.Write(record); err != nil {
+			return nil, err
+		}
+	}
+
+	writer.Flush()
+	return buf.Bytes(), writer.Error()
+}
+
+// csvSafe keeps client supplied values from being evaluated as formulas by spreadsheet apps.
+func csvSafe(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+func encodeLoginAttemptsJSONL(entries []models.LoginAttemptDTO) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	for _, entry := range entries {
+		if err := encoder.Encode(entry); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,126 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"log"
+	db "soul-connect/sc-auth/internal/db/sqlc"
+	"soul-connect/sc-auth/internal/mailer"
+	"soul-connect/sc-auth/internal/models"
+	"time"
+)
+
+// MagicLinkPolicy configures passwordless login links. The token is appended to LoginURL,
+// e.g. https://app.example.com/magic-link?token=
+type MagicLinkPolicy struct {
+	TokenTTL      time.Duration
+	LoginURL      string
+	RequestLimit  int
+	RequestWindow time.Duration
+}
+
+var ErrInvalidMagicLinkToken = errors.New("invalid or expired sign-in link")
+
+// RequestMagicLink emails a single-use sign-in link to the owner of the address. Unknown
+// addresses are not reported, and neither is hitting the request limit, since that would
+// tell the caller the account exists.
+func (s *AuthService) RequestMagicLink(ctx context.Context, email string) error {
+	user, err := s.authRepo.GetUserByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil
+		}
+		return err
+	}
+
+	policy := s.policy.MagicLink
+	if policy.RequestLimit > 0 {
+		sent, err := s.authRepo.CountMagicLinkTokensSince(ctx, db.CountMagicLinkTokensSinceParams{
+			UserID: user.ID,
+			Since:  pgtype.Timestamp{Time: time.Now().UTC().Add(-policy.RequestWindow), Valid: true},
+		})
+		if err != nil {
+			return err
+		}
+		if sent >= int64(policy.RequestLimit) {
+			log.Printf("magic link request limit reached for user %s", user.Username)
+			return nil
+		}
+	}
+
+	token, tokenHash, err := generateOneTimeToken()
+	if err != nil {
+		return errors.New("failed to generate sign-in link")
+	}
+
+	// Only the latest requested link stays usable
+	if err := s.authRepo.InvalidateMagicLinkTokens(ctx, user.ID); err != nil {
+		return err
+	}
+
+	expiresAt := time.Now().UTC().Add(policy.TokenTTL)
+	err = s.authRepo.CreateMagicLinkToken(ctx, db.CreateMagicLinkTokenParams{
+		UserID:    user.ID,
+		TokenHash: tokenHash,
+		ExpiresAt: pgtype.Timestamp{Time: expiresAt, Valid: true},
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.mailSender.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Your Soul Connect sign-in link",
+		Body: fmt.Sprintf("Hi %s,\n\nUse the link below to sign in. It can be used once and expires in %s.\n\n%s%s\n\n"+
+			"If you did not ask to sign in you can ignore this email.",
+			user.Username, policy.TokenTTL, policy.LoginURL, token),
+	})
+}
+
+// ConsumeMagicLink signs the user in with a token from RequestMagicLink. The link replaces
+// the password only: locked accounts stay locked and two-factor accounts still get a challenge.
+// Since the link arrived by email, the address counts as verified from now on.
+func (s *AuthService) ConsumeMagicLink(ctx context.Context, token string, client models.ClientInfo) (*models.UserLoginResponseDTO, error) {
+	// Consuming the token is atomic, so it cannot be used twice even by concurrent requests
+	userID, err := s.authRepo.ConsumeMagicLinkToken(ctx, hashOneTimeToken(token))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrInvalidMagicLinkToken
+		}
+		return nil, err
+	}
+
+	user, err := s.authRepo.GetUserById(ctx, userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrInvalidMagicLinkToken
+		}
+		return nil, err
+	}
+
+	lockout, err := s.checkAccountLockout(ctx, user.Username)
+	if err != nil {
+		return nil, err
+	}
+
+	if !user.EmailVerified {
+		if err := s.authRepo.MarkEmailVerified(ctx, user.ID); err != nil {
+			return nil, err
+		}
+		if err := s.authRepo.InvalidateEmailVerificationTokens(ctx, user.ID); err != nil {
+			return nil, err
+		}
+		user.EmailVerified = true
+	}
+
+	return s.continueLogin(ctx, db.GetUserByUsernameRow{
+		ID:            user.ID,
+		Username:      user.Username,
+		Email:         user.Email,
+		Password:      user.Password,
+		EmailVerified: user.EmailVerified,
+	}, lockout, client)
+}
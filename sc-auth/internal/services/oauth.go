@@ -0,0 +1,558 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"net"
+	"net/url"
+	"slices"
+	db "soul-connect/sc-auth/internal/db/sqlc"
+	"soul-connect/sc-auth/internal/models"
+	"soul-connect/sc-auth/internal/utils"
+	"strings"
+	"time"
+)
+
+const (
+	// OAuthAccessTokenType is reported by introspection for access tokens issued to an OAuth client.
+	OAuthAccessTokenType = "oauth_access_token"
+	// OAuthClientSecretPrefix marks client secrets for secret scanners.
+	OAuthClientSecretPrefix = "scs_"
+
+	GrantTypeAuthorizationCode = "authorization_code"
+	GrantTypeRefreshToken      = "refresh_token"
+	CodeChallengeMethodS256    = "S256"
+
+	maxOAuthClientNameLength = 100
+	maxOAuthRedirectURIs     = 10
+)
+
+// OAuthPolicy configures the OAuth2 authorization server. Clients are limited to the
+// scopes personal access tokens use, which the gateway maps onto its routes.
+type OAuthPolicy struct {
+	CodeTTL time.Duration
+}
+
+// OAuthError carries one of the error codes of RFC 6749, so the gateway can answer
+// OAuth clients in the format they expect.
+type OAuthError struct {
+	Code        string
+	Description string
+}
+
+func (e *OAuthError) Error() string {
+	return e.Description
+}
+
+var (
+	ErrInvalidOAuthClient   = &OAuthError{Code: "invalid_client", Description: "unknown client or invalid client credentials"}
+	ErrInvalidRedirectURI   = &OAuthError{Code: "invalid_request", Description: "redirect_uri is not registered for the client"}
+	ErrInvalidCodeChallenge = &OAuthError{Code: "invalid_request", Description: "a S256 code_challenge is required"}
+	ErrInvalidOAuthScope    = &OAuthError{Code: "invalid_scope", Description: "requested scope is not allowed for the client"}
+	ErrInvalidGrant         = &OAuthError{Code: "invalid_grant", Description: "invalid or expired authorization grant"}
+	ErrUnsupportedGrantType = &OAuthError{Code: "unsupported_grant_type", Description: "grant_type must be authorization_code or refresh_token"}
+
+	ErrInvalidOAuthClientName = errors.New("client name is required and must be at most 100 characters long")
+	ErrInvalidRedirectURIs    = errors.New("between 1 and 10 redirect uris are required, each an absolute https url without fragment, http is only allowed for localhost")
+	ErrOAuthClientNotFound    = errors.New("oauth client not found")
+	ErrOAuthConsentNotFound   = errors.New("oauth consent not found")
+)
+
+// RegisterOAuthClient registers a third-party app owned by the caller. The secret of a
+// confidential client is only part of this response, afterwards just its hash is known.
+func (s *AuthService) RegisterOAuthClient(ctx context.Context, accessToken string, request models.RegisterOAuthClientRequest) (*models.OAuthClientDTO, error) {
+	caller, err := s.authenticate(ctx, accessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	name := strings.TrimSpace(request.Name)
+	if name == "" || len(name) > maxOAuthClientNameLength {
+		return nil, ErrInvalidOAuthClientName
+	}
+	if len(request.RedirectURIs) == 0 || len(request.RedirectURIs) > maxOAuthRedirectURIs {
+		return nil, ErrInvalidRedirectURIs
+	}
+	redirectURIs := make([]string, 0, len(request.RedirectURIs))
+	for _, redirectURI := range request.RedirectURIs {
+		if !validRedirectURI(redirectURI) {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidRedirectURIs, redirectURI)
+		}
+		if !slices.Contains(redirectURIs, redirectURI) {
+			redirectURIs = append(redirectURIs, redirectURI)
+		}
+	}
+	scopes, err := normalizeTokenScopes(request.Scopes)
+	if err != nil {
+		return nil, err
+	}
+
+	var secret, secretHash string
+	if request.Confidential {
+		raw, _, err := generateOneTimeToken()
+		if err != nil {
+			return nil, errors.New("failed to generate client secret")
+		}
+		secret = OAuthClientSecretPrefix + raw
+		secretHash = hashOneTimeToken(secret)
+	}
+
+	client, err := s.authRepo.CreateOAuthClient(ctx, db.CreateOAuthClientParams{
+		OwnerID:          caller.UserID,
+		Name:             name,
+		ClientSecretHash: secretHash,
+		RedirectUris:     redirectURIs,
+		Scopes:           scopes,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	dto := toOAuthClientDTO(client)
+	dto.ClientSecret = secret
+	return &dto, nil
+}
+
+// ListOAuthClients returns the apps the caller registered, newest first.
+func (s *AuthService) ListOAuthClients(ctx context.Context, accessToken string) ([]models.OAuthClientDTO, error) {
+	caller, err := s.authenticate(ctx, accessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	clients, err := s.authRepo.ListOAuthClientsByOwner(ctx, caller.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]models.OAuthClientDTO, 0, len(clients))
+	for _, client := range clients {
+		result = append(result, toOAuthClientDTO(client))
+	}
+	return result, nil
+}
+
+// DeleteOAuthClient removes one of the caller's apps. Its consents go with it,
+// which ends every token issued to the app.
+func (s *AuthService) DeleteOAuthClient(ctx context.Context, accessToken string, clientID string) error {
+	caller, err := s.authenticate(ctx, accessToken)
+	if err != nil {
+		return err
+	}
+
+	clientUUID, err := uuid.Parse(clientID)
+	if err != nil {
+		return ErrOAuthClientNotFound
+	}
+
+	deleted, err := s.authRepo.DeleteOAuthClient(ctx, db.DeleteOAuthClientParams{
+		ID:      pgtype.UUID{Bytes: clientUUID, Valid: true},
+		OwnerID: caller.UserID,
+	})
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return ErrOAuthClientNotFound
+	}
+	return nil
+}
+
+// GetOAuthClient returns what the consent screen shows about an app. It is public,
+// like the client id itself.
+func (s *AuthService) GetOAuthClient(ctx context.Context, clientID string) (*models.OAuthClientDTO, error) {
+	client, err := s.oauthClient(ctx, clientID)
+	if err != nil {
+		if errors.Is(err, ErrInvalidOAuthClient) {
+			return nil, ErrOAuthClientNotFound
+		}
+		return nil, err
+	}
+
+	dto := toOAuthClientDTO(client)
+	return &dto, nil
+}
+
+// AuthorizeOAuthClient records the caller's consent and issues a single-use authorization
+// code bound to the client, the redirect URI and the PKCE challenge. Only S256 challenges
+// are accepted, so a code intercepted on its way back to the app cannot be redeemed.
+func (s *AuthService) AuthorizeOAuthClient(ctx context.Context, accessToken string, request models.OAuthAuthorizationRequest) (*models.OAuthAuthorizationDTO, error) {
+	caller, err := s.authenticate(ctx, accessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := s.oauthClient(ctx, request.ClientID)
+	if err != nil {
+		return nil, err
+	}
+	if !slices.Contains(client.RedirectUris, request.RedirectURI) {
+		return nil, ErrInvalidRedirectURI
+	}
+	if request.CodeChallengeMethod != CodeChallengeMethodS256 || !validCodeChallenge(request.CodeChallenge) {
+		return nil, ErrInvalidCodeChallenge
+	}
+
+	scopes := client.Scopes
+	if len(request.Scopes) > 0 {
+		scopes = make([]string, 0, len(request.Scopes))
+		for _, scope := range request.Scopes {
+			if !slices.Contains(client.Scopes, scope) {
+				return nil, ErrInvalidOAuthScope
+			}
+			if !slices.Contains(scopes, scope) {
+				scopes = append(scopes, scope)
+			}
+		}
+	}
+
+	code, codeHash, err := generateOneTimeToken()
+	if err != nil {
+		return nil, errors.New("failed to generate authorization code")
+	}
+
+	err = s.authRepo.InTx(ctx, func(repo IAuthRepository) error {
+		// Scopes granted earlier stay granted, so tokens the app already holds keep working
+		consented := scopes
+		consent, err := repo.GetOAuthConsent(ctx, db.GetOAuthConsentParams{UserID: caller.UserID, ClientID: client.ID})
+		if err == nil {
+			consented = slices.Clone(consent.Scopes)
+			for _, scope := range scopes {
+				if !slices.Contains(consented, scope) {
+					consented = append(consented, scope)
+				}
+			}
+		} else if !errors.Is(err, pgx.ErrNoRows) {
+			return err
+		}
+
+		err = repo.UpsertOAuthConsent(ctx, db.UpsertOAuthConsentParams{
+			UserID:   caller.UserID,
+			ClientID: client.ID,
+			Scopes:   consented,
+		})
+		if err != nil {
+			return err
+		}
+
+		return repo.CreateOAuthAuthorizationCode(ctx, db.CreateOAuthAuthorizationCodeParams{
+			CodeHash:      codeHash,
+			ClientID:      client.ID,
+			UserID:        caller.UserID,
+			RedirectUri:   request.RedirectURI,
+			Scopes:        scopes,
+			CodeChallenge: request.CodeChallenge,
+			ExpiresAt:     pgtype.Timestamp{Time: time.Now().UTC().Add(s.policy.OAuth.CodeTTL), Valid: true},
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	redirectTo, err := url.Parse(request.RedirectURI)
+	if err != nil {
+		return nil, ErrInvalidRedirectURI
+	}
+	query := redirectTo.Query()
+	query.Set("code", code)
+	if request.State != "" {
+		query.Set("state", request.State)
+	}
+	redirectTo.RawQuery = query.Encode()
+
+	return &models.OAuthAuthorizationDTO{
+		Code:       code,
+		RedirectTo: redirectTo.String(),
+		Scopes:     scopes,
+	}, nil
+}
+
+// ExchangeOAuthToken is the token endpoint. It redeems an authorization code or a refresh
+// token of the client for a new token pair, limited to the scopes the user consented to.
+func (s *AuthService) ExchangeOAuthToken(ctx context.Context, request models.OAuthTokenRequest, clientInfo models.ClientInfo) (*models.OAuthTokenDTO, error) {
+	if request.GrantType != GrantTypeAuthorizationCode && request.GrantType != GrantTypeRefreshToken {
+		return nil, ErrUnsupportedGrantType
+	}
+
+	client, err := s.authenticateOAuthClient(ctx, request.ClientID, request.ClientSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	var tokens *utils.Token
+	var subject utils.TokenSubject
+	if request.GrantType == GrantTypeAuthorizationCode {
+		tokens, subject, err = s.redeemAuthorizationCode(ctx, client, request, clientInfo)
+	} else {
+		tokens, subject, err = s.rotateSession(ctx, request.RefreshToken, uuid.UUID(client.ID.Bytes).String())
+		if errors.Is(err, ErrInvalidRefreshToken) || errors.Is(err, ErrRefreshTokenReused) {
+			return nil, ErrInvalidGrant
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.OAuthTokenDTO{
+		AccessToken:  tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresIn:    time.Until(tokens.ExpiresAt).Round(time.Second),
+		Scopes:       subject.Scopes,
+	}, nil
+}
+
+// ListOAuthConsents returns the apps the caller allowed to act on their behalf.
+func (s *AuthService) ListOAuthConsents(ctx context.Context, accessToken string) ([]models.OAuthConsentDTO, error) {
+	caller, err := s.authenticate(ctx, accessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	consents, err := s.authRepo.ListOAuthConsents(ctx, caller.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]models.OAuthConsentDTO, 0, len(consents))
+	for _, consent := range consents {
+		result = append(result, models.OAuthConsentDTO{
+			ClientID:   uuid.UUID(consent.ClientID.Bytes).String(),
+			ClientName: consent.Name,
+			Scopes:     consent.Scopes,
+			CreatedAt:  consent.CreatedAt.Time,
+			UpdatedAt:  consent.UpdatedAt.Time,
+		})
+	}
+	return result, nil
+}
+
+// RevokeOAuthConsent withdraws the caller's consent for an app. Access tokens of the app
+// stop passing introspection right away and its refresh tokens can no longer be used.
+func (s *AuthService) RevokeOAuthConsent(ctx context.Context, accessToken string, clientID string) error {
+	caller, err := s.authenticate(ctx, accessToken)
+	if err != nil {
+		return err
+	}
+
+	clientUUID, err := uuid.Parse(clientID)
+	if err != nil {
+		return ErrOAuthConsentNotFound
+	}
+
+	deleted, err := s.authRepo.DeleteOAuthConsent(ctx, db.DeleteOAuthConsentParams{
+		UserID:   caller.UserID,
+		ClientID: pgtype.UUID{Bytes: clientUUID, Valid: true},
+	})
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return ErrOAuthConsentNotFound
+	}
+	return nil
+}
+
+// redeemAuthorizationCode opens a session for the grant, so the client can refresh its
+// tokens and the user sees the app among their sessions.
+func (s *AuthService) redeemAuthorizationCode(
+	ctx context.Context,
+	client db.OauthClient,
+	request models.OAuthTokenRequest,
+	clientInfo models.ClientInfo,
+) (*utils.Token, utils.TokenSubject, error) {
+	// Consuming the code is atomic, so it cannot be redeemed twice even by concurrent requests
+	grant, err := s.authRepo.ConsumeOAuthAuthorizationCode(ctx, hashOneTimeToken(request.Code))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, utils.TokenSubject{}, ErrInvalidGrant
+		}
+		return nil, utils.TokenSubject{}, err
+	}
+	if grant.ClientID != client.ID || grant.RedirectUri != request.RedirectURI || !verifyCodeChallenge(request.CodeVerifier, grant.CodeChallenge) {
+		return nil, utils.TokenSubject{}, ErrInvalidGrant
+	}
+
+	user, err := s.authRepo.GetUserById(ctx, grant.UserID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, utils.TokenSubject{}, ErrInvalidGrant
+		}
+		return nil, utils.TokenSubject{}, err
+	}
+
+	scopes, err := s.consentedScopes(ctx, user.ID, client.ID, grant.Scopes)
+	if err != nil {
+		if errors.Is(err, ErrOAuthConsentNotFound) {
+			return nil, utils.TokenSubject{}, ErrInvalidGrant
+		}
+		return nil, utils.TokenSubject{}, err
+	}
+
+	sessionID := uuid.New()
+	subject := utils.TokenSubject{
+		UserID:        uuid.UUID(user.ID.Bytes).String(),
+		SessionID:     sessionID.String(),
+		EmailVerified: user.EmailVerified,
+		ClientID:      uuid.UUID(client.ID.Bytes).String(),
+		Scopes:        scopes,
+	}
+	tokens, err := utils.GenerateToken(subject, s.keys)
+	if err != nil {
+		return nil, utils.TokenSubject{}, errors.New("failed to generate tokens")
+	}
+
+	_, err = s.authRepo.CreateSession(ctx, db.CreateSessionParams{
+		ID:               pgtype.UUID{Bytes: sessionID, Valid: true},
+		UserID:           user.ID,
+		SessionToken:     tokens.RefreshToken,
+		SessionExpiresAt: pgtype.Timestamp{Time: tokens.RefreshExpiresAt, Valid: true},
+		UserAgent:        "OAuth app: " + client.Name,
+		IpAddress:        clientInfo.IPAddress,
+	})
+	if err != nil {
+		return nil, utils.TokenSubject{}, errors.New("failed to generate session")
+	}
+
+	return tokens, subject, nil
+}
+
+// oauthClientSubject builds the subject of tokens refreshed for an OAuth client. The scopes
+// are narrowed to the current consent, roles and permissions are never embedded.
+func (s *AuthService) oauthClientSubject(ctx context.Context, claims *utils.Claims, session db.Session, emailVerified bool) (utils.TokenSubject, error) {
+	clientUUID, err := uuid.Parse(claims.ClientID)
+	if err != nil {
+		return utils.TokenSubject{}, ErrInvalidRefreshToken
+	}
+
+	scopes, err := s.consentedScopes(ctx, session.UserID, pgtype.UUID{Bytes: clientUUID, Valid: true}, claims.Scopes)
+	if err != nil {
+		if !errors.Is(err, ErrOAuthConsentNotFound) {
+			return utils.TokenSubject{}, err
+		}
+		// The consent was revoked, the session of the grant goes with it
+		if err := s.authRepo.DeleteSessionByID(ctx, session.ID); err != nil {
+			return utils.TokenSubject{}, err
+		}
+		return utils.TokenSubject{}, ErrInvalidRefreshToken
+	}
+
+	return utils.TokenSubject{
+		UserID:        uuid.UUID(session.UserID.Bytes).String(),
+		SessionID:     uuid.UUID(session.ID.Bytes).String(),
+		EmailVerified: emailVerified,
+		ClientID:      claims.ClientID,
+		Scopes:        scopes,
+	}, nil
+}
+
+// consentedScopes returns the requested scopes the user still consents to for the client.
+func (s *AuthService) consentedScopes(ctx context.Context, userID pgtype.UUID, clientID pgtype.UUID, requested []string) ([]string, error) {
+	consent, err := s.authRepo.GetOAuthConsent(ctx, db.GetOAuthConsentParams{UserID: userID, ClientID: clientID})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrOAuthConsentNotFound
+		}
+		return nil, err
+	}
+
+	scopes := make([]string, 0, len(requested))
+	for _, scope := range requested {
+		if slices.Contains(consent.Scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+	if len(scopes) == 0 {
+		return nil, ErrOAuthConsentNotFound
+	}
+	return scopes, nil
+}
+
+func (s *AuthService) oauthClient(ctx context.Context, clientID string) (db.OauthClient, error) {
+	clientUUID, err := uuid.Parse(clientID)
+	if err != nil {
+		return db.OauthClient{}, ErrInvalidOAuthClient
+	}
+
+	client, err := s.authRepo.GetOAuthClient(ctx, pgtype.UUID{Bytes: clientUUID, Valid: true})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return db.OauthClient{}, ErrInvalidOAuthClient
+		}
+		return db.OauthClient{}, err
+	}
+	return client, nil
+}
+
+// authenticateOAuthClient checks the secret of confidential clients. Public clients are
+// only identified, PKCE proves they are the ones that started the authorization.
+func (s *AuthService) authenticateOAuthClient(ctx context.Context, clientID string, clientSecret string) (db.OauthClient, error) {
+	client, err := s.oauthClient(ctx, clientID)
+	if err != nil {
+		return db.OauthClient{}, err
+	}
+	if client.ClientSecretHash != "" &&
+		subtle.ConstantTimeCompare([]byte(hashOneTimeToken(clientSecret)), []byte(client.ClientSecretHash)) != 1 {
+		return db.OauthClient{}, ErrInvalidOAuthClient
+	}
+	return client, nil
+}
+
+// validRedirectURI accepts absolute https URLs without fragment. Plain http is only
+// allowed for loopback addresses, which native apps listen on during development.
+func validRedirectURI(raw string) bool {
+	redirectURI, err := url.Parse(raw)
+	if err != nil || redirectURI.Host == "" || strings.Contains(raw, "#") {
+		return false
+	}
+
+	switch redirectURI.Scheme {
+	case "https":
+		return true
+	case "http":
+		host := redirectURI.Hostname()
+		ip := net.ParseIP(host)
+		return host == "localhost" || (ip != nil && ip.IsLoopback())
+	default:
+		return false
+	}
+}
+
+// validCodeChallenge checks the format of a S256 challenge: a base64url encoded SHA-256 hash.
+func validCodeChallenge(challenge string) bool {
+	decoded, err := base64.RawURLEncoding.DecodeString(challenge)
+	return err == nil && len(decoded) == sha256.Size
+}
+
+// verifyCodeChallenge checks a PKCE code verifier (RFC 7636) against the S256 challenge.
+func verifyCodeChallenge(verifier string, challenge string) bool {
+	if len(verifier) < 43 || len(verifier) > 128 {
+		return false
+	}
+	for _, c := range verifier {
+		unreserved := (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') || strings.ContainsRune("-._~", c)
+		if !unreserved {
+			return false
+		}
+	}
+
+	sum := sha256.Sum256([]byte(verifier))
+	return subtle.ConstantTimeCompare([]byte(base64.RawURLEncoding.EncodeToString(sum[:])), []byte(challenge)) == 1
+}
+
+func toOAuthClientDTO(client db.OauthClient) models.OAuthClientDTO {
+	return models.OAuthClientDTO{
+		ClientID:     uuid.UUID(client.ID.Bytes).String(),
+		Name:         client.Name,
+		RedirectURIs: client.RedirectUris,
+		Scopes:       client.Scopes,
+		Confidential: client.ClientSecretHash != "",
+		CreatedAt:    client.CreatedAt.Time,
+	}
+}
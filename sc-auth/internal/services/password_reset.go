@@ -0,0 +1,127 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	db "soul-connect/sc-auth/internal/db/sqlc"
+	"soul-connect/sc-auth/internal/events"
+	"soul-connect/sc-auth/internal/mailer"
+	"time"
+)
+
+// PasswordResetPolicy configures reset tokens and the link sent to the user.
+// The token is appended to ResetURL, e.g. https://app.example.com/reset-password?token=
+type PasswordResetPolicy struct {
+	TokenTTL time.Duration
+	ResetURL string
+}
+
+var ErrInvalidResetToken = errors.New("invalid or expired password reset token")
+
+// RequestPasswordReset emails a single-use reset token to the owner of the address.
+// Unknown addresses are not reported, so the endpoint cannot be used to probe for accounts.
+func (s *AuthService) RequestPasswordReset(ctx context.Context, email string) error {
+	user, err := s.authRepo.GetUserByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil
+		}
+		return err
+	}
+
+	token, tokenHash, err := generateOneTimeToken()
+	if err != nil {
+		return errors.New("failed to generate password reset token")
+	}
+
+	// Only the latest requested token stays usable
+	if err := s.authRepo.InvalidatePasswordResetTokens(ctx, user.ID); err != nil {
+		return err
+	}
+
+	expiresAt := time.Now().UTC().Add(s.policy.PasswordReset.TokenTTL)
+	err = s.authRepo.CreatePasswordResetToken(ctx, db.CreatePasswordResetTokenParams{
+		UserID:    user.ID,
+		TokenHash: tokenHash,
+		ExpiresAt: pgtype.Timestamp{Time: expiresAt, Valid: true},
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.mailSender.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your Soul Connect password",
+		Body: fmt.Sprintf("Hi %s,\n\nUse the link below to set a new password. It expires in %s.\n\n%s%s\n\n"+
+			"If you did not ask for a password reset you can ignore this email.",
+			user.Username, s.policy.PasswordReset.TokenTTL, s.policy.PasswordReset.ResetURL, token),
+	})
+}
+
+// ResetPassword sets a new password using a reset token and signs the user out everywhere.
+func (s *AuthService) ResetPassword(ctx context.Context, token string, newPassword string) error {
+	if err := validatePassword(newPassword); err != nil {
+		return err
+	}
+
+	// Consuming the token is atomic, so it cannot be used twice even by concurrent requests
+	userID, err := s.authRepo.ConsumePasswordResetToken(ctx, hashOneTimeToken(token))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrInvalidResetToken
+		}
+		return err
+	}
+
+	hashedPassword, err := s.policy.PasswordHasher.Hash(newPassword)
+	if err != nil {
+		return errors.New("failed to hash password")
+	}
+
+	err = s.authRepo.InTx(ctx, func(repo IAuthRepository) error {
+		if err := repo.UpdateUserPassword(ctx, db.UpdateUserPasswordParams{
+			NewPassword: hashedPassword,
+			ID:          userID,
+		}); err != nil {
+			return err
+		}
+
+		if err := repo.InvalidatePasswordResetTokens(ctx, userID); err != nil {
+			return err
+		}
+
+		if err := repo.DeleteAllSessionsForUser(ctx, userID); err != nil {
+			return err
+		}
+
+		return enqueueEvent(ctx, repo, events.NewUserPasswordChangedEvent(uuid.UUID(userID.Bytes).String(), events.PasswordChangedByReset))
+	})
+	if err != nil {
+		return err
+	}
+	return s.revokeUserTokens(ctx, userID)
+}
+
+// generateOneTimeToken returns a random token for an email link and the hash stored in the database.
+func generateOneTimeToken() (string, string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", "", err
+	}
+
+	token := base64.RawURLEncoding.EncodeToString(raw)
+	return token, hashOneTimeToken(token), nil
+}
+
+func hashOneTimeToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
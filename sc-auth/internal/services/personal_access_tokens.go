@@ -0,0 +1,188 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"slices"
+	db "soul-connect/sc-auth/internal/db/sqlc"
+	"soul-connect/sc-auth/internal/models"
+	"strings"
+	"time"
+)
+
+const (
+	// PersonalAccessTokenPrefix tells personal access tokens apart from JWTs, also for secret scanners.
+	PersonalAccessTokenPrefix = "scp_"
+	// PersonalAccessTokenType is reported by introspection for personal access tokens.
+	PersonalAccessTokenType = "personal_access_token"
+
+	maxPersonalAccessTokenNameLength = 100
+)
+
+// PersonalAccessTokenScopes are the scopes a personal access token can be granted.
+// The gateway maps them onto its routes.
+var PersonalAccessTokenScopes = []string{
+	"posts:read",
+	"posts:write",
+	"users:read",
+	"users:write",
+}
+
+var (
+	ErrInvalidTokenName            = errors.New("token name is required and must be at most 100 characters long")
+	ErrInvalidTokenScope           = errors.New("invalid token scope")
+	ErrInvalidTokenExpiry          = errors.New("token expiry must not be negative")
+	ErrPersonalAccessTokenNotFound = errors.New("personal access token not found")
+)
+
+// CreatePersonalAccessToken issues a named, scoped token for the caller. The plain token
+// is only part of this response, afterwards just its hash is known.
+func (s *AuthService) CreatePersonalAccessToken(ctx context.Context, accessToken string, params models.CreatePersonalAccessTokenRequest) (*models.PersonalAccessTokenDTO, error) {
+	caller, err := s.authenticate(ctx, accessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	name := strings.TrimSpace(params.Name)
+	if name == "" || len(name) > maxPersonalAccessTokenNameLength {
+		return nil, ErrInvalidTokenName
+	}
+	scopes, err := normalizeTokenScopes(params.Scopes)
+	if err != nil {
+		return nil, err
+	}
+	if params.ExpiresIn < 0 {
+		return nil, ErrInvalidTokenExpiry
+	}
+
+	secret, _, err := generateOneTimeToken()
+	if err != nil {
+		return nil, errors.New("failed to generate personal access token")
+	}
+	token := PersonalAccessTokenPrefix + secret
+
+	var expiresAt pgtype.Timestamp
+	if params.ExpiresIn > 0 {
+		expiresAt = pgtype.Timestamp{Time: time.Now().UTC().Add(params.ExpiresIn), Valid: true}
+	}
+
+	created, err := s.authRepo.CreatePersonalAccessToken(ctx, db.CreatePersonalAccessTokenParams{
+		UserID:    caller.UserID,
+		Name:      name,
+		TokenHash: hashOneTimeToken(token),
+		Scopes:    scopes,
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	dto := toPersonalAccessTokenDTO(created)
+	dto.Token = token
+	return &dto, nil
+}
+
+// ListPersonalAccessTokens returns the caller's personal access tokens, newest first.
+func (s *AuthService) ListPersonalAccessTokens(ctx context.Context, accessToken string) ([]models.PersonalAccessTokenDTO, error) {
+	caller, err := s.authenticate(ctx, accessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens, err := s.authRepo.ListPersonalAccessTokens(ctx, caller.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]models.PersonalAccessTokenDTO, 0, len(tokens))
+	for _, token := range tokens {
+		result = append(result, toPersonalAccessTokenDTO(token))
+	}
+	return result, nil
+}
+
+// RevokePersonalAccessToken deletes one of the caller's personal access tokens.
+func (s *AuthService) RevokePersonalAccessToken(ctx context.Context, accessToken string, tokenID string) error {
+	caller, err := s.authenticate(ctx, accessToken)
+	if err != nil {
+		return err
+	}
+
+	tokenUUID, err := uuid.Parse(tokenID)
+	if err != nil {
+		return ErrPersonalAccessTokenNotFound
+	}
+
+	deleted, err := s.authRepo.DeletePersonalAccessToken(ctx, db.DeletePersonalAccessTokenParams{
+		ID:     pgtype.UUID{Bytes: tokenUUID, Valid: true},
+		UserID: caller.UserID,
+	})
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return ErrPersonalAccessTokenNotFound
+	}
+	return nil
+}
+
+// introspectPersonalAccessToken resolves a personal access token and records that it was used.
+func (s *AuthService) introspectPersonalAccessToken(ctx context.Context, token string) (*models.TokenIntrospectionDTO, error) {
+	personalToken, err := s.authRepo.GetPersonalAccessTokenByHash(ctx, hashOneTimeToken(token))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return &models.TokenIntrospectionDTO{Active: false}, nil
+		}
+		return nil, err
+	}
+	if personalToken.ExpiresAt.Valid && !time.Now().Before(personalToken.ExpiresAt.Time) {
+		return &models.TokenIntrospectionDTO{Active: false}, nil
+	}
+
+	if err := s.authRepo.TouchPersonalAccessToken(ctx, personalToken.ID); err != nil {
+		return nil, err
+	}
+
+	return &models.TokenIntrospectionDTO{
+		Active:    true,
+		UserID:    uuid.UUID(personalToken.UserID.Bytes).String(),
+		TokenType: PersonalAccessTokenType,
+		IssuedAt:  personalToken.CreatedAt.Time,
+		ExpiresAt: personalToken.ExpiresAt.Time,
+		Scopes:    personalToken.Scopes,
+	}, nil
+}
+
+// normalizeTokenScopes checks the requested scopes against PersonalAccessTokenScopes and drops duplicates.
+func normalizeTokenScopes(requested []string) ([]string, error) {
+	if len(requested) == 0 {
+		return nil, fmt.Errorf("%w: at least one scope is required", ErrInvalidTokenScope)
+	}
+
+	scopes := make([]string, 0, len(requested))
+	for _, scope := range requested {
+		scope = strings.TrimSpace(scope)
+		if !slices.Contains(PersonalAccessTokenScopes, scope) {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidTokenScope, scope)
+		}
+		if !slices.Contains(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+	return scopes, nil
+}
+
+func toPersonalAccessTokenDTO(token db.PersonalAccessToken) models.PersonalAccessTokenDTO {
+	return models.PersonalAccessTokenDTO{
+		ID:         uuid.UUID(token.ID.Bytes).String(),
+		Name:       token.Name,
+		Scopes:     token.Scopes,
+		CreatedAt:  token.CreatedAt.Time,
+		LastUsedAt: token.LastUsedAt.Time,
+		ExpiresAt:  token.ExpiresAt.Time,
+	}
+}
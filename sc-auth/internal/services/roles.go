@@ -0,0 +1,124 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	db "soul-connect/sc-auth/internal/db/sqlc"
+	"soul-connect/sc-auth/internal/utils"
+	"soul-connect/sc-auth/pkg/authz"
+)
+
+var (
+	ErrUnknownRole      = errors.New("unknown role")
+	ErrRoleNotGranted   = errors.New("user does not have this role")
+	ErrUserNotFound     = errors.New("user not found")
+	ErrCannotDemoteSelf = errors.New("admins cannot revoke their own admin role")
+)
+
+// GrantRole gives a user a role. Role changes reach the user's access token on the next refresh.
+func (s *AuthService) GrantRole(ctx context.Context, accessToken string, userID string, role string) error {
+	caller, err := s.authorize(ctx, accessToken, authz.PermissionManageRoles)
+	if err != nil {
+		return err
+	}
+
+	targetID, err := s.existingUserID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	exists, err := s.authRepo.RoleExists(ctx, role)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrUnknownRole
+	}
+
+	return s.authRepo.GrantRole(ctx, db.GrantRoleParams{
+		UserID:    targetID,
+		Role:      role,
+		GrantedBy: caller.UserID,
+	})
+}
+
+// RevokeRole takes a role away from a user.
+func (s *AuthService) RevokeRole(ctx context.Context, accessToken string, userID string, role string) error {
+	caller, err := s.authorize(ctx, accessToken, authz.PermissionManageRoles)
+	if err != nil {
+		return err
+	}
+
+	targetID, err := s.existingUserID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	// Keeps the last admin from locking everybody out of role management
+	if targetID == caller.UserID && role == authz.RoleAdmin {
+		return ErrCannotDemoteSelf
+	}
+
+	revoked, err := s.authRepo.RevokeRole(ctx, db.RevokeRoleParams{
+		UserID: targetID,
+		Role:   role,
+	})
+	if err != nil {
+		return err
+	}
+	if revoked == 0 {
+		return ErrRoleNotGranted
+	}
+	return nil
+}
+
+// authorize authenticates the caller and checks the permission embedded in their access token.
+func (s *AuthService) authorize(ctx context.Context, accessToken string, permission string) (*authenticatedCaller, error) {
+	caller, err := s.authenticate(ctx, accessToken)
+	if err != nil {
+		return nil, err
+	}
+	if err := caller.Principal.Require(permission); err != nil {
+		return nil, err
+	}
+	return caller, nil
+}
+
+// tokenSubject collects everything embedded in the tokens of a session, including the current roles.
+func (s *AuthService) tokenSubject(ctx context.Context, userID pgtype.UUID, sessionID uuid.UUID, emailVerified bool) (utils.TokenSubject, error) {
+	roles, err := s.authRepo.GetUserRoles(ctx, userID)
+	if err != nil {
+		return utils.TokenSubject{}, err
+	}
+	permissions, err := s.authRepo.GetUserPermissions(ctx, userID)
+	if err != nil {
+		return utils.TokenSubject{}, err
+	}
+
+	return utils.TokenSubject{
+		UserID:        uuid.UUID(userID.Bytes).String(),
+		SessionID:     sessionID.String(),
+		EmailVerified: emailVerified,
+		Roles:         roles,
+		Permissions:   permissions,
+	}, nil
+}
+
+func (s *AuthService) existingUserID(ctx context.Context, userID string) (pgtype.UUID, error) {
+	userUUID, err := uuid.Parse(userID)
+	if err != nil {
+		return pgtype.UUID{}, ErrUserNotFound
+	}
+
+	user, err := s.authRepo.GetUserById(ctx, pgtype.UUID{Bytes: userUUID, Valid: true})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return pgtype.UUID{}, ErrUserNotFound
+		}
+		return pgtype.UUID{}, err
+	}
+	return user.ID, nil
+}
@@ -1,17 +1,75 @@
 package services
 
 import (
+	"context"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"soul-connect/sc-auth/internal/config"
 	db "soul-connect/sc-auth/internal/db/sqlc"
+	"soul-connect/sc-auth/internal/mailer"
+	"soul-connect/sc-auth/internal/revocation"
+	"soul-connect/sc-auth/internal/utils"
 )
 
 type Service struct {
 	AuthService *AuthService
 }
 
-func NewService(pool *pgxpool.Pool) *Service {
-	queries := db.New(pool)
+func NewService(pool *pgxpool.Pool, keys *utils.KeySet, mailSender mailer.Sender, revocations revocation.List, passwordHasher utils.PasswordHasher, cfg *config.Config) *Service {
+	repo := &authRepository{Queries: db.New(pool), pool: pool}
+	policy := AuthPolicy{
+		PasswordHasher: passwordHasher,
+		Lockout: LockoutPolicy{
+			MaxAttempts: cfg.LockoutMaxAttempts,
+			Window:      cfg.LockoutWindow,
+			Duration:    cfg.LockoutDuration,
+			MaxDuration: cfg.LockoutMaxDuration,
+		},
+		PasswordReset: PasswordResetPolicy{
+			TokenTTL: cfg.PasswordResetTokenTTL,
+			ResetURL: cfg.PasswordResetURL,
+		},
+		EmailVerification: EmailVerificationPolicy{
+			Required:     cfg.RequireEmailVerification,
+			TokenTTL:     cfg.EmailVerificationTokenTTL,
+			VerifyURL:    cfg.EmailVerificationURL,
+			ResendLimit:  cfg.EmailVerificationResendLimit,
+			ResendWindow: cfg.EmailVerificationResendWindow,
+		},
+		TwoFactor: TwoFactorPolicy{
+			Issuer:       cfg.TOTPIssuer,
+			ChallengeTTL: cfg.TwoFactorChallengeTTL,
+		},
+		MagicLink: MagicLinkPolicy{
+			TokenTTL:      cfg.MagicLinkTokenTTL,
+			LoginURL:      cfg.MagicLinkURL,
+			RequestLimit:  cfg.MagicLinkRequestLimit,
+			RequestWindow: cfg.MagicLinkRequestWindow,
+		},
+		OAuth: OAuthPolicy{
+			CodeTTL: cfg.OAuthCodeTTL,
+		},
+		Impersonation: ImpersonationPolicy{
+			TokenTTL: cfg.ImpersonationTokenTTL,
+		},
+	}
 	return &Service{
-		AuthService: NewAuthService(queries),
+		AuthService: NewAuthService(repo, keys, mailSender, revocations, policy),
+	}
+}
+
+// authRepository runs the queries on the pool, or on a single transaction inside InTx.
+type authRepository struct {
+	*db.Queries
+	pool *pgxpool.Pool
+}
+
+func (r *authRepository) InTx(ctx context.Context, fn func(repo IAuthRepository) error) error {
+	// Already inside a transaction
+	if r.pool == nil {
+		return fn(r)
 	}
+	return pgx.BeginFunc(ctx, r.pool, func(tx pgx.Tx) error {
+		return fn(&authRepository{Queries: r.Queries.WithTx(tx)})
+	})
 }
@@ -0,0 +1,310 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	db "soul-connect/sc-auth/internal/db/sqlc"
+	"soul-connect/sc-auth/internal/models"
+	"soul-connect/sc-auth/internal/utils"
+	"strings"
+	"time"
+)
+
+// TwoFactorPolicy configures TOTP enrollment and the two-step login.
+type TwoFactorPolicy struct {
+	Issuer       string
+	ChallengeTTL time.Duration
+}
+
+const recoveryCodeCount = 10
+
+var (
+	ErrTwoFactorAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+	ErrTwoFactorNotEnrolled    = errors.New("two-factor authentication is not enrolled")
+	ErrInvalidTwoFactorCode    = errors.New("invalid two-factor code")
+	ErrInvalidChallengeToken   = errors.New("invalid or expired login challenge")
+)
+
+// EnrollTwoFactor starts TOTP enrollment for the caller. The secret only becomes
+// active once ConfirmTwoFactor receives a valid code. Recovery codes are returned
+// in plain text exactly once.
+func (s *AuthService) EnrollTwoFactor(ctx context.Context, accessToken string) (*models.TwoFactorEnrollmentDTO, error) {
+	caller, err := s.authenticate(ctx, accessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	totp, err := s.authRepo.GetUserTotp(ctx, caller.UserID)
+	if err == nil && totp.ConfirmedAt.Valid {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return nil, err
+	}
+
+	user, err := s.authRepo.GetUserById(ctx, caller.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return nil, errors.New("failed to generate two-factor secret")
+	}
+
+	if err := s.authRepo.UpsertUserTotp(ctx, db.UpsertUserTotpParams{
+		UserID: caller.UserID,
+		Secret: secret,
+	}); err != nil {
+		return nil, err
+	}
+
+	recoveryCodes, err := s.replaceRecoveryCodes(ctx, caller.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.TwoFactorEnrollmentDTO{
+		Secret:          secret,
+		ProvisioningURI: utils.TOTPProvisioningURI(s.policy.TwoFactor.Issuer, user.Username, secret),
+		RecoveryCodes:   recoveryCodes,
+	}, nil
+}
+
+// ConfirmTwoFactor activates a pending enrollment once the authenticator produces a valid code.
+func (s *AuthService) ConfirmTwoFactor(ctx context.Context, accessToken string, code string) error {
+	caller, err := s.authenticate(ctx, accessToken)
+	if err != nil {
+		return err
+	}
+
+	totp, err := s.authRepo.GetUserTotp(ctx, caller.UserID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrTwoFactorNotEnrolled
+		}
+		return err
+	}
+	if totp.ConfirmedAt.Valid {
+		return ErrTwoFactorAlreadyEnabled
+	}
+
+	if err := s.useTOTPCode(ctx, totp, code); err != nil {
+		return err
+	}
+
+	return s.authRepo.ConfirmUserTotp(ctx, caller.UserID)
+}
+
+// DisableTwoFactor removes TOTP and the recovery codes. A current code or an unused
+// recovery code is required, so a stolen access token alone cannot turn 2FA off.
+func (s *AuthService) DisableTwoFactor(ctx context.Context, accessToken string, code string) error {
+	caller, err := s.authenticate(ctx, accessToken)
+	if err != nil {
+		return err
+	}
+
+	totp, err := s.authRepo.GetUserTotp(ctx, caller.UserID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrTwoFactorNotEnrolled
+		}
+		return err
+	}
+
+	if totp.ConfirmedAt.Valid {
+		if err := s.verifySecondFactorCode(ctx, totp, code); err != nil {
+			return err
+		}
+	}
+
+	if err := s.authRepo.DeleteRecoveryCodes(ctx, caller.UserID); err != nil {
+		return err
+	}
+	return s.authRepo.DeleteUserTotp(ctx, caller.UserID)
+}
+
+// VerifySecondFactor completes a two-step login started by Login and issues the real tokens.
+func (s *AuthService) VerifySecondFactor(ctx context.Context, challengeToken string, code string, client models.ClientInfo) (*models.UserLoginResponseDTO, error) {
+	claims, err := utils.ValidateJWT(challengeToken, s.keys)
+	if err != nil || claims.TokenType != utils.ChallengeTokenType || claims.ExpiresAt == nil {
+		return nil, ErrInvalidChallengeToken
+	}
+
+	// A challenge completes a single login, it is revoked once it did
+	consumed, err := s.isRevoked(ctx, claims)
+	if err != nil {
+		return nil, err
+	}
+	if consumed {
+		return nil, ErrInvalidChallengeToken
+	}
+
+	userUUID, err := uuid.Parse(claims.UserID)
+	if err != nil {
+		return nil, ErrInvalidChallengeToken
+	}
+	userID := pgtype.UUID{Bytes: userUUID, Valid: true}
+
+	user, err := s.authRepo.GetUserById(ctx, userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrInvalidChallengeToken
+		}
+		return nil, err
+	}
+
+	// Guessing second factor codes counts towards the same lockout as guessing passwords
+	lockout, err := s.checkAccountLockout(ctx, user.Username)
+	if err != nil {
+		return nil, err
+	}
+
+	totp, err := s.authRepo.GetUserTotp(ctx, userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrInvalidChallengeToken
+		}
+		return nil, err
+	}
+	if !totp.ConfirmedAt.Valid {
+		return nil, ErrInvalidChallengeToken
+	}
+
+	if err := s.verifySecondFactorCode(ctx, totp, code); err != nil {
+		if !errors.Is(err, ErrInvalidTwoFactorCode) {
+			return nil, err
+		}
+		if err := s.authRepo.LogFailedLogin(ctx, db.LogFailedLoginParams{
+			Username:  user.Username,
+			IpAddress: client.IPAddress,
+			UserAgent: client.UserAgent,
+		}); err != nil {
+			return nil, err
+		}
+		if err := s.registerFailedLogin(ctx, user.Username, lockout); err != nil {
+			return nil, err
+		}
+		return nil, ErrInvalidTwoFactorCode
+	}
+
+	if err := s.revocations.RevokeToken(ctx, claims.ID, claims.ExpiresAt.Time); err != nil {
+		return nil, err
+	}
+
+	if lockout != nil {
+		if err := s.authRepo.DeleteAccountLockout(ctx, user.Username); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.completeLogin(ctx, user.ID, user.Username, user.Email, user.EmailVerified, client)
+}
+
+// twoFactorEnabled reports whether Login has to stop at the challenge step for the user.
+func (s *AuthService) twoFactorEnabled(ctx context.Context, userID pgtype.UUID) (bool, error) {
+	totp, err := s.authRepo.GetUserTotp(ctx, userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+	return totp.ConfirmedAt.Valid, nil
+}
+
+// verifySecondFactorCode accepts either a TOTP code or an unused recovery code.
+func (s *AuthService) verifySecondFactorCode(ctx context.Context, totp db.UserTotp, code string) error {
+	code = strings.TrimSpace(code)
+	if len(code) == utils.TOTPDigits {
+		return s.useTOTPCode(ctx, totp, code)
+	}
+	return s.useRecoveryCode(ctx, totp.UserID, code)
+}
+
+// useTOTPCode validates a code and records its time step, so the same code cannot be used twice.
+func (s *AuthService) useTOTPCode(ctx context.Context, totp db.UserTotp, code string) error {
+	step, ok := utils.ValidateTOTP(totp.Secret, code, time.Now())
+	if !ok {
+		return ErrInvalidTwoFactorCode
+	}
+
+	used, err := s.authRepo.UseTotpStep(ctx, db.UseTotpStepParams{
+		Step:   step,
+		UserID: totp.UserID,
+	})
+	if err != nil {
+		return err
+	}
+	if used == 0 {
+		return ErrInvalidTwoFactorCode
+	}
+	return nil
+}
+
+func (s *AuthService) useRecoveryCode(ctx context.Context, userID pgtype.UUID, code string) error {
+	normalized := normalizeRecoveryCode(code)
+	if normalized == "" {
+		return ErrInvalidTwoFactorCode
+	}
+
+	// Codes are random like the other one-time tokens, so a fast hash suffices and the
+	// code is found without checking every unused one
+	used, err := s.authRepo.UseRecoveryCode(ctx, db.UseRecoveryCodeParams{
+		UserID:   userID,
+		CodeHash: hashOneTimeToken(normalized),
+	})
+	if err != nil {
+		return err
+	}
+	if used == 0 {
+		return ErrInvalidTwoFactorCode
+	}
+	return nil
+}
+
+// replaceRecoveryCodes drops the previous recovery codes and stores new ones, hashed like
+// the other one-time tokens.
+func (s *AuthService) replaceRecoveryCodes(ctx context.Context, userID pgtype.UUID) ([]string, error) {
+	if err := s.authRepo.DeleteRecoveryCodes(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			return nil, errors.New("failed to generate recovery codes")
+		}
+
+		if err := s.authRepo.CreateRecoveryCode(ctx, db.CreateRecoveryCodeParams{
+			UserID:   userID,
+			CodeHash: hashOneTimeToken(normalizeRecoveryCode(code)),
+		}); err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+	}
+	return codes, nil
+}
+
+// generateRecoveryCode returns a code like "k3m9q-x7p2a", easy to copy from paper.
+func generateRecoveryCode() (string, error) {
+	raw := make([]byte, 7)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+
+	code := strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(raw))[:10]
+	return code[:5] + "-" + code[5:], nil
+}
+
+// normalizeRecoveryCode makes recovery codes case and separator insensitive.
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+}
@@ -3,71 +3,200 @@ package utils
 import (
 	"errors"
 	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
 	"time"
 )
 
+func init() {
+	// Tokens carry iat and exp in microseconds, so a token issued right after all tokens
+	// of its user were revoked can be told apart from the revoked ones, see revocation.List
+	jwt.TimePrecision = time.Microsecond
+}
+
 type Claims struct {
-	UserID string `json:"userID"`
+	UserID        string      `json:"userID"`
+	SessionID     string      `json:"sid,omitempty"`
+	TokenType     string      `json:"typ,omitempty"`
+	EmailVerified bool        `json:"email_verified"`
+	Roles         []string    `json:"roles,omitempty"`
+	Permissions   []string    `json:"permissions,omitempty"`
+	ClientID      string      `json:"client_id,omitempty"`
+	Scopes        []string    `json:"scopes,omitempty"`
+	Actor         *ActorClaim `json:"act,omitempty"`
 	jwt.RegisteredClaims
 }
 
+// ActorClaim is the "act" claim (RFC 8693) of an impersonation token, it names the
+// admin acting on behalf of the token's user.
+type ActorClaim struct {
+	Subject string `json:"sub"`
+}
+
+// TokenSubject describes who a token pair is issued for.
+// Roles and permissions are only embedded in the access token. Tokens issued to an
+// OAuth client name it in ClientID and are limited to Scopes, both tokens carry them.
+type TokenSubject struct {
+	UserID        string
+	SessionID     string
+	EmailVerified bool
+	Roles         []string
+	Permissions   []string
+	ClientID      string
+	Scopes        []string
+	// ActorID is only set for impersonation tokens, see GenerateImpersonationToken
+	ActorID string
+}
+
+// Issuer is the iss claim of every token sc-auth signs. Tokens of other issuers are
+// rejected even when they are signed with a known key.
+const Issuer = "soul-connect"
+
+const (
+	AccessTokenType    = "access"
+	RefreshTokenType   = "refresh"
+	ChallengeTokenType = "challenge"
+)
+
 const (
 	MaxAge               = 15 * time.Minute   // 10 days
 	RefreshTokenDuration = time.Hour * 24 * 7 // 7 days
 )
 
 type Token struct {
-	AccessToken  string    `json:"accessToken"`
-	RefreshToken string    `json:"refreshToken"`
-	ExpiresAt    time.Time `json:"expiresAt"`
+	AccessToken      string    `json:"accessToken"`
+	RefreshToken     string    `json:"refreshToken"`
+	ExpiresAt        time.Time `json:"expiresAt"`
+	RefreshExpiresAt time.Time `json:"refreshExpiresAt"`
 }
 
-func GenerateToken(userID string, secretSessionKey string) (*Token, error) {
+func GenerateToken(subject TokenSubject, keys *KeySet) (*Token, error) {
+	signingKey := keys.Active()
+
 	accessExpirationTime := time.Now().Add(MaxAge)
 
 	accessTokenClaims := &Claims{
-		UserID: userID,
+		UserID:        subject.UserID,
+		SessionID:     subject.SessionID,
+		TokenType:     AccessTokenType,
+		EmailVerified: subject.EmailVerified,
+		Roles:         subject.Roles,
+		Permissions:   subject.Permissions,
+		ClientID:      subject.ClientID,
+		Scopes:        subject.Scopes,
+		// The ID (jti) lets a single access token be revoked before it expires
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.NewString(),
 			ExpiresAt: jwt.NewNumericDate(accessExpirationTime),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			Issuer:    "soul-connect",
+			Issuer:    Issuer,
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, accessTokenClaims)
-	accessToken, err := token.SignedString([]byte(secretSessionKey))
+	accessToken, err := signToken(signingKey, accessTokenClaims)
 	if err != nil {
 		return nil, errors.New("failed to generate access token")
 	}
 
 	refreshExpirationTime := time.Now().Add(RefreshTokenDuration)
 
+	// Refresh tokens carry a unique ID so that two tokens issued within
+	// the same second never collide when a session is rotated
 	refreshTokenClaims := &Claims{
-		UserID: userID,
+		UserID:        subject.UserID,
+		SessionID:     subject.SessionID,
+		TokenType:     RefreshTokenType,
+		EmailVerified: subject.EmailVerified,
+		ClientID:      subject.ClientID,
+		Scopes:        subject.Scopes,
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.NewString(),
 			ExpiresAt: jwt.NewNumericDate(refreshExpirationTime),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			Issuer:    "soul-connect",
+			Issuer:    Issuer,
 		},
 	}
 
-	refreshToken := jwt.NewWithClaims(jwt.SigningMethodHS256, refreshTokenClaims)
-	refreshTokenStr, err := refreshToken.SignedString([]byte(secretSessionKey))
+	refreshTokenStr, err := signToken(signingKey, refreshTokenClaims)
 	if err != nil {
 		return nil, errors.New("failed to generate refresh token")
 	}
 
 	return &Token{
-		AccessToken:  accessToken,
-		RefreshToken: refreshTokenStr,
-		ExpiresAt:    accessExpirationTime,
+		AccessToken:      accessToken,
+		RefreshToken:     refreshTokenStr,
+		ExpiresAt:        accessExpirationTime,
+		RefreshExpiresAt: refreshExpirationTime,
 	}, nil
 }
 
-func ValidateJWT(tokenString string, secretSessionKey string) (*Claims, error) {
+// GenerateChallengeToken issues the short-lived token that proves the password step
+// of a two-step login succeeded. It cannot be used as an access or refresh token.
+func GenerateChallengeToken(userID string, ttl time.Duration, keys *KeySet) (string, error) {
+	expirationTime := time.Now().Add(ttl)
+
+	claims := &Claims{
+		UserID:    userID,
+		TokenType: ChallengeTokenType,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.NewString(),
+			ExpiresAt: jwt.NewNumericDate(expirationTime),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			Issuer:    Issuer,
+		},
+	}
+
+	token, err := signToken(keys.Active(), claims)
+	if err != nil {
+		return "", errors.New("failed to generate challenge token")
+	}
+	return token, nil
+}
+
+// GenerateImpersonationToken issues a short-lived access token for subject.UserID that
+// names subject.ActorID as the actor. It comes without a refresh token and cannot be renewed.
+func GenerateImpersonationToken(subject TokenSubject, ttl time.Duration, keys *KeySet) (string, *jwt.RegisteredClaims, error) {
+	expirationTime := time.Now().Add(ttl)
+
+	claims := &Claims{
+		UserID:        subject.UserID,
+		TokenType:     AccessTokenType,
+		EmailVerified: subject.EmailVerified,
+		Roles:         subject.Roles,
+		Permissions:   subject.Permissions,
+		Actor:         &ActorClaim{Subject: subject.ActorID},
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.NewString(),
+			ExpiresAt: jwt.NewNumericDate(expirationTime),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			Issuer:    Issuer,
+		},
+	}
+
+	token, err := signToken(keys.Active(), claims)
+	if err != nil {
+		return "", nil, errors.New("failed to generate impersonation token")
+	}
+	return token, &claims.RegisteredClaims, nil
+}
+
+func signToken(signingKey *SigningKey, claims *Claims) (string, error) {
+	token := jwt.NewWithClaims(signingKey.Method, claims)
+	token.Header["kid"] = signingKey.ID
+	return token.SignedString(signingKey.PrivateKey)
+}
+
+func ValidateJWT(tokenString string, keys *KeySet) (*Claims, error) {
 	claims := &Claims{}
 	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-		return []byte(secretSessionKey), nil
+		kid, _ := token.Header["kid"].(string)
+		signingKey, ok := keys.Lookup(kid)
+		if !ok {
+			return nil, errors.New("unknown signing key")
+		}
+		if token.Method.Alg() != signingKey.Method.Alg() {
+			return nil, errors.New("unexpected signing method")
+		}
+		return signingKey.PrivateKey.Public(), nil
 	})
 	if err != nil {
 		return nil, err
@@ -75,5 +204,8 @@ func ValidateJWT(tokenString string, secretSessionKey string) (*Claims, error) {
 	if !token.Valid {
 		return nil, errors.New("invalid token")
 	}
+	if !claims.VerifyIssuer(Issuer, true) {
+		return nil, errors.New("unexpected token issuer")
+	}
 	return claims, nil
 }
@@ -0,0 +1,96 @@
+package utils
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateJWT_KeyRotation(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	oldKey := &SigningKey{ID: "2024-01", Method: jwt.SigningMethodRS256, PrivateKey: rsaKey}
+	newKey := &SigningKey{ID: "2024-02", Method: jwt.SigningMethodEdDSA, PrivateKey: edKey}
+
+	before, err := NewKeySet("2024-01", oldKey)
+	require.NoError(t, err)
+	tokens, err := GenerateToken(TokenSubject{UserID: "user-id", SessionID: "session-id"}, before)
+	require.NoError(t, err)
+
+	// The retired key stays in the set, so its tokens keep verifying after rotation
+	after, err := NewKeySet("2024-02", oldKey, newKey)
+	require.NoError(t, err)
+	claims, err := ValidateJWT(tokens.AccessToken, after)
+	require.NoError(t, err)
+	require.Equal(t, "user-id", claims.UserID)
+
+	rotated, err := GenerateToken(TokenSubject{UserID: "user-id", SessionID: "session-id"}, after)
+	require.NoError(t, err)
+	header, _, err := new(jwt.Parser).ParseUnverified(rotated.AccessToken, &Claims{})
+	require.NoError(t, err)
+	require.Equal(t, "2024-02", header.Header["kid"])
+	require.Equal(t, "EdDSA", header.Header["alg"])
+
+	// Once the key is dropped, its tokens are rejected
+	_, err = ValidateJWT(rotated.AccessToken, before)
+	require.Error(t, err)
+
+	jwks := after.JWKS()
+	require.Len(t, jwks, 2)
+	require.Equal(t, "RSA", jwks[0].Kty)
+	require.NotEmpty(t, jwks[0].N)
+	require.Equal(t, "OKP", jwks[1].Kty)
+	require.Equal(t, "Ed25519", jwks[1].Crv)
+}
+
+func TestNewKeySet_RequiresActiveKeyForSeveralKeys(t *testing.T) {
+	_, first, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	_, second, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	_, err = NewKeySet("",
+		&SigningKey{ID: "a", Method: jwt.SigningMethodEdDSA, PrivateKey: first},
+		&SigningKey{ID: "b", Method: jwt.SigningMethodEdDSA, PrivateKey: second},
+	)
+	require.Error(t, err)
+}
+
+func TestValidateJWT_RejectsOtherIssuers(t *testing.T) {
+	keys, err := GenerateKeySet()
+	require.NoError(t, err)
+
+	for _, issuer := range []string{"", "someone-else"} {
+		token, err := signToken(keys.Active(), &Claims{
+			UserID:           "user-id",
+			TokenType:        AccessTokenType,
+			RegisteredClaims: jwt.RegisteredClaims{Issuer: issuer},
+		})
+		require.NoError(t, err)
+
+		_, err = ValidateJWT(token, keys)
+		require.Error(t, err, issuer)
+	}
+}
+
+func TestGenerateToken_IssuesWithSubSecondPrecision(t *testing.T) {
+	keys, err := GenerateKeySet()
+	require.NoError(t, err)
+
+	before := time.Now().Truncate(time.Microsecond)
+	tokens, err := GenerateToken(TokenSubject{UserID: "user-id", SessionID: "session-id"}, keys)
+	require.NoError(t, err)
+
+	claims, err := ValidateJWT(tokens.AccessToken, keys)
+	require.NoError(t, err)
+	require.WithinDuration(t, before, claims.IssuedAt.Time, time.Millisecond)
+	require.False(t, claims.IssuedAt.Time.Before(before.Add(-time.Microsecond)))
+}
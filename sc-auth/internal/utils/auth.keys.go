@@ -0,0 +1,177 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v4"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// SigningKey is a private key used to sign tokens, identified by the kid header.
+type SigningKey struct {
+	ID         string
+	Method     jwt.SigningMethod
+	PrivateKey crypto.Signer
+}
+
+// KeySet holds every key that may still verify tokens and the one used to sign new ones.
+type KeySet struct {
+	activeID string
+	keys     map[string]*SigningKey
+}
+
+// JSONWebKey is the public part of a signing key in JWK format (RFC 7517).
+type JSONWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+func NewKeySet(activeID string, keys ...*SigningKey) (*KeySet, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("at least one signing key is required")
+	}
+
+	set := &KeySet{activeID: activeID, keys: make(map[string]*SigningKey, len(keys))}
+	for _, key := range keys {
+		if _, exists := set.keys[key.ID]; exists {
+			return nil, fmt.Errorf("duplicate signing key id %q", key.ID)
+		}
+		set.keys[key.ID] = key
+	}
+
+	if set.activeID == "" {
+		if len(keys) > 1 {
+			return nil, errors.New("active signing key id is required when several keys are configured")
+		}
+		set.activeID = keys[0].ID
+	}
+
+	if _, ok := set.keys[set.activeID]; !ok {
+		return nil, fmt.Errorf("active signing key %q is not configured", set.activeID)
+	}
+
+	return set, nil
+}
+
+// LoadKeySet reads every *.pem private key from dir, using the file name as the key id.
+// Retired keys can stay in the directory so tokens they signed keep verifying until they expire.
+func LoadKeySet(dir string, activeID string) (*KeySet, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]*SigningKey, 0, len(paths))
+	for _, path := range paths {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("read signing key %s: %w", path, err)
+		}
+
+		id := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		key, err := ParseSigningKey(id, content)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	return NewKeySet(activeID, keys...)
+}
+
+// GenerateKeySet creates a set with a single ephemeral Ed25519 key, meant for local development only.
+func GenerateKeySet() (*KeySet, error) {
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewKeySet("", &SigningKey{
+		ID:         "ephemeral",
+		Method:     jwt.SigningMethodEdDSA,
+		PrivateKey: privateKey,
+	})
+}
+
+// ParseSigningKey parses a PEM encoded RSA (RS256) or Ed25519 (EdDSA) private key.
+func ParseSigningKey(id string, pemBytes []byte) (*SigningKey, error) {
+	block, _ := pem.Decode(pemBytes)
+	if block == nil {
+		return nil, fmt.Errorf("signing key %q is not PEM encoded", id)
+	}
+
+	var parsed any
+	var err error
+	if block.Type == "RSA PRIVATE KEY" {
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	} else {
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parse signing key %q: %w", id, err)
+	}
+
+	switch privateKey := parsed.(type) {
+	case *rsa.PrivateKey:
+		return &SigningKey{ID: id, Method: jwt.SigningMethodRS256, PrivateKey: privateKey}, nil
+	case ed25519.PrivateKey:
+		return &SigningKey{ID: id, Method: jwt.SigningMethodEdDSA, PrivateKey: privateKey}, nil
+	default:
+		return nil, fmt.Errorf("signing key %q has unsupported type %T", id, parsed)
+	}
+}
+
+func (ks *KeySet) Active() *SigningKey {
+	return ks.keys[ks.activeID]
+}
+
+func (ks *KeySet) Lookup(id string) (*SigningKey, bool) {
+	key, ok := ks.keys[id]
+	return key, ok
+}
+
+// JWKS returns the public keys of the set ordered by key id.
+func (ks *KeySet) JWKS() []JSONWebKey {
+	ids := make([]string, 0, len(ks.keys))
+	for id := range ks.keys {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	jwks := make([]JSONWebKey, 0, len(ids))
+	for _, id := range ids {
+		key := ks.keys[id]
+		jwk := JSONWebKey{Kid: key.ID, Use: "sig", Alg: key.Method.Alg()}
+
+		switch publicKey := key.PrivateKey.Public().(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(publicKey.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(publicKey.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(publicKey)
+		}
+
+		jwks = append(jwks, jwk)
+	}
+	return jwks
+}
@@ -0,0 +1,176 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+	"strings"
+)
+
+// PasswordHasher hashes passwords for storage. Every hasher verifies all supported
+// formats, so switching the configured algorithm keeps existing passwords working.
+type PasswordHasher interface {
+	// Hash returns the encoded hash to store for the password.
+	Hash(password string) (string, error)
+	// Verify reports whether the password matches an encoded hash of any supported format.
+	Verify(password string, encodedHash string) (bool, error)
+	// NeedsRehash reports whether the hash uses an older algorithm or weaker parameters
+	// than the hasher would use now.
+	NeedsRehash(encodedHash string) bool
+}
+
+var ErrUnsupportedPasswordHash = errors.New("unsupported password hash format")
+
+// Argon2idParams are the argon2id cost parameters. Memory is in KiB.
+type Argon2idParams struct {
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// DefaultArgon2idParams follow the OWASP recommendation for argon2id.
+var DefaultArgon2idParams = Argon2idParams{
+	Memory:      64 * 1024,
+	Iterations:  3,
+	Parallelism: 2,
+	SaltLength:  16,
+	KeyLength:   32,
+}
+
+const argon2idPrefix = "$argon2id$"
+
+var phcEncoding = base64.RawStdEncoding
+
+// Argon2idHasher stores passwords as PHC strings, e.g.
+// $argon2id$v=19$m=65536,t=3,p=2$<salt>$<hash>
+type Argon2idHasher struct {
+	params Argon2idParams
+}
+
+func NewArgon2idHasher(params Argon2idParams) *Argon2idHasher {
+	return &Argon2idHasher{params: params}
+}
+
+func (h *Argon2idHasher) Hash(password string) (string, error) {
+	salt := make([]byte, h.params.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	key := argon2.IDKey([]byte(password), salt, h.params.Iterations, h.params.Memory, h.params.Parallelism, h.params.KeyLength)
+	return fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s", argon2idPrefix, argon2.Version,
+		h.params.Memory, h.params.Iterations, h.params.Parallelism,
+		phcEncoding.EncodeToString(salt), phcEncoding.EncodeToString(key)), nil
+}
+
+func (h *Argon2idHasher) Verify(password string, encodedHash string) (bool, error) {
+	return verifyPassword(password, encodedHash)
+}
+
+// NeedsRehash is true for bcrypt hashes and for argon2id hashes with weaker parameters.
+// Hashes with stronger parameters are kept, lowering the configuration never downgrades them.
+func (h *Argon2idHasher) NeedsRehash(encodedHash string) bool {
+	params, _, _, err := decodeArgon2id(encodedHash)
+	if err != nil {
+		return true
+	}
+	return params.Memory < h.params.Memory ||
+		params.Iterations < h.params.Iterations ||
+		params.Parallelism < h.params.Parallelism ||
+		params.SaltLength < h.params.SaltLength ||
+		params.KeyLength < h.params.KeyLength
+}
+
+// BcryptHasher keeps storing bcrypt hashes, with a configurable cost.
+type BcryptHasher struct {
+	cost int
+}
+
+func NewBcryptHasher(cost int) *BcryptHasher {
+	return &BcryptHasher{cost: cost}
+}
+
+func (h *BcryptHasher) Hash(password string) (string, error) {
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), h.cost)
+	if err != nil {
+		return "", err
+	}
+	return string(hashed), nil
+}
+
+func (h *BcryptHasher) Verify(password string, encodedHash string) (bool, error) {
+	return verifyPassword(password, encodedHash)
+}
+
+// NeedsRehash is only true for bcrypt hashes with a lower cost. Argon2id is the
+// stronger algorithm, so those hashes are never turned back into bcrypt.
+func (h *BcryptHasher) NeedsRehash(encodedHash string) bool {
+	if strings.HasPrefix(encodedHash, argon2idPrefix) {
+		return false
+	}
+	cost, err := bcrypt.Cost([]byte(encodedHash))
+	if err != nil {
+		return true
+	}
+	return cost < h.cost
+}
+
+// verifyPassword picks the algorithm from the format of the stored hash.
+func verifyPassword(password string, encodedHash string) (bool, error) {
+	if strings.HasPrefix(encodedHash, argon2idPrefix) {
+		params, salt, key, err := decodeArgon2id(encodedHash)
+		if err != nil {
+			return false, err
+		}
+		candidate := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, params.KeyLength)
+		return subtle.ConstantTimeCompare(key, candidate) == 1, nil
+	}
+
+	err := bcrypt.CompareHashAndPassword([]byte(encodedHash), []byte(password))
+	switch {
+	case err == nil:
+		return true, nil
+	case errors.Is(err, bcrypt.ErrMismatchedHashAndPassword):
+		return false, nil
+	default:
+		return false, ErrUnsupportedPasswordHash
+	}
+}
+
+func decodeArgon2id(encodedHash string) (Argon2idParams, []byte, []byte, error) {
+	// "", "argon2id", "v=19", "m=...,t=...,p=...", salt, hash
+	parts := strings.Split(encodedHash, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return Argon2idParams{}, nil, nil, ErrUnsupportedPasswordHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return Argon2idParams{}, nil, nil, ErrUnsupportedPasswordHash
+	}
+
+	var params Argon2idParams
+	_, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism)
+	if err != nil || params.Iterations < 1 || params.Parallelism < 1 {
+		return Argon2idParams{}, nil, nil, ErrUnsupportedPasswordHash
+	}
+
+	salt, err := phcEncoding.DecodeString(parts[4])
+	if err != nil {
+		return Argon2idParams{}, nil, nil, ErrUnsupportedPasswordHash
+	}
+	key, err := phcEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return Argon2idParams{}, nil, nil, ErrUnsupportedPasswordHash
+	}
+	params.SaltLength = uint32(len(salt))
+	params.KeyLength = uint32(len(key))
+
+	return params, salt, key, nil
+}
@@ -0,0 +1,78 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var testArgon2idParams = Argon2idParams{Memory: 1024, Iterations: 2, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+
+func TestArgon2idHasher_HashesInPHCFormat(t *testing.T) {
+	hasher := NewArgon2idHasher(testArgon2idParams)
+
+	hash, err := hasher.Hash("password")
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(hash, "$argon2id$v=19$m=1024,t=2,p=1$"), hash)
+
+	other, err := hasher.Hash("password")
+	require.NoError(t, err)
+	require.NotEqual(t, hash, other, "every hash gets its own salt")
+
+	match, err := hasher.Verify("password", hash)
+	require.NoError(t, err)
+	require.True(t, match)
+
+	match, err = hasher.Verify("wrong-password", hash)
+	require.NoError(t, err)
+	require.False(t, match)
+
+	require.False(t, hasher.NeedsRehash(hash))
+}
+
+func TestArgon2idHasher_RehashesOlderAlgorithmsAndWeakerParams(t *testing.T) {
+	hasher := NewArgon2idHasher(testArgon2idParams)
+
+	legacy, err := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	require.NoError(t, err)
+	match, err := hasher.Verify("password", string(legacy))
+	require.NoError(t, err)
+	require.True(t, match)
+	require.True(t, hasher.NeedsRehash(string(legacy)))
+
+	weakerParams := testArgon2idParams
+	weakerParams.Iterations = 1
+	weaker, err := NewArgon2idHasher(weakerParams).Hash("password")
+	require.NoError(t, err)
+	require.True(t, hasher.NeedsRehash(weaker))
+
+	strongerParams := testArgon2idParams
+	strongerParams.Memory = 2048
+	stronger, err := NewArgon2idHasher(strongerParams).Hash("password")
+	require.NoError(t, err)
+	require.False(t, hasher.NeedsRehash(stronger))
+}
+
+func TestBcryptHasher_KeepsArgon2idHashes(t *testing.T) {
+	hasher := NewBcryptHasher(bcrypt.MinCost + 1)
+
+	argon2idHash, err := NewArgon2idHasher(testArgon2idParams).Hash("password")
+	require.NoError(t, err)
+	match, err := hasher.Verify("password", argon2idHash)
+	require.NoError(t, err)
+	require.True(t, match)
+	require.False(t, hasher.NeedsRehash(argon2idHash))
+
+	cheaper, err := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	require.NoError(t, err)
+	require.True(t, hasher.NeedsRehash(string(cheaper)))
+}
+
+func TestVerifyPassword_RejectsMalformedHashes(t *testing.T) {
+	for _, hash := range []string{"", "plain-text", "$argon2id$v=19$m=1024,t=0,p=1$c2FsdA$a2V5", "$argon2id$v=18$m=1024,t=2,p=1$c2FsdA$a2V5"} {
+		_, err := verifyPassword("password", hash)
+		require.ErrorIs(t, err, ErrUnsupportedPasswordHash, hash)
+	}
+}
@@ -0,0 +1,43 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// RFC 6238 appendix B vectors for the SHA1 seed, truncated to six digits
+func TestTOTPCode_RFC6238Vectors(t *testing.T) {
+	secret := totpEncoding.EncodeToString([]byte("12345678901234567890"))
+
+	vectors := map[int64]string{
+		59:         "287082",
+		1111111109: "081804",
+		1234567890: "005924",
+		2000000000: "279037",
+	}
+	for unix, expected := range vectors {
+		code, err := TOTPCode(secret, TOTPStep(time.Unix(unix, 0)))
+		require.NoError(t, err)
+		require.Equal(t, expected, code, "time %d", unix)
+	}
+}
+
+func TestValidateTOTP_AcceptsAdjacentSteps(t *testing.T) {
+	secret, err := GenerateTOTPSecret()
+	require.NoError(t, err)
+
+	now := time.Now()
+	previous, err := TOTPCode(secret, TOTPStep(now)-1)
+	require.NoError(t, err)
+
+	step, ok := ValidateTOTP(secret, previous, now)
+	require.True(t, ok)
+	require.Equal(t, TOTPStep(now)-1, step)
+
+	stale, err := TOTPCode(secret, TOTPStep(now)-3)
+	require.NoError(t, err)
+	_, ok = ValidateTOTP(secret, stale, now)
+	require.False(t, ok)
+}
@@ -0,0 +1,81 @@
+// Package authz holds the roles and permissions issued by sc-auth and the checks
+// the gateway and the gRPC services use to guard their endpoints.
+package authz
+
+import (
+	"context"
+	"errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"slices"
+)
+
+// Roles seeded by the sc-auth migrations.
+const (
+	RoleAdmin     = "admin"
+	RoleModerator = "moderator"
+)
+
+// Permissions granted through roles.
+const (
+	PermissionManageRoles      = "roles:manage"
+	PermissionDeleteUsers      = "users:delete"
+	PermissionUnlockUsers      = "users:unlock"
+	PermissionImpersonateUsers = "users:impersonate"
+	PermissionModeratePosts    = "posts:moderate"
+	PermissionReadAuditLog     = "audit:read"
+)
+
+var (
+	ErrUnauthenticated  = errors.New("authentication required")
+	ErrPermissionDenied = errors.New("permission denied")
+)
+
+// Principal is the authenticated caller as described by its access token.
+type Principal struct {
+	UserID      string
+	Roles       []string
+	Permissions []string
+}
+
+func (p Principal) HasRole(role string) bool {
+	return slices.Contains(p.Roles, role)
+}
+
+func (p Principal) HasPermission(permission string) bool {
+	return slices.Contains(p.Permissions, permission)
+}
+
+// Require returns ErrPermissionDenied unless the principal has the permission.
+func (p Principal) Require(permission string) error {
+	if !p.HasPermission(permission) {
+		return ErrPermissionDenied
+	}
+	return nil
+}
+
+type principalKey struct{}
+
+// NewContext returns a copy of ctx carrying the principal.
+func NewContext(ctx context.Context, principal Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// FromContext returns the principal stored by NewContext.
+func FromContext(ctx context.Context) (Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(Principal)
+	return principal, ok
+}
+
+// RequirePermission checks the principal in ctx and reports failures as gRPC
+// status errors, ready to be returned from an RPC handler.
+func RequirePermission(ctx context.Context, permission string) error {
+	principal, ok := FromContext(ctx)
+	if !ok {
+		return status.Error(codes.Unauthenticated, ErrUnauthenticated.Error())
+	}
+	if err := principal.Require(permission); err != nil {
+		return status.Error(codes.PermissionDenied, err.Error())
+	}
+	return nil
+}
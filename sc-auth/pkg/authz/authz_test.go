@@ -0,0 +1,25 @@
+package authz
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestRequirePermission(t *testing.T) {
+	ctx := context.Background()
+	require.Equal(t, codes.Unauthenticated, status.Code(RequirePermission(ctx, PermissionDeleteUsers)))
+
+	moderator := Principal{UserID: "moderator", Roles: []string{RoleModerator}, Permissions: []string{PermissionModeratePosts}}
+	ctx = NewContext(ctx, moderator)
+	require.NoError(t, RequirePermission(ctx, PermissionModeratePosts))
+	require.Equal(t, codes.PermissionDenied, status.Code(RequirePermission(ctx, PermissionDeleteUsers)))
+
+	principal, ok := FromContext(ctx)
+	require.True(t, ok)
+	require.True(t, principal.HasRole(RoleModerator))
+	require.False(t, principal.HasRole(RoleAdmin))
+}
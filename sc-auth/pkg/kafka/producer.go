@@ -0,0 +1,33 @@
+package kafka
+
+import (
+	"context"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// Producer writes messages to any topic of the configured brokers.
+type Producer struct {
+	writer *kafka.Writer
+}
+
+// NewProducer creates a producer for the brokers. Messages with the same key go to the
+// same partition, which keeps the events of one user in order.
+func NewProducer(brokers []string) *Producer {
+	return &Producer{
+		writer: &kafka.Writer{
+			Addr:                   kafka.TCP(brokers...),
+			Balancer:               &kafka.Hash{},
+			RequiredAcks:           kafka.RequireAll,
+			AllowAutoTopicCreation: true,
+		},
+	}
+}
+
+func (p *Producer) Publish(ctx context.Context, topic string, key string, payload []byte) error {
+	return p.writer.WriteMessages(ctx, kafka.Message{Topic: topic, Key: []byte(key), Value: payload})
+}
+
+func (p *Producer) Close() error {
+	return p.writer.Close()
+}
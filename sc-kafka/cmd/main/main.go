@@ -66,6 +66,34 @@ func main() {
 		})
 	})
 
+	runWorker("user-registered", func(ctx context.Context) error {
+		return consumer.ConsumeUserRegistered(ctx, func(ctx context.Context, event appkafka.UserRegisteredEvent) error {
+			log.Printf("kafka: received user registered event: %+v", event)
+			return nil
+		})
+	})
+
+	runWorker("user-deleted", func(ctx context.Context) error {
+		return consumer.ConsumeUserDeleted(ctx, func(ctx context.Context, event appkafka.UserDeletedEvent) error {
+			log.Printf("kafka: received user deleted event: %+v", event)
+			return nil
+		})
+	})
+
+	runWorker("user-password-changed", func(ctx context.Context) error {
+		return consumer.ConsumeUserPasswordChanged(ctx, func(ctx context.Context, event appkafka.UserPasswordChangedEvent) error {
+			log.Printf("kafka: received user password changed event: %+v", event)
+			return nil
+		})
+	})
+
+	runWorker("user-logged-in", func(ctx context.Context) error {
+		return consumer.ConsumeUserLoggedIn(ctx, func(ctx context.Context, event appkafka.UserLoggedInEvent) error {
+			log.Printf("kafka: received user logged in event: %+v", event)
+			return nil
+		})
+	})
+
 	<-ctx.Done()
 	log.Println("kafka: shutting down workers")
 	wg.Wait()
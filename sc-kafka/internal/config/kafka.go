@@ -36,6 +36,10 @@ type TopicConfig struct {
 	PostCreated         string
 	SubscriptionCreated string
 	Notification        string
+	UserRegistered      string
+	UserDeleted         string
+	UserPasswordChanged string
+	UserLoggedIn        string
 }
 
 // ProducerClientConfig exposes producer-specific settings.
@@ -51,6 +55,7 @@ type ConsumerClientConfig struct {
 	PostCreatedGroup  string
 	SubscriptionGroup string
 	NotificationGroup string
+	UserEventsGroup   string
 	MinBytes          int
 	MaxBytes          int
 	CommitInterval    time.Duration
@@ -92,6 +97,10 @@ func Load(path string) (Config, error) {
 			PostCreated:         v.GetString("KAFKA_TOPIC_POST_CREATED"),
 			SubscriptionCreated: v.GetString("KAFKA_TOPIC_SUBSCRIPTION_CREATED"),
 			Notification:        v.GetString("KAFKA_TOPIC_NOTIFICATION"),
+			UserRegistered:      v.GetString("KAFKA_TOPIC_USER_REGISTERED"),
+			UserDeleted:         v.GetString("KAFKA_TOPIC_USER_DELETED"),
+			UserPasswordChanged: v.GetString("KAFKA_TOPIC_USER_PASSWORD_CHANGED"),
+			UserLoggedIn:        v.GetString("KAFKA_TOPIC_USER_LOGGED_IN"),
 		},
 		Producer: ProducerClientConfig{
 			BatchSize:            v.GetInt("KAFKA_PRODUCER_BATCH_SIZE"),
@@ -102,6 +111,7 @@ func Load(path string) (Config, error) {
 			PostCreatedGroup:  v.GetString("KAFKA_CONSUMER_POST_CREATED_GROUP"),
 			SubscriptionGroup: v.GetString("KAFKA_CONSUMER_SUBSCRIPTION_GROUP"),
 			NotificationGroup: v.GetString("KAFKA_CONSUMER_NOTIFICATION_GROUP"),
+			UserEventsGroup:   v.GetString("KAFKA_CONSUMER_USER_EVENTS_GROUP"),
 			MinBytes:          v.GetInt("KAFKA_CONSUMER_MIN_BYTES"),
 			MaxBytes:          v.GetInt("KAFKA_CONSUMER_MAX_BYTES"),
 			CommitInterval:    v.GetDuration("KAFKA_CONSUMER_COMMIT_INTERVAL"),
@@ -143,6 +153,10 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("KAFKA_TOPIC_POST_CREATED", "post.created")
 	v.SetDefault("KAFKA_TOPIC_SUBSCRIPTION_CREATED", "subscription.created")
 	v.SetDefault("KAFKA_TOPIC_NOTIFICATION", "notification.created")
+	v.SetDefault("KAFKA_TOPIC_USER_REGISTERED", "user.registered")
+	v.SetDefault("KAFKA_TOPIC_USER_DELETED", "user.deleted")
+	v.SetDefault("KAFKA_TOPIC_USER_PASSWORD_CHANGED", "user.password_changed")
+	v.SetDefault("KAFKA_TOPIC_USER_LOGGED_IN", "user.logged_in")
 
 	v.SetDefault("KAFKA_PRODUCER_BATCH_SIZE", 100)
 	v.SetDefault("KAFKA_PRODUCER_BATCH_TIMEOUT", "200ms")
@@ -151,6 +165,7 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("KAFKA_CONSUMER_POST_CREATED_GROUP", "sc-post-consumers")
 	v.SetDefault("KAFKA_CONSUMER_SUBSCRIPTION_GROUP", "sc-subscription-consumers")
 	v.SetDefault("KAFKA_CONSUMER_NOTIFICATION_GROUP", "sc-notification-consumers")
+	v.SetDefault("KAFKA_CONSUMER_USER_EVENTS_GROUP", "sc-kafka-user-event-consumers")
 	v.SetDefault("KAFKA_CONSUMER_MIN_BYTES", 1)
 	v.SetDefault("KAFKA_CONSUMER_MAX_BYTES", 10<<20)
 	v.SetDefault("KAFKA_CONSUMER_COMMIT_INTERVAL", "1s")
@@ -42,6 +42,10 @@ func NewConsumer(cfg config.Config) (*Consumer, error) {
 		cfg.Topics.PostCreated:         cfg.Consumer.PostCreatedGroup,
 		cfg.Topics.SubscriptionCreated: cfg.Consumer.SubscriptionGroup,
 		cfg.Topics.Notification:        cfg.Consumer.NotificationGroup,
+		cfg.Topics.UserRegistered:      cfg.Consumer.UserEventsGroup,
+		cfg.Topics.UserDeleted:         cfg.Consumer.UserEventsGroup,
+		cfg.Topics.UserPasswordChanged: cfg.Consumer.UserEventsGroup,
+		cfg.Topics.UserLoggedIn:        cfg.Consumer.UserEventsGroup,
 	}
 
 	readers := make(map[string]*segment.Reader, len(topicsWithGroups))
@@ -98,6 +102,26 @@ func (c *Consumer) ConsumeNotification(ctx context.Context, handler func(context
 	return c.consumeNotification(ctx, handler)
 }
 
+// ConsumeUserRegistered runs the handler for user-registered events.
+func (c *Consumer) ConsumeUserRegistered(ctx context.Context, handler func(context.Context, UserRegisteredEvent) error) error {
+	return consumeMessages(ctx, c.readers, c.topics.UserRegistered, handler)
+}
+
+// ConsumeUserDeleted runs the handler for user-deleted events.
+func (c *Consumer) ConsumeUserDeleted(ctx context.Context, handler func(context.Context, UserDeletedEvent) error) error {
+	return consumeMessages(ctx, c.readers, c.topics.UserDeleted, handler)
+}
+
+// ConsumeUserPasswordChanged runs the handler for password-changed events.
+func (c *Consumer) ConsumeUserPasswordChanged(ctx context.Context, handler func(context.Context, UserPasswordChangedEvent) error) error {
+	return consumeMessages(ctx, c.readers, c.topics.UserPasswordChanged, handler)
+}
+
+// ConsumeUserLoggedIn runs the handler for user-logged-in events.
+func (c *Consumer) ConsumeUserLoggedIn(ctx context.Context, handler func(context.Context, UserLoggedInEvent) error) error {
+	return consumeMessages(ctx, c.readers, c.topics.UserLoggedIn, handler)
+}
+
 func (c *Consumer) consumePostCreated(ctx context.Context, handler func(context.Context, PostCreatedEvent) error) error {
 	return consumeMessages(ctx, c.readers, c.topics.PostCreated, handler)
 }
@@ -27,3 +27,38 @@ type NotificationEvent struct {
 	Metadata  map[string]string `json:"metadata,omitempty"`
 	CreatedAt time.Time         `json:"created_at"`
 }
+
+// UserRegisteredEvent is published by sc-auth once an account was created.
+type UserRegisteredEvent struct {
+	ID         string    `json:"id"`
+	UserID     string    `json:"user_id"`
+	Username   string    `json:"username"`
+	Email      string    `json:"email"`
+	OccurredAt time.Time `json:"occurred_at"`
+}
+
+// UserDeletedEvent is published by sc-auth once an account was deleted.
+type UserDeletedEvent struct {
+	ID         string    `json:"id"`
+	UserID     string    `json:"user_id"`
+	OccurredAt time.Time `json:"occurred_at"`
+}
+
+// UserPasswordChangedEvent is published by sc-auth when a password was changed
+// by its owner ("changed") or through a reset link ("reset").
+type UserPasswordChangedEvent struct {
+	ID         string    `json:"id"`
+	UserID     string    `json:"user_id"`
+	Reason     string    `json:"reason"`
+	OccurredAt time.Time `json:"occurred_at"`
+}
+
+// UserLoggedInEvent is published by sc-auth for every completed login.
+type UserLoggedInEvent struct {
+	ID         string    `json:"id"`
+	UserID     string    `json:"user_id"`
+	SessionID  string    `json:"session_id"`
+	UserAgent  string    `json:"user_agent,omitempty"`
+	IPAddress  string    `json:"ip_address,omitempty"`
+	OccurredAt time.Time `json:"occurred_at"`
+}
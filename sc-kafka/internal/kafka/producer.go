@@ -42,6 +42,10 @@ func NewProducer(cfg config.Config) (*Producer, error) {
 		cfg.Topics.PostCreated,
 		cfg.Topics.SubscriptionCreated,
 		cfg.Topics.Notification,
+		cfg.Topics.UserRegistered,
+		cfg.Topics.UserDeleted,
+		cfg.Topics.UserPasswordChanged,
+		cfg.Topics.UserLoggedIn,
 	}
 
 	writers := make(map[string]*segment.Writer, len(topics))
@@ -103,6 +107,27 @@ func (p *Producer) PublishNotification(ctx context.Context, event NotificationEv
 	return p.publish(ctx, p.topics.Notification, key, event)
 }
 
+// PublishUserRegistered emits a user registration event, keyed by user so the
+// events of one user keep their order.
+func (p *Producer) PublishUserRegistered(ctx context.Context, event UserRegisteredEvent) error {
+	return p.publish(ctx, p.topics.UserRegistered, keyOrFallback(event.UserID, event.ID), event)
+}
+
+// PublishUserDeleted emits a user deletion event to the configured topic.
+func (p *Producer) PublishUserDeleted(ctx context.Context, event UserDeletedEvent) error {
+	return p.publish(ctx, p.topics.UserDeleted, keyOrFallback(event.UserID, event.ID), event)
+}
+
+// PublishUserPasswordChanged emits a password change event to the configured topic.
+func (p *Producer) PublishUserPasswordChanged(ctx context.Context, event UserPasswordChangedEvent) error {
+	return p.publish(ctx, p.topics.UserPasswordChanged, keyOrFallback(event.UserID, event.ID), event)
+}
+
+// PublishUserLoggedIn emits a login event to the configured topic.
+func (p *Producer) PublishUserLoggedIn(ctx context.Context, event UserLoggedInEvent) error {
+	return p.publish(ctx, p.topics.UserLoggedIn, keyOrFallback(event.UserID, event.ID), event)
+}
+
 func (p *Producer) publish(ctx context.Context, topic string, key []byte, payload any) error {
 	writer, ok := p.writers[topic]
 	if !ok {
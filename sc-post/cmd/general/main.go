@@ -7,6 +7,7 @@ import (
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/reflection"
+	"soul-connect/pkg/requestctx"
 	"soul-connect/sc-post/internal/config"
 	"soul-connect/sc-post/internal/events"
 	"soul-connect/sc-post/internal/server"
@@ -38,7 +39,7 @@ func main() {
 	publisher := events.NewPostEventPublisher(producer, cfg.KafkaTopic)
 	svc := services.NewServices(pool, publisher)
 
-	grpcServer := grpc.NewServer()
+	grpcServer := grpc.NewServer(grpc.UnaryInterceptor(requestctx.UnaryServerInterceptor()))
 	reflection.Register(grpcServer)
 
 	postpb.RegisterPostServiceServer(grpcServer, server.NewPostServer(svc))
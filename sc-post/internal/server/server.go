@@ -2,8 +2,12 @@ package server
 
 import (
 	"context"
+	"errors"
 	"time"
 
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"soul-connect/sc-post/internal/models"
 	"soul-connect/sc-post/internal/services"
 	postpb "soul-connect/sc-post/pkg/postpb"
@@ -27,7 +31,7 @@ func (s *PostServer) CreatePost(ctx context.Context, req *postpb.CreatePostReque
 	}
 	post, err := s.services.Posts.CreatePost(ctx, input)
 	if err != nil {
-		return nil, err
+		return nil, postStatus(err)
 	}
 	return &postpb.CreatePostResponse{Post: toProtoPost(*post)}, nil
 }
@@ -35,11 +39,11 @@ func (s *PostServer) CreatePost(ctx context.Context, req *postpb.CreatePostReque
 func (s *PostServer) GetPost(ctx context.Context, req *postpb.GetPostRequest) (*postpb.GetPostResponse, error) {
 	post, err := s.services.Posts.GetPost(ctx, req.Id)
 	if err != nil {
-		return nil, err
+		return nil, postStatus(err)
 	}
 	comments, err := s.services.Comments.ListCommentsByPost(ctx, req.Id)
 	if err != nil {
-		return nil, err
+		return nil, postStatus(err)
 	}
 	return &postpb.GetPostResponse{
 		Post:     toProtoPost(*post),
@@ -50,7 +54,7 @@ func (s *PostServer) GetPost(ctx context.Context, req *postpb.GetPostRequest) (*
 func (s *PostServer) ListPosts(ctx context.Context, req *postpb.ListPostsRequest) (*postpb.ListPostsResponse, error) {
 	posts, err := s.services.Posts.ListPosts(ctx, req.LabelIds)
 	if err != nil {
-		return nil, err
+		return nil, postStatus(err)
 	}
 	summaries := make([]*postpb.PostSummary, 0, len(posts))
 	for _, summary := range posts {
@@ -71,7 +75,7 @@ func (s *PostServer) AddComment(ctx context.Context, req *postpb.AddCommentReque
 		Content: req.Content,
 	})
 	if err != nil {
-		return nil, err
+		return nil, postStatus(err)
 	}
 	return &postpb.AddCommentResponse{Comment: toProtoComment(*comment)}, nil
 }
@@ -79,7 +83,7 @@ func (s *PostServer) AddComment(ctx context.Context, req *postpb.AddCommentReque
 func (s *PostServer) ListComments(ctx context.Context, req *postpb.ListCommentsRequest) (*postpb.ListCommentsResponse, error) {
 	comments, err := s.services.Comments.ListCommentsByPost(ctx, req.PostId)
 	if err != nil {
-		return nil, err
+		return nil, postStatus(err)
 	}
 	return &postpb.ListCommentsResponse{Comments: toProtoComments(comments)}, nil
 }
@@ -87,7 +91,7 @@ func (s *PostServer) ListComments(ctx context.Context, req *postpb.ListCommentsR
 func (s *PostServer) LikePost(ctx context.Context, req *postpb.LikePostRequest) (*postpb.LikeCountResponse, error) {
 	likes, err := s.services.Likes.LikePost(ctx, models.LikeInput{TargetID: req.PostId, UserID: req.UserId})
 	if err != nil {
-		return nil, err
+		return nil, postStatus(err)
 	}
 	return &postpb.LikeCountResponse{LikesCount: likes}, nil
 }
@@ -95,7 +99,7 @@ func (s *PostServer) LikePost(ctx context.Context, req *postpb.LikePostRequest)
 func (s *PostServer) UnlikePost(ctx context.Context, req *postpb.UnlikePostRequest) (*postpb.LikeCountResponse, error) {
 	likes, err := s.services.Likes.UnlikePost(ctx, models.LikeInput{TargetID: req.PostId, UserID: req.UserId})
 	if err != nil {
-		return nil, err
+		return nil, postStatus(err)
 	}
 	return &postpb.LikeCountResponse{LikesCount: likes}, nil
 }
@@ -103,7 +107,7 @@ func (s *PostServer) UnlikePost(ctx context.Context, req *postpb.UnlikePostReque
 func (s *PostServer) LikeComment(ctx context.Context, req *postpb.LikeCommentRequest) (*postpb.LikeCountResponse, error) {
 	likes, err := s.services.Likes.LikeComment(ctx, models.LikeInput{TargetID: req.CommentId, UserID: req.UserId})
 	if err != nil {
-		return nil, err
+		return nil, postStatus(err)
 	}
 	return &postpb.LikeCountResponse{LikesCount: likes}, nil
 }
@@ -111,7 +115,7 @@ func (s *PostServer) LikeComment(ctx context.Context, req *postpb.LikeCommentReq
 func (s *PostServer) UnlikeComment(ctx context.Context, req *postpb.UnlikeCommentRequest) (*postpb.LikeCountResponse, error) {
 	likes, err := s.services.Likes.UnlikeComment(ctx, models.LikeInput{TargetID: req.CommentId, UserID: req.UserId})
 	if err != nil {
-		return nil, err
+		return nil, postStatus(err)
 	}
 	return &postpb.LikeCountResponse{LikesCount: likes}, nil
 }
@@ -119,7 +123,7 @@ func (s *PostServer) UnlikeComment(ctx context.Context, req *postpb.UnlikeCommen
 func (s *PostServer) ListLabels(ctx context.Context, _ *postpb.Empty) (*postpb.ListLabelsResponse, error) {
 	labels, err := s.services.Labels.ListLabels(ctx)
 	if err != nil {
-		return nil, err
+		return nil, postStatus(err)
 	}
 	return &postpb.ListLabelsResponse{Labels: toProtoLabels(labels)}, nil
 }
@@ -127,7 +131,7 @@ func (s *PostServer) ListLabels(ctx context.Context, _ *postpb.Empty) (*postpb.L
 func (s *PostServer) AddLabelToPost(ctx context.Context, req *postpb.AddLabelToPostRequest) (*postpb.Empty, error) {
 	err := s.services.Labels.AddLabelToPost(ctx, models.LabelAssignmentInput{PostID: req.PostId, LabelID: req.LabelId})
 	if err != nil {
-		return nil, err
+		return nil, postStatus(err)
 	}
 	return &postpb.Empty{}, nil
 }
@@ -135,7 +139,7 @@ func (s *PostServer) AddLabelToPost(ctx context.Context, req *postpb.AddLabelToP
 func (s *PostServer) RemoveLabelFromPost(ctx context.Context, req *postpb.RemoveLabelFromPostRequest) (*postpb.Empty, error) {
 	err := s.services.Labels.RemoveLabelFromPost(ctx, models.LabelAssignmentInput{PostID: req.PostId, LabelID: req.LabelId})
 	if err != nil {
-		return nil, err
+		return nil, postStatus(err)
 	}
 	return &postpb.Empty{}, nil
 }
@@ -153,14 +157,14 @@ func (s *PostServer) UpdatePost(ctx context.Context, req *postpb.UpdatePostReque
 	}
 	post, err := s.services.Posts.UpdatePost(ctx, models.UpdatePostInput{ID: req.Id, Title: titlePtr, Description: descriptionPtr})
 	if err != nil {
-		return nil, err
+		return nil, postStatus(err)
 	}
 	return toProtoPost(*post), nil
 }
 
 func (s *PostServer) DeletePost(ctx context.Context, req *postpb.GetPostRequest) (*postpb.Empty, error) {
 	if err := s.services.Posts.DeletePost(ctx, req.Id); err != nil {
-		return nil, err
+		return nil, postStatus(err)
 	}
 	return &postpb.Empty{}, nil
 }
@@ -205,3 +209,37 @@ func toProtoLabels(labels []models.Label) []*postpb.Label {
 	}
 	return proto
 }
+
+// postStatus maps the errors of the services to gRPC status codes. Anything unexpected
+// is reported as Internal, which requestctx.UnaryServerInterceptor logs and the gateway
+// does not pass on.
+func postStatus(err error) error {
+	var validationErr *services.ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		return fieldStatus(codes.InvalidArgument, validationErr.Field, validationErr.Message)
+	case errors.Is(err, services.ErrPostNotFound), errors.Is(err, services.ErrCommentNotFound),
+		errors.Is(err, services.ErrLabelNotFound), errors.Is(err, services.ErrUserNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, services.ErrAlreadyLiked):
+		return status.Error(codes.AlreadyExists, err.Error())
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return status.FromContextError(err).Err()
+	}
+	return status.Error(codes.Internal, err.Error())
+}
+
+// fieldStatus reports an invalid request with a BadRequest detail naming the field.
+func fieldStatus(code codes.Code, field string, message string) error {
+	st := status.New(code, message)
+	detailed, err := st.WithDetails(&errdetails.BadRequest{
+		FieldViolations: []*errdetails.BadRequest_FieldViolation{{
+			Field:       field,
+			Description: message,
+		}},
+	})
+	if err != nil {
+		return st.Err()
+	}
+	return detailed.Err()
+}
@@ -2,12 +2,10 @@ package services
 
 import (
 	"context"
-	"errors"
 
 	db "soul-connect/sc-post/internal/db/sqlc"
 	"soul-connect/sc-post/internal/models"
 	"soul-connect/sc-post/internal/repository"
-	"soul-connect/sc-post/internal/utils"
 )
 
 type CommentService struct {
@@ -20,13 +18,13 @@ func NewCommentService(repo repository.CommentRepository) *CommentService {
 
 func (s *CommentService) AddComment(ctx context.Context, input models.AddCommentInput) (*models.Comment, error) {
 	if input.Content == "" {
-		return nil, errors.New("content is required")
+		return nil, requiredError("content")
 	}
-	postID, err := utils.UUIDFromString(input.PostID)
+	postID, err := parseID("post_id", input.PostID)
 	if err != nil {
 		return nil, err
 	}
-	userID, err := utils.UUIDFromString(input.UserID)
+	userID, err := parseID("user_id", input.UserID)
 	if err != nil {
 		return nil, err
 	}
@@ -37,7 +35,7 @@ func (s *CommentService) AddComment(ctx context.Context, input models.AddComment
 		Content: input.Content,
 	})
 	if err != nil {
-		return nil, err
+		return nil, referenceError(err)
 	}
 
 	model := commentFromDB(created)
@@ -45,7 +43,7 @@ func (s *CommentService) AddComment(ctx context.Context, input models.AddComment
 }
 
 func (s *CommentService) ListCommentsByPost(ctx context.Context, postID string) ([]models.Comment, error) {
-	parsed, err := utils.UUIDFromString(postID)
+	parsed, err := parseID("post_id", postID)
 	if err != nil {
 		return nil, err
 	}
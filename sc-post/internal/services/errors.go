@@ -0,0 +1,76 @@
+package services
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"soul-connect/sc-post/internal/utils"
+)
+
+// Postgres error codes the services turn into domain errors.
+const (
+	uniqueViolationCode     = "23505"
+	foreignKeyViolationCode = "23503"
+)
+
+var (
+	ErrPostNotFound    = errors.New("post not found")
+	ErrCommentNotFound = errors.New("comment not found")
+	ErrLabelNotFound   = errors.New("label not found")
+	ErrUserNotFound    = errors.New("user not found")
+	ErrAlreadyLiked    = errors.New("already liked")
+)
+
+// ValidationError reports a request field the service rejected. Field is named like in
+// the gRPC request, so it can be passed on to clients.
+type ValidationError struct {
+	Field   string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return e.Message
+}
+
+func requiredError(field string) error {
+	return &ValidationError{Field: field, Message: field + " is required"}
+}
+
+// parseID parses the UUID in a request field and reports a bad one as ValidationError.
+func parseID(field string, value string) (pgtype.UUID, error) {
+	if value == "" {
+		return pgtype.UUID{}, requiredError(field)
+	}
+	id, err := utils.UUIDFromString(value)
+	if err != nil {
+		return pgtype.UUID{}, &ValidationError{Field: field, Message: field + " must be a UUID"}
+	}
+	return id, nil
+}
+
+// referenceError reports a write that referenced a missing row as the not found error
+// of that row. The foreign keys are named by Postgres after the referencing column.
+func referenceError(err error) error {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) || pgErr.Code != foreignKeyViolationCode {
+		return err
+	}
+	switch {
+	case strings.HasSuffix(pgErr.ConstraintName, "_post_id_fkey"):
+		return ErrPostNotFound
+	case strings.HasSuffix(pgErr.ConstraintName, "_comment_id_fkey"):
+		return ErrCommentNotFound
+	case strings.HasSuffix(pgErr.ConstraintName, "_label_id_fkey"):
+		return ErrLabelNotFound
+	case strings.HasSuffix(pgErr.ConstraintName, "_user_id_fkey"):
+		return ErrUserNotFound
+	}
+	return err
+}
+
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == uniqueViolationCode
+}
@@ -6,7 +6,6 @@ import (
 	db "soul-connect/sc-post/internal/db/sqlc"
 	"soul-connect/sc-post/internal/models"
 	"soul-connect/sc-post/internal/repository"
-	"soul-connect/sc-post/internal/utils"
 )
 
 type LabelService struct {
@@ -26,7 +25,7 @@ func (s *LabelService) ListLabels(ctx context.Context) ([]models.Label, error) {
 }
 
 func (s *LabelService) GetLabelsForPost(ctx context.Context, postID string) ([]models.Label, error) {
-	parsed, err := utils.UUIDFromString(postID)
+	parsed, err := parseID("post_id", postID)
 	if err != nil {
 		return nil, err
 	}
@@ -38,23 +37,23 @@ func (s *LabelService) GetLabelsForPost(ctx context.Context, postID string) ([]m
 }
 
 func (s *LabelService) AddLabelToPost(ctx context.Context, input models.LabelAssignmentInput) error {
-	postID, err := utils.UUIDFromString(input.PostID)
+	postID, err := parseID("post_id", input.PostID)
 	if err != nil {
 		return err
 	}
-	labelID, err := utils.UUIDFromString(input.LabelID)
+	labelID, err := parseID("label_id", input.LabelID)
 	if err != nil {
 		return err
 	}
-	return s.repo.AddLabelToPost(ctx, db.AddLabelToPostParams{PostID: postID, LabelID: labelID})
+	return referenceError(s.repo.AddLabelToPost(ctx, db.AddLabelToPostParams{PostID: postID, LabelID: labelID}))
 }
 
 func (s *LabelService) RemoveLabelFromPost(ctx context.Context, input models.LabelAssignmentInput) error {
-	postID, err := utils.UUIDFromString(input.PostID)
+	postID, err := parseID("post_id", input.PostID)
 	if err != nil {
 		return err
 	}
-	labelID, err := utils.UUIDFromString(input.LabelID)
+	labelID, err := parseID("label_id", input.LabelID)
 	if err != nil {
 		return err
 	}
@@ -2,11 +2,13 @@ package services
 
 import (
 	"context"
+	"errors"
 
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
 	db "soul-connect/sc-post/internal/db/sqlc"
 	"soul-connect/sc-post/internal/models"
 	"soul-connect/sc-post/internal/repository"
-	"soul-connect/sc-post/internal/utils"
 )
 
 type LikeService struct {
@@ -18,33 +20,27 @@ func NewLikeService(repo repository.LikeRepository) *LikeService {
 }
 
 func (s *LikeService) LikePost(ctx context.Context, input models.LikeInput) (int32, error) {
-	postID, err := utils.UUIDFromString(input.TargetID)
+	postID, err := parseID("post_id", input.TargetID)
 	if err != nil {
 		return 0, err
 	}
-	userID, err := utils.UUIDFromString(input.UserID)
+	userID, err := parseID("user_id", input.UserID)
 	if err != nil {
 		return 0, err
 	}
 	if err := s.repo.CreateLikeForPost(ctx, db.CreateLikeForPostParams{PostID: postID, UserID: userID}); err != nil {
-		return 0, err
+		return 0, likeError(err)
 	}
 	count, err := s.repo.GetLikesCountForPost(ctx, postID)
-	if err != nil {
-		return 0, err
-	}
-	if count.Valid {
-		return count.Int32, nil
-	}
-	return 0, nil
+	return likesCount(count, err, ErrPostNotFound)
 }
 
 func (s *LikeService) UnlikePost(ctx context.Context, input models.LikeInput) (int32, error) {
-	postID, err := utils.UUIDFromString(input.TargetID)
+	postID, err := parseID("post_id", input.TargetID)
 	if err != nil {
 		return 0, err
 	}
-	userID, err := utils.UUIDFromString(input.UserID)
+	userID, err := parseID("user_id", input.UserID)
 	if err != nil {
 		return 0, err
 	}
@@ -52,43 +48,31 @@ func (s *LikeService) UnlikePost(ctx context.Context, input models.LikeInput) (i
 		return 0, err
 	}
 	count, err := s.repo.GetLikesCountForPost(ctx, postID)
-	if err != nil {
-		return 0, err
-	}
-	if count.Valid {
-		return count.Int32, nil
-	}
-	return 0, nil
+	return likesCount(count, err, ErrPostNotFound)
 }
 
 func (s *LikeService) LikeComment(ctx context.Context, input models.LikeInput) (int32, error) {
-	commentID, err := utils.UUIDFromString(input.TargetID)
+	commentID, err := parseID("comment_id", input.TargetID)
 	if err != nil {
 		return 0, err
 	}
-	userID, err := utils.UUIDFromString(input.UserID)
+	userID, err := parseID("user_id", input.UserID)
 	if err != nil {
 		return 0, err
 	}
 	if err := s.repo.CreateLikeForComment(ctx, db.CreateLikeForCommentParams{CommentID: commentID, UserID: userID}); err != nil {
-		return 0, err
+		return 0, likeError(err)
 	}
 	count, err := s.repo.GetLikesCountForComment(ctx, commentID)
-	if err != nil {
-		return 0, err
-	}
-	if count.Valid {
-		return count.Int32, nil
-	}
-	return 0, nil
+	return likesCount(count, err, ErrCommentNotFound)
 }
 
 func (s *LikeService) UnlikeComment(ctx context.Context, input models.LikeInput) (int32, error) {
-	commentID, err := utils.UUIDFromString(input.TargetID)
+	commentID, err := parseID("comment_id", input.TargetID)
 	if err != nil {
 		return 0, err
 	}
-	userID, err := utils.UUIDFromString(input.UserID)
+	userID, err := parseID("user_id", input.UserID)
 	if err != nil {
 		return 0, err
 	}
@@ -96,6 +80,22 @@ func (s *LikeService) UnlikeComment(ctx context.Context, input models.LikeInput)
 		return 0, err
 	}
 	count, err := s.repo.GetLikesCountForComment(ctx, commentID)
+	return likesCount(count, err, ErrCommentNotFound)
+}
+
+func likeError(err error) error {
+	if isUniqueViolation(err) {
+		return ErrAlreadyLiked
+	}
+	return referenceError(err)
+}
+
+// likesCount returns the count read after a like changed. A target that does not exist
+// is reported as notFound.
+func likesCount(count pgtype.Int4, err error, notFound error) (int32, error) {
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, notFound
+	}
 	if err != nil {
 		return 0, err
 	}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
 	db "soul-connect/sc-post/internal/db/sqlc"
 	"soul-connect/sc-post/internal/events"
@@ -25,9 +26,9 @@ func NewPostService(postRepo repository.PostRepository, labelRepo repository.Lab
 
 func (s *PostService) CreatePost(ctx context.Context, input models.CreatePostInput) (*models.Post, error) {
 	if input.Title == "" {
-		return nil, errors.New("title is required")
+		return nil, requiredError("title")
 	}
-	userID, err := utils.UUIDFromString(input.UserID)
+	userID, err := parseID("user_id", input.UserID)
 	if err != nil {
 		return nil, err
 	}
@@ -40,7 +41,7 @@ func (s *PostService) CreatePost(ctx context.Context, input models.CreatePostInp
 
 	created, err := s.postRepo.CreatePost(ctx, params)
 	if err != nil {
-		return nil, err
+		return nil, referenceError(err)
 	}
 
 	for _, labelID := range input.LabelIDs {
@@ -65,12 +66,12 @@ func (s *PostService) CreatePost(ctx context.Context, input models.CreatePostInp
 }
 
 func (s *PostService) GetPost(ctx context.Context, id string) (*models.Post, error) {
-	postID, err := utils.UUIDFromString(id)
+	postID, err := parseID("id", id)
 	if err != nil {
 		return nil, err
 	}
 
-	post, err := s.postRepo.GetPostByID(ctx, postID)
+	post, err := s.getPost(ctx, postID)
 	if err != nil {
 		return nil, err
 	}
@@ -106,7 +107,7 @@ func (s *PostService) ListPosts(ctx context.Context, labelIDs []string) ([]model
 	summaries := make([]models.PostSummary, 0)
 	seen := make(map[string]struct{})
 	for _, labelIDStr := range labelIDs {
-		labelID, err := utils.UUIDFromString(labelIDStr)
+		labelID, err := parseID("label_ids", labelIDStr)
 		if err != nil {
 			return nil, err
 		}
@@ -141,12 +142,12 @@ func (s *PostService) ListPosts(ctx context.Context, labelIDs []string) ([]model
 }
 
 func (s *PostService) UpdatePost(ctx context.Context, input models.UpdatePostInput) (*models.Post, error) {
-	postID, err := utils.UUIDFromString(input.ID)
+	postID, err := parseID("id", input.ID)
 	if err != nil {
 		return nil, err
 	}
 
-	existing, err := s.postRepo.GetPostByID(ctx, postID)
+	existing, err := s.getPost(ctx, postID)
 	if err != nil {
 		return nil, err
 	}
@@ -184,23 +185,32 @@ func (s *PostService) UpdatePost(ctx context.Context, input models.UpdatePostInp
 }
 
 func (s *PostService) DeletePost(ctx context.Context, id string) error {
-	postID, err := utils.UUIDFromString(id)
+	postID, err := parseID("id", id)
 	if err != nil {
 		return err
 	}
 	return s.postRepo.DeletePost(ctx, postID)
 }
 
+// getPost reads a post and reports a missing one as ErrPostNotFound.
+func (s *PostService) getPost(ctx context.Context, id pgtype.UUID) (db.Post, error) {
+	post, err := s.postRepo.GetPostByID(ctx, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return db.Post{}, ErrPostNotFound
+	}
+	return post, err
+}
+
 func (s *PostService) attachLabel(ctx context.Context, postID pgtype.UUID, labelID string) error {
 	if labelID == "" {
 		return nil
 	}
-	parsed, err := utils.UUIDFromString(labelID)
+	parsed, err := parseID("label_ids", labelID)
 	if err != nil {
 		return err
 	}
-	return s.labelRepo.AddLabelToPost(ctx, db.AddLabelToPostParams{
+	return referenceError(s.labelRepo.AddLabelToPost(ctx, db.AddLabelToPostParams{
 		LabelID: parsed,
 		PostID:  postID,
-	})
+	}))
 }
@@ -6,6 +6,8 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/stretchr/testify/require"
 	db "soul-connect/sc-post/internal/db/sqlc"
@@ -177,6 +179,46 @@ func TestPostService_ListPostsAggregatesLabels(t *testing.T) {
 	require.Len(t, summary.Post.Labels, 1)
 }
 
+func TestPostService_ReportsTypedErrors(t *testing.T) {
+	ctx := context.Background()
+	labelID := uuid.New()
+
+	postRepo := &stubPostRepo{
+		CreatePostFn: func(_ context.Context, arg db.CreatePostParams) (db.Post, error) {
+			return db.Post{ID: toPgUUID(uuid.New()), UserID: arg.UserID, Title: arg.Title}, nil
+		},
+		GetPostByIDFn: func(context.Context, pgtype.UUID) (db.Post, error) {
+			return db.Post{}, pgx.ErrNoRows
+		},
+	}
+	labelRepo := &stubLabelRepo{
+		AddLabelToPostFn: func(context.Context, db.AddLabelToPostParams) error {
+			return &pgconn.PgError{Code: foreignKeyViolationCode, ConstraintName: "labels_posts_label_id_fkey"}
+		},
+	}
+	service := NewPostService(postRepo, labelRepo, &stubCommentRepo{}, nil)
+
+	var validationErr *ValidationError
+
+	_, err := service.CreatePost(ctx, models.CreatePostInput{UserID: uuid.NewString()})
+	require.ErrorAs(t, err, &validationErr)
+	require.Equal(t, "title", validationErr.Field)
+
+	_, err = service.CreatePost(ctx, models.CreatePostInput{UserID: "alice", Title: "Hello"})
+	require.ErrorAs(t, err, &validationErr)
+	require.Equal(t, "user_id", validationErr.Field)
+
+	_, err = service.CreatePost(ctx, models.CreatePostInput{UserID: uuid.NewString(), Title: "Hello", LabelIDs: []string{labelID.String()}})
+	require.ErrorIs(t, err, ErrLabelNotFound)
+
+	_, err = service.GetPost(ctx, uuid.NewString())
+	require.ErrorIs(t, err, ErrPostNotFound)
+
+	title := "Edited"
+	_, err = service.UpdatePost(ctx, models.UpdatePostInput{ID: uuid.NewString(), Title: &title})
+	require.ErrorIs(t, err, ErrPostNotFound)
+}
+
 func toPgUUID(id uuid.UUID) pgtype.UUID {
 	var b [16]byte
 	copy(b[:], id[:])
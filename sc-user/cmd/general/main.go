@@ -1,17 +1,22 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/reflection"
 	"log"
 	"net"
 	"os"
+	"os/signal"
+	"soul-connect/pkg/requestctx"
 	"soul-connect/sc-user/internal/config"
 	"soul-connect/sc-user/internal/generated"
+	"soul-connect/sc-user/internal/messaging"
 	"soul-connect/sc-user/internal/server"
 	"soul-connect/sc-user/internal/services"
 	"soul-connect/sc-user/pkg/database"
+	"syscall"
 )
 
 func main() {
@@ -32,14 +37,38 @@ func main() {
 		log.Fatalf("failed to listen on gRPC port %s: %v", cfg.ServerPort, err)
 	}
 
-	grpcServer := grpc.NewServer()
+	grpcServer := grpc.NewServer(grpc.UnaryInterceptor(requestctx.UnaryServerInterceptor()))
 	reflection.Register(grpcServer)
 
 	service := services.NewService(pool)
 	userServer := server.NewUserServer(service)
 
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if cfg.KafkaBrokers != "" {
+		consumer, err := messaging.NewAccountEventsConsumer(&cfg, service.UserService)
+		if err != nil {
+			log.Printf("account events consumer disabled: %v", err)
+		} else {
+			go consumer.Start(ctx)
+			defer func() {
+				if err := consumer.Close(); err != nil {
+					log.Printf("account events consumer close error: %v", err)
+				}
+			}()
+		}
+	} else {
+		log.Println("KAFKA_BROKERS is not set, profiles are not provisioned from account events")
+	}
+
 	generated.RegisterUserServiceServer(grpcServer, userServer)
 
+	go func() {
+		<-ctx.Done()
+		grpcServer.GracefulStop()
+	}()
+
 	log.Printf("User gRPC server listening on port %s", cfg.ServerPort)
 	if err := grpcServer.Serve(listener); err != nil {
 		log.Fatalf("failed to serve gRPC: %v", err)
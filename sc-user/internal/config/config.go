@@ -11,6 +11,11 @@ type Config struct {
 	ServerPort    string `mapstructure:"SERVER_PORT"`
 	WebappBaseUrl string `mapstructure:"WEBAPP_BASE_URL"`
 	DBSource      string `mapstructure:"DB_SOURCE"`
+
+	KafkaBrokers             string `mapstructure:"KAFKA_BROKERS"`
+	KafkaGroupID             string `mapstructure:"KAFKA_GROUP_ID"`
+	KafkaUserRegisteredTopic string `mapstructure:"KAFKA_USER_REGISTERED_TOPIC"`
+	KafkaUserDeletedTopic    string `mapstructure:"KAFKA_USER_DELETED_TOPIC"`
 }
 
 func LoadConfig(path string) (config Config, err error) {
@@ -18,6 +23,12 @@ func LoadConfig(path string) (config Config, err error) {
 
 	viper.AutomaticEnv()
 
+	// Without brokers the service runs without provisioning profiles from auth events
+	viper.SetDefault("KAFKA_BROKERS", "")
+	viper.SetDefault("KAFKA_GROUP_ID", "sc-user-account-events")
+	viper.SetDefault("KAFKA_USER_REGISTERED_TOPIC", "user.registered")
+	viper.SetDefault("KAFKA_USER_DELETED_TOPIC", "user.deleted")
+
 	err = viper.ReadInConfig()
 	if err != nil {
 		log.Fatalf("could not loadconfig: %v", err)
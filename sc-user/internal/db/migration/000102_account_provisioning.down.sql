@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS deleted_accounts;
+DROP INDEX IF EXISTS users_auth_id_key;
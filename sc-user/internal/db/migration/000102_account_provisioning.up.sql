@@ -0,0 +1,8 @@
+-- one profile per account, so provisioning from auth events can be repeated safely
+CREATE UNIQUE INDEX IF NOT EXISTS users_auth_id_key ON users (auth_id);
+
+-- accounts that were deleted in sc-auth, a late registration event must not bring the profile back
+CREATE TABLE IF NOT EXISTS deleted_accounts (
+    auth_id UUID PRIMARY KEY,
+    deleted_at TIMESTAMP DEFAULT NOW()
+);
@@ -9,6 +9,12 @@ FROM users
 WHERE id = @id
 LIMIT 1;
 
+-- name: GetUserByAuthID :one
+SELECT id, auth_id, full_name, bio, photo_link, created_at, updated_at
+FROM users
+WHERE auth_id = @auth_id
+LIMIT 1;
+
 -- name: UpdateUser :exec
 UPDATE users
 SET full_name = COALESCE(@full_name, full_name),
@@ -19,4 +25,21 @@ WHERE id = @id;
 
 -- name: DeleteUser :exec
 DELETE FROM users
-WHERE id = @id;
\ No newline at end of file
+WHERE id = @id;
+
+-- name: ProvisionUser :execrows
+INSERT INTO users (auth_id, full_name)
+SELECT @auth_id::uuid, @full_name::varchar
+WHERE NOT EXISTS (
+    SELECT 1 FROM deleted_accounts WHERE auth_id = @auth_id::uuid
+)
+ON CONFLICT (auth_id) DO NOTHING;
+
+-- name: MarkAccountDeleted :exec
+INSERT INTO deleted_accounts (auth_id)
+VALUES (@auth_id)
+ON CONFLICT (auth_id) DO NOTHING;
+
+-- name: DeleteUserByAuthID :execrows
+DELETE FROM users
+WHERE auth_id = @auth_id;
@@ -8,6 +8,11 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+type DeletedAccount struct {
+	AuthID    pgtype.UUID      `json:"auth_id"`
+	DeletedAt pgtype.Timestamp `json:"deleted_at"`
+}
+
 type Subscription struct {
 	ID           pgtype.UUID      `json:"id"`
 	SubscriberID pgtype.UUID      `json:"subscriber_id"`
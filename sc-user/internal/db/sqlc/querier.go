@@ -15,8 +15,12 @@ type Querier interface {
 	CreateUser(ctx context.Context, arg CreateUserParams) (User, error)
 	DeleteSubscription(ctx context.Context, arg DeleteSubscriptionParams) error
 	DeleteUser(ctx context.Context, id pgtype.UUID) error
+	DeleteUserByAuthID(ctx context.Context, authID pgtype.UUID) (int64, error)
 	GetSubscriptionsByUserID(ctx context.Context, subscriberID pgtype.UUID) ([]pgtype.UUID, error)
+	GetUserByAuthID(ctx context.Context, authID pgtype.UUID) (User, error)
 	GetUserByID(ctx context.Context, id pgtype.UUID) (User, error)
+	MarkAccountDeleted(ctx context.Context, authID pgtype.UUID) error
+	ProvisionUser(ctx context.Context, arg ProvisionUserParams) (int64, error)
 	UpdateUser(ctx context.Context, arg UpdateUserParams) error
 }
 
@@ -54,6 +54,41 @@ func (q *Queries) DeleteUser(ctx context.Context, id pgtype.UUID) error {
 	return err
 }
 
+const deleteUserByAuthID = `-- name: DeleteUserByAuthID :execrows
+DELETE FROM users
+WHERE auth_id = $1
+`
+
+func (q *Queries) DeleteUserByAuthID(ctx context.Context, authID pgtype.UUID) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteUserByAuthID, authID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const getUserByAuthID = `-- name: GetUserByAuthID :one
+SELECT id, auth_id, full_name, bio, photo_link, created_at, updated_at
+FROM users
+WHERE auth_id = $1
+LIMIT 1
+`
+
+func (q *Queries) GetUserByAuthID(ctx context.Context, authID pgtype.UUID) (User, error) {
+	row := q.db.QueryRow(ctx, getUserByAuthID, authID)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.AuthID,
+		&i.FullName,
+		&i.Bio,
+		&i.PhotoLink,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
 const getUserByID = `-- name: GetUserByID :one
 SELECT id, auth_id, full_name, bio, photo_link, created_at, updated_at
 FROM users
@@ -76,6 +111,39 @@ func (q *Queries) GetUserByID(ctx context.Context, id pgtype.UUID) (User, error)
 	return i, err
 }
 
+const markAccountDeleted = `-- name: MarkAccountDeleted :exec
+INSERT INTO deleted_accounts (auth_id)
+VALUES ($1)
+ON CONFLICT (auth_id) DO NOTHING
+`
+
+func (q *Queries) MarkAccountDeleted(ctx context.Context, authID pgtype.UUID) error {
+	_, err := q.db.Exec(ctx, markAccountDeleted, authID)
+	return err
+}
+
+const provisionUser = `-- name: ProvisionUser :execrows
+INSERT INTO users (auth_id, full_name)
+SELECT $1::uuid, $2::varchar
+WHERE NOT EXISTS (
+    SELECT 1 FROM deleted_accounts WHERE auth_id = $1::uuid
+)
+ON CONFLICT (auth_id) DO NOTHING
+`
+
+type ProvisionUserParams struct {
+	AuthID   pgtype.UUID `json:"auth_id"`
+	FullName string      `json:"full_name"`
+}
+
+func (q *Queries) ProvisionUser(ctx context.Context, arg ProvisionUserParams) (int64, error) {
+	result, err := q.db.Exec(ctx, provisionUser, arg.AuthID, arg.FullName)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const updateUser = `-- name: UpdateUser :exec
 UPDATE users
 SET full_name = COALESCE($1, full_name),
@@ -19,13 +19,14 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	UserService_CreateProfile_FullMethodName     = "/pb.UserService/CreateProfile"
-	UserService_GetProfile_FullMethodName        = "/pb.UserService/GetProfile"
-	UserService_UpdateProfile_FullMethodName     = "/pb.UserService/UpdateProfile"
-	UserService_DeleteProfile_FullMethodName     = "/pb.UserService/DeleteProfile"
-	UserService_Subscribe_FullMethodName         = "/pb.UserService/Subscribe"
-	UserService_Unsubscribe_FullMethodName       = "/pb.UserService/Unsubscribe"
-	UserService_ListSubscriptions_FullMethodName = "/pb.UserService/ListSubscriptions"
+	UserService_CreateProfile_FullMethodName      = "/pb.UserService/CreateProfile"
+	UserService_GetProfile_FullMethodName         = "/pb.UserService/GetProfile"
+	UserService_GetProfileByAuthId_FullMethodName = "/pb.UserService/GetProfileByAuthId"
+	UserService_UpdateProfile_FullMethodName      = "/pb.UserService/UpdateProfile"
+	UserService_DeleteProfile_FullMethodName      = "/pb.UserService/DeleteProfile"
+	UserService_Subscribe_FullMethodName          = "/pb.UserService/Subscribe"
+	UserService_Unsubscribe_FullMethodName        = "/pb.UserService/Unsubscribe"
+	UserService_ListSubscriptions_FullMethodName  = "/pb.UserService/ListSubscriptions"
 )
 
 // UserServiceClient is the client API for UserService service.
@@ -34,6 +35,7 @@ const (
 type UserServiceClient interface {
 	CreateProfile(ctx context.Context, in *CreateProfileRequest, opts ...grpc.CallOption) (*UserProfile, error)
 	GetProfile(ctx context.Context, in *GetProfileRequest, opts ...grpc.CallOption) (*UserProfile, error)
+	GetProfileByAuthId(ctx context.Context, in *GetProfileByAuthIdRequest, opts ...grpc.CallOption) (*UserProfile, error)
 	UpdateProfile(ctx context.Context, in *UpdateProfileRequest, opts ...grpc.CallOption) (*UserProfile, error)
 	DeleteProfile(ctx context.Context, in *DeleteProfileRequest, opts ...grpc.CallOption) (*Empty, error)
 	Subscribe(ctx context.Context, in *ModifySubscriptionRequest, opts ...grpc.CallOption) (*Empty, error)
@@ -69,6 +71,16 @@ func (c *userServiceClient) GetProfile(ctx context.Context, in *GetProfileReques
 	return out, nil
 }
 
+func (c *userServiceClient) GetProfileByAuthId(ctx context.Context, in *GetProfileByAuthIdRequest, opts ...grpc.CallOption) (*UserProfile, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(UserProfile)
+	err := c.cc.Invoke(ctx, UserService_GetProfileByAuthId_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *userServiceClient) UpdateProfile(ctx context.Context, in *UpdateProfileRequest, opts ...grpc.CallOption) (*UserProfile, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(UserProfile)
@@ -125,6 +137,7 @@ func (c *userServiceClient) ListSubscriptions(ctx context.Context, in *ListSubsc
 type UserServiceServer interface {
 	CreateProfile(context.Context, *CreateProfileRequest) (*UserProfile, error)
 	GetProfile(context.Context, *GetProfileRequest) (*UserProfile, error)
+	GetProfileByAuthId(context.Context, *GetProfileByAuthIdRequest) (*UserProfile, error)
 	UpdateProfile(context.Context, *UpdateProfileRequest) (*UserProfile, error)
 	DeleteProfile(context.Context, *DeleteProfileRequest) (*Empty, error)
 	Subscribe(context.Context, *ModifySubscriptionRequest) (*Empty, error)
@@ -146,6 +159,9 @@ func (UnimplementedUserServiceServer) CreateProfile(context.Context, *CreateProf
 func (UnimplementedUserServiceServer) GetProfile(context.Context, *GetProfileRequest) (*UserProfile, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetProfile not implemented")
 }
+func (UnimplementedUserServiceServer) GetProfileByAuthId(context.Context, *GetProfileByAuthIdRequest) (*UserProfile, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetProfileByAuthId not implemented")
+}
 func (UnimplementedUserServiceServer) UpdateProfile(context.Context, *UpdateProfileRequest) (*UserProfile, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method UpdateProfile not implemented")
 }
@@ -218,6 +234,24 @@ func _UserService_GetProfile_Handler(srv interface{}, ctx context.Context, dec f
 	return interceptor(ctx, in, info, handler)
 }
 
+func _UserService_GetProfileByAuthId_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetProfileByAuthIdRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServiceServer).GetProfileByAuthId(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: UserService_GetProfileByAuthId_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServiceServer).GetProfileByAuthId(ctx, req.(*GetProfileByAuthIdRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _UserService_UpdateProfile_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(UpdateProfileRequest)
 	if err := dec(in); err != nil {
@@ -323,6 +357,10 @@ var UserService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetProfile",
 			Handler:    _UserService_GetProfile_Handler,
 		},
+		{
+			MethodName: "GetProfileByAuthId",
+			Handler:    _UserService_GetProfileByAuthId_Handler,
+		},
 		{
 			MethodName: "UpdateProfile",
 			Handler:    _UserService_UpdateProfile_Handler,
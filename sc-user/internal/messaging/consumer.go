@@ -0,0 +1,174 @@
+package messaging
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/segmentio/kafka-go"
+
+	"soul-connect/sc-user/internal/config"
+)
+
+// UserRegisteredEvent and UserDeletedEvent mirror the payloads sc-auth publishes.
+type UserRegisteredEvent struct {
+	ID         string    `json:"id"`
+	UserID     string    `json:"user_id"`
+	Username   string    `json:"username"`
+	Email      string    `json:"email"`
+	OccurredAt time.Time `json:"occurred_at"`
+}
+
+type UserDeletedEvent struct {
+	ID         string    `json:"id"`
+	UserID     string    `json:"user_id"`
+	OccurredAt time.Time `json:"occurred_at"`
+}
+
+// AccountService is the part of the user service driven by account events.
+type AccountService interface {
+	ProvisionProfile(ctx context.Context, authID string, username string) error
+	RemoveAccount(ctx context.Context, authID string) error
+}
+
+// errInvalidEvent marks messages that can never be handled. They are skipped instead of retried.
+var errInvalidEvent = errors.New("invalid account event")
+
+const retryDelay = time.Second
+
+// AccountEventsConsumer keeps profiles in line with the accounts in sc-auth. Both event
+// types are handled idempotently, so redelivered messages do no harm.
+type AccountEventsConsumer struct {
+	reader          *kafka.Reader
+	service         AccountService
+	registeredTopic string
+	deletedTopic    string
+	retryDelay      time.Duration
+}
+
+func NewAccountEventsConsumer(cfg *config.Config, svc AccountService) (*AccountEventsConsumer, error) {
+	if cfg.KafkaBrokers == "" || cfg.KafkaUserRegisteredTopic == "" || cfg.KafkaUserDeletedTopic == "" {
+		return nil, errors.New("kafka configuration is incomplete")
+	}
+
+	brokers := parseBrokers(cfg.KafkaBrokers)
+	if len(brokers) == 0 {
+		return nil, errors.New("kafka brokers are empty")
+	}
+
+	reader := kafka.NewReader(kafka.ReaderConfig{
+		Brokers:     brokers,
+		GroupID:     cfg.KafkaGroupID,
+		GroupTopics: []string{cfg.KafkaUserRegisteredTopic, cfg.KafkaUserDeletedTopic},
+	})
+
+	return &AccountEventsConsumer{
+		reader:          reader,
+		service:         svc,
+		registeredTopic: cfg.KafkaUserRegisteredTopic,
+		deletedTopic:    cfg.KafkaUserDeletedTopic,
+		retryDelay:      retryDelay,
+	}, nil
+}
+
+func (c *AccountEventsConsumer) Start(ctx context.Context) {
+	for {
+		msg, err := c.reader.FetchMessage(ctx)
+		if err != nil {
+			if errors.Is(err, context.Canceled) {
+				return
+			}
+			log.Printf("account events consumer: fetch message error: %v", err)
+			time.Sleep(time.Second)
+			continue
+		}
+
+		if !c.process(ctx, msg) {
+			return
+		}
+
+		if err := c.reader.CommitMessages(ctx, msg); err != nil {
+			log.Printf("account events consumer: commit message error: %v", err)
+		}
+	}
+}
+
+// process handles a message until it succeeds or turns out to be invalid. Other errors
+// are retried, skipping them would leave profiles of deleted accounts behind. It returns
+// false once the context is done.
+func (c *AccountEventsConsumer) process(ctx context.Context, msg kafka.Message) bool {
+	for {
+		err := c.handleMessage(ctx, msg)
+		if err == nil {
+			return true
+		}
+		if errors.Is(err, errInvalidEvent) {
+			log.Printf("account events consumer: skipping message at %s/%d/%d: %v", msg.Topic, msg.Partition, msg.Offset, err)
+			return true
+		}
+
+		log.Printf("account events consumer: handle message error, retrying: %v", err)
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(c.retryDelay):
+		}
+	}
+}
+
+func (c *AccountEventsConsumer) handleMessage(ctx context.Context, msg kafka.Message) error {
+	switch msg.Topic {
+	case c.registeredTopic:
+		var event UserRegisteredEvent
+		if err := decodeEvent(msg.Value, &event, &event.UserID); err != nil {
+			return err
+		}
+		if event.Username == "" {
+			return fmt.Errorf("%w: username is empty", errInvalidEvent)
+		}
+		return c.service.ProvisionProfile(ctx, event.UserID, event.Username)
+	case c.deletedTopic:
+		var event UserDeletedEvent
+		if err := decodeEvent(msg.Value, &event, &event.UserID); err != nil {
+			return err
+		}
+		return c.service.RemoveAccount(ctx, event.UserID)
+	default:
+		return fmt.Errorf("%w: unexpected topic %q", errInvalidEvent, msg.Topic)
+	}
+}
+
+// decodeEvent unmarshals the payload and checks that the user id of the event is a UUID.
+func decodeEvent(payload []byte, event any, userID *string) error {
+	if err := json.Unmarshal(payload, event); err != nil {
+		return fmt.Errorf("%w: %v", errInvalidEvent, err)
+	}
+	if _, err := uuid.Parse(*userID); err != nil {
+		return fmt.Errorf("%w: user id %q: %v", errInvalidEvent, *userID, err)
+	}
+	return nil
+}
+
+func (c *AccountEventsConsumer) Close() error {
+	if c.reader == nil {
+		return nil
+	}
+	return c.reader.Close()
+}
+
+func parseBrokers(raw string) []string {
+	parts := strings.Split(raw, ",")
+	brokers := make([]string, 0, len(parts))
+	for _, part := range parts {
+		trimmed := strings.TrimSpace(part)
+		if trimmed != "" {
+			brokers = append(brokers, trimmed)
+		}
+	}
+	return brokers
+}
@@ -0,0 +1,115 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/require"
+)
+
+type stubAccountService struct {
+	ProvisionProfileFn func(ctx context.Context, authID string, username string) error
+	RemoveAccountFn    func(ctx context.Context, authID string) error
+}
+
+func (s *stubAccountService) ProvisionProfile(ctx context.Context, authID string, username string) error {
+	return s.ProvisionProfileFn(ctx, authID, username)
+}
+
+func (s *stubAccountService) RemoveAccount(ctx context.Context, authID string) error {
+	return s.RemoveAccountFn(ctx, authID)
+}
+
+func newTestConsumer(svc AccountService) *AccountEventsConsumer {
+	return &AccountEventsConsumer{
+		service:         svc,
+		registeredTopic: "user.registered",
+		deletedTopic:    "user.deleted",
+		retryDelay:      time.Millisecond,
+	}
+}
+
+func TestAccountEventsConsumer_HandlesRegisteredAndDeletedEvents(t *testing.T) {
+	const userID = "7f1d3c52-1f7a-4b7e-9f0a-2d5b6c8e9a10"
+	var provisioned, removed []string
+	consumer := newTestConsumer(&stubAccountService{
+		ProvisionProfileFn: func(ctx context.Context, authID string, username string) error {
+			provisioned = append(provisioned, authID+":"+username)
+			return nil
+		},
+		RemoveAccountFn: func(ctx context.Context, authID string) error {
+			removed = append(removed, authID)
+			return nil
+		},
+	})
+
+	require.NoError(t, consumer.handleMessage(context.Background(), kafka.Message{
+		Topic: "user.registered",
+		Value: []byte(`{"id":"e1","user_id":"` + userID + `","username":"alice","email":"alice@example.com"}`),
+	}))
+	require.NoError(t, consumer.handleMessage(context.Background(), kafka.Message{
+		Topic: "user.deleted",
+		Value: []byte(`{"id":"e2","user_id":"` + userID + `"}`),
+	}))
+
+	require.Equal(t, []string{userID + ":alice"}, provisioned)
+	require.Equal(t, []string{userID}, removed)
+}
+
+func TestAccountEventsConsumer_RejectsInvalidEvents(t *testing.T) {
+	consumer := newTestConsumer(&stubAccountService{})
+
+	for name, msg := range map[string]kafka.Message{
+		"malformed json":  {Topic: "user.registered", Value: []byte(`{`)},
+		"invalid user id": {Topic: "user.deleted", Value: []byte(`{"user_id":"nope"}`)},
+		"missing username": {
+			Topic: "user.registered",
+			Value: []byte(`{"user_id":"7f1d3c52-1f7a-4b7e-9f0a-2d5b6c8e9a10"}`),
+		},
+		"unknown topic": {Topic: "user.logged_in", Value: []byte(`{}`)},
+	} {
+		err := consumer.handleMessage(context.Background(), msg)
+		require.ErrorIs(t, err, errInvalidEvent, name)
+	}
+}
+
+func TestAccountEventsConsumer_RetriesUntilTheServiceSucceeds(t *testing.T) {
+	calls := 0
+	consumer := newTestConsumer(&stubAccountService{
+		RemoveAccountFn: func(ctx context.Context, authID string) error {
+			calls++
+			if calls < 3 {
+				return errors.New("database is unavailable")
+			}
+			return nil
+		},
+	})
+
+	handled := consumer.process(context.Background(), kafka.Message{
+		Topic: "user.deleted",
+		Value: []byte(`{"user_id":"7f1d3c52-1f7a-4b7e-9f0a-2d5b6c8e9a10"}`),
+	})
+
+	require.True(t, handled)
+	require.Equal(t, 3, calls)
+}
+
+func TestAccountEventsConsumer_StopsRetryingWhenTheContextIsDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	consumer := newTestConsumer(&stubAccountService{
+		RemoveAccountFn: func(context.Context, string) error {
+			cancel()
+			return errors.New("database is unavailable")
+		},
+	})
+
+	handled := consumer.process(ctx, kafka.Message{
+		Topic: "user.deleted",
+		Value: []byte(`{"user_id":"7f1d3c52-1f7a-4b7e-9f0a-2d5b6c8e9a10"}`),
+	})
+
+	require.False(t, handled)
+}
@@ -10,8 +10,14 @@ import (
 type IUserRepository interface {
 	Create(ctx context.Context, input models.CreateUserProfileInput) (*models.UserProfile, error)
 	GetByID(ctx context.Context, id string) (*models.UserProfile, error)
+	GetByAuthID(ctx context.Context, authID string) (*models.UserProfile, error)
 	Update(ctx context.Context, params models.UpdateUserProfileParams) (*models.UserProfile, error)
 	Delete(ctx context.Context, id string) error
+	// Provision creates a profile for an account unless it already has one or was deleted.
+	// It reports whether a profile was created.
+	Provision(ctx context.Context, authID string, fullName string) (bool, error)
+	// DeleteByAuthID deletes the profile of an account and remembers the account as deleted.
+	DeleteByAuthID(ctx context.Context, authID string) error
 }
 
 type UserRepository struct {
@@ -55,6 +61,20 @@ func (r *UserRepository) GetByID(ctx context.Context, id string) (*models.UserPr
 	return userToModel(user), nil
 }
 
+func (r *UserRepository) GetByAuthID(ctx context.Context, authID string) (*models.UserProfile, error) {
+	accountID, err := stringToUUID(authID)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := r.queries.GetUserByAuthID(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	return userToModel(user), nil
+}
+
 func (r *UserRepository) Update(ctx context.Context, params models.UpdateUserProfileParams) (*models.UserProfile, error) {
 	userID, err := stringToUUID(params.ID)
 	if err != nil {
@@ -87,4 +107,37 @@ func (r *UserRepository) Delete(ctx context.Context, id string) error {
 	return r.queries.DeleteUser(ctx, userID)
 }
 
+func (r *UserRepository) Provision(ctx context.Context, authID string, fullName string) (bool, error) {
+	accountID, err := stringToUUID(authID)
+	if err != nil {
+		return false, err
+	}
+
+	created, err := r.queries.ProvisionUser(ctx, db.ProvisionUserParams{
+		AuthID:   accountID,
+		FullName: fullName,
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return created > 0, nil
+}
+
+func (r *UserRepository) DeleteByAuthID(ctx context.Context, authID string) error {
+	accountID, err := stringToUUID(authID)
+	if err != nil {
+		return err
+	}
+
+	// The account is marked first, so a registration event that arrives late is ignored
+	// even if the delete below has to be retried.
+	if err := r.queries.MarkAccountDeleted(ctx, accountID); err != nil {
+		return err
+	}
+
+	_, err = r.queries.DeleteUserByAuthID(ctx, accountID)
+	return err
+}
+
 var _ IUserRepository = (*UserRepository)(nil)
@@ -2,8 +2,12 @@ package server
 
 import (
 	"context"
+	"errors"
 	"time"
 
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"soul-connect/sc-user/internal/generated"
 	"soul-connect/sc-user/internal/models"
 	"soul-connect/sc-user/internal/services"
@@ -36,7 +40,7 @@ func (s *UserServer) CreateProfile(ctx context.Context, request *generated.Creat
 
 	profile, err := s.userService.CreateProfile(ctx, input)
 	if err != nil {
-		return nil, err
+		return nil, userStatus(err)
 	}
 
 	return toProtoProfile(profile), nil
@@ -45,7 +49,15 @@ func (s *UserServer) CreateProfile(ctx context.Context, request *generated.Creat
 func (s *UserServer) GetProfile(ctx context.Context, request *generated.GetProfileRequest) (*generated.UserProfile, error) {
 	profile, err := s.userService.GetProfile(ctx, request.Id)
 	if err != nil {
-		return nil, err
+		return nil, userStatus(err)
+	}
+	return toProtoProfile(profile), nil
+}
+
+func (s *UserServer) GetProfileByAuthId(ctx context.Context, request *generated.GetProfileByAuthIdRequest) (*generated.UserProfile, error) {
+	profile, err := s.userService.GetProfileByAuthID(ctx, request.AuthId)
+	if err != nil {
+		return nil, userStatus(err)
 	}
 	return toProtoProfile(profile), nil
 }
@@ -67,14 +79,14 @@ func (s *UserServer) UpdateProfile(ctx context.Context, request *generated.Updat
 
 	profile, err := s.userService.UpdateProfile(ctx, input)
 	if err != nil {
-		return nil, err
+		return nil, userStatus(err)
 	}
 	return toProtoProfile(profile), nil
 }
 
 func (s *UserServer) DeleteProfile(ctx context.Context, request *generated.DeleteProfileRequest) (*generated.Empty, error) {
 	if err := s.userService.DeleteProfile(ctx, request.Id); err != nil {
-		return nil, err
+		return nil, userStatus(err)
 	}
 	return &generated.Empty{}, nil
 }
@@ -85,7 +97,7 @@ func (s *UserServer) Subscribe(ctx context.Context, request *generated.ModifySub
 		AuthorID:     request.AuthorId,
 	}
 	if err := s.userService.Subscribe(ctx, input); err != nil {
-		return nil, err
+		return nil, userStatus(err)
 	}
 	return &generated.Empty{}, nil
 }
@@ -96,7 +108,7 @@ func (s *UserServer) Unsubscribe(ctx context.Context, request *generated.ModifyS
 		AuthorID:     request.AuthorId,
 	}
 	if err := s.userService.Unsubscribe(ctx, input); err != nil {
-		return nil, err
+		return nil, userStatus(err)
 	}
 	return &generated.Empty{}, nil
 }
@@ -104,7 +116,7 @@ func (s *UserServer) Unsubscribe(ctx context.Context, request *generated.ModifyS
 func (s *UserServer) ListSubscriptions(ctx context.Context, request *generated.ListSubscriptionsRequest) (*generated.ListSubscriptionsResponse, error) {
 	result, err := s.userService.ListSubscriptions(ctx, request.SubscriberId)
 	if err != nil {
-		return nil, err
+		return nil, userStatus(err)
 	}
 	return &generated.ListSubscriptionsResponse{
 		SubscriberId: result.SubscriberID,
@@ -133,3 +145,36 @@ func toProtoProfile(profile *models.UserProfile) *generated.UserProfile {
 	}
 	return response
 }
+
+// userStatus maps the errors of the service to gRPC status codes. Anything unexpected
+// is reported as Internal, which requestctx.UnaryServerInterceptor logs and the gateway
+// does not pass on.
+func userStatus(err error) error {
+	var validationErr *services.ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		return fieldStatus(codes.InvalidArgument, validationErr.Field, validationErr.Message)
+	case errors.Is(err, services.ErrProfileNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, services.ErrProfileExists):
+		return status.Error(codes.AlreadyExists, err.Error())
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return status.FromContextError(err).Err()
+	}
+	return status.Error(codes.Internal, err.Error())
+}
+
+// fieldStatus reports an invalid request with a BadRequest detail naming the field.
+func fieldStatus(code codes.Code, field string, message string) error {
+	st := status.New(code, message)
+	detailed, err := st.WithDetails(&errdetails.BadRequest{
+		FieldViolations: []*errdetails.BadRequest_FieldViolation{{
+			Field:       field,
+			Description: message,
+		}},
+	})
+	if err != nil {
+		return st.Err()
+	}
+	return detailed.Err()
+}
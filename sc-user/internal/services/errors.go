@@ -0,0 +1,50 @@
+package services
+
+import (
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Postgres error codes the service turns into domain errors.
+const (
+	uniqueViolationCode     = "23505"
+	foreignKeyViolationCode = "23503"
+)
+
+var (
+	ErrProfileNotFound = errors.New("profile not found")
+	ErrProfileExists   = errors.New("the account already has a profile")
+)
+
+// ValidationError reports a request field the service rejected. Field is named like in
+// the gRPC request, so it can be passed on to clients.
+type ValidationError struct {
+	Field   string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return e.Message
+}
+
+func requiredError(field string) error {
+	return &ValidationError{Field: field, Message: field + " is required"}
+}
+
+// validateID checks that a field holds a UUID, which every id of the service is.
+func validateID(field string, value string) error {
+	if value == "" {
+		return requiredError(field)
+	}
+	if _, err := uuid.Parse(value); err != nil {
+		return &ValidationError{Field: field, Message: field + " must be a UUID"}
+	}
+	return nil
+}
+
+func hasPgErrorCode(err error, code string) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == code
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 
+	"github.com/jackc/pgx/v5"
 	"soul-connect/sc-user/internal/models"
 	"soul-connect/sc-user/internal/repositories"
 )
@@ -22,27 +23,47 @@ func NewUserService(userRepo repositories.IUserRepository, subscriptionRepo repo
 
 func (s *UserService) CreateProfile(ctx context.Context, input models.CreateUserProfileInput) (*models.UserProfile, error) {
 	if input.FullName == "" {
-		return nil, errors.New("full name is required")
+		return nil, requiredError("full_name")
 	}
-	if input.AuthID == "" {
-		return nil, errors.New("auth id is required")
+	if err := validateID("auth_id", input.AuthID); err != nil {
+		return nil, err
+	}
+
+	profile, err := s.userRepo.Create(ctx, input)
+	if hasPgErrorCode(err, uniqueViolationCode) {
+		return nil, ErrProfileExists
 	}
-	return s.userRepo.Create(ctx, input)
+	return profile, err
 }
 
 func (s *UserService) GetProfile(ctx context.Context, id string) (*models.UserProfile, error) {
-	if id == "" {
-		return nil, errors.New("user id is required")
+	if err := validateID("id", id); err != nil {
+		return nil, err
+	}
+	return s.getProfile(ctx, id)
+}
+
+// GetProfileByAuthID returns the profile of an sc-auth account.
+func (s *UserService) GetProfileByAuthID(ctx context.Context, authID string) (*models.UserProfile, error) {
+	if err := validateID("auth_id", authID); err != nil {
+		return nil, err
+	}
+	profile, err := s.userRepo.GetByAuthID(ctx, authID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrProfileNotFound
 	}
-	return s.userRepo.GetByID(ctx, id)
+	return profile, err
 }
 
 func (s *UserService) UpdateProfile(ctx context.Context, input models.UpdateUserProfileInput) (*models.UserProfile, error) {
-	if input.ID == "" {
-		return nil, errors.New("user id is required")
+	if err := validateID("id", input.ID); err != nil {
+		return nil, err
+	}
+	if input.FullName != nil && *input.FullName == "" {
+		return nil, &ValidationError{Field: "full_name", Message: "full_name must not be empty"}
 	}
 
-	existing, err := s.userRepo.GetByID(ctx, input.ID)
+	existing, err := s.getProfile(ctx, input.ID)
 	if err != nil {
 		return nil, err
 	}
@@ -63,32 +84,59 @@ func (s *UserService) UpdateProfile(ctx context.Context, input models.UpdateUser
 }
 
 func (s *UserService) DeleteProfile(ctx context.Context, id string) error {
-	if id == "" {
-		return errors.New("user id is required")
+	if err := validateID("id", id); err != nil {
+		return err
 	}
 	return s.userRepo.Delete(ctx, id)
 }
 
+// ProvisionProfile creates the default profile of a newly registered account, named after
+// its username. Repeated registration events and accounts that were deleted meanwhile are ignored.
+func (s *UserService) ProvisionProfile(ctx context.Context, authID string, username string) error {
+	if authID == "" {
+		return errors.New("auth id is required")
+	}
+	if username == "" {
+		return errors.New("username is required")
+	}
+	_, err := s.userRepo.Provision(ctx, authID, username)
+	return err
+}
+
+// RemoveAccount deletes the profile of an account that was deleted in sc-auth. Subscriptions
+// of and to the profile are removed with it. Deleting an unknown account is not an error.
+func (s *UserService) RemoveAccount(ctx context.Context, authID string) error {
+	if authID == "" {
+		return errors.New("auth id is required")
+	}
+	return s.userRepo.DeleteByAuthID(ctx, authID)
+}
+
 func (s *UserService) Subscribe(ctx context.Context, input models.ModifySubscriptionInput) error {
-	if input.SubscriberID == "" || input.AuthorID == "" {
-		return errors.New("subscriber and author ids are required")
+	if err := validateSubscription(input); err != nil {
+		return err
 	}
 	if input.SubscriberID == input.AuthorID {
-		return errors.New("subscriber and author cannot be the same user")
+		return &ValidationError{Field: "author_id", Message: "subscriber and author cannot be the same user"}
 	}
-	return s.subscriptionRepo.Subscribe(ctx, input.SubscriberID, input.AuthorID)
+
+	err := s.subscriptionRepo.Subscribe(ctx, input.SubscriberID, input.AuthorID)
+	if hasPgErrorCode(err, foreignKeyViolationCode) {
+		return ErrProfileNotFound
+	}
+	return err
 }
 
 func (s *UserService) Unsubscribe(ctx context.Context, input models.ModifySubscriptionInput) error {
-	if input.SubscriberID == "" || input.AuthorID == "" {
-		return errors.New("subscriber and author ids are required")
+	if err := validateSubscription(input); err != nil {
+		return err
 	}
 	return s.subscriptionRepo.Unsubscribe(ctx, input.SubscriberID, input.AuthorID)
 }
 
 func (s *UserService) ListSubscriptions(ctx context.Context, subscriberID string) (*models.SubscriptionList, error) {
-	if subscriberID == "" {
-		return nil, errors.New("subscriber id is required")
+	if err := validateID("subscriber_id", subscriberID); err != nil {
+		return nil, err
 	}
 
 	authorIDs, err := s.subscriptionRepo.ListAuthorIDs(ctx, subscriberID)
@@ -101,3 +149,19 @@ func (s *UserService) ListSubscriptions(ctx context.Context, subscriberID string
 		AuthorIDs:    authorIDs,
 	}, nil
 }
+
+// getProfile reads a profile and reports a missing one as ErrProfileNotFound.
+func (s *UserService) getProfile(ctx context.Context, id string) (*models.UserProfile, error) {
+	profile, err := s.userRepo.GetByID(ctx, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrProfileNotFound
+	}
+	return profile, err
+}
+
+func validateSubscription(input models.ModifySubscriptionInput) error {
+	if err := validateID("subscriber_id", input.SubscriberID); err != nil {
+		return err
+	}
+	return validateID("author_id", input.AuthorID)
+}
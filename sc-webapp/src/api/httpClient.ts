@@ -8,8 +8,15 @@ export interface HttpRequestOptions
   headers?: HeadersInit;
 }
 
+export interface HttpErrorField {
+  field: string;
+  description: string;
+}
+
 export interface HttpErrorPayload {
+  code?: string;
   message?: string;
+  fields?: HttpErrorField[];
   [key: string]: unknown;
 }
 